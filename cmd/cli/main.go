@@ -6,8 +6,10 @@ import (
 	"os"
 
 	"github.com/ray-d-song/go-echo-monolithic/internal/app"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/jwt"
 	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/logger"
 	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
 	"github.com/spf13/cobra"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
@@ -62,15 +64,20 @@ var rollbackCmd = &cobra.Command{
 var cleanupCmd = &cobra.Command{
 	Use:   "cleanup",
 	Short: "Cleanup expired tokens",
-	Long:  "Remove expired refresh tokens from the database",
+	Long:  "Remove expired tokens and stale failed login records from the database",
 	Run: func(cmd *cobra.Command, args []string) {
-		runWithDI(func(authRepo *repository.AuthRepository, logger *logger.Logger) {
+		runWithDI(func(authRepo *repository.AuthRepository, lockoutService *service.LockoutService, logger *logger.Logger) {
 			logger.Info("Cleaning up expired tokens...")
 			
 			if err := authRepo.CleanupExpiredTokens(); err != nil {
 				logger.Error("Cleanup failed", zap.Error(err))
 				return
 			}
+
+			if err := lockoutService.Cleanup(); err != nil {
+				logger.Error("Failed to clean up login throttles", zap.Error(err))
+				return
+			}
 			
 			logger.Info("Token cleanup completed successfully")
 		})
@@ -95,6 +102,133 @@ var seedCmd = &cobra.Command{
 	},
 }
 
+var jwtCmd = &cobra.Command{
+	Use:   "jwt",
+	Short: "Manage JWT signing keys",
+	Long:  "Rotate, retire and list the keys in the JWT key ring (requires jwt.key_ring_file)",
+}
+
+var jwtRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Generate a new signing key",
+	Long:  "Generate a new active signing key; the previous key keeps verifying existing tokens",
+	Run: func(cmd *cobra.Command, args []string) {
+		alg, _ := cmd.Flags().GetString("alg")
+		runWithDI(func(jwtManager *jwt.Manager, logger *logger.Logger) {
+			entry, err := jwtManager.RotateKey(alg)
+			if err != nil {
+				logger.Fatal("Key rotation failed", zap.Error(err))
+				return
+			}
+
+			logger.Info("Signing key rotated", zap.String("kid", entry.ID), zap.String("alg", entry.Algorithm))
+		})
+	},
+}
+
+var jwtRetireCmd = &cobra.Command{
+	Use:   "retire <kid>",
+	Short: "Retire a verification key",
+	Long:  "Retire a verify-only key; tokens it signed stay valid until they expire",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runWithDI(func(jwtManager *jwt.Manager, logger *logger.Logger) {
+			if err := jwtManager.RetireKey(args[0]); err != nil {
+				logger.Fatal("Key retirement failed", zap.Error(err))
+				return
+			}
+
+			logger.Info("Key retired", zap.String("kid", args[0]))
+		})
+	},
+}
+
+var jwtListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List signing and verification keys",
+	Run: func(cmd *cobra.Command, args []string) {
+		runWithDI(func(jwtManager *jwt.Manager) {
+			for _, entry := range jwtManager.Keys() {
+				fmt.Printf("%-48s %-6s %s\n", entry.ID, entry.Algorithm, entry.Status)
+			}
+		})
+	},
+}
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage users",
+}
+
+var userUnlockCmd = &cobra.Command{
+	Use:   "unlock <username>",
+	Short: "Unlock a user account",
+	Long:  "Clear the failed login attempts and lock of a user account",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runWithDI(func(userService *service.UserService, lockoutService *service.LockoutService, logger *logger.Logger) {
+			user, err := userService.GetByUsername(args[0])
+			if err != nil {
+				logger.Fatal("Failed to find user", zap.String("username", args[0]), zap.Error(err))
+				return
+			}
+
+			if err := lockoutService.Unlock(user, nil); err != nil {
+				logger.Fatal("Unlock failed", zap.Error(err))
+				return
+			}
+
+			logger.Info("User unlocked", zap.String("username", user.Username))
+		})
+	},
+}
+
+var userGrantCmd = &cobra.Command{
+	Use:   "grant <username> <role>",
+	Short: "Grant a role to a user",
+	Long:  "Grant a role to a user, e.g. to bootstrap the first administrator. Takes effect on the user's next token refresh.",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		runWithDI(func(userService *service.UserService, rbacService *service.RBACService, logger *logger.Logger) {
+			user, err := userService.GetByUsername(args[0])
+			if err != nil {
+				logger.Fatal("Failed to find user", zap.String("username", args[0]), zap.Error(err))
+				return
+			}
+
+			if err := rbacService.AssignRole(user, args[1]); err != nil {
+				logger.Fatal("Grant failed", zap.String("role", args[1]), zap.Error(err))
+				return
+			}
+
+			logger.Info("Role granted", zap.String("username", user.Username), zap.String("role", args[1]))
+		})
+	},
+}
+
+var userRevokeCmd = &cobra.Command{
+	Use:   "revoke <username> <role>",
+	Short: "Revoke a role from a user",
+	Long:  "Revoke a role from a user. Takes effect on the user's next token refresh.",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		runWithDI(func(userService *service.UserService, rbacService *service.RBACService, logger *logger.Logger) {
+			user, err := userService.GetByUsername(args[0])
+			if err != nil {
+				logger.Fatal("Failed to find user", zap.String("username", args[0]), zap.Error(err))
+				return
+			}
+
+			if err := rbacService.RevokeRole(user, args[1]); err != nil {
+				logger.Fatal("Revoke failed", zap.String("role", args[1]), zap.Error(err))
+				return
+			}
+
+			logger.Info("Role revoked", zap.String("username", user.Username), zap.String("role", args[1]))
+		})
+	},
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Show version information",
@@ -110,6 +244,17 @@ func init() {
 	rootCmd.AddCommand(seedCmd)
 	rootCmd.AddCommand(cleanupCmd)
 	rootCmd.AddCommand(versionCmd)
+
+	jwtRotateCmd.Flags().String("alg", "", "key algorithm: RS256, EdDSA or HS256 (default: algorithm of the current key)")
+	jwtCmd.AddCommand(jwtRotateCmd)
+	jwtCmd.AddCommand(jwtRetireCmd)
+	jwtCmd.AddCommand(jwtListCmd)
+	rootCmd.AddCommand(jwtCmd)
+
+	userCmd.AddCommand(userUnlockCmd)
+	userCmd.AddCommand(userGrantCmd)
+	userCmd.AddCommand(userRevokeCmd)
+	rootCmd.AddCommand(userCmd)
 }
 
 func main() {
@@ -18,7 +18,12 @@
 //	@securityDefinitions.apikey	BearerAuth
 //	@in							header
 //	@name						Authorization
-//	@description				Type "Bearer" followed by a space and JWT token.
+//	@description				Type "Bearer" followed by a space and JWT token or API key.
+//
+//	@securityDefinitions.apikey	APIKeyAuth
+//	@in							header
+//	@name						X-API-Key
+//	@description				Personal access token created under /users/profile/tokens.
 package main
 
 import (
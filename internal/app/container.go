@@ -1,17 +1,28 @@
 package app
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"github.com/labstack/echo/v4"
 	"github.com/ray-d-song/go-echo-monolithic/internal/config"
 	"github.com/ray-d-song/go-echo-monolithic/internal/handler"
 	"github.com/ray-d-song/go-echo-monolithic/internal/middleware"
 	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/database"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/denylist"
 	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/jwt"
 	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/logger"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/mailer"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/oauth"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/pagination"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/password"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/storage"
 	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/validator"
 	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
 	"github.com/ray-d-song/go-echo-monolithic/internal/service"
 	"go.uber.org/fx"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
@@ -39,9 +50,81 @@ var Container = fx.Options(
 		return jwt.NewManager(&cfg.JWT)
 	}),
 
+	// Token denylist
+	fx.Provide(func(cfg *config.Config, kvRepo *repository.KVRepository) (denylist.Store, error) {
+		switch cfg.Auth.DenylistStore {
+		case "memory":
+			return denylist.NewMemoryStore(), nil
+		case "database":
+			return repository.NewDenylistStore(kvRepo), nil
+		default:
+			return nil, fmt.Errorf("unsupported denylist store: %s", cfg.Auth.DenylistStore)
+		}
+	}),
+	fx.Provide(func(
+		lc fx.Lifecycle,
+		cfg *config.Config,
+		store denylist.Store,
+		kvRepo *repository.KVRepository,
+		jwtManager *jwt.Manager,
+		logger *logger.Logger,
+	) (*denylist.Denylist, error) {
+		purgeInterval, err := time.ParseDuration(cfg.Auth.DenylistPurgeInterval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse denylist purge interval: %w", err)
+		}
+
+		// User cutoffs reject the tokens issued before a password change, so they
+		// are kept in the database whatever the store
+		list := denylist.New(store, repository.NewDenylistStore(kvRepo), jwtManager.GetAccessTokenDuration())
+
+		var stop func()
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				stop = list.StartPurging(purgeInterval, func(err error) {
+					logger.Error("Failed to purge token denylist", zap.Error(err))
+				})
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				stop()
+				return nil
+			},
+		})
+
+		return list, nil
+	}),
+
 	// Validator
 	fx.Provide(validator.NewValidator),
 
+	// Password hashing
+	fx.Provide(func(cfg *config.Config) (*password.Manager, error) {
+		return password.NewManager(&cfg.Password)
+	}),
+
+	// Pagination cursors
+	fx.Provide(func(cfg *config.Config) *pagination.Codec {
+		return pagination.NewCodec(cfg.Pagination.CursorSecret)
+	}),
+
+	// File storage
+	fx.Provide(func(cfg *config.Config) (storage.Storage, error) {
+		return storage.NewStorage(&cfg.Storage)
+	}),
+	fx.Provide(func(cfg *config.Config) (*storage.URLSigner, error) {
+		urlDuration, err := time.ParseDuration(cfg.Storage.URLDuration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse file URL duration: %w", err)
+		}
+		return storage.NewURLSigner(cfg.Server.PublicURL, cfg.Storage.URLSecret, urlDuration), nil
+	}),
+
+	// Mailer
+	fx.Provide(func(cfg *config.Config, logger *logger.Logger) (mailer.Mailer, error) {
+		return mailer.NewMailer(&cfg.Mailer, logger)
+	}),
+
 	// Repositories
 	fx.Provide(func(db *gorm.DB) *repository.UserRepository {
 		return repository.NewUserRepository(db)
@@ -49,28 +132,250 @@ var Container = fx.Options(
 	fx.Provide(func(db *gorm.DB) *repository.AuthRepository {
 		return repository.NewAuthRepository(db)
 	}),
+	fx.Provide(func(db *gorm.DB) *repository.MFARepository {
+		return repository.NewMFARepository(db)
+	}),
+	fx.Provide(func(db *gorm.DB) *repository.AuditRepository {
+		return repository.NewAuditRepository(db)
+	}),
+	fx.Provide(func(db *gorm.DB) *repository.LoginThrottleRepository {
+		return repository.NewLoginThrottleRepository(db)
+	}),
+	fx.Provide(func(db *gorm.DB) *repository.RBACRepository {
+		return repository.NewRBACRepository(db)
+	}),
+	fx.Provide(func(db *gorm.DB) *repository.APIKeyRepository {
+		return repository.NewAPIKeyRepository(db)
+	}),
+	fx.Provide(func(db *gorm.DB) *repository.IdentityRepository {
+		return repository.NewIdentityRepository(db)
+	}),
+	fx.Provide(func(db *gorm.DB) *repository.OAuthClientRepository {
+		return repository.NewOAuthClientRepository(db)
+	}),
+	fx.Provide(func(db *gorm.DB) *repository.InvitationRepository {
+		return repository.NewInvitationRepository(db)
+	}),
+	fx.Provide(func(db *gorm.DB) *repository.PrivacyRepository {
+		return repository.NewPrivacyRepository(db)
+	}),
+	fx.Provide(func(db *gorm.DB) *repository.FileRepository {
+		return repository.NewFileRepository(db)
+	}),
+	fx.Provide(func(db *gorm.DB) *repository.OrganizationRepository {
+		return repository.NewOrganizationRepository(db)
+	}),
 	fx.Provide(func(db *gorm.DB) *repository.Migrator {
 		return repository.NewMigrator(db)
 	}),
 	fx.Provide(func(db *gorm.DB) *repository.KVRepository {
 		return repository.NewKVRepository(db)
 	}),
-	fx.Provide(func(db *gorm.DB, logger *logger.Logger) *repository.Seeder {
-		return repository.NewSeeder(db, logger)
+	fx.Provide(func(db *gorm.DB, logger *logger.Logger, passwords *password.Manager) *repository.Seeder {
+		return repository.NewSeeder(db, logger, passwords)
 	}),
 
 	// Services
-	fx.Provide(func(userRepo *repository.UserRepository) *service.UserService {
-		return service.NewUserService(userRepo)
+	fx.Provide(func(auditRepo *repository.AuditRepository, logger *logger.Logger) *service.AuditService {
+		return service.NewAuditService(auditRepo, logger)
+	}),
+	fx.Provide(func(
+		rbacRepo *repository.RBACRepository,
+		userRepo *repository.UserRepository,
+		kvRepo *repository.KVRepository,
+	) *service.RBACService {
+		return service.NewRBACService(rbacRepo, userRepo, kvRepo)
+	}),
+	fx.Provide(func(
+		cfg *config.Config,
+		apiKeyRepo *repository.APIKeyRepository,
+		userRepo *repository.UserRepository,
+		rbacService *service.RBACService,
+	) *service.APIKeyService {
+		return service.NewAPIKeyService(&cfg.Auth, apiKeyRepo, userRepo, rbacService)
+	}),
+	fx.Provide(func(
+		invitationRepo *repository.InvitationRepository,
+		rbacService *service.RBACService,
+		validator *validator.Validator,
+	) *service.InvitationService {
+		return service.NewInvitationService(invitationRepo, rbacService, validator)
 	}),
 	fx.Provide(func(
+		userRepo *repository.UserRepository,
+		userService *service.UserService,
+		rbacService *service.RBACService,
+		passwordService *service.PasswordService,
+		fileService *service.FileService,
+		auditService *service.AuditService,
+		validator *validator.Validator,
+	) *service.AdminUserService {
+		return service.NewAdminUserService(userRepo, userService, rbacService, passwordService, fileService, auditService, validator)
+	}),
+	fx.Provide(func(
+		cfg *config.Config,
+		throttleRepo *repository.LoginThrottleRepository,
+		auditService *service.AuditService,
+	) (*service.LockoutService, error) {
+		return service.NewLockoutService(&cfg.Lockout, throttleRepo, auditService)
+	}),
+	fx.Provide(func(
+		cfg *config.Config,
+		userRepo *repository.UserRepository,
+		authRepo *repository.AuthRepository,
+		mailer mailer.Mailer,
+		logger *logger.Logger,
+	) (*service.VerificationService, error) {
+		return service.NewVerificationService(cfg, userRepo, authRepo, mailer, logger)
+	}),
+	fx.Provide(func(
+		userRepo *repository.UserRepository,
+		authRepo *repository.AuthRepository,
+		verificationService *service.VerificationService,
+		lockoutService *service.LockoutService,
+		denylist *denylist.Denylist,
+		passwords *password.Manager,
+		cursors *pagination.Codec,
+		fileURLs *storage.URLSigner,
+		validator *validator.Validator,
+	) *service.UserService {
+		return service.NewUserService(userRepo, authRepo, verificationService, lockoutService, denylist, passwords, cursors, fileURLs, validator)
+	}),
+	fx.Provide(func(
+		cfg *config.Config,
+		userRepo *repository.UserRepository,
+		mfaRepo *repository.MFARepository,
+		userService *service.UserService,
+		lockoutService *service.LockoutService,
+	) *service.MFAService {
+		return service.NewMFAService(userRepo, mfaRepo, userService, lockoutService, cfg.Auth.MFAIssuer)
+	}),
+	fx.Provide(func(
+		cfg *config.Config,
 		userRepo *repository.UserRepository,
 		authRepo *repository.AuthRepository,
 		jwtManager *jwt.Manager,
 		validator *validator.Validator,
 		userService *service.UserService,
+		verificationService *service.VerificationService,
+		mfaService *service.MFAService,
+		auditService *service.AuditService,
+		denylist *denylist.Denylist,
+		lockoutService *service.LockoutService,
+		rbacService *service.RBACService,
+		invitationService *service.InvitationService,
+		kvRepo *repository.KVRepository,
+		orgRepo *repository.OrganizationRepository,
 	) *service.AuthService {
-		return service.NewAuthService(userRepo, authRepo, jwtManager, validator, userService)
+		return service.NewAuthService(&cfg.Auth, userRepo, authRepo, jwtManager, validator, userService, verificationService, mfaService, auditService, denylist, lockoutService, rbacService, invitationService, kvRepo, orgRepo)
+	}),
+	fx.Provide(func(cfg *config.Config) (*oauth.Registry, error) {
+		return oauth.NewRegistry(&cfg.OAuth)
+	}),
+	fx.Provide(func(
+		cfg *config.Config,
+		registry *oauth.Registry,
+		identityRepo *repository.IdentityRepository,
+		userRepo *repository.UserRepository,
+		kvRepo *repository.KVRepository,
+		authService *service.AuthService,
+		rbacService *service.RBACService,
+		auditService *service.AuditService,
+		logger *logger.Logger,
+	) (*service.OAuthService, error) {
+		return service.NewOAuthService(cfg, registry, identityRepo, userRepo, kvRepo, authService, rbacService, auditService, logger)
+	}),
+	fx.Provide(func(
+		cfg *config.Config,
+		clientRepo *repository.OAuthClientRepository,
+		authRepo *repository.AuthRepository,
+		userRepo *repository.UserRepository,
+		kvRepo *repository.KVRepository,
+		jwtManager *jwt.Manager,
+		authService *service.AuthService,
+		rbacService *service.RBACService,
+		auditService *service.AuditService,
+	) (*service.OAuthServerService, error) {
+		return service.NewOAuthServerService(cfg, clientRepo, authRepo, userRepo, kvRepo, jwtManager, authService, rbacService, auditService)
+	}),
+	fx.Provide(func(
+		cfg *config.Config,
+		userRepo *repository.UserRepository,
+		authRepo *repository.AuthRepository,
+		userService *service.UserService,
+		authService *service.AuthService,
+		validator *validator.Validator,
+		mailer mailer.Mailer,
+	) (*service.PasswordService, error) {
+		return service.NewPasswordService(cfg, userRepo, authRepo, userService, authService, validator, mailer)
+	}),
+	fx.Provide(func(
+		cfg *config.Config,
+		userRepo *repository.UserRepository,
+		authRepo *repository.AuthRepository,
+		kvRepo *repository.KVRepository,
+		authService *service.AuthService,
+		mailer mailer.Mailer,
+	) (*service.MagicLinkService, error) {
+		return service.NewMagicLinkService(cfg, userRepo, authRepo, kvRepo, authService, mailer)
+	}),
+	fx.Provide(func(
+		lc fx.Lifecycle,
+		cfg *config.Config,
+		privacyRepo *repository.PrivacyRepository,
+		userRepo *repository.UserRepository,
+		userService *service.UserService,
+		apiKeyService *service.APIKeyService,
+		fileService *service.FileService,
+		auditService *service.AuditService,
+		mailer mailer.Mailer,
+		logger *logger.Logger,
+	) (*service.PrivacyService, error) {
+		workerInterval, err := time.ParseDuration(cfg.Privacy.WorkerInterval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse privacy worker interval: %w", err)
+		}
+
+		privacyService, err := service.NewPrivacyService(cfg, privacyRepo, userRepo, userService, apiKeyService, fileService, auditService, mailer, logger)
+		if err != nil {
+			return nil, err
+		}
+
+		var stop func()
+		lc.Append(fx.Hook{
+			OnStart: func(ctx context.Context) error {
+				stop = privacyService.StartWorker(workerInterval, func(err error) {
+					logger.Error("Failed to process data exports and erasures", zap.Error(err))
+				})
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				stop()
+				return nil
+			},
+		})
+
+		return privacyService, nil
+	}),
+	fx.Provide(func(
+		cfg *config.Config,
+		fileRepo *repository.FileRepository,
+		userRepo *repository.UserRepository,
+		store storage.Storage,
+		urls *storage.URLSigner,
+	) (*service.FileService, error) {
+		return service.NewFileService(&cfg.Storage, fileRepo, userRepo, store, urls)
+	}),
+	fx.Provide(func(
+		cfg *config.Config,
+		orgRepo *repository.OrganizationRepository,
+		userRepo *repository.UserRepository,
+		authService *service.AuthService,
+		auditService *service.AuditService,
+		validator *validator.Validator,
+		mailer mailer.Mailer,
+	) (*service.OrganizationService, error) {
+		return service.NewOrganizationService(cfg, orgRepo, userRepo, authService, auditService, validator, mailer)
 	}),
 	fx.Provide(func(logger *logger.Logger) *service.WebSocketService {
 		return service.NewWebSocketService(logger)
@@ -80,21 +385,67 @@ var Container = fx.Options(
 	fx.Provide(func(authService *service.AuthService) *handler.AuthHandler {
 		return handler.NewAuthHandler(authService)
 	}),
+	fx.Provide(func(oauthService *service.OAuthService) *handler.OAuthHandler {
+		return handler.NewOAuthHandler(oauthService)
+	}),
+	fx.Provide(func(oauthServerService *service.OAuthServerService) *handler.OAuthServerHandler {
+		return handler.NewOAuthServerHandler(oauthServerService)
+	}),
+	fx.Provide(func(mfaService *service.MFAService, authService *service.AuthService) *handler.MFAHandler {
+		return handler.NewMFAHandler(mfaService, authService)
+	}),
+	fx.Provide(func(passwordService *service.PasswordService) *handler.PasswordHandler {
+		return handler.NewPasswordHandler(passwordService)
+	}),
+	fx.Provide(func(verificationService *service.VerificationService, userService *service.UserService) *handler.VerificationHandler {
+		return handler.NewVerificationHandler(verificationService, userService)
+	}),
+	fx.Provide(func(magicLinkService *service.MagicLinkService) *handler.MagicLinkHandler {
+		return handler.NewMagicLinkHandler(magicLinkService)
+	}),
 	fx.Provide(func(userService *service.UserService) *handler.UserHandler {
 		return handler.NewUserHandler(userService)
 	}),
+	fx.Provide(func(apiKeyService *service.APIKeyService) *handler.APIKeyHandler {
+		return handler.NewAPIKeyHandler(apiKeyService)
+	}),
 	fx.Provide(func(wsService *service.WebSocketService, logger *logger.Logger) *handler.WebSocketHandler {
 		return handler.NewWebSocketHandler(wsService, logger)
 	}),
+	fx.Provide(func(jwtManager *jwt.Manager, oauthServerService *service.OAuthServerService) *handler.WellKnownHandler {
+		return handler.NewWellKnownHandler(jwtManager, oauthServerService)
+	}),
 	fx.Provide(func(kvRepo *repository.KVRepository) *handler.ConfigHandler {
 		return handler.NewConfigHandler(kvRepo)
 	}),
+	fx.Provide(func(invitationService *service.InvitationService) *handler.InvitationHandler {
+		return handler.NewInvitationHandler(invitationService)
+	}),
+	fx.Provide(func(adminUserService *service.AdminUserService) *handler.AdminUserHandler {
+		return handler.NewAdminUserHandler(adminUserService)
+	}),
+	fx.Provide(func(privacyService *service.PrivacyService) *handler.PrivacyHandler {
+		return handler.NewPrivacyHandler(privacyService)
+	}),
+	fx.Provide(func(fileService *service.FileService, userService *service.UserService) *handler.FileHandler {
+		return handler.NewFileHandler(fileService, userService)
+	}),
+	fx.Provide(func(orgService *service.OrganizationService) *handler.OrganizationHandler {
+		return handler.NewOrganizationHandler(orgService)
+	}),
+	fx.Provide(func(userService *service.UserService, lockoutService *service.LockoutService, rbacService *service.RBACService, authService *service.AuthService) *handler.AdminHandler {
+		return handler.NewAdminHandler(userService, lockoutService, rbacService, authService)
+	}),
 
 	// Middleware
 	fx.Provide(
 		fx.Annotate(
-			func(jwtManager *jwt.Manager) echo.MiddlewareFunc {
-				return middleware.JWTAuth(jwtManager)
+			func(jwtManager *jwt.Manager, denylist *denylist.Denylist, apiKeyService *service.APIKeyService, auditService *service.AuditService) echo.MiddlewareFunc {
+				auth := middleware.APIKeyAuth(apiKeyService, middleware.JWTAuth(jwtManager, denylist))
+				audit := middleware.AuditImpersonation(auditService)
+				return func(next echo.HandlerFunc) echo.HandlerFunc {
+					return auth(audit(next))
+				}
 			},
 			fx.ResultTags(`name:"JWTAuthMiddleware"`),
 		),
@@ -37,10 +37,24 @@ type ServerParams struct {
 	Migrator *repository.Migrator
 
 	// Handlers
-	AuthHandler      *handler.AuthHandler
-	UserHandler      *handler.UserHandler
-	WebSocketHandler *handler.WebSocketHandler
-	ConfigHandler    *handler.ConfigHandler
+	AuthHandler         *handler.AuthHandler
+	PasswordHandler     *handler.PasswordHandler
+	VerificationHandler *handler.VerificationHandler
+	MagicLinkHandler    *handler.MagicLinkHandler
+	MFAHandler          *handler.MFAHandler
+	OAuthHandler        *handler.OAuthHandler
+	OAuthServerHandler  *handler.OAuthServerHandler
+	UserHandler         *handler.UserHandler
+	APIKeyHandler       *handler.APIKeyHandler
+	WebSocketHandler    *handler.WebSocketHandler
+	ConfigHandler       *handler.ConfigHandler
+	WellKnownHandler    *handler.WellKnownHandler
+	AdminHandler        *handler.AdminHandler
+	InvitationHandler   *handler.InvitationHandler
+	AdminUserHandler    *handler.AdminUserHandler
+	PrivacyHandler      *handler.PrivacyHandler
+	FileHandler         *handler.FileHandler
+	OrganizationHandler *handler.OrganizationHandler
 
 	// Middleware
 	AuthMiddleware   echo.MiddlewareFunc `name:"JWTAuthMiddleware"`
@@ -108,10 +122,24 @@ func (s *Server) setupRoutes(params ServerParams) {
 	s.echo.GET("/api/version", s.versionInfo)
 
 	// Register handler routes
-	params.AuthHandler.RegisterRoutes(s.echo)
+	params.AuthHandler.RegisterRoutes(s.echo, params.AuthMiddleware)
+	params.PasswordHandler.RegisterRoutes(s.echo, params.AuthMiddleware)
+	params.VerificationHandler.RegisterRoutes(s.echo)
+	params.MagicLinkHandler.RegisterRoutes(s.echo)
+	params.MFAHandler.RegisterRoutes(s.echo, params.AuthMiddleware)
+	params.OAuthHandler.RegisterRoutes(s.echo, params.AuthMiddleware)
+	params.OAuthServerHandler.RegisterRoutes(s.echo, params.AuthMiddleware)
 	params.UserHandler.RegisterRoutes(s.echo, params.AuthMiddleware)
+	params.APIKeyHandler.RegisterRoutes(s.echo, params.AuthMiddleware)
 	params.WebSocketHandler.RegisterRoutes(s.echo, params.AuthMiddleware)
 	params.ConfigHandler.RegisterRoutes(s.echo, params.AuthMiddleware)
+	params.WellKnownHandler.RegisterRoutes(s.echo)
+	params.AdminHandler.RegisterRoutes(s.echo, params.AuthMiddleware)
+	params.InvitationHandler.RegisterRoutes(s.echo, params.AuthMiddleware)
+	params.AdminUserHandler.RegisterRoutes(s.echo, params.AuthMiddleware)
+	params.PrivacyHandler.RegisterRoutes(s.echo, params.AuthMiddleware)
+	params.FileHandler.RegisterRoutes(s.echo, params.AuthMiddleware)
+	params.OrganizationHandler.RegisterRoutes(s.echo, params.AuthMiddleware)
 
 	// Embedded static file serving for SPA
 	s.echo.Use(echoMiddleware.StaticWithConfig(echoMiddleware.StaticConfig{
@@ -124,6 +152,8 @@ func (s *Server) setupRoutes(params ServerParams) {
 			path := c.Request().URL.Path
 			return (len(path) >= 4 && path[:4] == "/api") ||
 				path == "/health" ||
+				(len(path) >= 12 && path[:12] == "/.well-known") ||
+				(len(path) >= 6 && path[:6] == "/oauth") ||
 				(len(path) >= 8 && path[:8] == "/swagger")
 		},
 		Filesystem: http.FS(static.GetWebFS()),
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -9,16 +10,27 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Logger   LoggerConfig   `mapstructure:"logger"`
+	Server      ServerConfig      `mapstructure:"server"`
+	Database    DatabaseConfig    `mapstructure:"database"`
+	JWT         JWTConfig         `mapstructure:"jwt"`
+	Logger      LoggerConfig      `mapstructure:"logger"`
+	Auth        AuthConfig        `mapstructure:"auth"`
+	Password    PasswordConfig    `mapstructure:"password"`
+	Mailer      MailerConfig      `mapstructure:"mailer"`
+	Lockout     LockoutConfig     `mapstructure:"lockout"`
+	OAuth       OAuthConfig       `mapstructure:"oauth"`
+	OAuthServer OAuthServerConfig `mapstructure:"oauth_server"`
+	Pagination  PaginationConfig  `mapstructure:"pagination"`
+	Privacy     PrivacyConfig     `mapstructure:"privacy"`
+	Storage     StorageConfig     `mapstructure:"storage"`
+	Org         OrgConfig         `mapstructure:"org"`
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	Host string `mapstructure:"host"`
-	Port int    `mapstructure:"port"`
+	Host      string `mapstructure:"host"`
+	Port      int    `mapstructure:"port"`
+	PublicURL string `mapstructure:"public_url"`
 }
 
 // DatabaseConfig holds database configuration
@@ -38,6 +50,16 @@ type JWTConfig struct {
 	RefreshSecret        string `mapstructure:"refresh_secret"`
 	AccessTokenDuration  string `mapstructure:"access_token_duration"`
 	RefreshTokenDuration string `mapstructure:"refresh_token_duration"`
+	MFATokenDuration     string `mapstructure:"mfa_token_duration"`
+	SigningKeyFile       string `mapstructure:"signing_key_file"`
+	SigningKeyID         string `mapstructure:"signing_key_id"`
+	VerifyKeyFiles       string `mapstructure:"verify_key_files"`
+	// KeyRingFile is a JSON manifest of rotated keys, re-read while running
+	KeyRingFile           string `mapstructure:"key_ring_file"`
+	KeyRingReloadInterval string `mapstructure:"key_ring_reload_interval"`
+	// ImpersonationTokenDuration is how long administrators may act as another
+	// user; it is capped at the access token duration
+	ImpersonationTokenDuration string `mapstructure:"impersonation_token_duration"`
 }
 
 // LoggerConfig holds logger configuration
@@ -47,6 +69,157 @@ type LoggerConfig struct {
 	OutputPath string `mapstructure:"output_path"`
 }
 
+// AuthConfig holds account and credential lifecycle configuration
+type AuthConfig struct {
+	PasswordResetTokenDuration     string `mapstructure:"password_reset_token_duration"`
+	EmailVerificationTokenDuration string `mapstructure:"email_verification_token_duration"`
+	MagicLinkTokenDuration         string `mapstructure:"magic_link_token_duration"`
+	RequireEmailVerification       bool   `mapstructure:"require_email_verification"`
+	MFAIssuer                      string `mapstructure:"mfa_issuer"`
+	// DenylistStore selects where revoked access tokens and sessions are
+	// tracked: memory or database. Revocations of all tokens of a user are
+	// always kept in the database.
+	DenylistStore         string `mapstructure:"denylist_store"`
+	DenylistPurgeInterval string `mapstructure:"denylist_purge_interval"`
+	// APIKeyMaxPerUser limits the number of API keys a user can create
+	APIKeyMaxPerUser int `mapstructure:"api_key_max_per_user"`
+}
+
+// PasswordConfig holds password hashing configuration. New hashes use
+// Algorithm; stored hashes of the other algorithm or with other parameters are
+// upgraded on the next successful login.
+type PasswordConfig struct {
+	// Algorithm is argon2id or bcrypt
+	Algorithm  string `mapstructure:"algorithm"`
+	BcryptCost int    `mapstructure:"bcrypt_cost"`
+	// Argon2Memory is the argon2id memory cost in KiB
+	Argon2Memory      uint32 `mapstructure:"argon2_memory"`
+	Argon2Iterations  uint32 `mapstructure:"argon2_iterations"`
+	Argon2Parallelism uint8  `mapstructure:"argon2_parallelism"`
+	Argon2SaltLength  uint32 `mapstructure:"argon2_salt_length"`
+	Argon2KeyLength   uint32 `mapstructure:"argon2_key_length"`
+}
+
+// LockoutConfig holds login brute-force protection configuration
+type LockoutConfig struct {
+	// MaxFailures is the number of failed logins after which an account is locked
+	MaxFailures int `mapstructure:"max_failures"`
+	// IPMaxFailures is the number of failed logins after which a client IP is locked
+	IPMaxFailures   int    `mapstructure:"ip_max_failures"`
+	FailureWindow   string `mapstructure:"failure_window"`
+	LockoutDuration string `mapstructure:"lockout_duration"`
+	// DelayAfter is the number of failures tolerated before attempts are delayed
+	DelayAfter int    `mapstructure:"delay_after"`
+	BaseDelay  string `mapstructure:"base_delay"`
+	MaxDelay   string `mapstructure:"max_delay"`
+}
+
+// OAuthConfig holds social login configuration
+type OAuthConfig struct {
+	// Providers is a JSON array of provider definitions, see OAuthProviderConfig
+	Providers string `mapstructure:"providers"`
+	// StateDuration is how long a started login may take to come back from the provider
+	StateDuration string `mapstructure:"state_duration"`
+	// AllowSignup creates accounts for unknown identities on their first login
+	AllowSignup bool `mapstructure:"allow_signup"`
+}
+
+// OAuthProviderConfig describes a social login provider. Type is "oidc" or
+// "github"; the "github" and "google" names imply their type and endpoints.
+type OAuthProviderConfig struct {
+	Name         string   `json:"name"`
+	DisplayName  string   `json:"display_name"`
+	Type         string   `json:"type"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes"`
+	// Endpoint overrides for the github type, e.g. for GitHub Enterprise
+	AuthURL  string `json:"auth_url"`
+	TokenURL string `json:"token_url"`
+	APIURL   string `json:"api_url"`
+}
+
+// ProviderConfigs parses the configured social login providers
+func (c *OAuthConfig) ProviderConfigs() ([]OAuthProviderConfig, error) {
+	if strings.TrimSpace(c.Providers) == "" {
+		return nil, nil
+	}
+
+	var providers []OAuthProviderConfig
+	if err := json.Unmarshal([]byte(c.Providers), &providers); err != nil {
+		return nil, fmt.Errorf("failed to parse oauth providers: %w", err)
+	}
+	return providers, nil
+}
+
+// OAuthServerConfig holds authorization server configuration
+type OAuthServerConfig struct {
+	// AuthorizeRequestDuration is how long a user may take to sign in and answer the consent screen
+	AuthorizeRequestDuration string `mapstructure:"authorize_request_duration"`
+	// AuthorizationCodeDuration is how long a client may take to redeem an authorization code
+	AuthorizationCodeDuration string `mapstructure:"authorization_code_duration"`
+}
+
+// PaginationConfig holds listing pagination configuration
+type PaginationConfig struct {
+	// CursorSecret signs the cursors handed out for keyset pagination
+	CursorSecret string `mapstructure:"cursor_secret"`
+}
+
+// PrivacyConfig holds personal data export and erasure configuration
+type PrivacyConfig struct {
+	// ExportDir is where data export archives are kept until they are downloaded
+	ExportDir string `mapstructure:"export_dir"`
+	// ExportDuration is how long a finished export can be downloaded
+	ExportDuration string `mapstructure:"export_duration"`
+	// ErasureGracePeriod is how long users can cancel the erasure of their account
+	ErasureGracePeriod string `mapstructure:"erasure_grace_period"`
+	// ErasureMode is anonymize, which keeps an anonymous account, or delete
+	ErasureMode string `mapstructure:"erasure_mode"`
+	// WorkerInterval is how often pending exports and due erasures are processed
+	WorkerInterval string `mapstructure:"worker_interval"`
+}
+
+// StorageConfig holds file storage and upload configuration
+type StorageConfig struct {
+	// Driver is local or s3
+	Driver   string `mapstructure:"driver"`
+	LocalDir string `mapstructure:"local_dir"`
+	// S3 settings, for AWS or any S3-compatible service such as MinIO
+	S3Endpoint  string `mapstructure:"s3_endpoint"`
+	S3Region    string `mapstructure:"s3_region"`
+	S3Bucket    string `mapstructure:"s3_bucket"`
+	S3AccessKey string `mapstructure:"s3_access_key"`
+	S3SecretKey string `mapstructure:"s3_secret_key"`
+	// S3PathStyle addresses buckets as endpoint/bucket instead of bucket.endpoint
+	S3PathStyle bool `mapstructure:"s3_path_style"`
+	// MaxUploadSize is the largest accepted upload in bytes
+	MaxUploadSize int64 `mapstructure:"max_upload_size"`
+	// AllowedTypes is a comma separated list of the accepted MIME types,
+	// detected from the content of uploads
+	AllowedTypes string `mapstructure:"allowed_types"`
+	// ThumbnailSize is the largest width and height of image thumbnails in pixels
+	ThumbnailSize int `mapstructure:"thumbnail_size"`
+	// URLSecret signs file download URLs, which expire after URLDuration
+	URLSecret   string `mapstructure:"url_secret"`
+	URLDuration string `mapstructure:"url_duration"`
+}
+
+// OrgConfig holds organization configuration
+type OrgConfig struct {
+	// InvitationDuration is how long an invitation to join an organization can be accepted
+	InvitationDuration string `mapstructure:"invitation_duration"`
+}
+
+// MailerConfig holds outgoing mail configuration. The log and file drivers
+// do not deliver mail and are meant for development only.
+type MailerConfig struct {
+	Driver    string `mapstructure:"driver"`
+	From      string `mapstructure:"from"`
+	OutputDir string `mapstructure:"output_dir"`
+}
+
 // Load loads configuration from environment variables and config files
 func Load() (*Config, error) {
 	v := viper.New()
@@ -85,6 +258,7 @@ func setDefaults(v *viper.Viper) {
 	// Server defaults
 	v.SetDefault("server.host", "localhost")
 	v.SetDefault("server.port", 8080)
+	v.SetDefault("server.public_url", "http://localhost:8080")
 
 	// Database defaults
 	v.SetDefault("database.type", "sqlite")
@@ -98,11 +272,88 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("jwt.refresh_secret", "your-refresh-secret")
 	v.SetDefault("jwt.access_token_duration", "15m")
 	v.SetDefault("jwt.refresh_token_duration", "168h")
+	v.SetDefault("jwt.mfa_token_duration", "5m")
+	v.SetDefault("jwt.impersonation_token_duration", "10m")
+	v.SetDefault("jwt.signing_key_file", "")
+	v.SetDefault("jwt.signing_key_id", "")
+	v.SetDefault("jwt.verify_key_files", "")
+	v.SetDefault("jwt.key_ring_file", "")
+	v.SetDefault("jwt.key_ring_reload_interval", "30s")
 
 	// Logger defaults
 	v.SetDefault("logger.level", "info")
 	v.SetDefault("logger.encoding", "json")
 	v.SetDefault("logger.output_path", "stdout")
+
+	// Auth defaults
+	v.SetDefault("auth.password_reset_token_duration", "1h")
+	v.SetDefault("auth.email_verification_token_duration", "24h")
+	v.SetDefault("auth.magic_link_token_duration", "15m")
+	v.SetDefault("auth.require_email_verification", false)
+	v.SetDefault("auth.mfa_issuer", "go-echo-monolithic")
+	v.SetDefault("auth.denylist_store", "memory")
+	v.SetDefault("auth.denylist_purge_interval", "5m")
+	v.SetDefault("auth.api_key_max_per_user", 25)
+
+	// Lockout defaults
+	v.SetDefault("lockout.max_failures", 5)
+	v.SetDefault("lockout.ip_max_failures", 20)
+	v.SetDefault("lockout.failure_window", "15m")
+	v.SetDefault("lockout.lockout_duration", "15m")
+	v.SetDefault("lockout.delay_after", 3)
+	v.SetDefault("lockout.base_delay", "1s")
+	v.SetDefault("lockout.max_delay", "30s")
+
+	// OAuth defaults
+	v.SetDefault("oauth.providers", "")
+	v.SetDefault("oauth.state_duration", "10m")
+	v.SetDefault("oauth.allow_signup", true)
+
+	// OAuth server defaults
+	v.SetDefault("oauth_server.authorize_request_duration", "10m")
+	v.SetDefault("oauth_server.authorization_code_duration", "1m")
+
+	// Password hashing defaults (argon2id parameters as recommended by RFC 9106)
+	v.SetDefault("password.algorithm", "argon2id")
+	v.SetDefault("password.bcrypt_cost", 10)
+	v.SetDefault("password.argon2_memory", 65536)
+	v.SetDefault("password.argon2_iterations", 3)
+	v.SetDefault("password.argon2_parallelism", 4)
+	v.SetDefault("password.argon2_salt_length", 16)
+	v.SetDefault("password.argon2_key_length", 32)
+
+	// Pagination defaults
+	v.SetDefault("pagination.cursor_secret", "your-cursor-secret")
+
+	// Privacy defaults
+	v.SetDefault("privacy.export_dir", "./exports")
+	v.SetDefault("privacy.export_duration", "24h")
+	v.SetDefault("privacy.erasure_grace_period", "720h")
+	v.SetDefault("privacy.erasure_mode", "anonymize")
+	v.SetDefault("privacy.worker_interval", "1m")
+
+	// Storage defaults
+	v.SetDefault("storage.driver", "local")
+	v.SetDefault("storage.local_dir", "./storage")
+	v.SetDefault("storage.s3_endpoint", "")
+	v.SetDefault("storage.s3_region", "us-east-1")
+	v.SetDefault("storage.s3_bucket", "")
+	v.SetDefault("storage.s3_access_key", "")
+	v.SetDefault("storage.s3_secret_key", "")
+	v.SetDefault("storage.s3_path_style", true)
+	v.SetDefault("storage.max_upload_size", 10<<20)
+	v.SetDefault("storage.allowed_types", "image/jpeg,image/png,image/gif,image/webp,application/pdf,text/plain")
+	v.SetDefault("storage.thumbnail_size", 256)
+	v.SetDefault("storage.url_secret", "your-storage-url-secret")
+	v.SetDefault("storage.url_duration", "15m")
+
+	// Organization defaults
+	v.SetDefault("org.invitation_duration", "168h")
+
+	// Mailer defaults
+	v.SetDefault("mailer.driver", "log")
+	v.SetDefault("mailer.from", "no-reply@localhost")
+	v.SetDefault("mailer.output_dir", "./mail")
 }
 
 // GetDSN returns database connection string based on the database type
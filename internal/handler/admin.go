@@ -0,0 +1,430 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/middleware"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// AdminHandler handles administrative HTTP requests
+type AdminHandler struct {
+	userService    *service.UserService
+	lockoutService *service.LockoutService
+	rbacService    *service.RBACService
+	authService    *service.AuthService
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(userService *service.UserService, lockoutService *service.LockoutService, rbacService *service.RBACService, authService *service.AuthService) *AdminHandler {
+	return &AdminHandler{
+		userService:    userService,
+		lockoutService: lockoutService,
+		rbacService:    rbacService,
+		authService:    authService,
+	}
+}
+
+// ListLockouts handles listing locked and delayed logins
+// @Summary		List login lockouts
+// @Description	List accounts and client IPs whose login attempts are currently locked or delayed after failed attempts
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response{data=[]types.LockoutStatusResponse}	"Lockouts retrieved successfully"
+// @Failure		401	{object}	response.Response										"Unauthorized"
+// @Failure		403	{object}	response.Response										"Forbidden"
+// @Failure		500	{object}	response.Response										"Internal server error"
+// @Router			/admin/lockouts [get]
+func (h *AdminHandler) ListLockouts(c echo.Context) error {
+	lockouts, err := h.lockoutService.ListActive()
+	if err != nil {
+		return response.InternalServerError(c, "Failed to retrieve lockouts")
+	}
+
+	return response.Success(c, lockouts, "Lockouts retrieved successfully")
+}
+
+// GetUserLockout handles retrieving the lockout state of a user
+// @Summary		Get user lockout state
+// @Description	Get the failed login count and lock state of a user account
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int													true	"User ID"
+// @Success		200	{object}	response.Response{data=types.LockoutStatusResponse}	"Lockout state retrieved successfully"
+// @Failure		400	{object}	response.Response									"Invalid user ID"
+// @Failure		401	{object}	response.Response									"Unauthorized"
+// @Failure		403	{object}	response.Response									"Forbidden"
+// @Failure		404	{object}	response.Response									"User not found"
+// @Failure		500	{object}	response.Response									"Internal server error"
+// @Router			/admin/users/{id}/lockout [get]
+func (h *AdminHandler) GetUserLockout(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	user, err := h.userService.GetByID(uint(id))
+	if err != nil {
+		if err == types.ErrUserNotFound {
+			return response.NotFound(c, "User not found")
+		}
+		return response.InternalServerError(c, "Failed to get user")
+	}
+
+	status, err := h.lockoutService.Status(user.Username)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to retrieve lockout state")
+	}
+
+	return response.Success(c, status, "Lockout state retrieved successfully")
+}
+
+// UnlockUser handles unlocking a user account
+// @Summary		Unlock user
+// @Description	Clear the failed login attempts and lock of a user account
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int					true	"User ID"
+// @Success		200	{object}	response.Response	"User unlocked successfully"
+// @Failure		400	{object}	response.Response	"Invalid user ID"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		403	{object}	response.Response	"Forbidden"
+// @Failure		404	{object}	response.Response	"User not found"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/admin/users/{id}/unlock [post]
+func (h *AdminHandler) UnlockUser(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	user, err := h.userService.GetByID(uint(id))
+	if err != nil {
+		if err == types.ErrUserNotFound {
+			return response.NotFound(c, "User not found")
+		}
+		return response.InternalServerError(c, "Failed to get user")
+	}
+
+	if err := h.lockoutService.Unlock(user, clientInfo(c)); err != nil {
+		return response.InternalServerError(c, "Failed to unlock user")
+	}
+
+	return response.Success(c, nil, "User unlocked successfully")
+}
+
+// ImpersonateUser handles starting an impersonation
+// @Summary		Impersonate user
+// @Description	Issue a short-lived access token for acting as a user, e.g. to see exactly what they see. The token names the administrator as its actor, cannot be refreshed and cannot change passwords, two-factor authentication, sessions or API keys. Every request made with it is recorded in the audit log. Only active users whose permissions the administrator holds can be impersonated.
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int													true	"User ID"
+// @Success		200	{object}	response.Response{data=types.ImpersonationResponse}	"Impersonation started successfully"
+// @Failure		400	{object}	response.Response									"Invalid user ID"
+// @Failure		401	{object}	response.Response									"Unauthorized"
+// @Failure		403	{object}	response.Response									"Forbidden or user cannot be impersonated"
+// @Failure		404	{object}	response.Response									"User not found"
+// @Failure		500	{object}	response.Response									"Internal server error"
+// @Router			/admin/users/{id}/impersonate [post]
+func (h *AdminHandler) ImpersonateUser(c echo.Context) error {
+	adminID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	result, err := h.authService.Impersonate(adminID, uint(id), clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrCannotImpersonate):
+			return response.Forbidden(c, err.Error())
+		case errors.Is(err, types.ErrUserNotFound):
+			return response.NotFound(c, "User not found")
+		default:
+			return response.InternalServerError(c, "Failed to impersonate user")
+		}
+	}
+
+	return response.Success(c, result, "Impersonation started successfully")
+}
+
+// ListRoles handles listing roles
+// @Summary		List roles
+// @Description	List all roles with their permissions
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response{data=[]types.RoleResponse}	"Roles retrieved successfully"
+// @Failure		401	{object}	response.Response							"Unauthorized"
+// @Failure		403	{object}	response.Response							"Forbidden"
+// @Failure		500	{object}	response.Response							"Internal server error"
+// @Router			/admin/roles [get]
+func (h *AdminHandler) ListRoles(c echo.Context) error {
+	roles, err := h.rbacService.ListRoles()
+	if err != nil {
+		return response.InternalServerError(c, "Failed to retrieve roles")
+	}
+
+	return response.Success(c, roles, "Roles retrieved successfully")
+}
+
+// GetRole handles retrieving a role
+// @Summary		Get role
+// @Description	Get a role and its permissions by ID
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int										true	"Role ID"
+// @Success		200	{object}	response.Response{data=types.RoleResponse}	"Role retrieved successfully"
+// @Failure		400	{object}	response.Response						"Invalid role ID"
+// @Failure		401	{object}	response.Response						"Unauthorized"
+// @Failure		403	{object}	response.Response						"Forbidden"
+// @Failure		404	{object}	response.Response						"Role not found"
+// @Failure		500	{object}	response.Response						"Internal server error"
+// @Router			/admin/roles/{id} [get]
+func (h *AdminHandler) GetRole(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid role ID")
+	}
+
+	role, err := h.rbacService.GetRole(uint(id))
+	if err != nil {
+		if err == types.ErrRoleNotFound {
+			return response.NotFound(c, "Role not found")
+		}
+		return response.InternalServerError(c, "Failed to get role")
+	}
+
+	return response.Success(c, h.rbacService.ToRoleResponse(role), "Role retrieved successfully")
+}
+
+// CreateRole handles role creation
+// @Summary		Create role
+// @Description	Create a role granting a set of permissions. Only permissions the administrator holds can be granted.
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		types.CreateRoleRequest						true	"Role definition"
+// @Success		201		{object}	response.Response{data=types.RoleResponse}	"Role created successfully"
+// @Failure		400		{object}	response.Response							"Bad request"
+// @Failure		401		{object}	response.Response							"Unauthorized"
+// @Failure		403		{object}	response.Response							"Forbidden"
+// @Failure		409		{object}	response.Response							"Role already exists"
+// @Failure		500		{object}	response.Response							"Internal server error"
+// @Router			/admin/roles [post]
+func (h *AdminHandler) CreateRole(c echo.Context) error {
+	var req types.CreateRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	adminID := c.Get("user_id").(uint)
+	role, err := h.rbacService.CreateRole(adminID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrValidationFailed):
+			return response.BadRequest(c, err.Error())
+		case errors.Is(err, types.ErrRoleAlreadyExists):
+			return response.Conflict(c, "Role already exists")
+		default:
+			return response.InternalServerError(c, "Failed to create role")
+		}
+	}
+
+	return response.Created(c, h.rbacService.ToRoleResponse(role), "Role created successfully")
+}
+
+// UpdateRole handles role updates
+// @Summary		Update role
+// @Description	Update the name, description or permissions of a role. Built-in roles cannot be renamed and the admin role always holds every permission. Administrators cannot change roles they hold or that grant a permission they lack, and can only grant permissions they hold.
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id		path		int											true	"Role ID"
+// @Param			request	body		types.UpdateRoleRequest						true	"Role changes"
+// @Success		200		{object}	response.Response{data=types.RoleResponse}	"Role updated successfully"
+// @Failure		400		{object}	response.Response							"Bad request"
+// @Failure		401		{object}	response.Response							"Unauthorized"
+// @Failure		403		{object}	response.Response							"Forbidden"
+// @Failure		404		{object}	response.Response							"Role not found"
+// @Failure		409		{object}	response.Response							"Role already exists"
+// @Failure		500		{object}	response.Response							"Internal server error"
+// @Router			/admin/roles/{id} [put]
+func (h *AdminHandler) UpdateRole(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid role ID")
+	}
+
+	var req types.UpdateRoleRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	adminID := c.Get("user_id").(uint)
+	role, err := h.rbacService.UpdateRole(adminID, uint(id), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrValidationFailed):
+			return response.BadRequest(c, err.Error())
+		case errors.Is(err, types.ErrOwnRole), errors.Is(err, types.ErrRoleOutranks):
+			return response.Forbidden(c, err.Error())
+		case errors.Is(err, types.ErrRoleNotFound):
+			return response.NotFound(c, "Role not found")
+		case errors.Is(err, types.ErrRoleAlreadyExists):
+			return response.Conflict(c, "Role already exists")
+		case errors.Is(err, types.ErrSystemRole):
+			return response.Forbidden(c, "Built-in roles cannot be renamed and the admin role cannot lose permissions")
+		default:
+			return response.InternalServerError(c, "Failed to update role")
+		}
+	}
+
+	return response.Success(c, h.rbacService.ToRoleResponse(role), "Role updated successfully")
+}
+
+// DeleteRole handles role deletion
+// @Summary		Delete role
+// @Description	Delete a role and remove it from all users. Built-in roles cannot be deleted, nor roles the administrator holds or that grant a permission they lack.
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int					true	"Role ID"
+// @Success		200	{object}	response.Response	"Role deleted successfully"
+// @Failure		400	{object}	response.Response	"Invalid role ID"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		403	{object}	response.Response	"Forbidden"
+// @Failure		404	{object}	response.Response	"Role not found"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/admin/roles/{id} [delete]
+func (h *AdminHandler) DeleteRole(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid role ID")
+	}
+
+	adminID := c.Get("user_id").(uint)
+	if err := h.rbacService.DeleteRole(adminID, uint(id)); err != nil {
+		switch {
+		case errors.Is(err, types.ErrRoleNotFound):
+			return response.NotFound(c, "Role not found")
+		case errors.Is(err, types.ErrSystemRole):
+			return response.Forbidden(c, "Built-in roles cannot be deleted")
+		case errors.Is(err, types.ErrOwnRole), errors.Is(err, types.ErrRoleOutranks):
+			return response.Forbidden(c, err.Error())
+		default:
+			return response.InternalServerError(c, "Failed to delete role")
+		}
+	}
+
+	return response.Success(c, nil, "Role deleted successfully")
+}
+
+// ListPermissions handles listing permissions
+// @Summary		List permissions
+// @Description	List the permissions that can be granted to roles
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response{data=[]types.PermissionResponse}	"Permissions retrieved successfully"
+// @Failure		401	{object}	response.Response									"Unauthorized"
+// @Failure		403	{object}	response.Response									"Forbidden"
+// @Failure		500	{object}	response.Response									"Internal server error"
+// @Router			/admin/permissions [get]
+func (h *AdminHandler) ListPermissions(c echo.Context) error {
+	permissions, err := h.rbacService.ListPermissions()
+	if err != nil {
+		return response.InternalServerError(c, "Failed to retrieve permissions")
+	}
+
+	return response.Success(c, permissions, "Permissions retrieved successfully")
+}
+
+// SetUserRoles handles replacing the roles of a user
+// @Summary		Set user roles
+// @Description	Replace the roles of a user. The change applies to the user's tokens from their next refresh. Administrators can only change the roles of users whose permissions they hold, only grant roles whose permissions they hold, and cannot change their own roles.
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id		path		int											true	"User ID"
+// @Param			request	body		types.SetUserRolesRequest					true	"Role names"
+// @Success		200		{object}	response.Response{data=types.UserResponse}	"User roles updated successfully"
+// @Failure		400		{object}	response.Response							"Bad request"
+// @Failure		401		{object}	response.Response							"Unauthorized"
+// @Failure		403		{object}	response.Response							"Forbidden"
+// @Failure		404		{object}	response.Response							"User or role not found"
+// @Failure		500		{object}	response.Response							"Internal server error"
+// @Router			/admin/users/{id}/roles [put]
+func (h *AdminHandler) SetUserRoles(c echo.Context) error {
+	adminID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	var req types.SetUserRolesRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	if err := h.rbacService.CheckRoleChange(adminID, uint(id), req.Roles); err != nil {
+		switch {
+		case errors.Is(err, types.ErrValidationFailed):
+			return response.BadRequest(c, err.Error())
+		case errors.Is(err, types.ErrOwnAccount), errors.Is(err, types.ErrOutranked):
+			return response.Forbidden(c, err.Error())
+		case errors.Is(err, types.ErrRoleNotFound):
+			return response.NotFound(c, "Role not found")
+		default:
+			return response.InternalServerError(c, "Failed to update user roles")
+		}
+	}
+
+	user, err := h.rbacService.SetUserRoles(uint(id), &req)
+	if err != nil {
+		switch err {
+		case types.ErrUserNotFound:
+			return response.NotFound(c, "User not found")
+		case types.ErrRoleNotFound:
+			return response.NotFound(c, "Role not found")
+		default:
+			return response.InternalServerError(c, "Failed to update user roles")
+		}
+	}
+
+	return response.Success(c, h.userService.ToResponse(user), "User roles updated successfully")
+}
+
+// RegisterRoutes registers admin routes
+func (h *AdminHandler) RegisterRoutes(e *echo.Echo, authMiddleware echo.MiddlewareFunc) {
+	admin := e.Group("/api/admin")
+
+	admin.Use(authMiddleware)
+	admin.GET("/lockouts", h.ListLockouts, middleware.RequirePermission(model.PermissionUsersRead))
+	admin.GET("/users/:id/lockout", h.GetUserLockout, middleware.RequirePermission(model.PermissionUsersRead))
+	admin.POST("/users/:id/unlock", h.UnlockUser, middleware.RequirePermission(model.PermissionUsersWrite))
+	admin.PUT("/users/:id/roles", h.SetUserRoles, middleware.RequirePermission(model.PermissionRolesWrite))
+	admin.POST("/users/:id/impersonate", h.ImpersonateUser, middleware.RequireJWT(), middleware.RequirePermission(model.PermissionUsersImpersonate))
+
+	admin.GET("/roles", h.ListRoles, middleware.RequirePermission(model.PermissionRolesRead))
+	admin.GET("/roles/:id", h.GetRole, middleware.RequirePermission(model.PermissionRolesRead))
+	admin.POST("/roles", h.CreateRole, middleware.RequirePermission(model.PermissionRolesWrite))
+	admin.PUT("/roles/:id", h.UpdateRole, middleware.RequirePermission(model.PermissionRolesWrite))
+	admin.DELETE("/roles/:id", h.DeleteRole, middleware.RequirePermission(model.PermissionRolesWrite))
+	admin.GET("/permissions", h.ListPermissions, middleware.RequirePermission(model.PermissionRolesRead))
+}
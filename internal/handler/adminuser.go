@@ -0,0 +1,313 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/middleware"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// AdminUserHandler handles user management HTTP requests from administrators
+type AdminUserHandler struct {
+	adminUserService *service.AdminUserService
+}
+
+// NewAdminUserHandler creates a new admin user handler
+func NewAdminUserHandler(adminUserService *service.AdminUserService) *AdminUserHandler {
+	return &AdminUserHandler{
+		adminUserService: adminUserService,
+	}
+}
+
+// CreateUser handles user creation by an administrator
+// @Summary		Create user
+// @Description	Create a user with the given roles, or the default role. Without a password the user is mailed a link to choose one. Only roles whose permissions the administrator holds can be granted.
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		types.AdminCreateUserRequest					true	"User details"
+// @Success		201		{object}	response.Response{data=types.AdminUserResponse}	"User created successfully"
+// @Failure		400		{object}	response.Response								"Bad request"
+// @Failure		401		{object}	response.Response								"Unauthorized"
+// @Failure		403		{object}	response.Response								"Forbidden"
+// @Failure		404		{object}	response.Response								"Role not found"
+// @Failure		409		{object}	response.Response								"User already exists"
+// @Failure		500		{object}	response.Response								"Internal server error"
+// @Router			/admin/users [post]
+func (h *AdminUserHandler) CreateUser(c echo.Context) error {
+	adminID := c.Get("user_id").(uint)
+
+	var req types.AdminCreateUserRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	if len(req.Roles) > 0 && !middleware.HasPermission(c, model.PermissionRolesWrite) {
+		return response.Forbidden(c, "Insufficient permissions to assign roles")
+	}
+
+	user, err := h.adminUserService.Create(adminID, &req, clientInfo(c))
+	if err != nil {
+		return adminUserError(c, err, "Failed to create user")
+	}
+
+	return response.Created(c, h.adminUserService.ToResponse(user), "User created successfully")
+}
+
+// GetUser handles retrieving a user for an administrator
+// @Summary		Get user
+// @Description	Get a user by ID, including soft-deleted users
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int												true	"User ID"
+// @Success		200	{object}	response.Response{data=types.AdminUserResponse}	"User retrieved successfully"
+// @Failure		400	{object}	response.Response								"Invalid user ID"
+// @Failure		401	{object}	response.Response								"Unauthorized"
+// @Failure		403	{object}	response.Response								"Forbidden"
+// @Failure		404	{object}	response.Response								"User not found"
+// @Failure		500	{object}	response.Response								"Internal server error"
+// @Router			/admin/users/{id} [get]
+func (h *AdminUserHandler) GetUser(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	user, err := h.adminUserService.Get(uint(id))
+	if err != nil {
+		return adminUserError(c, err, "Failed to get user")
+	}
+
+	return response.Success(c, h.adminUserService.ToResponse(user), "User retrieved successfully")
+}
+
+// UpdateUser handles user updates by an administrator
+// @Summary		Update user
+// @Description	Update any field of a user. Disabling a user signs them out everywhere. Changing roles also requires the roles:write permission; administrators cannot disable or change the roles of their own account. Only users whose permissions the administrator holds can be updated.
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id		path		int												true	"User ID"
+// @Param			request	body		types.AdminUpdateUserRequest					true	"User changes"
+// @Success		200		{object}	response.Response{data=types.AdminUserResponse}	"User updated successfully"
+// @Failure		400		{object}	response.Response								"Bad request"
+// @Failure		401		{object}	response.Response								"Unauthorized"
+// @Failure		403		{object}	response.Response								"Forbidden"
+// @Failure		404		{object}	response.Response								"User or role not found"
+// @Failure		409		{object}	response.Response								"Username or email already in use"
+// @Failure		500		{object}	response.Response								"Internal server error"
+// @Router			/admin/users/{id} [put]
+func (h *AdminUserHandler) UpdateUser(c echo.Context) error {
+	adminID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	var req types.AdminUpdateUserRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	if req.Roles != nil && !middleware.HasPermission(c, model.PermissionRolesWrite) {
+		return response.Forbidden(c, "Insufficient permissions to assign roles")
+	}
+
+	user, err := h.adminUserService.Update(adminID, uint(id), &req, clientInfo(c))
+	if err != nil {
+		return adminUserError(c, err, "Failed to update user")
+	}
+
+	return response.Success(c, h.adminUserService.ToResponse(user), "User updated successfully")
+}
+
+// ForcePasswordReset handles forcing a user to choose a new password
+// @Summary		Force password reset
+// @Description	Invalidate a user's password, sign them out everywhere and mail them a link to choose a new one
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int					true	"User ID"
+// @Success		200	{object}	response.Response	"Password reset forced successfully"
+// @Failure		400	{object}	response.Response	"Invalid user ID"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		403	{object}	response.Response	"Forbidden"
+// @Failure		404	{object}	response.Response	"User not found"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/admin/users/{id}/password-reset [post]
+func (h *AdminUserHandler) ForcePasswordReset(c echo.Context) error {
+	adminID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	if err := h.adminUserService.ForcePasswordReset(adminID, uint(id), clientInfo(c)); err != nil {
+		return adminUserError(c, err, "Failed to force password reset")
+	}
+
+	return response.Success(c, nil, "Password reset forced successfully")
+}
+
+// RevokeSessions handles signing a user out of all devices
+// @Summary		Revoke user sessions
+// @Description	Revoke all refresh tokens and outstanding access tokens of a user
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int					true	"User ID"
+// @Success		200	{object}	response.Response	"Sessions revoked successfully"
+// @Failure		400	{object}	response.Response	"Invalid user ID"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		403	{object}	response.Response	"Forbidden"
+// @Failure		404	{object}	response.Response	"User not found"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/admin/users/{id}/sessions [delete]
+func (h *AdminUserHandler) RevokeSessions(c echo.Context) error {
+	adminID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	if err := h.adminUserService.RevokeSessions(adminID, uint(id), clientInfo(c)); err != nil {
+		return adminUserError(c, err, "Failed to revoke sessions")
+	}
+
+	return response.Success(c, nil, "Sessions revoked successfully")
+}
+
+// DeleteUser handles soft deleting a user
+// @Summary		Delete user
+// @Description	Soft delete a user. The user can be restored until they are purged.
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int					true	"User ID"
+// @Success		200	{object}	response.Response	"User deleted successfully"
+// @Failure		400	{object}	response.Response	"Invalid user ID"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		403	{object}	response.Response	"Forbidden"
+// @Failure		404	{object}	response.Response	"User not found"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/admin/users/{id} [delete]
+func (h *AdminUserHandler) DeleteUser(c echo.Context) error {
+	adminID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	if err := h.adminUserService.Delete(adminID, uint(id), clientInfo(c)); err != nil {
+		return adminUserError(c, err, "Failed to delete user")
+	}
+
+	return response.Success(c, nil, "User deleted successfully")
+}
+
+// RestoreUser handles restoring a soft-deleted user
+// @Summary		Restore user
+// @Description	Restore a soft-deleted user. Their previous sessions stay revoked.
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int												true	"User ID"
+// @Success		200	{object}	response.Response{data=types.AdminUserResponse}	"User restored successfully"
+// @Failure		400	{object}	response.Response								"Invalid user ID"
+// @Failure		401	{object}	response.Response								"Unauthorized"
+// @Failure		403	{object}	response.Response								"Forbidden"
+// @Failure		404	{object}	response.Response								"User not found"
+// @Failure		409	{object}	response.Response								"User is not deleted"
+// @Failure		500	{object}	response.Response								"Internal server error"
+// @Router			/admin/users/{id}/restore [post]
+func (h *AdminUserHandler) RestoreUser(c echo.Context) error {
+	adminID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	user, err := h.adminUserService.Restore(adminID, uint(id), clientInfo(c))
+	if err != nil {
+		return adminUserError(c, err, "Failed to restore user")
+	}
+
+	return response.Success(c, h.adminUserService.ToResponse(user), "User restored successfully")
+}
+
+// PurgeUser handles permanently deleting a user
+// @Summary		Purge user
+// @Description	Permanently delete a user, deleted or not, together with their sessions, credentials, linked accounts and role assignments. Audit events are kept. This cannot be undone.
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int					true	"User ID"
+// @Success		200	{object}	response.Response	"User purged successfully"
+// @Failure		400	{object}	response.Response	"Invalid user ID"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		403	{object}	response.Response	"Forbidden"
+// @Failure		404	{object}	response.Response	"User not found"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/admin/users/{id}/purge [delete]
+func (h *AdminUserHandler) PurgeUser(c echo.Context) error {
+	adminID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	if err := h.adminUserService.Purge(c.Request().Context(), adminID, uint(id), clientInfo(c)); err != nil {
+		return adminUserError(c, err, "Failed to purge user")
+	}
+
+	return response.Success(c, nil, "User purged successfully")
+}
+
+// adminUserError maps user management errors to responses
+func adminUserError(c echo.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, types.ErrValidationFailed):
+		return response.BadRequest(c, err.Error())
+	case errors.Is(err, types.ErrUserNotFound):
+		return response.NotFound(c, "User not found")
+	case errors.Is(err, types.ErrRoleNotFound):
+		return response.NotFound(c, "Role not found")
+	case errors.Is(err, types.ErrUserAlreadyExists):
+		return response.Conflict(c, "Username or email already in use")
+	case errors.Is(err, types.ErrUserNotDeleted):
+		return response.Conflict(c, "User is not deleted")
+	case errors.Is(err, types.ErrOwnAccount), errors.Is(err, types.ErrOutranked):
+		return response.Forbidden(c, err.Error())
+	default:
+		return response.InternalServerError(c, message)
+	}
+}
+
+// RegisterRoutes registers admin user management routes
+func (h *AdminUserHandler) RegisterRoutes(e *echo.Echo, authMiddleware echo.MiddlewareFunc) {
+	users := e.Group("/api/admin/users")
+
+	users.Use(authMiddleware)
+	users.POST("", h.CreateUser, middleware.RequirePermission(model.PermissionUsersWrite))
+	users.GET("/:id", h.GetUser, middleware.RequirePermission(model.PermissionUsersRead))
+	users.PUT("/:id", h.UpdateUser, middleware.RequirePermission(model.PermissionUsersWrite))
+	users.POST("/:id/password-reset", h.ForcePasswordReset, middleware.RequirePermission(model.PermissionUsersWrite))
+	users.DELETE("/:id/sessions", h.RevokeSessions, middleware.RequirePermission(model.PermissionUsersWrite))
+	users.POST("/:id/restore", h.RestoreUser, middleware.RequirePermission(model.PermissionUsersWrite))
+	users.DELETE("/:id", h.DeleteUser, middleware.RequirePermission(model.PermissionUsersDelete))
+	users.DELETE("/:id/purge", h.PurgeUser, middleware.RequirePermission(model.PermissionUsersDelete))
+}
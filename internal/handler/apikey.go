@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/middleware"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// APIKeyHandler handles personal access token HTTP requests
+type APIKeyHandler struct {
+	apiKeyService *service.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeyService *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// ListAPIKeys handles listing the current user's API keys
+// @Summary		List API keys
+// @Description	List the current user's API keys. Only the key prefixes are shown.
+// @Tags			api-keys
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response{data=[]types.APIKeyResponse}	"API keys retrieved successfully"
+// @Failure		401	{object}	response.Response								"Unauthorized"
+// @Failure		403	{object}	response.Response								"Not available to API keys"
+// @Failure		500	{object}	response.Response								"Internal server error"
+// @Router			/users/profile/tokens [get]
+func (h *APIKeyHandler) ListAPIKeys(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	keys, err := h.apiKeyService.List(userID)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to retrieve API keys")
+	}
+
+	return response.Success(c, keys, "API keys retrieved successfully")
+}
+
+// CreateAPIKey handles API key creation
+// @Summary		Create API key
+// @Description	Create a long-lived API key for scripts and CI jobs. Send it as "Authorization: Bearer <key>" or "X-API-Key: <key>". The key is only shown once.
+// @Tags			api-keys
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		types.CreateAPIKeyRequest							true	"API key details"
+// @Success		201		{object}	response.Response{data=types.APIKeyCreatedResponse}	"API key created successfully"
+// @Failure		400		{object}	response.Response									"Bad request"
+// @Failure		401		{object}	response.Response									"Unauthorized"
+// @Failure		403		{object}	response.Response									"Not available to API keys"
+// @Failure		500		{object}	response.Response									"Internal server error"
+// @Router			/users/profile/tokens [post]
+func (h *APIKeyHandler) CreateAPIKey(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	var req types.CreateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	key, err := h.apiKeyService.Create(userID, &req)
+	if err != nil {
+		if errors.Is(err, types.ErrValidationFailed) {
+			return response.BadRequest(c, err.Error())
+		}
+		return response.InternalServerError(c, "Failed to create API key")
+	}
+
+	return response.Created(c, key, "API key created successfully")
+}
+
+// GetAPIKey handles retrieving an API key
+// @Summary		Get API key
+// @Description	Get one of the current user's API keys
+// @Tags			api-keys
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int											true	"API key ID"
+// @Success		200	{object}	response.Response{data=types.APIKeyResponse}	"API key retrieved successfully"
+// @Failure		400	{object}	response.Response							"Invalid API key ID"
+// @Failure		401	{object}	response.Response							"Unauthorized"
+// @Failure		403	{object}	response.Response							"Not available to API keys"
+// @Failure		404	{object}	response.Response							"API key not found"
+// @Failure		500	{object}	response.Response							"Internal server error"
+// @Router			/users/profile/tokens/{id} [get]
+func (h *APIKeyHandler) GetAPIKey(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid API key ID")
+	}
+
+	key, err := h.apiKeyService.Get(userID, uint(id))
+	if err != nil {
+		if err == types.ErrAPIKeyNotFound {
+			return response.NotFound(c, "API key not found")
+		}
+		return response.InternalServerError(c, "Failed to get API key")
+	}
+
+	return response.Success(c, key, "API key retrieved successfully")
+}
+
+// UpdateAPIKey handles API key updates
+// @Summary		Update API key
+// @Description	Rename one of the current user's API keys or change its scopes
+// @Tags			api-keys
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id		path		int											true	"API key ID"
+// @Param			request	body		types.UpdateAPIKeyRequest					true	"API key changes"
+// @Success		200		{object}	response.Response{data=types.APIKeyResponse}	"API key updated successfully"
+// @Failure		400		{object}	response.Response							"Bad request"
+// @Failure		401		{object}	response.Response							"Unauthorized"
+// @Failure		403		{object}	response.Response							"Not available to API keys"
+// @Failure		404		{object}	response.Response							"API key not found"
+// @Failure		500		{object}	response.Response							"Internal server error"
+// @Router			/users/profile/tokens/{id} [put]
+func (h *APIKeyHandler) UpdateAPIKey(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid API key ID")
+	}
+
+	var req types.UpdateAPIKeyRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	key, err := h.apiKeyService.Update(userID, uint(id), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrValidationFailed):
+			return response.BadRequest(c, err.Error())
+		case errors.Is(err, types.ErrAPIKeyNotFound):
+			return response.NotFound(c, "API key not found")
+		default:
+			return response.InternalServerError(c, "Failed to update API key")
+		}
+	}
+
+	return response.Success(c, key, "API key updated successfully")
+}
+
+// DeleteAPIKey handles API key revocation
+// @Summary		Revoke API key
+// @Description	Permanently revoke one of the current user's API keys
+// @Tags			api-keys
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int					true	"API key ID"
+// @Success		200	{object}	response.Response	"API key revoked successfully"
+// @Failure		400	{object}	response.Response	"Invalid API key ID"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		403	{object}	response.Response	"Not available to API keys"
+// @Failure		404	{object}	response.Response	"API key not found"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/users/profile/tokens/{id} [delete]
+func (h *APIKeyHandler) DeleteAPIKey(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid API key ID")
+	}
+
+	if err := h.apiKeyService.Delete(userID, uint(id)); err != nil {
+		if err == types.ErrAPIKeyNotFound {
+			return response.NotFound(c, "API key not found")
+		}
+		return response.InternalServerError(c, "Failed to revoke API key")
+	}
+
+	return response.Success(c, nil, "API key revoked successfully")
+}
+
+// RegisterRoutes registers API key routes. API keys cannot manage API keys.
+func (h *APIKeyHandler) RegisterRoutes(e *echo.Echo, authMiddleware echo.MiddlewareFunc) {
+	tokens := e.Group("/api/users/profile/tokens")
+
+	tokens.Use(authMiddleware, middleware.RequireJWT())
+	tokens.GET("", h.ListAPIKeys)
+	tokens.POST("", h.CreateAPIKey)
+	tokens.GET("/:id", h.GetAPIKey)
+	tokens.PUT("/:id", h.UpdateAPIKey)
+	tokens.DELETE("/:id", h.DeleteAPIKey)
+}
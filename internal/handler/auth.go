@@ -2,6 +2,7 @@ package handler
 
 import (
 	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/middleware"
 	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
 	"github.com/ray-d-song/go-echo-monolithic/internal/service"
 	"github.com/ray-d-song/go-echo-monolithic/internal/types"
@@ -21,13 +22,14 @@ func NewAuthHandler(authService *service.AuthService) *AuthHandler {
 
 // Register handles user registration
 // @Summary		Register a new user
-// @Description	Register a new user with username, email and password
+// @Description	Register a new user with username, email and password. An invitation code is required while self-service registration is closed.
 // @Tags			auth
 // @Accept			json
 // @Produce		json
 // @Param			request	body		types.RegisterRequest	true	"Registration request"
 // @Success		200		{object}	response.Response		"User registered successfully"
-// @Failure		400		{object}	response.Response		"Bad request"
+// @Failure		400		{object}	response.Response		"Bad request or invalid invitation code"
+// @Failure		403		{object}	response.Response		"Registration is closed"
 // @Failure		409		{object}	response.Response		"User already exists"
 // @Failure		500		{object}	response.Response		"Internal server error"
 // @Router			/auth/register [post]
@@ -37,13 +39,17 @@ func (h *AuthHandler) Register(c echo.Context) error {
 		return response.BadRequest(c, "Invalid request data")
 	}
 
-	result, err := h.authService.Register(&req)
+	result, err := h.authService.Register(&req, clientInfo(c))
 	if err != nil {
 		switch err {
 		case types.ErrUserAlreadyExists:
 			return response.Conflict(c, "User already exists")
 		case types.ErrValidationFailed:
 			return response.BadRequest(c, "Validation failed")
+		case types.ErrInvalidInvitation:
+			return response.BadRequest(c, "Invalid or expired invitation code")
+		case types.ErrRegistrationClosed:
+			return response.Forbidden(c, "Registration is closed, an invitation code is required")
 		default:
 			return response.InternalServerError(c, "Registration failed")
 		}
@@ -62,6 +68,8 @@ func (h *AuthHandler) Register(c echo.Context) error {
 // @Success		200		{object}	response.Response	"Login successful"
 // @Failure		400		{object}	response.Response	"Bad request"
 // @Failure		401		{object}	response.Response	"Invalid credentials"
+// @Failure		403		{object}	response.Response	"Account disabled or email not verified"
+// @Failure		429		{object}	response.Response	"Too many failed attempts"
 // @Failure		500		{object}	response.Response	"Internal server error"
 // @Router			/auth/login [post]
 func (h *AuthHandler) Login(c echo.Context) error {
@@ -70,13 +78,19 @@ func (h *AuthHandler) Login(c echo.Context) error {
 		return response.BadRequest(c, "Invalid request data")
 	}
 
-	result, err := h.authService.Login(&req)
+	result, err := h.authService.Login(&req, clientInfo(c))
 	if err != nil {
+		if ok, resp := throttled(c, err); ok {
+			return resp
+		}
+
 		switch err {
 		case types.ErrInvalidCredentials:
 			return response.Unauthorized(c, "Invalid credentials")
 		case types.ErrForbidden:
 			return response.Forbidden(c, "Account is disabled")
+		case types.ErrEmailNotVerified:
+			return response.Forbidden(c, "Email address is not verified")
 		case types.ErrValidationFailed:
 			return response.BadRequest(c, "Validation failed")
 		default:
@@ -89,7 +103,7 @@ func (h *AuthHandler) Login(c echo.Context) error {
 
 // RefreshToken handles token refresh
 // @Summary		Refresh access token
-// @Description	Refresh access token using refresh token
+// @Description	Refresh access token using refresh token. The refresh token is rotated; presenting an already rotated token revokes every token of its login session.
 // @Tags			auth
 // @Accept			json
 // @Produce		json
@@ -106,7 +120,7 @@ func (h *AuthHandler) RefreshToken(c echo.Context) error {
 		return response.BadRequest(c, "Invalid request data")
 	}
 
-	result, err := h.authService.RefreshToken(&req)
+	result, err := h.authService.RefreshToken(&req, clientInfo(c))
 	if err != nil {
 		switch err {
 		case types.ErrInvalidToken:
@@ -170,13 +184,64 @@ func (h *AuthHandler) LogoutAllDevices(c echo.Context) error {
 	return response.Success(c, nil, "Logged out from all devices successfully")
 }
 
+// ListSessions handles listing the user's login sessions
+// @Summary		List sessions
+// @Description	List the active login sessions of the current user, one per device. The session making the request is flagged as current.
+// @Tags			auth
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200		{object}	response.Response{data=[]types.SessionResponse}	"Sessions retrieved successfully"
+// @Failure		401		{object}	response.Response								"Unauthorized"
+// @Failure		500		{object}	response.Response								"Internal server error"
+// @Router			/auth/sessions [get]
+func (h *AuthHandler) ListSessions(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+	sessionID, _ := middleware.GetSessionID(c)
+
+	sessions, err := h.authService.ListSessions(userID, sessionID)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to retrieve sessions")
+	}
+
+	return response.Success(c, sessions, "Sessions retrieved successfully")
+}
+
+// RevokeSession handles logging out a single session
+// @Summary		Revoke session
+// @Description	Log out one of the current user's sessions by revoking its refresh token
+// @Tags			auth
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id		path		string				true	"Session ID"
+// @Success		200		{object}	response.Response	"Session revoked successfully"
+// @Failure		401		{object}	response.Response	"Unauthorized"
+// @Failure		404		{object}	response.Response	"Session not found"
+// @Failure		500		{object}	response.Response	"Internal server error"
+// @Router			/auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	if err := h.authService.RevokeSession(userID, c.Param("id")); err != nil {
+		switch err {
+		case types.ErrSessionNotFound:
+			return response.NotFound(c, "Session not found")
+		default:
+			return response.InternalServerError(c, "Failed to revoke session")
+		}
+	}
+
+	return response.Success(c, nil, "Session revoked successfully")
+}
+
 // RegisterRoutes registers auth routes
-func (h *AuthHandler) RegisterRoutes(e *echo.Echo) {
+func (h *AuthHandler) RegisterRoutes(e *echo.Echo, authMiddleware echo.MiddlewareFunc) {
 	auth := e.Group("/api/auth")
 
 	auth.POST("/register", h.Register)
 	auth.POST("/login", h.Login)
 	auth.POST("/refresh", h.RefreshToken)
 	auth.POST("/logout", h.Logout)
-	auth.POST("/logout-all", h.LogoutAllDevices)
+	auth.POST("/logout-all", h.LogoutAllDevices, authMiddleware, middleware.RequireJWT())
+	auth.GET("/sessions", h.ListSessions, authMiddleware, middleware.RequireJWT())
+	auth.DELETE("/sessions/:id", h.RevokeSession, authMiddleware, middleware.RequireJWT())
 }
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// clientInfo describes the client making the current request
+func clientInfo(c echo.Context) *types.ClientInfo {
+	return &types.ClientInfo{
+		IPAddress: c.RealIP(),
+		UserAgent: c.Request().UserAgent(),
+	}
+}
+
+// throttled writes a 429 response if err refuses the client for a limited time
+func throttled(c echo.Context, err error) (bool, error) {
+	var retryErr *types.RetryAfterError
+	if !errors.As(err, &retryErr) {
+		return false, nil
+	}
+
+	message := "Too many failed attempts, try again later"
+	if errors.Is(err, types.ErrAccountLocked) {
+		message = "Too many failed attempts, account temporarily locked"
+	}
+	return true, response.TooManyRequests(c, retryErr.RetryAfter, message)
+}
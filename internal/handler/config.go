@@ -4,7 +4,8 @@ import (
 	"strconv"
 
 	"github.com/labstack/echo/v4"
-	"github.com/ray-d-song/go-echo-monolithic/internal/handler/wrapper"
+	"github.com/ray-d-song/go-echo-monolithic/internal/middleware"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
 	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
 	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
 )
@@ -30,29 +31,11 @@ func NewConfigHandler(kvRepo *repository.KVRepository) *ConfigHandler {
 // @Security		BearerAuth
 // @Success		200	{object}	response.Response	"Registration setting toggled successfully"
 // @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		403	{object}	response.Response	"Forbidden"
 // @Failure		500	{object}	response.Response	"Internal server error"
 // @Router			/api/config/registration/toggle [post]
 func (h *ConfigHandler) ToggleUserRegistration(c echo.Context) error {
-	key := "system:allow_register"
-
-	// Get current value
-	currentValue, err := h.kvRepo.Get(key)
-	if err != nil {
-		return response.InternalServerError(c, "Failed to get registration setting")
-	}
-
-	// Convert to boolean (default to false if empty)
-	currentBool := false
-	if currentValue != "" {
-		currentBool, err = strconv.ParseBool(currentValue)
-		if err != nil {
-			return response.InternalServerError(c, "Invalid registration setting format")
-		}
-	}
-
-	// Toggle the value
-	newValue := !currentBool
-	err = h.kvRepo.Set(key, strconv.FormatBool(newValue))
+	newValue, err := h.toggleSetting(model.SettingAllowRegister)
 	if err != nil {
 		return response.InternalServerError(c, "Failed to update registration setting")
 	}
@@ -63,10 +46,50 @@ func (h *ConfigHandler) ToggleUserRegistration(c echo.Context) error {
 	}, "Registration setting toggled successfully")
 }
 
+// ToggleMagicLinkLogin toggles the magic link login setting
+// @Summary		Toggle magic link login
+// @Description	Toggle whether users can sign in with single-use links sent by email
+// @Tags			config
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response	"Magic link setting toggled successfully"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		403	{object}	response.Response	"Forbidden"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/api/config/magic-link/toggle [post]
+func (h *ConfigHandler) ToggleMagicLinkLogin(c echo.Context) error {
+	newValue, err := h.toggleSetting(model.SettingAllowMagicLink)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to update magic link setting")
+	}
+
+	return response.Success(c, map[string]interface{}{
+		"allow_magic_link": newValue,
+		"message":          "Magic link setting updated successfully",
+	}, "Magic link setting toggled successfully")
+}
+
+// toggleSetting flips a boolean setting, which defaults to false, and returns
+// its new value
+func (h *ConfigHandler) toggleSetting(key string) (bool, error) {
+	currentValue, err := h.kvRepo.GetBool(key)
+	if err != nil {
+		return false, err
+	}
+
+	newValue := !currentValue
+	if err := h.kvRepo.Set(key, strconv.FormatBool(newValue)); err != nil {
+		return false, err
+	}
+	return newValue, nil
+}
+
 // RegisterRoutes registers config routes
 func (h *ConfigHandler) RegisterRoutes(e *echo.Echo, authMiddleware echo.MiddlewareFunc) {
 	config := e.Group("/api/config")
 
 	config.Use(authMiddleware)
-	config.POST("/registration/toggle", wrapper.AdminWrapper(h.ToggleUserRegistration))
+	config.POST("/registration/toggle", h.ToggleUserRegistration, middleware.RequirePermission(model.PermissionConfigWrite))
+	config.POST("/magic-link/toggle", h.ToggleMagicLinkLogin, middleware.RequirePermission(model.PermissionConfigWrite))
 }
@@ -0,0 +1,316 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// multipartOverhead is the room left for multipart headers and boundaries
+// above the largest accepted upload
+const multipartOverhead = 64 << 10
+
+// FileHandler handles file upload, download and avatar HTTP requests
+type FileHandler struct {
+	fileService *service.FileService
+	userService *service.UserService
+}
+
+// NewFileHandler creates a new file handler
+func NewFileHandler(fileService *service.FileService, userService *service.UserService) *FileHandler {
+	return &FileHandler{
+		fileService: fileService,
+		userService: userService,
+	}
+}
+
+// Upload handles uploading a file
+// @Summary		Upload file
+// @Description	Upload a file as multipart form data. Its type is detected from its content and must be one of the allowed types. Images get a thumbnail. The response carries signed download URLs that expire.
+// @Tags			files
+// @Accept			multipart/form-data
+// @Produce		json
+// @Security		BearerAuth
+// @Param			file	formData	file											true	"File to upload"
+// @Success		201		{object}	response.Response{data=types.FileResponse}	"File uploaded successfully"
+// @Failure		400		{object}	response.Response							"Bad request"
+// @Failure		401		{object}	response.Response							"Unauthorized"
+// @Failure		413		{object}	response.Response							"File too large"
+// @Failure		415		{object}	response.Response							"File type not allowed"
+// @Failure		500		{object}	response.Response							"Internal server error"
+// @Router			/files [post]
+func (h *FileHandler) Upload(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	header, content, err := h.formFile(c, "file")
+	if err != nil {
+		return fileError(c, err, "Failed to upload file")
+	}
+	defer content.Close()
+
+	file, err := h.fileService.Upload(c.Request().Context(), userID, header.Filename, content, header.Size)
+	if err != nil {
+		return fileError(c, err, "Failed to upload file")
+	}
+
+	return response.Created(c, h.fileService.ToResponse(file), "File uploaded successfully")
+}
+
+// ListFiles handles listing the current user's files
+// @Summary		List files
+// @Description	List the files uploaded by the current user, newest first, with freshly signed download URLs
+// @Tags			files
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response{data=[]types.FileResponse}	"Files retrieved successfully"
+// @Failure		401	{object}	response.Response							"Unauthorized"
+// @Failure		500	{object}	response.Response							"Internal server error"
+// @Router			/files [get]
+func (h *FileHandler) ListFiles(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	files, err := h.fileService.List(userID)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to list files")
+	}
+
+	responses := make([]*types.FileResponse, len(files))
+	for i, file := range files {
+		responses[i] = h.fileService.ToResponse(file)
+	}
+
+	return response.Success(c, responses, "Files retrieved successfully")
+}
+
+// GetFile handles retrieving one of the current user's files
+// @Summary		Get file
+// @Description	Get one of the current user's files with freshly signed download URLs
+// @Tags			files
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int											true	"File ID"
+// @Success		200	{object}	response.Response{data=types.FileResponse}	"File retrieved successfully"
+// @Failure		400	{object}	response.Response							"Invalid file ID"
+// @Failure		401	{object}	response.Response							"Unauthorized"
+// @Failure		404	{object}	response.Response							"File not found"
+// @Failure		500	{object}	response.Response							"Internal server error"
+// @Router			/files/{id} [get]
+func (h *FileHandler) GetFile(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid file ID")
+	}
+
+	file, err := h.fileService.Get(userID, uint(id))
+	if err != nil {
+		return fileError(c, err, "Failed to get file")
+	}
+
+	return response.Success(c, h.fileService.ToResponse(file), "File retrieved successfully")
+}
+
+// DeleteFile handles deleting one of the current user's files
+// @Summary		Delete file
+// @Description	Delete one of the current user's files. Its signed URLs stop working.
+// @Tags			files
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int					true	"File ID"
+// @Success		200	{object}	response.Response	"File deleted successfully"
+// @Failure		400	{object}	response.Response	"Invalid file ID"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		404	{object}	response.Response	"File not found"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/files/{id} [delete]
+func (h *FileHandler) DeleteFile(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid file ID")
+	}
+
+	if err := h.fileService.Delete(c.Request().Context(), userID, uint(id)); err != nil {
+		return fileError(c, err, "Failed to delete file")
+	}
+
+	return response.Success(c, nil, "File deleted successfully")
+}
+
+// Download handles downloading a file or its thumbnail through a signed URL
+// @Summary		Download file
+// @Description	Download the content or the JPEG thumbnail of a file. The URL must carry the expiry and signature it was issued with; no other authentication is needed.
+// @Tags			files
+// @Produce		octet-stream
+// @Param			id			path		int					true	"File ID"
+// @Param			variant		path		string				true	"Variant"	Enums(content, thumbnail)
+// @Param			expires		query		int					true	"Expiry as a Unix timestamp"
+// @Param			signature	query		string				true	"URL signature"
+// @Success		200			{file}		file				"File content"
+// @Failure		400			{object}	response.Response	"Invalid file ID"
+// @Failure		403			{object}	response.Response	"Invalid or expired link"
+// @Failure		404			{object}	response.Response	"File not found"
+// @Failure		500			{object}	response.Response	"Internal server error"
+// @Router			/files/{id}/{variant} [get]
+func (h *FileHandler) Download(variant string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return response.BadRequest(c, "Invalid file ID")
+		}
+
+		content, err := h.fileService.OpenSigned(c.Request().Context(), uint(id), variant, c.QueryParams())
+		if err != nil {
+			if errors.Is(err, types.ErrForbidden) {
+				return response.Forbidden(c, "Invalid or expired link")
+			}
+			return fileError(c, err, "Failed to download file")
+		}
+		defer content.Body.Close()
+
+		disposition := "attachment"
+		if strings.HasPrefix(content.ContentType, "image/") {
+			disposition = "inline"
+		}
+		name := content.Name
+		if variant == service.FileVariantThumbnail {
+			name = "thumbnail.jpg"
+		}
+
+		header := c.Response().Header()
+		header.Set(echo.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{"filename": name}))
+		header.Set(echo.HeaderXContentTypeOptions, "nosniff")
+		if content.Size >= 0 {
+			header.Set(echo.HeaderContentLength, strconv.FormatInt(content.Size, 10))
+		}
+		return c.Stream(http.StatusOK, content.ContentType, content.Body)
+	}
+}
+
+// SetAvatar handles replacing the current user's avatar
+// @Summary		Set avatar
+// @Description	Upload a JPEG, PNG or GIF image as multipart form data to replace the current user's avatar. Its thumbnail is the centered square of the image.
+// @Tags			users
+// @Accept			multipart/form-data
+// @Produce		json
+// @Security		BearerAuth
+// @Param			avatar	formData	file											true	"Avatar image"
+// @Success		200		{object}	response.Response{data=types.UserResponse}	"Avatar updated successfully"
+// @Failure		400		{object}	response.Response							"Bad request"
+// @Failure		401		{object}	response.Response							"Unauthorized"
+// @Failure		413		{object}	response.Response							"File too large"
+// @Failure		415		{object}	response.Response							"File type not allowed"
+// @Failure		500		{object}	response.Response							"Internal server error"
+// @Router			/users/profile/avatar [put]
+func (h *FileHandler) SetAvatar(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	header, content, err := h.formFile(c, "avatar")
+	if err != nil {
+		return fileError(c, err, "Failed to update avatar")
+	}
+	defer content.Close()
+
+	user, err := h.fileService.SetAvatar(c.Request().Context(), userID, header.Filename, content, header.Size)
+	if err != nil {
+		return fileError(c, err, "Failed to update avatar")
+	}
+
+	return response.Success(c, h.userService.ToResponse(user), "Avatar updated successfully")
+}
+
+// RemoveAvatar handles removing the current user's avatar
+// @Summary		Remove avatar
+// @Description	Remove the current user's avatar
+// @Tags			users
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response{data=types.UserResponse}	"Avatar removed successfully"
+// @Failure		401	{object}	response.Response							"Unauthorized"
+// @Failure		500	{object}	response.Response							"Internal server error"
+// @Router			/users/profile/avatar [delete]
+func (h *FileHandler) RemoveAvatar(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	user, err := h.fileService.RemoveAvatar(c.Request().Context(), userID)
+	if err != nil {
+		return fileError(c, err, "Failed to remove avatar")
+	}
+
+	return response.Success(c, h.userService.ToResponse(user), "Avatar removed successfully")
+}
+
+// formFile reads a file from a multipart form, refusing request bodies
+// larger than the largest accepted upload
+func (h *FileHandler) formFile(c echo.Context, field string) (*multipart.FileHeader, multipart.File, error) {
+	maxUploadSize := h.fileService.MaxUploadSize()
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, maxUploadSize+multipartOverhead)
+
+	header, err := c.FormFile(field)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, nil, types.ErrFileTooLarge
+		}
+		return nil, nil, fmt.Errorf("%w: %s is required", types.ErrValidationFailed, field)
+	}
+	if header.Size > maxUploadSize {
+		return nil, nil, types.ErrFileTooLarge
+	}
+
+	content, err := header.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, content, nil
+}
+
+// fileError maps file errors to responses
+func fileError(c echo.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, types.ErrFileTooLarge):
+		return response.RequestEntityTooLarge(c, "File too large")
+	case errors.Is(err, types.ErrFileTypeNotAllowed):
+		return response.UnsupportedMediaType(c, "File type not allowed")
+	case errors.Is(err, types.ErrValidationFailed):
+		return response.BadRequest(c, err.Error())
+	case errors.Is(err, types.ErrFileNotFound):
+		return response.NotFound(c, "File not found")
+	case errors.Is(err, types.ErrUserNotFound):
+		return response.NotFound(c, "User not found")
+	default:
+		return response.InternalServerError(c, message)
+	}
+}
+
+// RegisterRoutes registers file and avatar routes. Files are downloaded
+// through signed URLs, which work without authentication so that they can be
+// used in links and image tags.
+func (h *FileHandler) RegisterRoutes(e *echo.Echo, authMiddleware echo.MiddlewareFunc) {
+	e.GET("/api/files/:id/content", h.Download(service.FileVariantContent))
+	e.GET("/api/files/:id/thumbnail", h.Download(service.FileVariantThumbnail))
+
+	files := e.Group("/api/files")
+	files.Use(authMiddleware)
+	files.POST("", h.Upload)
+	files.GET("", h.ListFiles)
+	files.GET("/:id", h.GetFile)
+	files.DELETE("/:id", h.DeleteFile)
+
+	avatar := e.Group("/api/users/profile/avatar")
+	avatar.Use(authMiddleware)
+	avatar.PUT("", h.SetAvatar)
+	avatar.DELETE("", h.RemoveAvatar)
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/middleware"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// InvitationHandler handles registration invitation HTTP requests
+type InvitationHandler struct {
+	invitationService *service.InvitationService
+}
+
+// NewInvitationHandler creates a new invitation handler
+func NewInvitationHandler(invitationService *service.InvitationService) *InvitationHandler {
+	return &InvitationHandler{
+		invitationService: invitationService,
+	}
+}
+
+// ListInvitations handles listing registration invitations
+// @Summary		List invitations
+// @Description	List registration invitations. Only the code prefixes are shown.
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response{data=[]types.InvitationResponse}	"Invitations retrieved successfully"
+// @Failure		401	{object}	response.Response									"Unauthorized"
+// @Failure		403	{object}	response.Response									"Forbidden"
+// @Failure		500	{object}	response.Response									"Internal server error"
+// @Router			/admin/invitations [get]
+func (h *InvitationHandler) ListInvitations(c echo.Context) error {
+	invitations, err := h.invitationService.List()
+	if err != nil {
+		return response.InternalServerError(c, "Failed to retrieve invitations")
+	}
+
+	return response.Success(c, invitations, "Invitations retrieved successfully")
+}
+
+// GetInvitation handles retrieving a registration invitation
+// @Summary		Get invitation
+// @Description	Get a registration invitation and how often it has been used
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int												true	"Invitation ID"
+// @Success		200	{object}	response.Response{data=types.InvitationResponse}	"Invitation retrieved successfully"
+// @Failure		400	{object}	response.Response								"Invalid invitation ID"
+// @Failure		401	{object}	response.Response								"Unauthorized"
+// @Failure		403	{object}	response.Response								"Forbidden"
+// @Failure		404	{object}	response.Response								"Invitation not found"
+// @Failure		500	{object}	response.Response								"Internal server error"
+// @Router			/admin/invitations/{id} [get]
+func (h *InvitationHandler) GetInvitation(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid invitation ID")
+	}
+
+	invitation, err := h.invitationService.Get(uint(id))
+	if err != nil {
+		if err == types.ErrInvitationNotFound {
+			return response.NotFound(c, "Invitation not found")
+		}
+		return response.InternalServerError(c, "Failed to retrieve invitation")
+	}
+
+	return response.Success(c, invitation, "Invitation retrieved successfully")
+}
+
+// CreateInvitation handles registration invitation creation
+// @Summary		Create invitation
+// @Description	Create an invitation code that allows registering while self-service registration is closed. It can be bound to an email address, grant a role other than the default one, be used several times and expire. The code is only shown once.
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		types.CreateInvitationRequest							true	"Invitation details"
+// @Success		201		{object}	response.Response{data=types.InvitationCreatedResponse}	"Invitation created successfully"
+// @Failure		400		{object}	response.Response										"Bad request"
+// @Failure		401		{object}	response.Response										"Unauthorized"
+// @Failure		403		{object}	response.Response										"Forbidden"
+// @Failure		404		{object}	response.Response										"Role not found"
+// @Failure		500		{object}	response.Response										"Internal server error"
+// @Router			/admin/invitations [post]
+func (h *InvitationHandler) CreateInvitation(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	var req types.CreateInvitationRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	invitation, err := h.invitationService.Create(userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrValidationFailed):
+			return response.BadRequest(c, err.Error())
+		case errors.Is(err, types.ErrRoleNotFound):
+			return response.NotFound(c, "Role not found")
+		default:
+			return response.InternalServerError(c, "Failed to create invitation")
+		}
+	}
+
+	return response.Created(c, invitation, "Invitation created successfully")
+}
+
+// RevokeInvitation handles registration invitation revocation
+// @Summary		Revoke invitation
+// @Description	Revoke a registration invitation. Users who already registered with it are not affected.
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int					true	"Invitation ID"
+// @Success		200	{object}	response.Response	"Invitation revoked successfully"
+// @Failure		400	{object}	response.Response	"Invalid invitation ID"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		403	{object}	response.Response	"Forbidden"
+// @Failure		404	{object}	response.Response	"Invitation not found"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/admin/invitations/{id} [delete]
+func (h *InvitationHandler) RevokeInvitation(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid invitation ID")
+	}
+
+	if err := h.invitationService.Revoke(uint(id)); err != nil {
+		if err == types.ErrInvitationNotFound {
+			return response.NotFound(c, "Invitation not found")
+		}
+		return response.InternalServerError(c, "Failed to revoke invitation")
+	}
+
+	return response.Success(c, nil, "Invitation revoked successfully")
+}
+
+// RegisterRoutes registers invitation routes
+func (h *InvitationHandler) RegisterRoutes(e *echo.Echo, authMiddleware echo.MiddlewareFunc) {
+	invitations := e.Group("/api/admin/invitations")
+
+	invitations.Use(authMiddleware)
+	invitations.GET("", h.ListInvitations, middleware.RequirePermission(model.PermissionInvitationsRead))
+	invitations.GET("/:id", h.GetInvitation, middleware.RequirePermission(model.PermissionInvitationsRead))
+	invitations.POST("", h.CreateInvitation, middleware.RequirePermission(model.PermissionInvitationsWrite))
+	invitations.DELETE("/:id", h.RevokeInvitation, middleware.RequirePermission(model.PermissionInvitationsWrite))
+}
@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// MagicLinkHandler handles passwordless login HTTP requests
+type MagicLinkHandler struct {
+	magicLinkService *service.MagicLinkService
+}
+
+// NewMagicLinkHandler creates a new magic link handler
+func NewMagicLinkHandler(magicLinkService *service.MagicLinkService) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		magicLinkService: magicLinkService,
+	}
+}
+
+// SendLink handles magic link requests
+// @Summary		Request a magic link
+// @Description	Send a single-use sign-in link to the given email address. The response is the same whether or not the address is registered.
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			request	body		types.MagicLinkRequest	true	"Magic link request"
+// @Success		200		{object}	response.Response		"Magic link sent"
+// @Failure		400		{object}	response.Response		"Bad request"
+// @Failure		403		{object}	response.Response		"Magic link login is disabled"
+// @Failure		500		{object}	response.Response		"Internal server error"
+// @Router			/auth/magic-link [post]
+func (h *MagicLinkHandler) SendLink(c echo.Context) error {
+	var req types.MagicLinkRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	if err := h.magicLinkService.SendLink(&req); err != nil {
+		switch err {
+		case types.ErrMagicLinkDisabled:
+			return response.Forbidden(c, "Magic link login is disabled")
+		case types.ErrValidationFailed:
+			return response.BadRequest(c, "Email is required")
+		default:
+			return response.InternalServerError(c, "Failed to send magic link")
+		}
+	}
+
+	return response.Success(c, nil, "If the email is registered, a sign-in link has been sent")
+}
+
+// Verify handles magic link login
+// @Summary		Sign in with a magic link
+// @Description	Exchange a magic link token for an access and refresh token pair. Users with two-factor authentication enabled receive an mfa_token to complete the login at /auth/mfa/verify.
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			request	body		types.MagicLinkVerifyRequest	true	"Magic link verify request"
+// @Success		200		{object}	response.Response{data=types.AuthResponse}	"Login successful"
+// @Failure		400		{object}	response.Response							"Bad request"
+// @Failure		401		{object}	response.Response							"Invalid or expired token"
+// @Failure		403		{object}	response.Response							"Account disabled or magic link login disabled"
+// @Failure		500		{object}	response.Response							"Internal server error"
+// @Router			/auth/magic-link/verify [post]
+func (h *MagicLinkHandler) Verify(c echo.Context) error {
+	var req types.MagicLinkVerifyRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	result, err := h.magicLinkService.Login(&req, clientInfo(c))
+	if err != nil {
+		switch err {
+		case types.ErrInvalidToken:
+			return response.Unauthorized(c, "Invalid magic link")
+		case types.ErrTokenExpired:
+			return response.Unauthorized(c, "Magic link expired")
+		case types.ErrTokenRevoked:
+			return response.Unauthorized(c, "Magic link already used")
+		case types.ErrForbidden:
+			return response.Forbidden(c, "Account is disabled")
+		case types.ErrMagicLinkDisabled:
+			return response.Forbidden(c, "Magic link login is disabled")
+		case types.ErrUserNotFound:
+			return response.NotFound(c, "User not found")
+		default:
+			return response.InternalServerError(c, "Login failed")
+		}
+	}
+
+	return response.Success(c, result, "Login successful")
+}
+
+// RegisterRoutes registers magic link routes
+func (h *MagicLinkHandler) RegisterRoutes(e *echo.Echo) {
+	magicLink := e.Group("/api/auth/magic-link")
+
+	magicLink.POST("", h.SendLink)
+	magicLink.POST("/verify", h.Verify)
+}
@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/middleware"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// MFAHandler handles two-factor authentication HTTP requests
+type MFAHandler struct {
+	mfaService  *service.MFAService
+	authService *service.AuthService
+}
+
+// NewMFAHandler creates a new MFA handler
+func NewMFAHandler(mfaService *service.MFAService, authService *service.AuthService) *MFAHandler {
+	return &MFAHandler{
+		mfaService:  mfaService,
+		authService: authService,
+	}
+}
+
+// Enroll starts two-factor enrollment
+// @Summary		Start two-factor enrollment
+// @Description	Generate a new TOTP secret and otpauth URI for the current user. Two-factor authentication is not active until confirmed.
+// @Tags			mfa
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response	"Enrollment started"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		409	{object}	response.Response	"Two-factor authentication already enabled"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/auth/mfa/enroll [post]
+func (h *MFAHandler) Enroll(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	result, err := h.mfaService.Enroll(userID)
+	if err != nil {
+		switch err {
+		case types.ErrMFAAlreadyEnabled:
+			return response.Conflict(c, "Two-factor authentication is already enabled")
+		case types.ErrUserNotFound:
+			return response.NotFound(c, "User not found")
+		default:
+			return response.InternalServerError(c, "Failed to start two-factor enrollment")
+		}
+	}
+
+	return response.Success(c, result, "Two-factor enrollment started")
+}
+
+// Confirm completes two-factor enrollment
+// @Summary		Confirm two-factor enrollment
+// @Description	Activate two-factor authentication with a code from the authenticator app. Returns one-time recovery codes that are only shown once.
+// @Tags			mfa
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		types.MFACodeRequest	true	"Confirmation code"
+// @Success		200		{object}	response.Response		"Two-factor authentication enabled"
+// @Failure		400		{object}	response.Response		"Bad request or enrollment not started"
+// @Failure		401		{object}	response.Response		"Invalid code"
+// @Failure		409		{object}	response.Response		"Two-factor authentication already enabled"
+// @Failure		500		{object}	response.Response		"Internal server error"
+// @Router			/auth/mfa/confirm [post]
+func (h *MFAHandler) Confirm(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	var req types.MFACodeRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	result, err := h.mfaService.Confirm(userID, &req)
+	if err != nil {
+		switch err {
+		case types.ErrMFAAlreadyEnabled:
+			return response.Conflict(c, "Two-factor authentication is already enabled")
+		case types.ErrMFANotEnrolled:
+			return response.BadRequest(c, "Two-factor enrollment has not been started")
+		case types.ErrInvalidMFACode:
+			return response.Unauthorized(c, "Invalid two-factor code")
+		case types.ErrUserNotFound:
+			return response.NotFound(c, "User not found")
+		default:
+			return response.InternalServerError(c, "Failed to confirm two-factor enrollment")
+		}
+	}
+
+	return response.Success(c, result, "Two-factor authentication enabled")
+}
+
+// Disable turns off two-factor authentication
+// @Summary		Disable two-factor authentication
+// @Description	Turn off two-factor authentication. Requires the current password and a TOTP or recovery code.
+// @Tags			mfa
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		types.DisableMFARequest	true	"Disable request"
+// @Success		200		{object}	response.Response		"Two-factor authentication disabled"
+// @Failure		400		{object}	response.Response		"Two-factor authentication not enabled"
+// @Failure		401		{object}	response.Response		"Invalid password or code"
+// @Failure		429		{object}	response.Response		"Too many failed attempts"
+// @Failure		500		{object}	response.Response		"Internal server error"
+// @Router			/auth/mfa/disable [post]
+func (h *MFAHandler) Disable(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	var req types.DisableMFARequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	if err := h.mfaService.Disable(userID, &req, clientInfo(c)); err != nil {
+		if ok, resp := throttled(c, err); ok {
+			return resp
+		}
+
+		switch err {
+		case types.ErrMFANotEnabled:
+			return response.BadRequest(c, "Two-factor authentication is not enabled")
+		case types.ErrInvalidCredentials:
+			return response.Unauthorized(c, "Invalid password")
+		case types.ErrInvalidMFACode:
+			return response.Unauthorized(c, "Invalid two-factor code")
+		case types.ErrUserNotFound:
+			return response.NotFound(c, "User not found")
+		default:
+			return response.InternalServerError(c, "Failed to disable two-factor authentication")
+		}
+	}
+
+	return response.Success(c, nil, "Two-factor authentication disabled")
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the current user
+// @Summary		Regenerate recovery codes
+// @Description	Invalidate all existing recovery codes and issue a new set. Requires a TOTP or recovery code.
+// @Tags			mfa
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		types.MFACodeRequest	true	"Current code"
+// @Success		200		{object}	response.Response		"Recovery codes regenerated"
+// @Failure		400		{object}	response.Response		"Two-factor authentication not enabled"
+// @Failure		401		{object}	response.Response		"Invalid code"
+// @Failure		429		{object}	response.Response		"Too many failed attempts"
+// @Failure		500		{object}	response.Response		"Internal server error"
+// @Router			/auth/mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	var req types.MFACodeRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	result, err := h.mfaService.RegenerateRecoveryCodes(userID, &req, clientInfo(c))
+	if err != nil {
+		if ok, resp := throttled(c, err); ok {
+			return resp
+		}
+
+		switch err {
+		case types.ErrMFANotEnabled:
+			return response.BadRequest(c, "Two-factor authentication is not enabled")
+		case types.ErrInvalidMFACode:
+			return response.Unauthorized(c, "Invalid two-factor code")
+		case types.ErrUserNotFound:
+			return response.NotFound(c, "User not found")
+		default:
+			return response.InternalServerError(c, "Failed to regenerate recovery codes")
+		}
+	}
+
+	return response.Success(c, result, "Recovery codes regenerated")
+}
+
+// Verify completes a two-factor login
+// @Summary		Complete two-factor login
+// @Description	Exchange the mfa_token returned by login and a TOTP or recovery code for an access and refresh token pair
+// @Tags			mfa
+// @Accept			json
+// @Produce		json
+// @Param			request	body		types.MFAVerifyRequest	true	"Verification request"
+// @Success		200		{object}	response.Response		"Login successful"
+// @Failure		400		{object}	response.Response		"Bad request"
+// @Failure		401		{object}	response.Response		"Invalid token or code"
+// @Failure		403		{object}	response.Response		"Account disabled"
+// @Failure		429		{object}	response.Response		"Too many failed attempts"
+// @Failure		500		{object}	response.Response		"Internal server error"
+// @Router			/auth/mfa/verify [post]
+func (h *MFAHandler) Verify(c echo.Context) error {
+	var req types.MFAVerifyRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	result, err := h.authService.VerifyMFA(&req, clientInfo(c))
+	if err != nil {
+		if ok, resp := throttled(c, err); ok {
+			return resp
+		}
+
+		switch err {
+		case types.ErrInvalidToken:
+			return response.Unauthorized(c, "Invalid or expired mfa token")
+		case types.ErrInvalidMFACode:
+			return response.Unauthorized(c, "Invalid two-factor code")
+		case types.ErrMFANotEnabled:
+			return response.BadRequest(c, "Two-factor authentication is not enabled")
+		case types.ErrForbidden:
+			return response.Forbidden(c, "Account is disabled")
+		case types.ErrUserNotFound:
+			return response.Unauthorized(c, "Invalid or expired mfa token")
+		default:
+			return response.InternalServerError(c, "Login failed")
+		}
+	}
+
+	return response.Success(c, result, "Login successful")
+}
+
+// RegisterRoutes registers two-factor authentication routes
+func (h *MFAHandler) RegisterRoutes(e *echo.Echo, authMiddleware echo.MiddlewareFunc) {
+	mfa := e.Group("/api/auth/mfa")
+
+	mfa.POST("/verify", h.Verify)
+
+	// Protected routes
+	mfa.POST("/enroll", h.Enroll, authMiddleware, middleware.RequireJWT())
+	mfa.POST("/confirm", h.Confirm, authMiddleware, middleware.RequireJWT())
+	mfa.POST("/disable", h.Disable, authMiddleware, middleware.RequireJWT())
+	mfa.POST("/recovery-codes", h.RegenerateRecoveryCodes, authMiddleware, middleware.RequireJWT())
+}
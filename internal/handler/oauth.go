@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/middleware"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/oauth"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// oauthStateCookie remembers the state of the authorization flow the browser
+// started, binding the callback to that browser
+const oauthStateCookie = "oauth_state"
+
+// OAuthHandler handles social login HTTP requests
+type OAuthHandler struct {
+	oauthService *service.OAuthService
+}
+
+// NewOAuthHandler creates a new OAuth handler
+func NewOAuthHandler(oauthService *service.OAuthService) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+	}
+}
+
+// ListProviders handles listing social login providers
+// @Summary		List social login providers
+// @Description	List the providers users can sign in with
+// @Tags			oauth
+// @Produce		json
+// @Success		200	{object}	response.Response{data=[]types.OAuthProviderResponse}	"Providers retrieved successfully"
+// @Router			/auth/oauth/providers [get]
+func (h *OAuthHandler) ListProviders(c echo.Context) error {
+	return response.Success(c, h.oauthService.Providers(), "Providers retrieved successfully")
+}
+
+// Authorize handles starting a social login
+// @Summary		Sign in with provider
+// @Description	Redirect the browser to the provider to sign in, using the authorization code flow with PKCE. A cookie binds the flow to the browser.
+// @Tags			oauth
+// @Param			provider	path		string				true	"Provider name"
+// @Param			return_to	query		string				false	"Path to send the browser to afterwards, with the tokens in the URL fragment"
+// @Success		302			"Redirect to the provider"
+// @Failure		400			{object}	response.Response	"Bad request"
+// @Failure		404			{object}	response.Response	"Provider not found"
+// @Failure		502			{object}	response.Response	"Provider unavailable"
+// @Router			/auth/oauth/{provider}/authorize [get]
+func (h *OAuthHandler) Authorize(c echo.Context) error {
+	authURL, state, err := h.oauthService.StartLogin(c.Request().Context(), c.Param("provider"), c.QueryParam("return_to"), 0)
+	if err != nil {
+		return h.startError(c, err)
+	}
+
+	h.setStateCookie(c, state, h.oauthService.StateDuration())
+	return c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback handles the provider redirect after sign in
+// @Summary		Social login callback
+// @Description	Complete a social login or account link in the browser that started it. Returns the token pair, or the mfa_token for users with two-factor authentication. When the login was started with return_to, the browser is redirected there with the result in the URL fragment instead.
+// @Tags			oauth
+// @Produce		json
+// @Param			provider	path		string										true	"Provider name"
+// @Param			code		query		string										true	"Authorization code"
+// @Param			state		query		string										true	"State"
+// @Success		200			{object}	response.Response{data=types.AuthResponse}	"Login successful"
+// @Success		302			"Redirect to return_to"
+// @Failure		400			{object}	response.Response							"Bad request"
+// @Failure		401			{object}	response.Response							"Sign in with provider failed"
+// @Failure		403			{object}	response.Response							"Account disabled or registration closed"
+// @Failure		404			{object}	response.Response							"Provider not found"
+// @Failure		409			{object}	response.Response							"Account already exists or identity linked to another user"
+// @Failure		500			{object}	response.Response							"Internal server error"
+// @Router			/auth/oauth/{provider}/callback [get]
+func (h *OAuthHandler) Callback(c echo.Context) error {
+	if c.QueryParam("error") != "" {
+		return response.Unauthorized(c, "Sign in was cancelled or denied by the provider")
+	}
+
+	var browserState string
+	if cookie, err := c.Cookie(oauthStateCookie); err == nil {
+		browserState = cookie.Value
+	}
+	h.setStateCookie(c, "", -1)
+
+	result, err := h.oauthService.Callback(c.Request().Context(), c.Param("provider"), c.QueryParam("code"), c.QueryParam("state"), browserState, clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, oauth.ErrProviderNotFound):
+			return response.NotFound(c, "Provider not found")
+		case errors.Is(err, types.ErrValidationFailed):
+			return response.BadRequest(c, err.Error())
+		case errors.Is(err, types.ErrInvalidToken):
+			return response.Unauthorized(c, "Invalid or expired sign in request")
+		case errors.Is(err, types.ErrOAuthFailed):
+			return response.Unauthorized(c, "Sign in with provider failed")
+		case errors.Is(err, types.ErrEmailNotVerified):
+			return response.Forbidden(c, "Email address not verified")
+		case errors.Is(err, types.ErrForbidden):
+			return response.Forbidden(c, "Account is disabled")
+		case errors.Is(err, types.ErrRegistrationClosed):
+			return response.Forbidden(c, "Registration is closed")
+		case errors.Is(err, types.ErrUserAlreadyExists):
+			return response.Conflict(c, "An account with this email already exists, sign in and link the provider from your profile")
+		case errors.Is(err, types.ErrIdentityLinked):
+			return response.Conflict(c, "This provider account is linked to another user")
+		default:
+			return response.InternalServerError(c, "Failed to sign in with provider")
+		}
+	}
+
+	if result.ReturnTo != "" {
+		return c.Redirect(http.StatusFound, result.ReturnTo+"#"+callbackFragment(result).Encode())
+	}
+
+	if result.Identity != nil {
+		return response.Success(c, result.Identity, "Provider account linked successfully")
+	}
+	if result.Auth.MFARequired {
+		return response.Success(c, result.Auth, "Two-factor authentication required")
+	}
+	return response.Success(c, result.Auth, "Login successful")
+}
+
+// Link handles starting to link a provider account to the current user
+// @Summary		Link provider account
+// @Description	Start linking a provider account to the current user. Send the browser that made this request to the returned URL; the callback links the account.
+// @Tags			oauth
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			provider	path		string												true	"Provider name"
+// @Param			request		body		types.OAuthLinkRequest								false	"Link options"
+// @Success		200			{object}	response.Response{data=types.AuthorizationURLResponse}	"Authorization URL created"
+// @Failure		400			{object}	response.Response									"Bad request"
+// @Failure		401			{object}	response.Response									"Unauthorized"
+// @Failure		404			{object}	response.Response									"Provider not found"
+// @Failure		502			{object}	response.Response									"Provider unavailable"
+// @Router			/auth/oauth/{provider}/link [post]
+func (h *OAuthHandler) Link(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	var req types.OAuthLinkRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	authURL, state, err := h.oauthService.StartLogin(c.Request().Context(), c.Param("provider"), req.ReturnTo, userID)
+	if err != nil {
+		return h.startError(c, err)
+	}
+
+	h.setStateCookie(c, state, h.oauthService.StateDuration())
+
+	return response.Success(c, &types.AuthorizationURLResponse{AuthorizationURL: authURL}, "Authorization URL created")
+}
+
+// ListIdentities handles listing the current user's linked provider accounts
+// @Summary		List linked accounts
+// @Description	List the provider accounts linked to the current user
+// @Tags			oauth
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response{data=[]types.IdentityResponse}	"Linked accounts retrieved successfully"
+// @Failure		401	{object}	response.Response								"Unauthorized"
+// @Failure		500	{object}	response.Response								"Internal server error"
+// @Router			/auth/identities [get]
+func (h *OAuthHandler) ListIdentities(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	identities, err := h.oauthService.ListIdentities(userID)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to retrieve linked accounts")
+	}
+
+	return response.Success(c, identities, "Linked accounts retrieved successfully")
+}
+
+// UnlinkIdentity handles unlinking a provider account
+// @Summary		Unlink account
+// @Description	Unlink a provider account from the current user. Users without a password cannot unlink their last account.
+// @Tags			oauth
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int					true	"Linked account ID"
+// @Success		200	{object}	response.Response	"Account unlinked successfully"
+// @Failure		400	{object}	response.Response	"Bad request"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		404	{object}	response.Response	"Linked account not found"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/auth/identities/{id} [delete]
+func (h *OAuthHandler) UnlinkIdentity(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid linked account ID")
+	}
+
+	if err := h.oauthService.Unlink(userID, uint(id), clientInfo(c)); err != nil {
+		switch {
+		case errors.Is(err, types.ErrValidationFailed):
+			return response.BadRequest(c, err.Error())
+		case errors.Is(err, types.ErrIdentityNotFound):
+			return response.NotFound(c, "Linked account not found")
+		default:
+			return response.InternalServerError(c, "Failed to unlink account")
+		}
+	}
+
+	return response.Success(c, nil, "Account unlinked successfully")
+}
+
+// setStateCookie remembers the state of an authorization flow in the browser
+// for maxAge, or forgets it when maxAge is negative. The cookie is sent on the
+// top-level redirect back from the provider, but not on cross-site requests.
+func (h *OAuthHandler) setStateCookie(c echo.Context, state string, maxAge time.Duration) {
+	cookie := &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oauth",
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   -1,
+	}
+	if maxAge >= 0 {
+		cookie.MaxAge = int(maxAge.Seconds())
+	}
+	c.SetCookie(cookie)
+}
+
+// startError writes the response for a failure to start the authorization flow
+func (h *OAuthHandler) startError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, oauth.ErrProviderNotFound):
+		return response.NotFound(c, "Provider not found")
+	case errors.Is(err, types.ErrValidationFailed):
+		return response.BadRequest(c, err.Error())
+	case errors.Is(err, types.ErrOAuthFailed):
+		return response.BadGateway(c, "Provider is unavailable")
+	default:
+		return response.InternalServerError(c, "Failed to start sign in")
+	}
+}
+
+// callbackFragment encodes a callback result for the URL fragment of return_to
+func callbackFragment(result *types.OAuthCallbackResult) url.Values {
+	fragment := url.Values{}
+	switch {
+	case result.Identity != nil:
+		fragment.Set("linked", result.Identity.Provider)
+	case result.Auth.MFARequired:
+		fragment.Set("mfa_required", "true")
+		fragment.Set("mfa_token", result.Auth.MFAToken)
+	default:
+		fragment.Set("access_token", result.Auth.AccessToken)
+		fragment.Set("refresh_token", result.Auth.RefreshToken)
+	}
+	return fragment
+}
+
+// RegisterRoutes registers social login routes
+func (h *OAuthHandler) RegisterRoutes(e *echo.Echo, authMiddleware echo.MiddlewareFunc) {
+	oauthGroup := e.Group("/api/auth/oauth")
+
+	oauthGroup.GET("/providers", h.ListProviders)
+	oauthGroup.GET("/:provider/authorize", h.Authorize)
+	oauthGroup.GET("/:provider/callback", h.Callback)
+	oauthGroup.POST("/:provider/link", h.Link, authMiddleware, middleware.RequireJWT())
+
+	identities := e.Group("/api/auth/identities")
+	identities.Use(authMiddleware)
+	identities.GET("", h.ListIdentities)
+	identities.DELETE("/:id", h.UnlinkIdentity, middleware.RequireJWT())
+}
@@ -0,0 +1,454 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/middleware"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// OAuthServerHandler handles the HTTP requests of the OAuth 2.0 authorization
+// server other applications sign users in with
+type OAuthServerHandler struct {
+	oauthServerService *service.OAuthServerService
+}
+
+// NewOAuthServerHandler creates a new OAuth server handler
+func NewOAuthServerHandler(oauthServerService *service.OAuthServerService) *OAuthServerHandler {
+	return &OAuthServerHandler{
+		oauthServerService: oauthServerService,
+	}
+}
+
+// Authorize handles an OAuth 2.0 authorization request
+// @Summary		Authorization endpoint
+// @Description	Start the authorization code flow of a registered client. PKCE with the S256 method is required. The browser is sent to the consent screen, or back to the client with an error.
+// @Tags			oauth-server
+// @Param			response_type			query		string				true	"Must be code"
+// @Param			client_id				query		string				true	"Client ID"
+// @Param			redirect_uri			query		string				false	"Registered redirect URI, optional if the client has only one"
+// @Param			scope					query		string				true	"Space separated scopes"
+// @Param			state					query		string				false	"Opaque value returned to the client"
+// @Param			nonce					query		string				false	"Value embedded in the ID token"
+// @Param			code_challenge			query		string				true	"PKCE code challenge"
+// @Param			code_challenge_method	query		string				true	"Must be S256"
+// @Success		302						"Redirect to the consent screen or the client"
+// @Failure		400						{object}	response.Response	"Unknown client or redirect URI"
+// @Router			/oauth/authorize [get]
+func (h *OAuthServerHandler) Authorize(c echo.Context) error {
+	var req types.OAuthAuthorizeRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	target, err := h.oauthServerService.Authorize(&req)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrOAuthClientNotFound):
+			return response.BadRequest(c, "Unknown client")
+		case errors.Is(err, types.ErrValidationFailed):
+			return response.BadRequest(c, err.Error())
+		default:
+			return response.InternalServerError(c, "Failed to process authorization request")
+		}
+	}
+
+	return c.Redirect(http.StatusFound, target)
+}
+
+// Token handles an OAuth 2.0 token request
+// @Summary		Token endpoint
+// @Description	Exchange an authorization code, a refresh token or client credentials for tokens. Clients authenticate with HTTP Basic or client_id and client_secret form fields; public clients send their client_id only. An ID token is returned when the openid scope was granted.
+// @Tags			oauth-server
+// @Accept			x-www-form-urlencoded
+// @Produce		json
+// @Param			grant_type		formData	string						true	"authorization_code, refresh_token or client_credentials"
+// @Param			code			formData	string						false	"Authorization code"
+// @Param			redirect_uri	formData	string						false	"Redirect URI of the authorization request"
+// @Param			code_verifier	formData	string						false	"PKCE code verifier"
+// @Param			refresh_token	formData	string						false	"Refresh token"
+// @Param			scope			formData	string						false	"Requested scopes for client credentials"
+// @Param			client_id		formData	string						false	"Client ID"
+// @Param			client_secret	formData	string						false	"Client secret"
+// @Success		200				{object}	types.OAuthTokenResponse	"Tokens issued"
+// @Failure		400				{object}	types.OAuthErrorResponse	"Invalid request or grant"
+// @Failure		401				{object}	types.OAuthErrorResponse	"Client authentication failed"
+// @Failure		500				{object}	types.OAuthErrorResponse	"Internal server error"
+// @Router			/oauth/token [post]
+func (h *OAuthServerHandler) Token(c echo.Context) error {
+	// Token responses must never be cached
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	c.Response().Header().Set("Pragma", "no-cache")
+
+	var req types.OAuthTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return oauthError(c, types.NewOAuthError("invalid_request", "malformed token request"))
+	}
+
+	if clientID, secret, ok := c.Request().BasicAuth(); ok {
+		if req.ClientSecret != "" {
+			return oauthError(c, types.NewOAuthError("invalid_request", "use only one client authentication method"))
+		}
+
+		// Basic credentials are form encoded before being joined (RFC 6749 section 2.3.1)
+		var err error
+		if req.ClientID, err = url.QueryUnescape(clientID); err != nil {
+			return oauthError(c, types.NewOAuthError("invalid_client", "malformed client credentials"))
+		}
+		if req.ClientSecret, err = url.QueryUnescape(secret); err != nil {
+			return oauthError(c, types.NewOAuthError("invalid_client", "malformed client credentials"))
+		}
+	}
+
+	tokens, err := h.oauthServerService.Token(&req, clientInfo(c))
+	if err != nil {
+		return oauthError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// UserInfo handles an OpenID Connect userinfo request
+// @Summary		Userinfo endpoint
+// @Description	Claims about the user an access token was issued for, limited to the scopes granted to the client. Requires a token issued to an OAuth client with the openid scope.
+// @Tags			oauth-server
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	types.UserInfoResponse		"User claims"
+// @Failure		401	{object}	response.Response			"Unauthorized"
+// @Failure		403	{object}	types.OAuthErrorResponse	"Insufficient scope"
+// @Router			/oauth/userinfo [get]
+func (h *OAuthServerHandler) UserInfo(c echo.Context) error {
+	scope, _ := middleware.GetScope(c)
+	if _, ok := middleware.GetClientID(c); !ok || !slices.Contains(strings.Fields(scope), model.ScopeOpenID) {
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="insufficient_scope", scope="openid"`)
+		return c.JSON(http.StatusForbidden, &types.OAuthErrorResponse{
+			Error:            "insufficient_scope",
+			ErrorDescription: "the access token was not granted the openid scope",
+		})
+	}
+
+	userID := c.Get("user_id").(uint)
+
+	claims, err := h.oauthServerService.UserInfo(userID, scope)
+	if err != nil {
+		if err == types.ErrUserNotFound {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+			return c.JSON(http.StatusUnauthorized, &types.OAuthErrorResponse{Error: "invalid_token"})
+		}
+		return c.JSON(http.StatusInternalServerError, &types.OAuthErrorResponse{Error: "server_error"})
+	}
+
+	return c.JSON(http.StatusOK, claims)
+}
+
+// GetConsent handles retrieving a pending authorization request
+// @Summary		Get authorization request
+// @Description	Describe a pending authorization request to the signed-in user on the consent screen
+// @Tags			oauth-server
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		string											true	"Authorization request ID"
+// @Success		200	{object}	response.Response{data=types.OAuthConsentResponse}	"Authorization request retrieved successfully"
+// @Failure		401	{object}	response.Response								"Unauthorized"
+// @Failure		404	{object}	response.Response								"Authorization request not found or expired"
+// @Failure		500	{object}	response.Response								"Internal server error"
+// @Router			/oauth/consent/{id} [get]
+func (h *OAuthServerHandler) GetConsent(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	consent, err := h.oauthServerService.GetConsent(userID, c.Param("id"))
+	if err != nil {
+		if err == types.ErrInvalidToken {
+			return response.NotFound(c, "Authorization request not found or expired")
+		}
+		return response.InternalServerError(c, "Failed to retrieve authorization request")
+	}
+
+	return response.Success(c, consent, "Authorization request retrieved successfully")
+}
+
+// Consent handles the user's answer to an authorization request
+// @Summary		Answer authorization request
+// @Description	Approve or deny a pending authorization request. Send the browser to the returned URL, which carries the authorization code or an error for the client.
+// @Tags			oauth-server
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id		path		string												true	"Authorization request ID"
+// @Param			request	body		types.OAuthConsentRequest							true	"Answer"
+// @Success		200		{object}	response.Response{data=types.OAuthRedirectResponse}	"Authorization request answered"
+// @Failure		400		{object}	response.Response									"Bad request"
+// @Failure		401		{object}	response.Response									"Unauthorized"
+// @Failure		403		{object}	response.Response									"Account disabled"
+// @Failure		404		{object}	response.Response									"Authorization request not found or expired"
+// @Failure		500		{object}	response.Response									"Internal server error"
+// @Router			/oauth/consent/{id} [post]
+func (h *OAuthServerHandler) Consent(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	var req types.OAuthConsentRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	target, err := h.oauthServerService.Consent(userID, c.Param("id"), req.Approve, clientInfo(c))
+	if err != nil {
+		switch err {
+		case types.ErrInvalidToken:
+			return response.NotFound(c, "Authorization request not found or expired")
+		case types.ErrForbidden:
+			return response.Forbidden(c, "Account is disabled")
+		default:
+			return response.InternalServerError(c, "Failed to answer authorization request")
+		}
+	}
+
+	return response.Success(c, &types.OAuthRedirectResponse{RedirectTo: target}, "Authorization request answered")
+}
+
+// ListClients handles listing OAuth clients
+// @Summary		List OAuth clients
+// @Description	List the applications registered with the authorization server
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response{data=[]types.OAuthClientResponse}	"Clients retrieved successfully"
+// @Failure		401	{object}	response.Response									"Unauthorized"
+// @Failure		403	{object}	response.Response									"Forbidden"
+// @Failure		500	{object}	response.Response									"Internal server error"
+// @Router			/admin/oauth/clients [get]
+func (h *OAuthServerHandler) ListClients(c echo.Context) error {
+	clients, err := h.oauthServerService.ListClients()
+	if err != nil {
+		return response.InternalServerError(c, "Failed to retrieve clients")
+	}
+
+	return response.Success(c, clients, "Clients retrieved successfully")
+}
+
+// GetClient handles retrieving an OAuth client
+// @Summary		Get OAuth client
+// @Description	Get a registered application by ID
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int												true	"Client ID"
+// @Success		200	{object}	response.Response{data=types.OAuthClientResponse}	"Client retrieved successfully"
+// @Failure		400	{object}	response.Response								"Invalid client ID"
+// @Failure		401	{object}	response.Response								"Unauthorized"
+// @Failure		403	{object}	response.Response								"Forbidden"
+// @Failure		404	{object}	response.Response								"Client not found"
+// @Failure		500	{object}	response.Response								"Internal server error"
+// @Router			/admin/oauth/clients/{id} [get]
+func (h *OAuthServerHandler) GetClient(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid client ID")
+	}
+
+	client, err := h.oauthServerService.GetClient(uint(id))
+	if err != nil {
+		if err == types.ErrOAuthClientNotFound {
+			return response.NotFound(c, "Client not found")
+		}
+		return response.InternalServerError(c, "Failed to get client")
+	}
+
+	return response.Success(c, h.oauthServerService.ToClientResponse(client), "Client retrieved successfully")
+}
+
+// CreateClient handles OAuth client registration
+// @Summary		Register OAuth client
+// @Description	Register an application with the authorization server. Confidential clients receive a secret, shown only once. Permission scopes must be held by the caller.
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		types.CreateOAuthClientRequest							true	"Client definition"
+// @Success		201		{object}	response.Response{data=types.OAuthClientCreatedResponse}	"Client registered successfully"
+// @Failure		400		{object}	response.Response										"Bad request"
+// @Failure		401		{object}	response.Response										"Unauthorized"
+// @Failure		403		{object}	response.Response										"Forbidden"
+// @Failure		500		{object}	response.Response										"Internal server error"
+// @Router			/admin/oauth/clients [post]
+func (h *OAuthServerHandler) CreateClient(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	var req types.CreateOAuthClientRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	client, err := h.oauthServerService.CreateClient(userID, &req)
+	if err != nil {
+		if errors.Is(err, types.ErrValidationFailed) {
+			return response.BadRequest(c, err.Error())
+		}
+		return response.InternalServerError(c, "Failed to register client")
+	}
+
+	return response.Created(c, client, "Client registered successfully")
+}
+
+// UpdateClient handles OAuth client updates
+// @Summary		Update OAuth client
+// @Description	Change the name, redirect URIs, scopes, grant types or trust of a registered application
+// @Tags			admin
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id		path		int												true	"Client ID"
+// @Param			request	body		types.UpdateOAuthClientRequest					true	"Client changes"
+// @Success		200		{object}	response.Response{data=types.OAuthClientResponse}	"Client updated successfully"
+// @Failure		400		{object}	response.Response								"Bad request"
+// @Failure		401		{object}	response.Response								"Unauthorized"
+// @Failure		403		{object}	response.Response								"Forbidden"
+// @Failure		404		{object}	response.Response								"Client not found"
+// @Failure		500		{object}	response.Response								"Internal server error"
+// @Router			/admin/oauth/clients/{id} [put]
+func (h *OAuthServerHandler) UpdateClient(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid client ID")
+	}
+
+	var req types.UpdateOAuthClientRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	client, err := h.oauthServerService.UpdateClient(userID, uint(id), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrValidationFailed):
+			return response.BadRequest(c, err.Error())
+		case errors.Is(err, types.ErrOAuthClientNotFound):
+			return response.NotFound(c, "Client not found")
+		default:
+			return response.InternalServerError(c, "Failed to update client")
+		}
+	}
+
+	return response.Success(c, h.oauthServerService.ToClientResponse(client), "Client updated successfully")
+}
+
+// RotateClientSecret handles replacing the secret of an OAuth client
+// @Summary		Rotate OAuth client secret
+// @Description	Replace the secret of a confidential client. The new secret is shown only once and the old one stops working immediately.
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int														true	"Client ID"
+// @Success		200	{object}	response.Response{data=types.OAuthClientCreatedResponse}	"Client secret rotated successfully"
+// @Failure		400	{object}	response.Response										"Bad request"
+// @Failure		401	{object}	response.Response										"Unauthorized"
+// @Failure		403	{object}	response.Response										"Forbidden"
+// @Failure		404	{object}	response.Response										"Client not found"
+// @Failure		500	{object}	response.Response										"Internal server error"
+// @Router			/admin/oauth/clients/{id}/secret [post]
+func (h *OAuthServerHandler) RotateClientSecret(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid client ID")
+	}
+
+	client, err := h.oauthServerService.RotateClientSecret(uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrValidationFailed):
+			return response.BadRequest(c, err.Error())
+		case errors.Is(err, types.ErrOAuthClientNotFound):
+			return response.NotFound(c, "Client not found")
+		default:
+			return response.InternalServerError(c, "Failed to rotate client secret")
+		}
+	}
+
+	return response.Success(c, client, "Client secret rotated successfully")
+}
+
+// DeleteClient handles OAuth client removal
+// @Summary		Delete OAuth client
+// @Description	Remove a registered application and the consents given to it. Its refresh tokens can no longer be used.
+// @Tags			admin
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int					true	"Client ID"
+// @Success		200	{object}	response.Response	"Client deleted successfully"
+// @Failure		400	{object}	response.Response	"Invalid client ID"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		403	{object}	response.Response	"Forbidden"
+// @Failure		404	{object}	response.Response	"Client not found"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/admin/oauth/clients/{id} [delete]
+func (h *OAuthServerHandler) DeleteClient(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid client ID")
+	}
+
+	if err := h.oauthServerService.DeleteClient(uint(id)); err != nil {
+		if err == types.ErrOAuthClientNotFound {
+			return response.NotFound(c, "Client not found")
+		}
+		return response.InternalServerError(c, "Failed to delete client")
+	}
+
+	return response.Success(c, nil, "Client deleted successfully")
+}
+
+// oauthError writes an OAuth 2.0 error response (RFC 6749 section 5.2)
+func oauthError(c echo.Context, err error) error {
+	var oauthErr *types.OAuthError
+	if !errors.As(err, &oauthErr) {
+		return c.JSON(http.StatusInternalServerError, &types.OAuthErrorResponse{Error: "server_error"})
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == "invalid_client" {
+		status = http.StatusUnauthorized
+		c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Basic realm="oauth"`)
+	}
+
+	return c.JSON(status, &types.OAuthErrorResponse{
+		Error:            oauthErr.Code,
+		ErrorDescription: oauthErr.Description,
+	})
+}
+
+// RegisterRoutes registers authorization server routes
+func (h *OAuthServerHandler) RegisterRoutes(e *echo.Echo, authMiddleware echo.MiddlewareFunc) {
+	oauth := e.Group("/oauth")
+	oauth.GET("/authorize", h.Authorize)
+	oauth.POST("/token", h.Token)
+	oauth.GET("/userinfo", h.UserInfo, authMiddleware)
+	oauth.POST("/userinfo", h.UserInfo, authMiddleware)
+
+	// The consent screen of the embedded SPA answers authorization requests
+	// for the signed-in user
+	consent := e.Group("/api/oauth/consent")
+	consent.Use(authMiddleware, middleware.RequireJWT())
+	consent.GET("/:id", h.GetConsent)
+	consent.POST("/:id", h.Consent)
+
+	clients := e.Group("/api/admin/oauth/clients")
+	clients.Use(authMiddleware)
+	clients.GET("", h.ListClients, middleware.RequirePermission(model.PermissionClientsRead))
+	clients.GET("/:id", h.GetClient, middleware.RequirePermission(model.PermissionClientsRead))
+	clients.POST("", h.CreateClient, middleware.RequirePermission(model.PermissionClientsWrite))
+	clients.PUT("/:id", h.UpdateClient, middleware.RequirePermission(model.PermissionClientsWrite))
+	clients.POST("/:id/secret", h.RotateClientSecret, middleware.RequirePermission(model.PermissionClientsWrite))
+	clients.DELETE("/:id", h.DeleteClient, middleware.RequirePermission(model.PermissionClientsWrite))
+}
@@ -0,0 +1,470 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/middleware"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// OrganizationHandler handles organization, membership and organization
+// invitation HTTP requests
+type OrganizationHandler struct {
+	orgService *service.OrganizationService
+}
+
+// NewOrganizationHandler creates a new organization handler
+func NewOrganizationHandler(orgService *service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		orgService: orgService,
+	}
+}
+
+// CreateOrg handles organization creation
+// @Summary		Create organization
+// @Description	Create an organization owned by the current user. The slug is derived from the name unless given. Switch to the organization to work in it.
+// @Tags			organizations
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		types.CreateOrgRequest					true	"Create organization request"
+// @Success		201		{object}	response.Response{data=types.OrgResponse}	"Organization created successfully"
+// @Failure		400		{object}	response.Response						"Bad request"
+// @Failure		401		{object}	response.Response						"Unauthorized"
+// @Failure		403		{object}	response.Response						"Not available to API keys or OAuth clients"
+// @Failure		409		{object}	response.Response						"Slug already taken"
+// @Failure		500		{object}	response.Response						"Internal server error"
+// @Router			/orgs [post]
+func (h *OrganizationHandler) CreateOrg(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+	activeOrgID, _ := middleware.GetOrgID(c)
+
+	var req types.CreateOrgRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	membership, err := h.orgService.Create(userID, &req, clientInfo(c))
+	if err != nil {
+		return orgError(c, err, "Failed to create organization")
+	}
+
+	return response.Created(c, h.orgService.ToResponse(membership, activeOrgID), "Organization created successfully")
+}
+
+// ListOrgs handles listing the current user's organizations
+// @Summary		List organizations
+// @Description	List the organizations the current user is a member of, with their role and which one is active in the current session
+// @Tags			organizations
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response{data=[]types.OrgResponse}	"Organizations retrieved successfully"
+// @Failure		401	{object}	response.Response							"Unauthorized"
+// @Failure		500	{object}	response.Response							"Internal server error"
+// @Router			/orgs [get]
+func (h *OrganizationHandler) ListOrgs(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+	activeOrgID, _ := middleware.GetOrgID(c)
+
+	memberships, err := h.orgService.List(userID)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to retrieve organizations")
+	}
+
+	responses := make([]*types.OrgResponse, len(memberships))
+	for i, membership := range memberships {
+		responses[i] = h.orgService.ToResponse(membership, activeOrgID)
+	}
+
+	return response.Success(c, responses, "Organizations retrieved successfully")
+}
+
+// SwitchOrg handles changing the active organization of the current session
+// @Summary		Switch organization
+// @Description	Make one of the current user's organizations the active organization of the current session, or leave the session without one with org_id 0. The session receives a new token pair carrying the organization; the refresh token of the session is rotated.
+// @Tags			organizations
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		types.SwitchOrgRequest						true	"Switch organization request"
+// @Success		200		{object}	response.Response{data=types.TokenResponse}	"Organization switched successfully"
+// @Failure		400		{object}	response.Response							"Bad request"
+// @Failure		401		{object}	response.Response							"Unauthorized or session ended"
+// @Failure		403		{object}	response.Response							"Not available to API keys or OAuth clients"
+// @Failure		404		{object}	response.Response							"Organization not found"
+// @Failure		500		{object}	response.Response							"Internal server error"
+// @Router			/orgs/switch [post]
+func (h *OrganizationHandler) SwitchOrg(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+	sessionID, _ := middleware.GetSessionID(c)
+
+	var req types.SwitchOrgRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	tokens, err := h.orgService.Switch(userID, sessionID, &req, clientInfo(c))
+	if err != nil {
+		if errors.Is(err, types.ErrSessionNotFound) {
+			return response.Unauthorized(c, "Session ended, please sign in again")
+		}
+		return orgError(c, err, "Failed to switch organization")
+	}
+
+	return response.Success(c, tokens, "Organization switched successfully")
+}
+
+// AcceptInvitation handles joining an organization with an invitation
+// @Summary		Accept organization invitation
+// @Description	Join the organization an invitation was mailed for. The invitation must be addressed to the current user's verified email address. Switch to the organization to work in it.
+// @Tags			organizations
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		types.AcceptOrgInvitationRequest			true	"Accept invitation request"
+// @Success		200		{object}	response.Response{data=types.OrgResponse}	"Invitation accepted successfully"
+// @Failure		400		{object}	response.Response						"Bad request"
+// @Failure		401		{object}	response.Response						"Unauthorized"
+// @Failure		403		{object}	response.Response						"Invitation addressed to another email or email not verified"
+// @Failure		404		{object}	response.Response						"Invalid or expired invitation"
+// @Failure		409		{object}	response.Response						"Already a member"
+// @Failure		500		{object}	response.Response						"Internal server error"
+// @Router			/orgs/invitations/accept [post]
+func (h *OrganizationHandler) AcceptInvitation(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+	activeOrgID, _ := middleware.GetOrgID(c)
+
+	var req types.AcceptOrgInvitationRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	membership, err := h.orgService.AcceptInvitation(userID, &req, clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrForbidden):
+			return response.Forbidden(c, "Invitation addressed to another email address")
+		case errors.Is(err, types.ErrEmailNotVerified):
+			return response.Forbidden(c, "Verify your email address to accept the invitation")
+		case errors.Is(err, types.ErrInvitationNotFound):
+			return response.NotFound(c, "Invalid or expired invitation")
+		}
+		return orgError(c, err, "Failed to accept invitation")
+	}
+
+	return response.Success(c, h.orgService.ToResponse(membership, activeOrgID), "Invitation accepted successfully")
+}
+
+// GetCurrentOrg handles retrieving the active organization
+// @Summary		Get active organization
+// @Description	Get the active organization of the current session and the current user's role in it
+// @Tags			organizations
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response{data=types.OrgResponse}	"Organization retrieved successfully"
+// @Failure		401	{object}	response.Response						"Unauthorized"
+// @Failure		403	{object}	response.Response						"No active organization or not a member"
+// @Failure		500	{object}	response.Response						"Internal server error"
+// @Router			/orgs/current [get]
+func (h *OrganizationHandler) GetCurrentOrg(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+	orgID, _ := middleware.GetOrgID(c)
+
+	membership, err := h.orgService.Current(c.Request().Context(), userID)
+	if err != nil {
+		return orgError(c, err, "Failed to retrieve organization")
+	}
+
+	return response.Success(c, h.orgService.ToResponse(membership, orgID), "Organization retrieved successfully")
+}
+
+// UpdateCurrentOrg handles updating the active organization
+// @Summary		Update active organization
+// @Description	Rename the active organization or change its slug. Requires the admin or owner role.
+// @Tags			organizations
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		types.UpdateOrgRequest					true	"Update organization request"
+// @Success		200		{object}	response.Response{data=types.OrgResponse}	"Organization updated successfully"
+// @Failure		400		{object}	response.Response						"Bad request"
+// @Failure		401		{object}	response.Response						"Unauthorized"
+// @Failure		403		{object}	response.Response						"Forbidden"
+// @Failure		409		{object}	response.Response						"Slug already taken"
+// @Failure		500		{object}	response.Response						"Internal server error"
+// @Router			/orgs/current [put]
+func (h *OrganizationHandler) UpdateCurrentOrg(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+	orgID, _ := middleware.GetOrgID(c)
+
+	var req types.UpdateOrgRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	membership, err := h.orgService.Update(c.Request().Context(), userID, &req)
+	if err != nil {
+		return orgError(c, err, "Failed to update organization")
+	}
+
+	return response.Success(c, h.orgService.ToResponse(membership, orgID), "Organization updated successfully")
+}
+
+// DeleteCurrentOrg handles deleting the active organization
+// @Summary		Delete active organization
+// @Description	Permanently delete the active organization with its memberships and invitations. Requires the owner role. Sessions working in it are left without an active organization.
+// @Tags			organizations
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response	"Organization deleted successfully"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		403	{object}	response.Response	"Forbidden"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/orgs/current [delete]
+func (h *OrganizationHandler) DeleteCurrentOrg(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	if err := h.orgService.Delete(c.Request().Context(), userID, clientInfo(c)); err != nil {
+		return orgError(c, err, "Failed to delete organization")
+	}
+
+	return response.Success(c, nil, "Organization deleted successfully")
+}
+
+// ListMembers handles listing the members of the active organization
+// @Summary		List members
+// @Description	List the members of the active organization with their roles
+// @Tags			organizations
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response{data=[]types.MemberResponse}	"Members retrieved successfully"
+// @Failure		401	{object}	response.Response								"Unauthorized"
+// @Failure		403	{object}	response.Response								"No active organization or not a member"
+// @Failure		500	{object}	response.Response								"Internal server error"
+// @Router			/orgs/current/members [get]
+func (h *OrganizationHandler) ListMembers(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	members, err := h.orgService.ListMembers(c.Request().Context(), userID)
+	if err != nil {
+		return orgError(c, err, "Failed to retrieve members")
+	}
+
+	responses := make([]*types.MemberResponse, len(members))
+	for i, member := range members {
+		responses[i] = h.orgService.ToMemberResponse(member)
+	}
+
+	return response.Success(c, responses, "Members retrieved successfully")
+}
+
+// UpdateMember handles changing the role of a member of the active organization
+// @Summary		Update member
+// @Description	Change the role of a member of the active organization. Requires the admin or owner role; only owners can make or unmake owners, and the last owner cannot be demoted.
+// @Tags			organizations
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		int											true	"User ID"
+// @Param			request	body		types.UpdateMemberRequest					true	"Update member request"
+// @Success		200		{object}	response.Response{data=types.MemberResponse}	"Member updated successfully"
+// @Failure		400		{object}	response.Response							"Bad request"
+// @Failure		401		{object}	response.Response							"Unauthorized"
+// @Failure		403		{object}	response.Response							"Forbidden"
+// @Failure		404		{object}	response.Response							"Member not found"
+// @Failure		409		{object}	response.Response							"Last owner"
+// @Failure		500		{object}	response.Response							"Internal server error"
+// @Router			/orgs/current/members/{user_id} [put]
+func (h *OrganizationHandler) UpdateMember(c echo.Context) error {
+	actorID := c.Get("user_id").(uint)
+
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	var req types.UpdateMemberRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	member, err := h.orgService.UpdateMember(c.Request().Context(), actorID, uint(userID), &req, clientInfo(c))
+	if err != nil {
+		return orgError(c, err, "Failed to update member")
+	}
+
+	return response.Success(c, h.orgService.ToMemberResponse(member), "Member updated successfully")
+}
+
+// RemoveMember handles removing a member from the active organization
+// @Summary		Remove member
+// @Description	Remove a member from the active organization, or leave it by passing the current user's ID. Removing others requires the admin role, and removing an owner the owner role. The last owner cannot leave.
+// @Tags			organizations
+// @Produce		json
+// @Security		BearerAuth
+// @Param			user_id	path		int					true	"User ID"
+// @Success		200		{object}	response.Response	"Member removed successfully"
+// @Failure		400		{object}	response.Response	"Invalid user ID"
+// @Failure		401		{object}	response.Response	"Unauthorized"
+// @Failure		403		{object}	response.Response	"Forbidden"
+// @Failure		404		{object}	response.Response	"Member not found"
+// @Failure		409		{object}	response.Response	"Last owner"
+// @Failure		500		{object}	response.Response	"Internal server error"
+// @Router			/orgs/current/members/{user_id} [delete]
+func (h *OrganizationHandler) RemoveMember(c echo.Context) error {
+	actorID := c.Get("user_id").(uint)
+
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID")
+	}
+
+	if err := h.orgService.RemoveMember(c.Request().Context(), actorID, uint(userID), clientInfo(c)); err != nil {
+		return orgError(c, err, "Failed to remove member")
+	}
+
+	return response.Success(c, nil, "Member removed successfully")
+}
+
+// ListInvitations handles listing the pending invitations to the active organization
+// @Summary		List organization invitations
+// @Description	List the unexpired invitations to the active organization. Requires the admin or owner role.
+// @Tags			organizations
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response{data=[]types.OrgInvitationResponse}	"Invitations retrieved successfully"
+// @Failure		401	{object}	response.Response										"Unauthorized"
+// @Failure		403	{object}	response.Response										"Forbidden"
+// @Failure		500	{object}	response.Response										"Internal server error"
+// @Router			/orgs/current/invitations [get]
+func (h *OrganizationHandler) ListInvitations(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	invitations, err := h.orgService.ListInvitations(c.Request().Context(), userID)
+	if err != nil {
+		return orgError(c, err, "Failed to retrieve invitations")
+	}
+
+	responses := make([]*types.OrgInvitationResponse, len(invitations))
+	for i, invitation := range invitations {
+		responses[i] = h.orgService.ToInvitationResponse(invitation)
+	}
+
+	return response.Success(c, responses, "Invitations retrieved successfully")
+}
+
+// CreateInvitation handles inviting someone to the active organization
+// @Summary		Invite to organization
+// @Description	Mail an invitation to join the active organization to an email address, replacing earlier invitations of the address. Requires the admin or owner role; only owners can invite owners.
+// @Tags			organizations
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		types.CreateOrgInvitationRequest					true	"Invitation request"
+// @Success		201		{object}	response.Response{data=types.OrgInvitationResponse}	"Invitation sent successfully"
+// @Failure		400		{object}	response.Response									"Bad request"
+// @Failure		401		{object}	response.Response									"Unauthorized"
+// @Failure		403		{object}	response.Response									"Forbidden"
+// @Failure		409		{object}	response.Response									"Already a member"
+// @Failure		500		{object}	response.Response									"Internal server error"
+// @Router			/orgs/current/invitations [post]
+func (h *OrganizationHandler) CreateInvitation(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	var req types.CreateOrgInvitationRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	invitation, err := h.orgService.Invite(c.Request().Context(), userID, &req, clientInfo(c))
+	if err != nil {
+		return orgError(c, err, "Failed to send invitation")
+	}
+
+	return response.Created(c, h.orgService.ToInvitationResponse(invitation), "Invitation sent successfully")
+}
+
+// RevokeInvitation handles revoking a pending invitation to the active organization
+// @Summary		Revoke organization invitation
+// @Description	Delete a pending invitation to the active organization. Its link stops working. Requires the admin or owner role.
+// @Tags			organizations
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int					true	"Invitation ID"
+// @Success		200	{object}	response.Response	"Invitation revoked successfully"
+// @Failure		400	{object}	response.Response	"Invalid invitation ID"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		403	{object}	response.Response	"Forbidden"
+// @Failure		404	{object}	response.Response	"Invitation not found"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/orgs/current/invitations/{id} [delete]
+func (h *OrganizationHandler) RevokeInvitation(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid invitation ID")
+	}
+
+	if err := h.orgService.RevokeInvitation(c.Request().Context(), userID, uint(id)); err != nil {
+		return orgError(c, err, "Failed to revoke invitation")
+	}
+
+	return response.Success(c, nil, "Invitation revoked successfully")
+}
+
+// orgError maps organization errors to responses
+func orgError(c echo.Context, err error, message string) error {
+	switch {
+	case errors.Is(err, types.ErrValidationFailed):
+		return response.BadRequest(c, err.Error())
+	case errors.Is(err, types.ErrForbidden):
+		return response.Forbidden(c, "Insufficient organization role")
+	case errors.Is(err, types.ErrNoActiveOrg):
+		return response.Forbidden(c, "No active organization")
+	case errors.Is(err, types.ErrOrgNotFound):
+		return response.NotFound(c, "Organization not found")
+	case errors.Is(err, types.ErrMemberNotFound):
+		return response.NotFound(c, "Member not found")
+	case errors.Is(err, types.ErrInvitationNotFound):
+		return response.NotFound(c, "Invitation not found")
+	case errors.Is(err, types.ErrUserNotFound):
+		return response.NotFound(c, "User not found")
+	case errors.Is(err, types.ErrOrgSlugTaken):
+		return response.Conflict(c, "Slug already taken")
+	case errors.Is(err, types.ErrAlreadyMember):
+		return response.Conflict(c, "Already a member of the organization")
+	case errors.Is(err, types.ErrLastOwner):
+		return response.Conflict(c, "The organization must keep at least one owner")
+	default:
+		return response.InternalServerError(c, message)
+	}
+}
+
+// RegisterRoutes registers organization routes. Routes under
+// /api/orgs/current work in the active organization of the token, with
+// database statements scoped to it.
+func (h *OrganizationHandler) RegisterRoutes(e *echo.Echo, authMiddleware echo.MiddlewareFunc) {
+	orgs := e.Group("/api/orgs")
+	orgs.Use(authMiddleware)
+	orgs.GET("", h.ListOrgs)
+	orgs.POST("", h.CreateOrg, middleware.RequireJWT())
+	orgs.POST("/switch", h.SwitchOrg, middleware.RequireJWT())
+	orgs.POST("/invitations/accept", h.AcceptInvitation, middleware.RequireJWT())
+
+	current := orgs.Group("/current")
+	current.Use(middleware.RequireOrganization())
+	current.GET("", h.GetCurrentOrg)
+	current.PUT("", h.UpdateCurrentOrg)
+	current.DELETE("", h.DeleteCurrentOrg)
+	current.GET("/members", h.ListMembers)
+	current.PUT("/members/:user_id", h.UpdateMember)
+	current.DELETE("/members/:user_id", h.RemoveMember)
+	current.GET("/invitations", h.ListInvitations)
+	current.POST("/invitations", h.CreateInvitation)
+	current.DELETE("/invitations/:id", h.RevokeInvitation)
+}
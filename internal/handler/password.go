@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/middleware"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// PasswordHandler handles password change and recovery HTTP requests
+type PasswordHandler struct {
+	passwordService *service.PasswordService
+}
+
+// NewPasswordHandler creates a new password handler
+func NewPasswordHandler(passwordService *service.PasswordService) *PasswordHandler {
+	return &PasswordHandler{
+		passwordService: passwordService,
+	}
+}
+
+// ForgotPassword handles password reset link requests
+// @Summary		Request a password reset
+// @Description	Send a single-use password reset link to the given email address. The response is the same whether or not the address is registered.
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			request	body		types.ForgotPasswordRequest	true	"Forgot password request"
+// @Success		200		{object}	response.Response			"Password reset email sent"
+// @Failure		400		{object}	response.Response			"Bad request"
+// @Failure		500		{object}	response.Response			"Internal server error"
+// @Router			/auth/password/forgot [post]
+func (h *PasswordHandler) ForgotPassword(c echo.Context) error {
+	var req types.ForgotPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	if err := h.passwordService.ForgotPassword(&req); err != nil {
+		switch err {
+		case types.ErrValidationFailed:
+			return response.BadRequest(c, "Email is required")
+		default:
+			return response.InternalServerError(c, "Failed to request password reset")
+		}
+	}
+
+	return response.Success(c, nil, "If the email is registered, a password reset link has been sent")
+}
+
+// ResetPassword handles password reset with a reset token
+// @Summary		Reset password
+// @Description	Set a new password using a password reset token. All sessions of the user are revoked.
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			request	body		types.ResetPasswordRequest	true	"Reset password request"
+// @Success		200		{object}	response.Response			"Password reset successfully"
+// @Failure		400		{object}	response.Response			"Bad request"
+// @Failure		401		{object}	response.Response			"Invalid or expired token"
+// @Failure		403		{object}	response.Response			"Account disabled"
+// @Failure		500		{object}	response.Response			"Internal server error"
+// @Router			/auth/password/reset [post]
+func (h *PasswordHandler) ResetPassword(c echo.Context) error {
+	var req types.ResetPasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	if err := h.passwordService.ResetPassword(&req); err != nil {
+		switch {
+		case errors.Is(err, types.ErrValidationFailed):
+			return response.BadRequest(c, err.Error())
+		case errors.Is(err, types.ErrInvalidToken):
+			return response.Unauthorized(c, "Invalid reset token")
+		case errors.Is(err, types.ErrTokenExpired):
+			return response.Unauthorized(c, "Reset token expired")
+		case errors.Is(err, types.ErrTokenRevoked):
+			return response.Unauthorized(c, "Reset token already used")
+		case errors.Is(err, types.ErrForbidden):
+			return response.Forbidden(c, "Account is disabled")
+		default:
+			return response.InternalServerError(c, "Failed to reset password")
+		}
+	}
+
+	return response.Success(c, nil, "Password reset successfully")
+}
+
+// ChangePassword handles password changes by the signed-in user
+// @Summary		Change password
+// @Description	Change the password of the current user, who must provide the current password. Other sessions are signed out unless keep_other_sessions is set; the current session then receives a new token pair, as access tokens issued before the change are revoked.
+// @Tags			users
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		types.ChangePasswordRequest					true	"Change password request"
+// @Success		200		{object}	response.Response{data=types.TokenResponse}	"Password changed successfully"
+// @Failure		400		{object}	response.Response							"Bad request"
+// @Failure		401		{object}	response.Response							"Unauthorized or wrong current password"
+// @Failure		403		{object}	response.Response							"Not available to API keys or OAuth clients"
+// @Failure		429		{object}	response.Response							"Too many wrong passwords"
+// @Failure		500		{object}	response.Response							"Internal server error"
+// @Router			/users/profile/password [put]
+func (h *PasswordHandler) ChangePassword(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+	sessionID, _ := middleware.GetSessionID(c)
+
+	var req types.ChangePasswordRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	tokens, err := h.passwordService.ChangePassword(userID, sessionID, &req, clientInfo(c))
+	if err != nil {
+		if ok, resp := throttled(c, err); ok {
+			return resp
+		}
+
+		switch {
+		case errors.Is(err, types.ErrValidationFailed):
+			return response.BadRequest(c, err.Error())
+		case errors.Is(err, types.ErrInvalidCredentials):
+			return response.Unauthorized(c, "Current password is incorrect")
+		case errors.Is(err, types.ErrUserNotFound):
+			return response.NotFound(c, "User not found")
+		case errors.Is(err, types.ErrSessionNotFound):
+			// The password was changed, but the current session had already ended
+			return response.Unauthorized(c, "Password changed, please sign in again")
+		default:
+			return response.InternalServerError(c, "Failed to change password")
+		}
+	}
+
+	return response.Success(c, tokens, "Password changed successfully")
+}
+
+// RegisterRoutes registers password routes
+func (h *PasswordHandler) RegisterRoutes(e *echo.Echo, authMiddleware echo.MiddlewareFunc) {
+	password := e.Group("/api/auth/password")
+
+	password.POST("/forgot", h.ForgotPassword)
+	password.POST("/reset", h.ResetPassword)
+
+	// Protected routes
+	e.PUT("/api/users/profile/password", h.ChangePassword, authMiddleware, middleware.RequireJWT())
+}
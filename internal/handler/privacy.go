@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/middleware"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// PrivacyHandler handles personal data export and account erasure HTTP requests
+type PrivacyHandler struct {
+	privacyService *service.PrivacyService
+}
+
+// NewPrivacyHandler creates a new privacy handler
+func NewPrivacyHandler(privacyService *service.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyService: privacyService,
+	}
+}
+
+// RequestExport handles requesting an export of the current user's data
+// @Summary		Request data export
+// @Description	Start building a ZIP archive of everything stored about the current user: profile, sessions, API keys, linked accounts, OAuth consents, stored values, uploaded files and audit events. The archive is built in the background, the user is mailed once it is ready and it can be downloaded once.
+// @Tags			privacy
+// @Produce		json
+// @Security		BearerAuth
+// @Success		202	{object}	response.Response{data=types.DataExportResponse}	"Data export requested successfully"
+// @Failure		401	{object}	response.Response								"Unauthorized"
+// @Failure		403	{object}	response.Response								"Not available to API keys"
+// @Failure		409	{object}	response.Response								"A data export is already in progress"
+// @Failure		500	{object}	response.Response								"Internal server error"
+// @Router			/users/profile/export [post]
+func (h *PrivacyHandler) RequestExport(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	export, err := h.privacyService.RequestExport(userID, clientInfo(c))
+	if err != nil {
+		if errors.Is(err, types.ErrExportInProgress) {
+			return response.Conflict(c, "A data export is already in progress or waiting to be downloaded")
+		}
+		return response.InternalServerError(c, "Failed to request data export")
+	}
+
+	return response.Accepted(c, export, "Data export requested successfully")
+}
+
+// GetExport handles retrieving the status of a data export
+// @Summary		Get data export
+// @Description	Get the status of one of the current user's data exports
+// @Tags			privacy
+// @Produce		json
+// @Security		BearerAuth
+// @Param			id	path		int												true	"Data export ID"
+// @Success		200	{object}	response.Response{data=types.DataExportResponse}	"Data export retrieved successfully"
+// @Failure		400	{object}	response.Response								"Invalid data export ID"
+// @Failure		401	{object}	response.Response								"Unauthorized"
+// @Failure		403	{object}	response.Response								"Not available to API keys"
+// @Failure		404	{object}	response.Response								"Data export not found"
+// @Failure		500	{object}	response.Response								"Internal server error"
+// @Router			/users/profile/export/{id} [get]
+func (h *PrivacyHandler) GetExport(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid data export ID")
+	}
+
+	export, err := h.privacyService.GetExport(userID, uint(id))
+	if err != nil {
+		if errors.Is(err, types.ErrExportNotFound) {
+			return response.NotFound(c, "Data export not found")
+		}
+		return response.InternalServerError(c, "Failed to get data export")
+	}
+
+	return response.Success(c, export, "Data export retrieved successfully")
+}
+
+// DownloadExport handles downloading a data export
+// @Summary		Download data export
+// @Description	Download the ZIP archive of a ready data export. Each export can be downloaded once, after which the archive is deleted.
+// @Tags			privacy
+// @Produce		application/zip
+// @Security		BearerAuth
+// @Param			id	path		int					true	"Data export ID"
+// @Success		200	{file}		file				"Data export archive"
+// @Failure		400	{object}	response.Response	"Invalid data export ID"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		403	{object}	response.Response	"Not available to API keys"
+// @Failure		404	{object}	response.Response	"Data export not found"
+// @Failure		410	{object}	response.Response	"Data export not ready, expired or already downloaded"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/users/profile/export/{id}/download [get]
+func (h *PrivacyHandler) DownloadExport(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid data export ID")
+	}
+
+	file, err := h.privacyService.OpenExport(userID, uint(id), clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, types.ErrExportNotFound):
+			return response.NotFound(c, "Data export not found")
+		case errors.Is(err, types.ErrExportNotReady):
+			return response.Gone(c, "Data export is not ready, expired or was already downloaded")
+		default:
+			return response.InternalServerError(c, "Failed to download data export")
+		}
+	}
+	defer file.Close()
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", file.Name))
+	return c.Stream(http.StatusOK, "application/zip", file)
+}
+
+// RequestErasure handles scheduling the erasure of the current user's account
+// @Summary		Request account erasure
+// @Description	Schedule the erasure of the current user's account and personal data at the end of a grace period, during which it can be cancelled. Accounts with a password must confirm it.
+// @Tags			privacy
+// @Accept			json
+// @Produce		json
+// @Security		BearerAuth
+// @Param			request	body		types.ErasureRequest							true	"Password confirmation"
+// @Success		202		{object}	response.Response{data=types.ErasureResponse}	"Account erasure scheduled successfully"
+// @Failure		400		{object}	response.Response								"Bad request"
+// @Failure		401		{object}	response.Response								"Invalid password"
+// @Failure		403		{object}	response.Response								"Not available to API keys"
+// @Failure		409		{object}	response.Response								"Account erasure already scheduled"
+// @Failure		429		{object}	response.Response								"Too many wrong passwords"
+// @Failure		500		{object}	response.Response								"Internal server error"
+// @Router			/users/profile/erasure [post]
+func (h *PrivacyHandler) RequestErasure(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	var req types.ErasureRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	erasure, err := h.privacyService.RequestErasure(userID, &req, clientInfo(c))
+	if err != nil {
+		if ok, resp := throttled(c, err); ok {
+			return resp
+		}
+
+		switch {
+		case errors.Is(err, types.ErrInvalidCredentials):
+			return response.Unauthorized(c, "Invalid password")
+		case errors.Is(err, types.ErrErasureScheduled):
+			return response.Conflict(c, "Account erasure already scheduled")
+		default:
+			return response.InternalServerError(c, "Failed to schedule account erasure")
+		}
+	}
+
+	return response.Accepted(c, erasure, "Account erasure scheduled successfully")
+}
+
+// CancelErasure handles cancelling the scheduled erasure of the current user's account
+// @Summary		Cancel account erasure
+// @Description	Cancel the scheduled erasure of the current user's account
+// @Tags			privacy
+// @Produce		json
+// @Security		BearerAuth
+// @Success		200	{object}	response.Response	"Account erasure cancelled successfully"
+// @Failure		401	{object}	response.Response	"Unauthorized"
+// @Failure		403	{object}	response.Response	"Not available to API keys"
+// @Failure		404	{object}	response.Response	"Account erasure not scheduled"
+// @Failure		500	{object}	response.Response	"Internal server error"
+// @Router			/users/profile/erasure [delete]
+func (h *PrivacyHandler) CancelErasure(c echo.Context) error {
+	userID := c.Get("user_id").(uint)
+
+	if err := h.privacyService.CancelErasure(userID, clientInfo(c)); err != nil {
+		if errors.Is(err, types.ErrErasureNotScheduled) {
+			return response.NotFound(c, "Account erasure not scheduled")
+		}
+		return response.InternalServerError(c, "Failed to cancel account erasure")
+	}
+
+	return response.Success(c, nil, "Account erasure cancelled successfully")
+}
+
+// RegisterRoutes registers data export and erasure routes. Only the user
+// themselves can use them, not API keys, OAuth clients or impersonators.
+func (h *PrivacyHandler) RegisterRoutes(e *echo.Echo, authMiddleware echo.MiddlewareFunc) {
+	profile := e.Group("/api/users/profile")
+
+	profile.Use(authMiddleware, middleware.RequireJWT())
+	profile.POST("/export", h.RequestExport)
+	profile.GET("/export/:id", h.GetExport)
+	profile.GET("/export/:id/download", h.DownloadExport)
+	profile.POST("/erasure", h.RequestErasure)
+	profile.DELETE("/erasure", h.CancelErasure)
+}
@@ -1,9 +1,14 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/middleware"
 	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
 	"github.com/ray-d-song/go-echo-monolithic/internal/service"
 	"github.com/ray-d-song/go-echo-monolithic/internal/types"
@@ -115,15 +120,16 @@ func (h *UserHandler) GetUserByUsername(c echo.Context) error {
 
 // UpdateProfile updates current user profile
 // @Summary		Update user profile
-// @Description	Update the profile of the currently authenticated user
+// @Description	Update the profile of the currently authenticated user. A new email address takes effect once confirmed. Not available to API keys, OAuth clients or impersonation tokens, as the email address can be used to recover the account.
 // @Tags			users
 // @Accept			json
 // @Produce		json
 // @Security		BearerAuth
 // @Param			request	body		types.UpdateUserRequest	true	"Update user request"
 // @Success		200		{object}	response.Response			"Profile updated successfully"
-// @Failure		400		{object}	response.Response			"Bad request"
+// @Failure		400		{object}	response.Response			"Invalid request data or email address"
 // @Failure		401		{object}	response.Response			"Unauthorized"
+// @Failure		403		{object}	response.Response			"Not available to API keys, OAuth clients or impersonation tokens"
 // @Failure		404		{object}	response.Response			"User not found"
 // @Failure		409		{object}	response.Response			"Email already in use"
 // @Failure		500		{object}	response.Response			"Internal server error"
@@ -138,10 +144,12 @@ func (h *UserHandler) UpdateProfile(c echo.Context) error {
 
 	user, err := h.userService.Update(userID, &req)
 	if err != nil {
-		switch err {
-		case types.ErrUserNotFound:
+		switch {
+		case errors.Is(err, types.ErrValidationFailed):
+			return response.BadRequest(c, err.Error())
+		case errors.Is(err, types.ErrUserNotFound):
 			return response.NotFound(c, "User not found")
-		case types.ErrUserAlreadyExists:
+		case errors.Is(err, types.ErrUserAlreadyExists):
 			return response.Conflict(c, "Email already in use")
 		default:
 			return response.InternalServerError(c, "Failed to update profile")
@@ -154,85 +162,122 @@ func (h *UserHandler) UpdateProfile(c echo.Context) error {
 
 // ListUsers retrieves users with pagination
 // @Summary		List users
-// @Description	Retrieve a paginated list of users
+// @Description	Retrieve a paginated list of users, optionally searched, filtered and sorted. The search matches words of usernames, emails and names by prefix. Every page carries next_cursor and prev_cursor when there are further users; passing one as cursor, with the same filters and sort order, switches to keyset pagination, which stays fast and consistent on large tables; its pages have no page number.
 // @Tags			users
 // @Accept			json
 // @Produce		json
 // @Security		BearerAuth
-// @Param			page	query		int	false	"Page number (default: 1)"
-// @Param			limit	query		int	false	"Items per page (default: 20, max: 100)"
-// @Success		200	{object}	response.Response	"Users retrieved successfully"
-// @Failure		401	{object}	response.Response	"Unauthorized"
-// @Failure		500	{object}	response.Response	"Internal server error"
+// @Param			page			query		int		false	"Page number (default: 1), ignored with a cursor"
+// @Param			limit			query		int		false	"Items per page (default: 20, max: 100)"
+// @Param			cursor			query		string	false	"Cursor of a previous page"
+// @Param			q				query		string	false	"Search query"
+// @Param			role			query		string	false	"Only users with this role"
+// @Param			is_active		query		bool	false	"Only active or inactive users"
+// @Param			created_after	query		string	false	"Only users created at or after this RFC 3339 time or date"
+// @Param			created_before	query		string	false	"Only users created before this RFC 3339 time or date"
+// @Param			sort			query		string	false	"Sort field: id, username, email, first_name, last_name, created_at or updated_at (default: id)"
+// @Param			order			query		string	false	"Sort order: asc or desc (default: asc)"
+// @Success		200	{object}	response.Response{data=types.PaginatedResponse}	"Users retrieved successfully"
+// @Failure		400	{object}	response.Response							"Invalid filter or cursor"
+// @Failure		401	{object}	response.Response							"Unauthorized"
+// @Failure		500	{object}	response.Response							"Internal server error"
 // @Router			/users [get]
 func (h *UserHandler) ListUsers(c echo.Context) error {
-	page, _ := strconv.Atoi(c.QueryParam("page"))
-	if page < 1 {
-		page = 1
+	pageRequest := types.PageRequestBox{Cursor: c.QueryParam("cursor")}
+
+	pageRequest.Page, _ = strconv.Atoi(c.QueryParam("page"))
+	if pageRequest.Page < 1 {
+		pageRequest.Page = 1
 	}
 
-	limit, _ := strconv.Atoi(c.QueryParam("limit"))
-	if limit < 1 || limit > 100 {
-		limit = 20
+	pageRequest.Size, _ = strconv.Atoi(c.QueryParam("limit"))
+	if pageRequest.Size < 1 || pageRequest.Size > 100 {
+		pageRequest.Size = 20
 	}
 
-	offset := (page - 1) * limit
+	filter, err := parseUserFilter(c)
+	if err != nil {
+		return response.BadRequest(c, err.Error())
+	}
 
-	users, err := h.userService.List(offset, limit)
+	var page *service.UserPage
+	if pageRequest.Cursor != "" {
+		page, err = h.userService.ListAfter(filter, pageRequest.Cursor, pageRequest.Size)
+	} else {
+		page, err = h.userService.List(filter, (pageRequest.Page-1)*pageRequest.Size, pageRequest.Size)
+	}
 	if err != nil {
-		return response.InternalServerError(c, "Failed to list users")
+		switch {
+		case errors.Is(err, types.ErrValidationFailed):
+			return response.BadRequest(c, err.Error())
+		case errors.Is(err, types.ErrInvalidCursor):
+			return response.BadRequest(c, "Invalid cursor or cursor of another sort order")
+		default:
+			return response.InternalServerError(c, "Failed to list users")
+		}
 	}
 
-	total, err := h.userService.Count()
+	total, err := h.userService.Count(filter)
 	if err != nil {
 		return response.InternalServerError(c, "Failed to get user count")
 	}
 
-	userResponses := make([]*types.UserResponse, len(users))
-	for i, user := range users {
+	userResponses := make([]*types.UserResponse, len(page.Users))
+	for i, user := range page.Users {
 		userResponses[i] = h.userService.ToResponse(user)
 	}
 
 	paginatedResponse := types.PaginatedResponse{
-		Data:    userResponses,
-		Total:   total,
-		Page:    page,
-		Limit:   limit,
-		Pages:   (total + int64(limit) - 1) / int64(limit),
+		Data:       userResponses,
+		Total:      total,
+		Limit:      pageRequest.Size,
+		Pages:      (total + int64(pageRequest.Size) - 1) / int64(pageRequest.Size),
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	}
+	if pageRequest.Cursor == "" {
+		paginatedResponse.Page = pageRequest.Page
 	}
 
 	return response.Success(c, paginatedResponse, "Users retrieved successfully")
 }
 
-// DeleteUser soft deletes a user
-// @Summary		Delete user
-// @Description	Soft delete a user by ID
-// @Tags			users
-// @Accept			json
-// @Produce		json
-// @Security		BearerAuth
-// @Param			id	path		int	true	"User ID"
-// @Success		200	{object}	response.Response	"User deleted successfully"
-// @Failure		400	{object}	response.Response	"Invalid user ID"
-// @Failure		401	{object}	response.Response	"Unauthorized"
-// @Failure		404	{object}	response.Response	"User not found"
-// @Failure		500	{object}	response.Response	"Internal server error"
-// @Router			/users/{id} [delete]
-func (h *UserHandler) DeleteUser(c echo.Context) error {
-	idParam := c.Param("id")
-	id, err := strconv.ParseUint(idParam, 10, 32)
-	if err != nil {
-		return response.BadRequest(c, "Invalid user ID")
+// parseUserFilter reads the search, filter and sort query parameters of a
+// user listing
+func parseUserFilter(c echo.Context) (*types.UserFilter, error) {
+	filter := &types.UserFilter{
+		Query: strings.TrimSpace(c.QueryParam("q")),
+		Role:  c.QueryParam("role"),
+		Sort:  c.QueryParam("sort"),
+		Order: strings.ToLower(c.QueryParam("order")),
 	}
 
-	if err := h.userService.Delete(uint(id)); err != nil {
-		if err == types.ErrUserNotFound {
-			return response.NotFound(c, "User not found")
+	if value := c.QueryParam("is_active"); value != "" {
+		isActive, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("Invalid is_active value")
+		}
+		filter.IsActive = &isActive
+	}
+
+	for param, field := range map[string]**time.Time{
+		"created_after":  &filter.CreatedAfter,
+		"created_before": &filter.CreatedBefore,
+	} {
+		value := c.QueryParam(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			if t, err = time.Parse(time.DateOnly, value); err != nil {
+				return nil, fmt.Errorf("Invalid %s value, expected an RFC 3339 time or a date", param)
+			}
 		}
-		return response.InternalServerError(c, "Failed to delete user")
+		*field = &t
 	}
 
-	return response.Success(c, nil, "User deleted successfully")
+	return filter, nil
 }
 
 // RegisterRoutes registers user routes
@@ -242,9 +287,8 @@ func (h *UserHandler) RegisterRoutes(e *echo.Echo, authMiddleware echo.Middlewar
 	// Protected routes
 	users.Use(authMiddleware)
 	users.GET("/profile", h.GetProfile)
-	users.PUT("/profile", h.UpdateProfile)
+	users.PUT("/profile", h.UpdateProfile, middleware.RequireJWT())
 	users.GET("/:id", h.GetUserByID)
 	users.GET("/username/:username", h.GetUserByUsername)
 	users.GET("", h.ListUsers)
-	users.DELETE("/:id", h.DeleteUser)
 }
\ No newline at end of file
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// VerificationHandler handles email verification HTTP requests
+type VerificationHandler struct {
+	verificationService *service.VerificationService
+	userService         *service.UserService
+}
+
+// NewVerificationHandler creates a new verification handler
+func NewVerificationHandler(verificationService *service.VerificationService, userService *service.UserService) *VerificationHandler {
+	return &VerificationHandler{
+		verificationService: verificationService,
+		userService:         userService,
+	}
+}
+
+// VerifyEmail handles email verification
+// @Summary		Verify email address
+// @Description	Confirm ownership of an email address using a verification token. Confirms either the registration address or a pending email change.
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			request	body		types.VerifyEmailRequest	true	"Verify email request"
+// @Success		200		{object}	response.Response			"Email verified successfully"
+// @Failure		400		{object}	response.Response			"Bad request"
+// @Failure		401		{object}	response.Response			"Invalid or expired token"
+// @Failure		409		{object}	response.Response			"Email already in use"
+// @Failure		500		{object}	response.Response			"Internal server error"
+// @Router			/auth/verify-email [post]
+func (h *VerificationHandler) VerifyEmail(c echo.Context) error {
+	var req types.VerifyEmailRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	user, err := h.verificationService.VerifyEmail(&req)
+	if err != nil {
+		switch err {
+		case types.ErrInvalidToken:
+			return response.Unauthorized(c, "Invalid verification token")
+		case types.ErrTokenExpired:
+			return response.Unauthorized(c, "Verification token expired")
+		case types.ErrTokenRevoked:
+			return response.Unauthorized(c, "Verification token already used")
+		case types.ErrUserAlreadyExists:
+			return response.Conflict(c, "Email already in use")
+		case types.ErrUserNotFound:
+			return response.NotFound(c, "User not found")
+		default:
+			return response.InternalServerError(c, "Failed to verify email")
+		}
+	}
+
+	return response.Success(c, h.userService.ToResponse(user), "Email verified successfully")
+}
+
+// ResendVerification handles verification email resend requests
+// @Summary		Resend verification email
+// @Description	Send a new verification link to an unverified or pending email address. The response is the same whether or not the address is registered.
+// @Tags			auth
+// @Accept			json
+// @Produce		json
+// @Param			request	body		types.ResendVerificationRequest	true	"Resend verification request"
+// @Success		200		{object}	response.Response				"Verification email sent"
+// @Failure		400		{object}	response.Response				"Bad request"
+// @Failure		500		{object}	response.Response				"Internal server error"
+// @Router			/auth/verify-email/resend [post]
+func (h *VerificationHandler) ResendVerification(c echo.Context) error {
+	var req types.ResendVerificationRequest
+	if err := c.Bind(&req); err != nil {
+		return response.BadRequest(c, "Invalid request data")
+	}
+
+	if err := h.verificationService.ResendVerification(&req); err != nil {
+		switch err {
+		case types.ErrValidationFailed:
+			return response.BadRequest(c, "Email is required")
+		default:
+			return response.InternalServerError(c, "Failed to resend verification email")
+		}
+	}
+
+	return response.Success(c, nil, "If the email is awaiting verification, a new link has been sent")
+}
+
+// RegisterRoutes registers email verification routes
+func (h *VerificationHandler) RegisterRoutes(e *echo.Echo) {
+	verify := e.Group("/api/auth/verify-email")
+
+	verify.POST("", h.VerifyEmail)
+	verify.POST("/resend", h.ResendVerification)
+}
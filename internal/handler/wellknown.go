@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/jwt"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+)
+
+// WellKnownHandler serves discovery documents under /.well-known
+type WellKnownHandler struct {
+	jwtManager         *jwt.Manager
+	oauthServerService *service.OAuthServerService
+}
+
+// NewWellKnownHandler creates a new well-known handler
+func NewWellKnownHandler(jwtManager *jwt.Manager, oauthServerService *service.OAuthServerService) *WellKnownHandler {
+	return &WellKnownHandler{
+		jwtManager:         jwtManager,
+		oauthServerService: oauthServerService,
+	}
+}
+
+// JWKS returns the public keys used to verify access tokens
+// @Summary		JSON Web Key Set
+// @Description	Public keys that verify access tokens, selected by the kid token header. Empty when tokens are signed with a shared secret.
+// @Tags			system
+// @Produce		json
+// @Success		200	{object}	jwt.JWKS	"Key set"
+// @Router			/.well-known/jwks.json [get]
+func (h *WellKnownHandler) JWKS(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(http.StatusOK, h.jwtManager.JWKS())
+}
+
+// OpenIDConfiguration returns the OpenID Connect discovery document
+// @Summary		OpenID Connect discovery
+// @Description	Endpoints and capabilities of the authorization server. The openid scope is only offered when tokens are signed with an asymmetric key.
+// @Tags			system
+// @Produce		json
+// @Success		200	{object}	types.OpenIDConfiguration	"Discovery document"
+// @Failure		500	{object}	response.Response			"Internal server error"
+// @Router			/.well-known/openid-configuration [get]
+func (h *WellKnownHandler) OpenIDConfiguration(c echo.Context) error {
+	document, err := h.oauthServerService.Discovery()
+	if err != nil {
+		return response.InternalServerError(c, "Failed to build discovery document")
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=300")
+	return c.JSON(http.StatusOK, document)
+}
+
+// RegisterRoutes registers well-known routes
+func (h *WellKnownHandler) RegisterRoutes(e *echo.Echo) {
+	wellKnown := e.Group("/.well-known")
+
+	wellKnown.GET("/jwks.json", h.JWKS)
+	wellKnown.GET("/openid-configuration", h.OpenIDConfiguration)
+}
@@ -4,12 +4,14 @@ import (
 	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/denylist"
 	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/jwt"
 	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
 )
 
-// JWTAuth returns JWT authentication middleware
-func JWTAuth(jwtManager *jwt.Manager) echo.MiddlewareFunc {
+// JWTAuth returns JWT authentication middleware. Tokens on the denylist are
+// rejected even if they have not expired yet.
+func JWTAuth(jwtManager *jwt.Manager, denylist *denylist.Denylist) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// Extract token from Authorization header
@@ -35,11 +37,15 @@ func JWTAuth(jwtManager *jwt.Manager) echo.MiddlewareFunc {
 				return response.Unauthorized(c, "Invalid or expired token")
 			}
 
-			// Set user information in context
-			c.Set("user_id", claims.UserID)
-			c.Set("username", claims.Username)
-			c.Set("email", claims.Email)
+			revoked, err := denylist.IsRevoked(claims)
+			if err != nil {
+				return response.InternalServerError(c, "Failed to verify token")
+			}
+			if revoked {
+				return response.Unauthorized(c, "Token has been revoked")
+			}
 
+			setClaims(c, claims)
 			return next(c)
 		}
 	}
@@ -47,7 +53,7 @@ func JWTAuth(jwtManager *jwt.Manager) echo.MiddlewareFunc {
 
 // OptionalJWTAuth returns optional JWT authentication middleware
 // This middleware doesn't return an error if no token is provided
-func OptionalJWTAuth(jwtManager *jwt.Manager) echo.MiddlewareFunc {
+func OptionalJWTAuth(jwtManager *jwt.Manager, denylist *denylist.Denylist) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// Extract token from Authorization header
@@ -73,17 +79,38 @@ func OptionalJWTAuth(jwtManager *jwt.Manager) echo.MiddlewareFunc {
 				return next(c)
 			}
 
-			// Set user information in context if token is valid
-			c.Set("user_id", claims.UserID)
-			c.Set("username", claims.Username)
-			c.Set("email", claims.Email)
-			c.Set("role", claims.Role)
+			if revoked, err := denylist.IsRevoked(claims); err != nil || revoked {
+				return next(c)
+			}
 
+			setClaims(c, claims)
 			return next(c)
 		}
 	}
 }
 
+// setClaims sets the user information of a valid access token in context
+func setClaims(c echo.Context, claims *jwt.Claims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("username", claims.Username)
+	c.Set("email", claims.Email)
+	c.Set("session_id", claims.SessionID)
+	c.Set("roles", claims.Roles)
+	c.Set("permissions", claims.Permissions)
+	if claims.ClientID != "" {
+		c.Set("client_id", claims.ClientID)
+		c.Set("scope", claims.Scope)
+	}
+	if claims.OrgID != 0 {
+		c.Set("org_id", claims.OrgID)
+		c.Set("org_role", claims.OrgRole)
+	}
+	if claims.Actor != nil {
+		c.Set("impersonator_id", claims.Actor.UserID)
+		c.Set("impersonator", claims.Actor.Username)
+	}
+}
+
 // GetUserID extracts user ID from context
 func GetUserID(c echo.Context) (uint, bool) {
 	userID, ok := c.Get("user_id").(uint)
@@ -96,6 +123,31 @@ func GetUsername(c echo.Context) (string, bool) {
 	return username, ok
 }
 
+// GetSessionID extracts the login session ID from context
+func GetSessionID(c echo.Context) (string, bool) {
+	sessionID, ok := c.Get("session_id").(string)
+	return sessionID, ok
+}
+
+// GetClientID extracts the OAuth client the access token was issued to
+func GetClientID(c echo.Context) (string, bool) {
+	clientID, ok := c.Get("client_id").(string)
+	return clientID, ok
+}
+
+// GetScope extracts the scopes granted to the OAuth client the access token was issued to
+func GetScope(c echo.Context) (string, bool) {
+	scope, ok := c.Get("scope").(string)
+	return scope, ok
+}
+
+// GetImpersonatorID extracts the ID of the administrator impersonating the
+// authenticated user
+func GetImpersonatorID(c echo.Context) (uint, bool) {
+	actorID, ok := c.Get("impersonator_id").(uint)
+	return actorID, ok
+}
+
 // GetEmail extracts email from context
 func GetEmail(c echo.Context) (string, bool) {
 	email, ok := c.Get("email").(string)
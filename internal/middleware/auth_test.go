@@ -0,0 +1,112 @@
+package middleware_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/middleware"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/denylist"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/jwt"
+)
+
+// jwtAuthTestEnv is a route protected by JWTAuth
+type jwtAuthTestEnv struct {
+	echo     *echo.Echo
+	tokens   *jwt.Manager
+	denylist *denylist.Denylist
+}
+
+func newJWTAuthTestEnv(t *testing.T) *jwtAuthTestEnv {
+	t.Helper()
+
+	tokens, err := jwt.NewManager(&config.JWTConfig{
+		AccessSecret:               "access-secret",
+		RefreshSecret:              "refresh-secret",
+		AccessTokenDuration:        "15m",
+		RefreshTokenDuration:       "1h",
+		MFATokenDuration:           "5m",
+		ImpersonationTokenDuration: "10m",
+		KeyRingReloadInterval:      "30s",
+	})
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	store := denylist.NewMemoryStore()
+	env := &jwtAuthTestEnv{
+		echo:     echo.New(),
+		tokens:   tokens,
+		denylist: denylist.New(store, store, tokens.GetAccessTokenDuration()),
+	}
+	env.echo.GET("/protected", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}, middleware.JWTAuth(env.tokens, env.denylist))
+	return env
+}
+
+// token issues an access token for a user and returns it with its claims
+func (e *jwtAuthTestEnv) token(t *testing.T, userID uint) (string, *jwt.Claims) {
+	t.Helper()
+
+	token, err := e.tokens.GenerateAccessToken(userID, "user", "user@example.com", jwt.WithSessionID(fmt.Sprintf("session-%d", userID)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := e.tokens.ValidateAccessToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token, claims
+}
+
+// status requests the protected route with a token
+func (e *jwtAuthTestEnv) status(token string) int {
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	e.echo.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestJWTAuthRejectsRevokedTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		revoke func(list *denylist.Denylist, claims *jwt.Claims) error
+	}{
+		{"revoked token", func(list *denylist.Denylist, claims *jwt.Claims) error {
+			return list.RevokeToken(claims)
+		}},
+		{"revoked session", func(list *denylist.Denylist, claims *jwt.Claims) error {
+			return list.RevokeSession(claims.SessionID)
+		}},
+		{"user cutoff", func(list *denylist.Denylist, claims *jwt.Claims) error {
+			return list.RevokeUser(claims.UserID, time.Now())
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newJWTAuthTestEnv(t)
+			token, claims := env.token(t, 1)
+			other, _ := env.token(t, 2)
+
+			if status := env.status(token); status != http.StatusNoContent {
+				t.Fatalf("valid token got status %d, want %d", status, http.StatusNoContent)
+			}
+			if err := tt.revoke(env.denylist, claims); err != nil {
+				t.Fatal(err)
+			}
+			if status := env.status(token); status != http.StatusUnauthorized {
+				t.Errorf("revoked token got status %d, want %d", status, http.StatusUnauthorized)
+			}
+			if status := env.status(other); status != http.StatusNoContent {
+				t.Errorf("token of another user got status %d, want %d", status, http.StatusNoContent)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// ImpersonationAuditor records requests made while impersonating a user
+type ImpersonationAuditor interface {
+	RecordImpersonatedRequest(actorID, userID uint, method, path string, status int, client *types.ClientInfo)
+}
+
+// AuditImpersonation returns middleware recording every request authenticated
+// with an impersonation token in the audit log. It must run after JWTAuth.
+func AuditImpersonation(auditor ImpersonationAuditor) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			actorID, ok := GetImpersonatorID(c)
+			if !ok {
+				return next(c)
+			}
+
+			err := next(c)
+
+			status := c.Response().Status
+			if httpErr, ok := err.(*echo.HTTPError); ok && !c.Response().Committed {
+				status = httpErr.Code
+			}
+
+			userID, _ := GetUserID(c)
+			auditor.RecordImpersonatedRequest(actorID, userID, c.Request().Method, c.Request().URL.Path, status, &types.ClientInfo{
+				IPAddress: c.RealIP(),
+				UserAgent: c.Request().UserAgent(),
+			})
+			return err
+		}
+	}
+}
@@ -22,27 +22,30 @@ func Logger(logger *logger.Logger) echo.MiddlewareFunc {
 		LogRemoteIP:  true,
 		HandleError:  true, // forwards error to the global error handler, so it can decide appropriate status code
 		LogValuesFunc: func(c echo.Context, v middleware.RequestLoggerValues) error {
-			if v.Error == nil {
-				logger.Logger.Info("request",
-					zap.String("method", v.Method),
-					zap.String("uri", v.URI),
-					zap.Int("status", v.Status),
-					zap.Duration("latency", v.Latency),
-					zap.String("request_id", v.RequestID),
-					zap.String("remote_ip", v.RemoteIP),
-					zap.String("user_agent", v.UserAgent),
+			fields := []zap.Field{
+				zap.String("method", v.Method),
+				zap.String("uri", v.URI),
+				zap.Int("status", v.Status),
+				zap.Duration("latency", v.Latency),
+				zap.String("request_id", v.RequestID),
+				zap.String("remote_ip", v.RemoteIP),
+				zap.String("user_agent", v.UserAgent),
+			}
+
+			// Tag requests an administrator made as another user
+			if actorID, ok := GetImpersonatorID(c); ok {
+				userID, _ := GetUserID(c)
+				fields = append(fields,
+					zap.Bool("impersonated", true),
+					zap.Uint("impersonator_id", actorID),
+					zap.Uint("user_id", userID),
 				)
+			}
+
+			if v.Error == nil {
+				logger.Logger.Info("request", fields...)
 			} else {
-				logger.Logger.Error("request",
-					zap.String("method", v.Method),
-					zap.String("uri", v.URI),
-					zap.Int("status", v.Status),
-					zap.Duration("latency", v.Latency),
-					zap.String("request_id", v.RequestID),
-					zap.String("remote_ip", v.RemoteIP),
-					zap.String("user_agent", v.UserAgent),
-					zap.Error(v.Error),
-				)
+				logger.Logger.Error("request", append(fields, zap.Error(v.Error))...)
 			}
 			return nil
 		},
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/tenant"
+)
+
+// RequireOrganization returns middleware that only lets requests through whose
+// token carries an active organization, and scopes the database statements of
+// the request to it. It must run after JWTAuth.
+func RequireOrganization() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			orgID, ok := GetOrgID(c)
+			if !ok {
+				return response.Forbidden(c, "No active organization")
+			}
+
+			req := c.Request()
+			c.SetRequest(req.WithContext(tenant.WithOrgID(req.Context(), orgID)))
+			return next(c)
+		}
+	}
+}
+
+// GetOrgID extracts the active organization from context
+func GetOrgID(c echo.Context) (uint, bool) {
+	orgID, ok := c.Get("org_id").(uint)
+	return orgID, ok
+}
+
+// GetOrgRole extracts the role in the active organization from context. It is
+// the role when the token was issued; authorization decisions re-check it.
+func GetOrgRole(c echo.Context) (string, bool) {
+	role, ok := c.Get("org_role").(string)
+	return role, ok
+}
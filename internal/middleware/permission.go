@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"slices"
+
+	"github.com/labstack/echo/v4"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/response"
+)
+
+// RequirePermission returns middleware that only lets requests through whose
+// token carries the given permission. It must run after JWTAuth.
+func RequirePermission(permission string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !HasPermission(c, permission) {
+				return response.Forbidden(c, "Insufficient permissions")
+			}
+			return next(c)
+		}
+	}
+}
+
+// HasPermission reports whether the authenticated user holds a permission
+func HasPermission(c echo.Context, permission string) bool {
+	permissions, _ := GetPermissions(c)
+	return slices.Contains(permissions, permission)
+}
+
+// GetRoles extracts role names from context
+func GetRoles(c echo.Context) ([]string, bool) {
+	roles, ok := c.Get("roles").([]string)
+	return roles, ok
+}
+
+// GetPermissions extracts permission names from context
+func GetPermissions(c echo.Context) ([]string, bool) {
+	permissions, ok := c.Get("permissions").([]string)
+	return permissions, ok
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// APIKey represents a long-lived personal access token for machine clients.
+// Only the SHA-256 hash of the key is stored; the prefix identifies it to its owner.
+type APIKey struct {
+	BaseModel
+	UserID  uint   `json:"user_id" gorm:"not null;index"`
+	Name    string `json:"name" gorm:"not null"`
+	Prefix  string `json:"prefix" gorm:"not null"`
+	KeyHash string `json:"-" gorm:"uniqueIndex;not null"`
+	// Scopes is a space separated list of the permissions the key may use
+	Scopes     string     `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	User       User       `json:"user" gorm:"foreignKey:UserID"`
+}
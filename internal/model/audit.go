@@ -0,0 +1,74 @@
+package model
+
+// Audit event types
+const (
+	// AuditEventRefreshTokenReuse is recorded when a revoked refresh token is presented again
+	AuditEventRefreshTokenReuse = "refresh_token_reuse"
+	// AuditEventAccountLocked is recorded when repeated failed logins lock an account
+	AuditEventAccountLocked = "account_locked"
+	// AuditEventAccountUnlocked is recorded when an administrator unlocks an account
+	AuditEventAccountUnlocked = "account_unlocked"
+	// AuditEventIdentityLinked is recorded when a social login account is linked to a user
+	AuditEventIdentityLinked = "identity_linked"
+	// AuditEventIdentityUnlinked is recorded when a user removes a linked social login account
+	AuditEventIdentityUnlinked = "identity_unlinked"
+	// AuditEventOAuthConsentGranted is recorded when a user authorizes an OAuth client
+	AuditEventOAuthConsentGranted = "oauth_consent_granted"
+	// AuditEventAuthorizationCodeReuse is recorded when a redeemed authorization code is presented again
+	AuditEventAuthorizationCodeReuse = "authorization_code_reuse"
+	// AuditEventImpersonationStarted is recorded when an administrator starts impersonating a user
+	AuditEventImpersonationStarted = "impersonation_started"
+	// AuditEventImpersonatedRequest is recorded for every request made while impersonating a user
+	AuditEventImpersonatedRequest = "impersonated_request"
+	// AuditEventUserCreated is recorded when an administrator creates a user
+	AuditEventUserCreated = "user_created"
+	// AuditEventUserUpdated is recorded when an administrator changes a user
+	AuditEventUserUpdated = "user_updated"
+	// AuditEventPasswordResetForced is recorded when an administrator forces a user to choose a new password
+	AuditEventPasswordResetForced = "password_reset_forced"
+	// AuditEventSessionsRevoked is recorded when an administrator signs a user out everywhere
+	AuditEventSessionsRevoked = "sessions_revoked"
+	// AuditEventUserDeleted is recorded when an administrator soft-deletes a user
+	AuditEventUserDeleted = "user_deleted"
+	// AuditEventUserRestored is recorded when an administrator restores a soft-deleted user
+	AuditEventUserRestored = "user_restored"
+	// AuditEventUserPurged is recorded when an administrator permanently deletes a user
+	AuditEventUserPurged = "user_purged"
+	// AuditEventDataExportRequested is recorded when a user requests an export of their data
+	AuditEventDataExportRequested = "data_export_requested"
+	// AuditEventDataExportDownloaded is recorded when a user downloads an export of their data
+	AuditEventDataExportDownloaded = "data_export_downloaded"
+	// AuditEventErasureRequested is recorded when a user schedules the erasure of their account
+	AuditEventErasureRequested = "erasure_requested"
+	// AuditEventErasureCancelled is recorded when a user cancels the scheduled erasure of their account
+	AuditEventErasureCancelled = "erasure_cancelled"
+	// AuditEventUserErased is recorded when an account is erased at the end of its grace period
+	AuditEventUserErased = "user_erased"
+	// AuditEventOrgCreated is recorded when a user creates an organization
+	AuditEventOrgCreated = "org_created"
+	// AuditEventOrgDeleted is recorded when an owner deletes an organization
+	AuditEventOrgDeleted = "org_deleted"
+	// AuditEventOrgMemberInvited is recorded when someone is invited to join an organization
+	AuditEventOrgMemberInvited = "org_member_invited"
+	// AuditEventOrgMemberJoined is recorded when a user accepts an invitation to an organization
+	AuditEventOrgMemberJoined = "org_member_joined"
+	// AuditEventOrgMemberRoleChanged is recorded when the role of an organization member changes
+	AuditEventOrgMemberRoleChanged = "org_member_role_changed"
+	// AuditEventOrgMemberRemoved is recorded when a member leaves or is removed from an organization
+	AuditEventOrgMemberRemoved = "org_member_removed"
+)
+
+// AuditEvent records a security-relevant event for a user
+type AuditEvent struct {
+	BaseModel
+	UserID    uint   `json:"user_id" gorm:"index"`
+	Event     string `json:"event" gorm:"not null;index"`
+	IPAddress string `json:"ip_address"`
+	UserAgent string `json:"user_agent"`
+	// ActorID is the user who acted on the user, such as an administrator, if any
+	ActorID *uint `json:"actor_id,omitempty" gorm:"index"`
+	// Impersonated is set when the actor acted as the user while impersonating them
+	Impersonated bool `json:"impersonated" gorm:"default:false"`
+	// Details holds event-specific data as a JSON object
+	Details string `json:"details"`
+}
@@ -0,0 +1,23 @@
+package model
+
+// File purposes
+const (
+	// FilePurposeUpload marks files uploaded by users
+	FilePurposeUpload = "upload"
+	// FilePurposeAvatar marks avatar images
+	FilePurposeAvatar = "avatar"
+)
+
+// File is an uploaded file. Its content is stored under a key derived from its
+// SHA-256 hash, so that identical uploads share the stored object.
+type File struct {
+	BaseModel
+	UserID      uint   `json:"user_id" gorm:"not null;index"`
+	Purpose     string `json:"purpose" gorm:"not null;index"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	ObjectKey   string `json:"-" gorm:"not null;index"`
+	// ThumbnailKey is set for images and holds a scaled down JPEG version
+	ThumbnailKey string `json:"-" gorm:"index"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// UserIdentity links a user to an account at a social login provider
+type UserIdentity struct {
+	BaseModel
+	UserID uint `json:"user_id" gorm:"not null;index"`
+	// Provider and Subject identify the account at the provider
+	Provider    string     `json:"provider" gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject     string     `json:"subject" gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	User        User       `json:"user" gorm:"foreignKey:UserID"`
+}
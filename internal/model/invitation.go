@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Invitation lets people register while self-service registration is closed.
+// Only the SHA-256 hash of the code is stored; the prefix identifies it to administrators.
+type Invitation struct {
+	BaseModel
+	Prefix   string `json:"prefix" gorm:"not null"`
+	CodeHash string `json:"-" gorm:"uniqueIndex;not null"`
+	// Email restricts the invitation to one address when set
+	Email string `json:"email"`
+	// RoleID is granted to users registering with the invitation instead of the default role
+	RoleID      *uint      `json:"role_id"`
+	MaxUses     int        `json:"max_uses" gorm:"not null;default:1"`
+	UseCount    int        `json:"use_count" gorm:"not null;default:0"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	CreatedByID uint       `json:"created_by_id" gorm:"index"`
+	Role        *Role      `json:"role,omitempty" gorm:"foreignKey:RoleID"`
+}
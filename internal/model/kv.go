@@ -1,9 +1,24 @@
 package model
 
+import "time"
+
+// KV keys of settings administrators can change at runtime
+const (
+	// SettingAllowRegister enables self-service registration
+	SettingAllowRegister = "system:allow_register"
+	// SettingAllowMagicLink enables passwordless login with links sent by email
+	SettingAllowMagicLink = "system:allow_magic_link"
+)
+
 // Use a kv table to simulate a kv implementation with persistence
 // similar to valkey and redis
 type KV struct {
 	BaseModel
 	Key   string `json:"key" gorm:"uniqueIndex;not null"`
 	Value string `json:"value"`
+	// ExpiresAt is set for entries with a time to live; expired entries are ignored
+	ExpiresAt *time.Time `json:"expires_at" gorm:"index"`
+	// UserID is set for entries holding data of a user, which are exported and
+	// erased with the user's other data
+	UserID *uint `json:"user_id,omitempty" gorm:"index"`
 }
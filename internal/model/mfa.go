@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// MFARecoveryCode represents a hashed one-time recovery code for two-factor authentication
+type MFARecoveryCode struct {
+	BaseModel
+	UserID   uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	UsedAt   *time.Time `json:"used_at"`
+	User     User       `json:"user" gorm:"foreignKey:UserID"`
+}
@@ -0,0 +1,61 @@
+package model
+
+// OAuth 2.0 grant types clients can be allowed to use
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// OpenID Connect scopes, granted next to permission scopes
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// OAuthClient is an application signing users in through this service's
+// authorization server. Only the SHA-256 hash of the secret is stored.
+type OAuthClient struct {
+	BaseModel
+	ClientID string `json:"client_id" gorm:"uniqueIndex;not null"`
+	// SecretHash is empty for public clients, which authenticate with PKCE only
+	SecretHash string `json:"-"`
+	Name       string `json:"name" gorm:"not null"`
+	// RedirectURIs, Scopes and GrantTypes are space separated lists
+	RedirectURIs string `json:"redirect_uris"`
+	Scopes       string `json:"scopes"`
+	GrantTypes   string `json:"grant_types"`
+	// Trusted clients are first-party applications that skip the consent screen
+	Trusted bool `json:"trusted" gorm:"default:false"`
+}
+
+// Confidential reports whether the client authenticates with a secret
+func (c *OAuthClient) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+// OAuthConsent records the scopes a user has allowed a client to use
+type OAuthConsent struct {
+	BaseModel
+	UserID   uint   `json:"user_id" gorm:"not null;uniqueIndex:idx_oauth_consents_user_client"`
+	ClientID string `json:"client_id" gorm:"not null;uniqueIndex:idx_oauth_consents_user_client"`
+	// Scope is a space separated list of the consented scopes
+	Scope string `json:"scope"`
+}
+
+// OAuthAuthorizationCode is a single-use code exchanged by a client for tokens
+type OAuthAuthorizationCode struct {
+	BaseModel
+	SingleUseToken
+	ClientID      string `json:"client_id" gorm:"not null;index"`
+	UserID        uint   `json:"user_id" gorm:"not null;index"`
+	RedirectURI   string `json:"redirect_uri"`
+	Scope         string `json:"scope"`
+	CodeChallenge string `json:"-"`
+	Nonce         string `json:"-"`
+	// FamilyID is the refresh token family the code is exchanged into, so that
+	// the tokens can be revoked if the code is replayed
+	FamilyID string `json:"family_id"`
+	User     User   `json:"user" gorm:"foreignKey:UserID"`
+}
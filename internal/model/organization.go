@@ -0,0 +1,63 @@
+package model
+
+import "time"
+
+// Organization membership roles, from most to least privileged
+const (
+	// OrgRoleOwner can do everything, including deleting the organization and
+	// managing owners
+	OrgRoleOwner = "owner"
+	// OrgRoleAdmin manages the organization, its members and invitations
+	OrgRoleAdmin = "admin"
+	// OrgRoleMember uses the organization
+	OrgRoleMember = "member"
+)
+
+// OrgRoleRank orders membership roles by privilege; unknown roles rank lowest
+func OrgRoleRank(role string) int {
+	switch role {
+	case OrgRoleOwner:
+		return 3
+	case OrgRoleAdmin:
+		return 2
+	case OrgRoleMember:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Organization is a workspace shared by its members. Rows of other models
+// with an org_id column belong to one organization and are only visible
+// within it.
+type Organization struct {
+	BaseModel
+	Name        string `json:"name" gorm:"not null"`
+	Slug        string `json:"slug" gorm:"uniqueIndex;not null"`
+	CreatedByID uint   `json:"created_by_id" gorm:"index"`
+}
+
+// Membership makes a user a member of an organization with a role
+type Membership struct {
+	BaseModel
+	OrgID        uint         `json:"org_id" gorm:"not null;uniqueIndex:idx_memberships_org_user"`
+	UserID       uint         `json:"user_id" gorm:"not null;uniqueIndex:idx_memberships_org_user;index"`
+	Role         string       `json:"role" gorm:"not null"`
+	User         User         `json:"user" gorm:"foreignKey:UserID"`
+	Organization Organization `json:"organization" gorm:"foreignKey:OrgID"`
+}
+
+// OrgInvitation invites someone to join an organization by email. Only the
+// SHA-256 hash of the token is stored.
+type OrgInvitation struct {
+	BaseModel
+	OrgID uint   `json:"org_id" gorm:"not null;index"`
+	Email string `json:"email" gorm:"not null"`
+	// EmailNormalized is matched against the verified address of the user accepting
+	EmailNormalized string       `json:"-" gorm:"not null;index"`
+	Role            string       `json:"role" gorm:"not null"`
+	TokenHash       string       `json:"-" gorm:"uniqueIndex;not null"`
+	InvitedByID     uint         `json:"invited_by_id" gorm:"index"`
+	ExpiresAt       time.Time    `json:"expires_at" gorm:"not null"`
+	Organization    Organization `json:"organization" gorm:"foreignKey:OrgID"`
+}
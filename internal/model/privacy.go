@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Data export statuses
+const (
+	DataExportPending    = "pending"
+	DataExportRunning    = "running"
+	DataExportReady      = "ready"
+	DataExportFailed     = "failed"
+	DataExportDownloaded = "downloaded"
+	DataExportExpired    = "expired"
+)
+
+// DataExport is an archive of everything tied to a user, built in the
+// background and downloadable once
+type DataExport struct {
+	BaseModel
+	UserID uint   `json:"user_id" gorm:"not null;index"`
+	Status string `json:"status" gorm:"not null;index"`
+	// FilePath is where the archive is kept until it is downloaded or expires
+	FilePath     string     `json:"-"`
+	Size         int64      `json:"size"`
+	Error        string     `json:"-"`
+	CompletedAt  *time.Time `json:"completed_at"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	DownloadedAt *time.Time `json:"downloaded_at"`
+}
@@ -0,0 +1,58 @@
+package model
+
+// Built-in roles
+const (
+	// RoleAdmin is granted every permission
+	RoleAdmin = "admin"
+	// RoleUser is the default role of registered users
+	RoleUser = "user"
+)
+
+// Permissions checked by the application
+const (
+	PermissionUsersRead    = "users:read"
+	PermissionUsersWrite   = "users:write"
+	PermissionUsersDelete  = "users:delete"
+	PermissionRolesRead    = "roles:read"
+	PermissionRolesWrite   = "roles:write"
+	PermissionConfigWrite  = "config:write"
+	PermissionClientsRead  = "clients:read"
+	PermissionClientsWrite = "clients:write"
+	// Invitations let people register while registration is closed
+	PermissionInvitationsRead  = "invitations:read"
+	PermissionInvitationsWrite = "invitations:write"
+	// Impersonation lets support staff see the application as another user
+	PermissionUsersImpersonate = "users:impersonate"
+)
+
+// BuiltinPermissions lists every permission known to the application
+var BuiltinPermissions = []Permission{
+	{Name: PermissionUsersRead, Description: "View users, their roles and lockout state"},
+	{Name: PermissionUsersWrite, Description: "Modify users and unlock accounts"},
+	{Name: PermissionUsersDelete, Description: "Delete users"},
+	{Name: PermissionUsersImpersonate, Description: "Sign in as another user for support"},
+	{Name: PermissionRolesRead, Description: "View roles and permissions"},
+	{Name: PermissionRolesWrite, Description: "Manage roles and assign them to users"},
+	{Name: PermissionConfigWrite, Description: "Change system configuration"},
+	{Name: PermissionClientsRead, Description: "View registered OAuth clients"},
+	{Name: PermissionClientsWrite, Description: "Register and manage OAuth clients"},
+	{Name: PermissionInvitationsRead, Description: "View registration invitations"},
+	{Name: PermissionInvitationsWrite, Description: "Create and revoke registration invitations"},
+}
+
+// Permission represents a named action that roles can be allowed to perform
+type Permission struct {
+	BaseModel
+	Name        string `json:"name" gorm:"uniqueIndex;not null"`
+	Description string `json:"description"`
+}
+
+// Role represents a named set of permissions assigned to users
+type Role struct {
+	BaseModel
+	Name        string `json:"name" gorm:"uniqueIndex;not null"`
+	Description string `json:"description"`
+	// System roles are created by the application and cannot be renamed or deleted
+	System      bool         `json:"system" gorm:"default:false"`
+	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions"`
+}
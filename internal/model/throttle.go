@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+)
+
+// LoginThrottle tracks failed login attempts for an account ("user:<username>")
+// or a client IP address ("ip:<address>")
+type LoginThrottle struct {
+	BaseModel
+	Key           string     `json:"key" gorm:"uniqueIndex;not null"`
+	Failures      int        `json:"failures" gorm:"not null;default:0"`
+	LastFailureAt time.Time  `json:"last_failure_at"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+}
@@ -11,5 +11,60 @@ type RefreshToken struct {
 	Token     string    `json:"token" gorm:"uniqueIndex;not null"`
 	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
 	IsRevoked bool      `json:"is_revoked" gorm:"default:false"`
-	User      User      `json:"user" gorm:"foreignKey:UserID"`
-}
\ No newline at end of file
+	// FamilyID is shared by all tokens descending from the same login through rotation
+	// and identifies the login session
+	FamilyID string `json:"family_id" gorm:"index"`
+	// Session details, carried over on rotation
+	UserAgent        string     `json:"user_agent"`
+	IPAddress        string     `json:"ip_address"`
+	DeviceName       string     `json:"device_name"`
+	SessionStartedAt time.Time  `json:"session_started_at"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	// ClientID and Scope are set for tokens issued to an OAuth client, which
+	// can only be refreshed by that client
+	ClientID string `json:"client_id" gorm:"index"`
+	Scope    string `json:"scope"`
+	// ActiveOrgID is the organization the session works in, carried over on rotation
+	ActiveOrgID *uint `json:"active_org_id" gorm:"index"`
+	User        User  `json:"user" gorm:"foreignKey:UserID"`
+}
+
+// SingleUseToken contains the common fields of hashed, single-use, expiring tokens.
+// Only the SHA-256 hash of the token is stored.
+type SingleUseToken struct {
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+// SingleUse returns the embedded single-use token fields
+func (t *SingleUseToken) SingleUse() *SingleUseToken {
+	return t
+}
+
+// PasswordResetToken represents a single-use password reset token
+type PasswordResetToken struct {
+	BaseModel
+	SingleUseToken
+	UserID uint `json:"user_id" gorm:"not null;index"`
+	User   User `json:"user" gorm:"foreignKey:UserID"`
+}
+
+// MagicLinkToken represents a single-use token signing a user in without a password.
+// Email is the address the link was sent to.
+type MagicLinkToken struct {
+	BaseModel
+	SingleUseToken
+	UserID uint   `json:"user_id" gorm:"not null;index"`
+	Email  string `json:"email" gorm:"not null"`
+	User   User   `json:"user" gorm:"foreignKey:UserID"`
+}
+
+// EmailVerificationToken represents a single-use token proving ownership of an email address
+type EmailVerificationToken struct {
+	BaseModel
+	SingleUseToken
+	UserID uint   `json:"user_id" gorm:"not null;index"`
+	Email  string `json:"email" gorm:"not null"`
+	User   User   `json:"user" gorm:"foreignKey:UserID"`
+}
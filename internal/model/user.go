@@ -1,13 +1,44 @@
 package model
 
+import (
+	"strings"
+	"time"
+)
+
 // User represents a user in the system
 type User struct {
 	BaseModel
-	Username     string `json:"username" gorm:"uniqueIndex;not null"`
-	Email        string `json:"email" gorm:"uniqueIndex;not null"`
-	PasswordHash string `json:"-" gorm:"not null"`
-	FirstName    string `json:"first_name"`
-	LastName     string `json:"last_name"`
-	Role         string `json:"role"`
-	IsActive     bool   `json:"is_active" gorm:"default:true"`
+	Username          string     `json:"username" gorm:"uniqueIndex;not null"`
+	Email             string     `json:"email" gorm:"uniqueIndex;not null"`
+	EmailVerifiedAt   *time.Time `json:"email_verified_at"`
+	PendingEmail      string     `json:"pending_email" gorm:"index"`
+	PasswordHash      string     `json:"-" gorm:"not null"`
+	PasswordChangedAt *time.Time `json:"password_changed_at"`
+	FirstName         string     `json:"first_name"`
+	LastName          string     `json:"last_name"`
+	IsActive          bool       `json:"is_active" gorm:"default:true"`
+	MFASecret         string     `json:"-"`
+	MFAEnabledAt      *time.Time `json:"mfa_enabled_at"`
+	MFALastUsedStep   int64      `json:"-"`
+	Roles             []Role     `json:"roles,omitempty" gorm:"many2many:user_roles"`
+	// ErasureScheduledAt is when the account will be erased at the user's
+	// request, unless they cancel before
+	ErasureScheduledAt *time.Time `json:"erasure_scheduled_at" gorm:"index"`
+	// AvatarID is the uploaded file holding the user's avatar
+	AvatarID *uint `json:"avatar_id"`
+	// UsernameNormalized and EmailNormalized hold the canonical forms that
+	// make usernames and emails unique regardless of case
+	UsernameNormalized string `json:"-" gorm:"uniqueIndex"`
+	EmailNormalized    string `json:"-" gorm:"uniqueIndex"`
+}
+
+// NormalizeIdentifier returns the canonical form of a username or email address
+func NormalizeIdentifier(identifier string) string {
+	return strings.ToLower(strings.TrimSpace(identifier))
+}
+
+// Normalize updates the canonical forms of the username and email address
+func (u *User) Normalize() {
+	u.UsernameNormalized = NormalizeIdentifier(u.Username)
+	u.EmailNormalized = NormalizeIdentifier(u.Email)
 }
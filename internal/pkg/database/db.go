@@ -6,6 +6,7 @@ import (
 
 	"github.com/ray-d-song/go-echo-monolithic/internal/config"
 	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/logger"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/tenant"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
@@ -39,6 +40,11 @@ func NewConnection(cfg *config.DatabaseConfig, log *logger.Logger) (*Connection,
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// Scope statements on organization data to the organization of their context
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		return nil, fmt.Errorf("failed to register tenant scoping: %w", err)
+	}
+
 	return &Connection{DB: db}, nil
 }
 
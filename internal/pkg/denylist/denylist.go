@@ -0,0 +1,144 @@
+package denylist
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/jwt"
+)
+
+// Store persists denylist entries until they expire
+type Store interface {
+	// Set stores a value that is kept until expiresAt
+	Set(key, value string, expiresAt time.Time) error
+	// Get returns the value of an unexpired entry
+	Get(key string) (value string, found bool, err error)
+	// Purge removes expired entries
+	Purge() error
+}
+
+// Denylist rejects access tokens before they expire. Entries are kept for the
+// lifetime of an access token, after which the tokens are rejected anyway.
+type Denylist struct {
+	store Store
+	users Store
+	ttl   time.Duration
+}
+
+// New creates a denylist. The cutoffs of users, which end every session of a
+// user after a password change or sign-out everywhere, are kept in users,
+// which should survive restarts; other entries are kept in store. ttl must be
+// at least the access token lifetime.
+func New(store, users Store, ttl time.Duration) *Denylist {
+	return &Denylist{
+		store: store,
+		users: users,
+		ttl:   ttl,
+	}
+}
+
+// RevokeToken denies a single token until it expires
+func (d *Denylist) RevokeToken(claims *jwt.Claims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return fmt.Errorf("token has no id or expiry")
+	}
+	return d.store.Set("jti:"+claims.ID, "1", claims.ExpiresAt.Time)
+}
+
+// RevokeSession denies every token issued for a login session
+func (d *Denylist) RevokeSession(sessionID string) error {
+	return d.store.Set("sid:"+sessionID, "1", time.Now().Add(d.ttl))
+}
+
+// RevokeUser denies every token of a user issued until the given time. Token
+// issue times have a resolution of one second, so tokens issued later within
+// the same second are denied as well.
+func (d *Denylist) RevokeUser(userID uint, at time.Time) error {
+	return d.setCutoff(userID, at.Truncate(time.Second).Add(time.Second), at)
+}
+
+// RevokeUserBefore denies every token of a user issued before the given time,
+// but accepts tokens issued within the same second, since issue times have a
+// resolution of one second. This lets a caller issue a fresh token right
+// after revoking the old ones; tokens issued just before within that second
+// stay valid too, so only use it when the user keeps a session anyway.
+func (d *Denylist) RevokeUserBefore(userID uint, before time.Time) error {
+	return d.setCutoff(userID, before.Truncate(time.Second), before)
+}
+
+// setCutoff stores the issue time before which tokens of a user are denied,
+// kept until every token issued before the revocation has expired
+func (d *Denylist) setCutoff(userID uint, cutoff, revokedAt time.Time) error {
+	return d.users.Set(userKey(userID), strconv.FormatInt(cutoff.Unix(), 10), revokedAt.Add(d.ttl))
+}
+
+// IsRevoked reports whether a token has been denied
+func (d *Denylist) IsRevoked(claims *jwt.Claims) (bool, error) {
+	if claims.ID != "" {
+		if _, found, err := d.store.Get("jti:" + claims.ID); err != nil || found {
+			return found, err
+		}
+	}
+
+	if claims.SessionID != "" {
+		if _, found, err := d.store.Get("sid:" + claims.SessionID); err != nil || found {
+			return found, err
+		}
+	}
+
+	value, found, err := d.users.Get(userKey(claims.UserID))
+	if err != nil || !found {
+		return false, err
+	}
+
+	cutoff, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid denylist cutoff for user %d: %w", claims.UserID, err)
+	}
+
+	// Tokens without an issue time predate every cutoff
+	if claims.IssuedAt == nil {
+		return true, nil
+	}
+	return claims.IssuedAt.Unix() < cutoff, nil
+}
+
+// Purge removes expired entries
+func (d *Denylist) Purge() error {
+	if err := d.store.Purge(); err != nil {
+		return err
+	}
+	if d.users == d.store {
+		return nil
+	}
+	return d.users.Purge()
+}
+
+// StartPurging purges expired entries at the given interval until the returned
+// function is called
+func (d *Denylist) StartPurging(interval time.Duration, onError func(error)) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if err := d.Purge(); err != nil && onError != nil {
+					onError(err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// userKey returns the key holding the revocation cutoff of a user
+func userKey(userID uint) string {
+	return "user:" + strconv.FormatUint(uint64(userID), 10)
+}
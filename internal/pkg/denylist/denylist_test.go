@@ -0,0 +1,73 @@
+package denylist_test
+
+import (
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/denylist"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/jwt"
+)
+
+// issuedAt returns the claims of a token of user 1 issued at the given time
+func issuedAt(t time.Time) *jwt.Claims {
+	return &jwt.Claims{
+		UserID: 1,
+		RegisteredClaims: gojwt.RegisteredClaims{
+			ID:       t.String(),
+			IssuedAt: gojwt.NewNumericDate(t),
+		},
+	}
+}
+
+func TestDenylistUserCutoff(t *testing.T) {
+	// Halfway through a second; entries expire relative to the current time
+	revokedAt := time.Now().Truncate(time.Second).Add(500 * time.Millisecond)
+
+	tests := []struct {
+		name   string
+		revoke func(list *denylist.Denylist) error
+		// sameSecond is whether tokens issued within the second of the
+		// revocation are denied
+		sameSecond bool
+	}{
+		{"RevokeUser", func(list *denylist.Denylist) error { return list.RevokeUser(1, revokedAt) }, true},
+		{"RevokeUserBefore", func(list *denylist.Denylist) error { return list.RevokeUserBefore(1, revokedAt) }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := denylist.NewMemoryStore()
+			list := denylist.New(store, store, time.Hour)
+			if err := tt.revoke(list); err != nil {
+				t.Fatal(err)
+			}
+
+			cases := []struct {
+				issued time.Time
+				want   bool
+			}{
+				{revokedAt.Add(-time.Second), true},
+				{revokedAt.Add(-100 * time.Millisecond), tt.sameSecond},
+				{revokedAt.Add(400 * time.Millisecond), tt.sameSecond},
+				{revokedAt.Add(time.Second), false},
+			}
+			for _, c := range cases {
+				revoked, err := list.IsRevoked(issuedAt(c.issued))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if revoked != c.want {
+					t.Errorf("token issued at %s: revoked = %v, want %v", c.issued.Format(time.StampMilli), revoked, c.want)
+				}
+			}
+
+			// Tokens of other users are unaffected
+			other := issuedAt(revokedAt.Add(-time.Second))
+			other.UserID = 2
+			if revoked, err := list.IsRevoked(other); err != nil || revoked {
+				t.Errorf("token of another user: revoked = %v, %v; want false, nil", revoked, err)
+			}
+		})
+	}
+}
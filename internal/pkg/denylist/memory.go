@@ -0,0 +1,60 @@
+package denylist
+
+import (
+	"sync"
+	"time"
+)
+
+// memoryEntry is a value with its expiry
+type memoryEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// MemoryStore keeps denylist entries in process memory. Entries are lost on
+// restart and not shared between instances.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]memoryEntry
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]memoryEntry),
+	}
+}
+
+// Set stores a value that is kept until expiresAt
+func (s *MemoryStore) Set(key, value string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryEntry{value: value, expiresAt: expiresAt}
+	return nil
+}
+
+// Get returns the value of an unexpired entry
+func (s *MemoryStore) Get(key string) (string, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.entries[key]
+	if !ok || !entry.expiresAt.After(time.Now()) {
+		return "", false, nil
+	}
+	return entry.value, true, nil
+}
+
+// Purge removes expired entries
+func (s *MemoryStore) Purge() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range s.entries {
+		if !entry.expiresAt.After(now) {
+			delete(s.entries, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Package imaging decodes uploaded images and scales them down to thumbnails
+// using only the standard library.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+
+	// Register the decoders of the supported formats
+	_ "image/gif"
+	_ "image/png"
+)
+
+// MaxPixels is the largest number of pixels of a decoded image, which keeps
+// small files that decode to huge images from exhausting memory
+const MaxPixels = 40_000_000
+
+// ErrTooLarge is returned for images with more than MaxPixels pixels
+var ErrTooLarge = errors.New("image dimensions too large")
+
+// thumbnailQuality is the JPEG quality of thumbnails
+const thumbnailQuality = 85
+
+// Decode decodes a JPEG, PNG or GIF image after checking its dimensions
+func Decode(r io.ReadSeeker) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// Fit scales an image down to fit within size×size pixels, keeping its
+// aspect ratio. Smaller images are returned unchanged.
+func Fit(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= size && h <= size {
+		return img
+	}
+
+	if w >= h {
+		return resize(img, b, size, max(1, h*size/w))
+	}
+	return resize(img, b, max(1, w*size/h), size)
+}
+
+// Fill crops the centered square of an image and scales it to size×size
+// pixels, or to its own size if smaller
+func Fill(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	x := b.Min.X + (b.Dx()-side)/2
+	y := b.Min.Y + (b.Dy()-side)/2
+	square := image.Rect(x, y, x+side, y+side)
+
+	return resize(img, square, min(size, side), min(size, side))
+}
+
+// EncodeJPEG encodes an image as a JPEG thumbnail. Transparent areas become white.
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	opaque := image.NewRGBA(img.Bounds())
+	draw.Draw(opaque, opaque.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(opaque, opaque.Bounds(), img, img.Bounds().Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, opaque, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// resize scales a region of an image to w×h pixels by averaging the source
+// pixels covered by each target pixel, which gives smooth downscaled images
+func resize(img image.Image, src image.Rectangle, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	sw, sh := src.Dx(), src.Dy()
+
+	for y := 0; y < h; y++ {
+		y0 := src.Min.Y + y*sh/h
+		y1 := max(y0+1, src.Min.Y+(y+1)*sh/h)
+		for x := 0; x < w; x++ {
+			x0 := src.Min.X + x*sw/w
+			x1 := max(x0+1, src.Min.X+(x+1)*sw/w)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					n++
+				}
+			}
+
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
@@ -1,6 +1,7 @@
 package jwt
 
 import (
+	"crypto/rand"
 	"fmt"
 	"time"
 
@@ -8,15 +9,87 @@ import (
 	"github.com/ray-d-song/go-echo-monolithic/internal/config"
 )
 
+// PurposeMFAPending marks a token proving the password step of a two-step login
+const PurposeMFAPending = "mfa_pending"
+
 // Claims represents JWT claims
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
-	Role     string `json:"role"`
+	// Roles and Permissions are the user's grants when the token was issued;
+	// changes take effect on the next token refresh
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	// Purpose restricts a token to a single use case; it is empty for access and refresh tokens
+	Purpose string `json:"purpose,omitempty"`
+	// SessionID identifies the login session (refresh token family) the token belongs to
+	SessionID string `json:"sid,omitempty"`
+	// ClientID and Scope are set for tokens issued to an OAuth client; the
+	// permissions are then limited to those among the granted scopes
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// OrgID and OrgRole identify the active organization of the session and the
+	// user's role in it when the token was issued
+	OrgID   uint   `json:"org_id,omitempty"`
+	OrgRole string `json:"org_role,omitempty"`
+	// Actor is set when an administrator acts as the user (RFC 8693 act claim)
+	Actor *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Actor identifies the administrator behind an impersonation token
+type Actor struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// IDTokenClaims represents the claims of an OpenID Connect ID token
+type IDTokenClaims struct {
+	Nonce             string `json:"nonce,omitempty"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Name              string `json:"name,omitempty"`
+	GivenName         string `json:"given_name,omitempty"`
+	FamilyName        string `json:"family_name,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// TokenOption customizes the claims of generated tokens
+type TokenOption func(*Claims)
+
+// WithSessionID binds the tokens to a login session
+func WithSessionID(sessionID string) TokenOption {
+	return func(claims *Claims) {
+		claims.SessionID = sessionID
+	}
+}
+
+// WithRoles embeds the user's roles and permissions in the tokens
+func WithRoles(roles, permissions []string) TokenOption {
+	return func(claims *Claims) {
+		claims.Roles = roles
+		claims.Permissions = permissions
+	}
+}
+
+// WithClient binds the tokens to an OAuth client and the scopes granted to it
+func WithClient(clientID, scope string) TokenOption {
+	return func(claims *Claims) {
+		claims.ClientID = clientID
+		claims.Scope = scope
+	}
+}
+
+// WithOrganization makes an organization the active organization of the tokens
+func WithOrganization(orgID uint, role string) TokenOption {
+	return func(claims *Claims) {
+		claims.OrgID = orgID
+		claims.OrgRole = role
+	}
+}
+
 // TokenPair represents access and refresh token pair
 type TokenPair struct {
 	AccessToken  string `json:"access_token"`
@@ -25,10 +98,13 @@ type TokenPair struct {
 
 // Manager handles JWT token operations
 type Manager struct {
-	accessSecret         []byte
-	refreshSecret        []byte
+	accessKeys           *keyRing
+	refreshKeys          *keySet
 	accessTokenDuration  time.Duration
 	refreshTokenDuration time.Duration
+	mfaTokenDuration     time.Duration
+	// impersonationTokenDuration never exceeds accessTokenDuration
+	impersonationTokenDuration time.Duration
 }
 
 // NewManager creates a new JWT manager
@@ -43,24 +119,82 @@ func NewManager(cfg *config.JWTConfig) (*Manager, error) {
 		return nil, fmt.Errorf("failed to parse refresh token duration: %w", err)
 	}
 
+	mfaDuration, err := time.ParseDuration(cfg.MFATokenDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse mfa token duration: %w", err)
+	}
+
+	impersonationDuration, err := time.ParseDuration(cfg.ImpersonationTokenDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse impersonation token duration: %w", err)
+	}
+	if impersonationDuration > accessDuration {
+		impersonationDuration = accessDuration
+	}
+
+	// Access tokens are signed with the configured private key when one is set,
+	// so that other services can verify them using the JWKS endpoint
+	accessKey := NewHMACKey("", []byte(cfg.AccessSecret))
+	if cfg.SigningKeyFile != "" {
+		accessKey, err = LoadKeyFile(cfg.SigningKeyID, cfg.SigningKeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	verifyKeys, err := parseVerifyKeyFiles(cfg.VerifyKeyFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	baseKeys, err := newKeySet(accessKey, verifyKeys...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build access key set: %w", err)
+	}
+
+	reloadInterval, err := time.ParseDuration(cfg.KeyRingReloadInterval)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key ring reload interval: %w", err)
+	}
+
+	// Retired keys stay valid until every token they signed has expired
+	retention := accessDuration
+	if mfaDuration > retention {
+		retention = mfaDuration
+	}
+
+	accessKeys, err := newKeyRing(baseKeys, cfg.KeyRingFile, reloadInterval, retention)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key ring: %w", err)
+	}
+
+	// Refresh tokens are only ever verified by this service
+	refreshKeys, err := newKeySet(NewHMACKey("", []byte(cfg.RefreshSecret)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build refresh key set: %w", err)
+	}
+
 	return &Manager{
-		accessSecret:         []byte(cfg.AccessSecret),
-		refreshSecret:        []byte(cfg.RefreshSecret),
+		accessKeys:           accessKeys,
+		refreshKeys:          refreshKeys,
 		accessTokenDuration:  accessDuration,
 		refreshTokenDuration: refreshDuration,
+		mfaTokenDuration:     mfaDuration,
+
+		impersonationTokenDuration: impersonationDuration,
 	}, nil
 }
 
 // GenerateTokenPair generates access and refresh token pair
-func (m *Manager) GenerateTokenPair(userID uint, username, email string) (*TokenPair, error) {
+func (m *Manager) GenerateTokenPair(userID uint, username, email string, opts ...TokenOption) (*TokenPair, error) {
 	// Generate access token
-	accessToken, err := m.generateToken(userID, username, email, m.accessSecret, m.accessTokenDuration)
+	accessToken, err := m.generateToken(userID, username, email, m.accessKeys.current(), m.accessTokenDuration, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
 	// Generate refresh token
-	refreshToken, err := m.generateToken(userID, username, email, m.refreshSecret, m.refreshTokenDuration)
+	refreshToken, err := m.generateToken(userID, username, email, m.refreshKeys, m.refreshTokenDuration, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
@@ -71,42 +205,134 @@ func (m *Manager) GenerateTokenPair(userID uint, username, email string) (*Token
 	}, nil
 }
 
+// GenerateAccessToken generates an access token without a refresh token, for
+// clients acting on their own behalf
+func (m *Manager) GenerateAccessToken(userID uint, username, email string, opts ...TokenOption) (string, error) {
+	token, err := m.generateToken(userID, username, email, m.accessKeys.current(), m.accessTokenDuration, opts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate access token: %w", err)
+	}
+	return token, nil
+}
+
+// GenerateIDToken signs an OpenID Connect ID token with the access token
+// signing key. The caller sets the issuer, subject, audience and user claims.
+func (m *Manager) GenerateIDToken(claims *IDTokenClaims) (string, error) {
+	keys := m.accessKeys.current()
+	if keys.active.IsSymmetric() {
+		return "", fmt.Errorf("id tokens require an asymmetric signing key")
+	}
+
+	now := time.Now()
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(m.accessTokenDuration))
+
+	token, err := keys.sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate id token: %w", err)
+	}
+	return token, nil
+}
+
+// SupportsIDTokens reports whether ID tokens can be issued, which requires
+// an asymmetric signing key that relying parties can verify using the JWKS
+func (m *Manager) SupportsIDTokens() bool {
+	return !m.accessKeys.current().active.IsSymmetric()
+}
+
+// SigningAlgorithm returns the algorithm of the active access token signing key
+func (m *Manager) SigningAlgorithm() string {
+	return m.accessKeys.current().active.Method.Alg()
+}
+
+// GenerateMFAPendingToken generates a short-lived token that can only be
+// exchanged for a token pair after a second factor has been verified
+func (m *Manager) GenerateMFAPendingToken(userID uint, username, email string) (string, error) {
+	claims := m.newClaims(userID, username, email, m.mfaTokenDuration)
+	claims.Purpose = PurposeMFAPending
+
+	token, err := m.accessKeys.current().sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate mfa token: %w", err)
+	}
+	return token, nil
+}
+
+// GenerateImpersonationToken generates a short-lived access token for a user
+// that records the administrator acting as them. No refresh token is issued,
+// so the impersonation ends when the token expires.
+func (m *Manager) GenerateImpersonationToken(userID uint, username, email string, actor Actor, opts ...TokenOption) (string, error) {
+	claims := m.newClaims(userID, username, email, m.impersonationTokenDuration)
+	for _, opt := range opts {
+		opt(claims)
+	}
+	claims.Actor = &actor
+
+	token, err := m.accessKeys.current().sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate impersonation token: %w", err)
+	}
+	return token, nil
+}
+
 // ValidateAccessToken validates access token and returns claims
 func (m *Manager) ValidateAccessToken(tokenString string) (*Claims, error) {
-	return m.validateToken(tokenString, m.accessSecret)
+	return m.validatePurpose(tokenString, m.accessKeys.current(), "")
 }
 
 // ValidateRefreshToken validates refresh token and returns claims
 func (m *Manager) ValidateRefreshToken(tokenString string) (*Claims, error) {
-	return m.validateToken(tokenString, m.refreshSecret)
+	return m.validatePurpose(tokenString, m.refreshKeys, "")
+}
+
+// ValidateMFAPendingToken validates a token issued by GenerateMFAPendingToken
+func (m *Manager) ValidateMFAPendingToken(tokenString string) (*Claims, error) {
+	return m.validatePurpose(tokenString, m.accessKeys.current(), PurposeMFAPending)
 }
 
 // generateToken generates a JWT token with given parameters
-func (m *Manager) generateToken(userID uint, username, email string, secret []byte, duration time.Duration) (string, error) {
-	claims := &Claims{
+func (m *Manager) generateToken(userID uint, username, email string, keys *keySet, duration time.Duration, opts ...TokenOption) (string, error) {
+	claims := m.newClaims(userID, username, email, duration)
+	for _, opt := range opts {
+		opt(claims)
+	}
+	return keys.sign(claims)
+}
+
+// newClaims builds the claims for a token valid for the given duration
+func (m *Manager) newClaims(userID uint, username, email string, duration time.Duration) *Claims {
+	return &Claims{
 		UserID:   userID,
 		Username: username,
 		Email:    email,
 		RegisteredClaims: jwt.RegisteredClaims{
+			// A unique ID per token allows revoking single tokens
+			ID:        rand.Text(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "go-echo-monolithic",
 		},
 	}
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secret)
+// validatePurpose validates a token and checks that it was issued for the given purpose
+func (m *Manager) validatePurpose(tokenString string, keys *keySet, purpose string) (*Claims, error) {
+	claims, err := m.validateToken(tokenString, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Purpose != purpose {
+		return nil, fmt.Errorf("invalid token purpose")
+	}
+
+	return claims, nil
 }
 
 // validateToken validates a JWT token and returns claims
-func (m *Manager) validateToken(tokenString string, secret []byte) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return secret, nil
-	})
+func (m *Manager) validateToken(tokenString string, keys *keySet) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keys.keyFunc)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -120,7 +346,47 @@ func (m *Manager) validateToken(tokenString string, secret []byte) (*Claims, err
 	return claims, nil
 }
 
+// JWKS returns the public keys that can verify access tokens
+func (m *Manager) JWKS() *JWKS {
+	return m.accessKeys.current().jwks()
+}
+
+// Keys lists the access token keys, with the active signing key first
+func (m *Manager) Keys() []KeyRingEntry {
+	return m.accessKeys.entries()
+}
+
+// RotateKey generates a new signing key for the given algorithm, or the
+// algorithm of the current key when empty. The previous signing key keeps
+// verifying tokens until it is retired.
+func (m *Manager) RotateKey(alg string) (*KeyRingEntry, error) {
+	return m.accessKeys.rotate(alg)
+}
+
+// RetireKey retires a verify-only key. Tokens it signed stay valid until they expire.
+func (m *Manager) RetireKey(kid string) error {
+	return m.accessKeys.retire(kid)
+}
+
+// ReloadKeys re-reads the key ring file
+func (m *Manager) ReloadKeys() error {
+	if m.accessKeys.file == "" {
+		return nil
+	}
+	return m.accessKeys.reload()
+}
+
+// GetAccessTokenDuration returns access token duration
+func (m *Manager) GetAccessTokenDuration() time.Duration {
+	return m.accessTokenDuration
+}
+
 // GetRefreshTokenDuration returns refresh token duration
 func (m *Manager) GetRefreshTokenDuration() time.Duration {
 	return m.refreshTokenDuration
 }
+
+// GetImpersonationTokenDuration returns impersonation token duration
+func (m *Manager) GetImpersonationTokenDuration() time.Duration {
+	return m.impersonationTokenDuration
+}
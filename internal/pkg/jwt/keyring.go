@@ -0,0 +1,484 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyStatus is the lifecycle state of a key in the key ring
+type KeyStatus string
+
+const (
+	// KeyStatusActive marks the single key used for signing new tokens
+	KeyStatusActive KeyStatus = "active"
+	// KeyStatusVerify marks a key that only verifies existing tokens
+	KeyStatusVerify KeyStatus = "verify"
+	// KeyStatusRetired marks a key that verifies tokens until they can no longer be valid
+	KeyStatusRetired KeyStatus = "retired"
+)
+
+// KeyRingEntry describes a key in the key ring manifest. Entries without a
+// file refer to a key from the static configuration by its key ID.
+type KeyRingEntry struct {
+	ID        string     `json:"kid"`
+	Algorithm string     `json:"alg,omitempty"`
+	File      string     `json:"file,omitempty"`
+	Status    KeyStatus  `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+}
+
+// KeyRingManifest is the on-disk description of the key ring
+type KeyRingManifest struct {
+	Keys []KeyRingEntry `json:"keys"`
+}
+
+// ReadKeyRingManifest reads a key ring manifest. A missing file yields an empty manifest.
+func ReadKeyRingManifest(path string) (*KeyRingManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &KeyRingManifest{}, nil
+		}
+		return nil, fmt.Errorf("failed to read key ring: %w", err)
+	}
+
+	var manifest KeyRingManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse key ring: %w", err)
+	}
+	return &manifest, nil
+}
+
+// Write atomically replaces the manifest file
+func (km *KeyRingManifest) Write(path string) error {
+	data, err := json.MarshalIndent(km, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write key ring: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// find returns the entry with the given key ID
+func (km *KeyRingManifest) find(id string) *KeyRingEntry {
+	for i := range km.Keys {
+		if km.Keys[i].ID == id {
+			return &km.Keys[i]
+		}
+	}
+	return nil
+}
+
+// GenerateKey generates a new key for the given algorithm (RS256, EdDSA or
+// HS256) and returns it with its file contents
+func GenerateKey(alg string) (*Key, []byte, error) {
+	switch strings.ToUpper(alg) {
+	case "RS256":
+		private, err := rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+		if err != nil {
+			return nil, nil, err
+		}
+		return encodePrivateKey(private)
+	case "EDDSA", "ED25519":
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return encodePrivateKey(private)
+	case "HS256":
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, nil, err
+		}
+		encoded := []byte(hex.EncodeToString(secret))
+		return NewHMACKey("", encoded), encoded, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported algorithm: %s", alg)
+	}
+}
+
+// encodePrivateKey encodes a private key as PKCS#8 PEM and parses it back into a Key
+func encodePrivateKey(private interface{}) (*Key, []byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	key, err := ParseKeyPEM("", data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, data, nil
+}
+
+// loadEntryKey loads the key file referenced by a manifest entry
+func loadEntryKey(entry *KeyRingEntry, dir string) (*Key, error) {
+	path := entry.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+
+	if strings.EqualFold(entry.Algorithm, "HS256") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file %s: %w", path, err)
+		}
+		return NewHMACKey(entry.ID, []byte(strings.TrimSpace(string(data)))), nil
+	}
+
+	return LoadKeyFile(entry.ID, path)
+}
+
+// keyRing combines the statically configured keys with the keys listed in a
+// manifest file. The manifest is re-read when it changes, so keys can be
+// rotated and retired without restarting the service.
+type keyRing struct {
+	mu             sync.RWMutex
+	base           *keySet
+	set            *keySet
+	file           string
+	reloadInterval time.Duration
+	retention      time.Duration
+	modTime        time.Time
+	checkedAt      time.Time
+}
+
+// newKeyRing creates a key ring. Retired keys keep verifying for the retention
+// period, which should be the longest lifetime of a token signed by the ring.
+func newKeyRing(base *keySet, file string, reloadInterval, retention time.Duration) (*keyRing, error) {
+	ring := &keyRing{
+		base:           base,
+		set:            base,
+		file:           file,
+		reloadInterval: reloadInterval,
+		retention:      retention,
+	}
+
+	if file != "" {
+		if err := ring.reload(); err != nil {
+			return nil, err
+		}
+	}
+
+	return ring, nil
+}
+
+// current returns the key set, re-reading the manifest if it changed since
+// the last check
+func (r *keyRing) current() *keySet {
+	if r.file != "" {
+		r.mu.RLock()
+		due := time.Since(r.checkedAt) >= r.reloadInterval
+		r.mu.RUnlock()
+
+		if due {
+			// A broken manifest must not take down token handling; the
+			// previous keys stay in use until the file is fixed
+			_ = r.reloadIfChanged()
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.set
+}
+
+// reloadIfChanged reloads the manifest when its modification time changed
+func (r *keyRing) reloadIfChanged() error {
+	r.mu.Lock()
+	r.checkedAt = time.Now()
+	r.mu.Unlock()
+
+	info, err := os.Stat(r.file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	var modTime time.Time
+	if info != nil {
+		modTime = info.ModTime()
+	}
+
+	r.mu.RLock()
+	changed := !modTime.Equal(r.modTime)
+	r.mu.RUnlock()
+
+	// Retired keys age out even when the manifest is untouched
+	if !changed && !r.hasRetiredKeys() {
+		return nil
+	}
+	return r.reload()
+}
+
+// hasRetiredKeys reports whether any key in the current set is on its way out
+func (r *keyRing) hasRetiredKeys() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.set.retiring > 0
+}
+
+// reload reads the manifest and rebuilds the key set
+func (r *keyRing) reload() error {
+	info, err := os.Stat(r.file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	manifest, err := ReadKeyRingManifest(r.file)
+	if err != nil {
+		return err
+	}
+
+	set, err := r.build(manifest, time.Now())
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.set = set
+	r.checkedAt = time.Now()
+	if info != nil {
+		r.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// build applies the manifest on top of the configured keys
+func (r *keyRing) build(manifest *KeyRingManifest, now time.Time) (*keySet, error) {
+	set := &keySet{
+		active: r.base.active,
+		keys:   make(map[string]*Key, len(r.base.keys)),
+	}
+	for id, key := range r.base.keys {
+		set.keys[id] = key
+	}
+
+	dir := filepath.Dir(r.file)
+	var ringActive *Key
+	for i := range manifest.Keys {
+		entry := &manifest.Keys[i]
+
+		key := set.keys[entry.ID]
+		if entry.File != "" {
+			loaded, err := loadEntryKey(entry, dir)
+			if err != nil {
+				return nil, err
+			}
+			key = loaded
+		}
+		if key == nil {
+			return nil, fmt.Errorf("key ring entry %s has no key file and is not configured", entry.ID)
+		}
+
+		switch entry.Status {
+		case KeyStatusActive:
+			if ringActive != nil {
+				return nil, fmt.Errorf("key ring has more than one active key: %s, %s", ringActive.ID, entry.ID)
+			}
+			if !key.CanSign() {
+				return nil, fmt.Errorf("active key %s has no private key", entry.ID)
+			}
+			ringActive = key
+		case KeyStatusVerify:
+		case KeyStatusRetired:
+			if entry.RetiredAt == nil {
+				return nil, fmt.Errorf("retired key %s has no retirement time", entry.ID)
+			}
+			if now.After(entry.RetiredAt.Add(r.retention)) {
+				// No token signed by this key can still be valid
+				delete(set.keys, entry.ID)
+				continue
+			}
+			set.retiring++
+		default:
+			return nil, fmt.Errorf("key ring entry %s has unknown status %q", entry.ID, entry.Status)
+		}
+
+		set.keys[entry.ID] = key
+	}
+
+	if ringActive != nil {
+		set.active = ringActive
+	}
+
+	if entry := manifest.find(set.active.ID); entry != nil && entry.Status == KeyStatusRetired {
+		return nil, fmt.Errorf("signing key %s is retired and no other key is active", set.active.ID)
+	}
+	if _, ok := set.keys[set.active.ID]; !ok {
+		return nil, fmt.Errorf("signing key %s is not in the key ring", set.active.ID)
+	}
+
+	return set, nil
+}
+
+// rotate generates a new active key. The previous active key becomes verify-only.
+func (r *keyRing) rotate(alg string) (*KeyRingEntry, error) {
+	if r.file == "" {
+		return nil, fmt.Errorf("no key ring file configured")
+	}
+
+	manifest, err := ReadKeyRingManifest(r.file)
+	if err != nil {
+		return nil, err
+	}
+
+	current := r.current().active
+	if alg == "" {
+		alg = current.Method.Alg()
+	}
+
+	key, data, err := GenerateKey(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.file), 0700); err != nil {
+		return nil, err
+	}
+
+	fileName := key.ID + ".key"
+	if err := os.WriteFile(filepath.Join(filepath.Dir(r.file), fileName), data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key file: %w", err)
+	}
+
+	// Demote the current signing key, adding an entry for it if it came from the configuration
+	if entry := manifest.find(current.ID); entry != nil {
+		entry.Status = KeyStatusVerify
+	} else {
+		manifest.Keys = append(manifest.Keys, KeyRingEntry{
+			ID:        current.ID,
+			Algorithm: current.Method.Alg(),
+			Status:    KeyStatusVerify,
+			CreatedAt: time.Now().UTC(),
+		})
+	}
+
+	entry := KeyRingEntry{
+		ID:        key.ID,
+		Algorithm: key.Method.Alg(),
+		File:      fileName,
+		Status:    KeyStatusActive,
+		CreatedAt: time.Now().UTC(),
+	}
+	manifest.Keys = append(manifest.Keys, entry)
+
+	if err := r.commit(manifest); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// retire marks a verify-only key as retired. It keeps verifying tokens until
+// they have expired.
+func (r *keyRing) retire(id string) error {
+	if r.file == "" {
+		return fmt.Errorf("no key ring file configured")
+	}
+
+	manifest, err := ReadKeyRingManifest(r.file)
+	if err != nil {
+		return err
+	}
+
+	set := r.current()
+	if set.active.ID == id {
+		return fmt.Errorf("key %s is the active signing key, rotate first", id)
+	}
+
+	key, ok := set.keys[id]
+	if !ok {
+		return fmt.Errorf("unknown key id: %s", id)
+	}
+
+	now := time.Now().UTC()
+	if entry := manifest.find(id); entry != nil {
+		if entry.Status == KeyStatusRetired {
+			return nil
+		}
+		entry.Status = KeyStatusRetired
+		entry.RetiredAt = &now
+	} else {
+		manifest.Keys = append(manifest.Keys, KeyRingEntry{
+			ID:        id,
+			Algorithm: key.Method.Alg(),
+			Status:    KeyStatusRetired,
+			CreatedAt: now,
+			RetiredAt: &now,
+		})
+	}
+
+	return r.commit(manifest)
+}
+
+// commit validates and writes the manifest, then reloads the key set
+func (r *keyRing) commit(manifest *KeyRingManifest) error {
+	if _, err := r.build(manifest, time.Now()); err != nil {
+		return err
+	}
+	if err := manifest.Write(r.file); err != nil {
+		return err
+	}
+	return r.reload()
+}
+
+// entries describes every key currently accepted by the ring
+func (r *keyRing) entries() []KeyRingEntry {
+	set := r.current()
+
+	manifest, err := ReadKeyRingManifest(r.file)
+	if err != nil || r.file == "" {
+		manifest = &KeyRingManifest{}
+	}
+
+	var entries []KeyRingEntry
+	for id, key := range set.keys {
+		entry := KeyRingEntry{
+			ID:        id,
+			Algorithm: key.Method.Alg(),
+			Status:    KeyStatusVerify,
+		}
+		if existing := manifest.find(id); existing != nil {
+			entry = *existing
+		}
+		if id == set.active.ID {
+			entry.Status = KeyStatusActive
+		}
+		entries = append(entries, entry)
+	}
+
+	sortEntries(entries)
+	return entries
+}
+
+// sortEntries orders entries with the active key first, then by creation time
+func sortEntries(entries []KeyRingEntry) {
+	rank := map[KeyStatus]int{KeyStatusActive: 0, KeyStatusVerify: 1, KeyStatusRetired: 2}
+	for i := 1; i < len(entries); i++ {
+		for j := i; j > 0; j-- {
+			a, b := entries[j-1], entries[j]
+			if rank[a.Status] < rank[b.Status] || (a.Status == b.Status && !a.CreatedAt.After(b.CreatedAt)) {
+				break
+			}
+			entries[j-1], entries[j] = b, a
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSAKeyBits is the smallest RSA modulus accepted for signing or verification
+const minRSAKeyBits = 2048
+
+// Key is a signing or verification key identified by a key ID (kid)
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// JWK represents a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS represents a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewHMACKey creates an HS256 key from a shared secret. When id is empty it is
+// derived from the secret so that it is stable across restarts.
+func NewHMACKey(id string, secret []byte) *Key {
+	if id == "" {
+		sum := sha256.Sum256(secret)
+		id = "hs256-" + hex.EncodeToString(sum[:4])
+	}
+
+	return &Key{
+		ID:        id,
+		Method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}
+}
+
+// LoadKeyFile loads an RSA or Ed25519 key from a PEM file.
+// See ParseKeyPEM for the accepted formats.
+func LoadKeyFile(id, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file %s: %w", path, err)
+	}
+
+	key, err := ParseKeyPEM(id, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key file %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// ParseKeyPEM parses an RSA or Ed25519 key. Private keys (PKCS#1 or PKCS#8)
+// can sign and verify; public keys (PKIX or PKCS#1) are verify-only. When id is
+// empty the RFC 7638 thumbprint of the public key is used.
+func ParseKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type: %s", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: id}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.signKey, key.verifyKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.verifyKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.signKey, key.verifyKey = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.verifyKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, expected RSA or Ed25519", parsed)
+	}
+
+	if pub, ok := key.verifyKey.(*rsa.PublicKey); ok && pub.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("RSA key must be at least %d bits", minRSAKeyBits)
+	}
+
+	if key.ID == "" {
+		key.ID, err = key.Thumbprint()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return key, nil
+}
+
+// CanSign reports whether the key holds private key material
+func (k *Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+// IsSymmetric reports whether the key is a shared secret
+func (k *Key) IsSymmetric() bool {
+	_, ok := k.verifyKey.([]byte)
+	return ok
+}
+
+// PublicJWK returns the public part of an asymmetric key as a JWK
+func (k *Key) PublicJWK() (*JWK, bool) {
+	jwk := &JWK{
+		Use: "sig",
+		Kid: k.ID,
+		Alg: k.Method.Alg(),
+	}
+
+	switch pub := k.verifyKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return nil, false
+	}
+
+	return jwk, true
+}
+
+// Thumbprint returns the RFC 7638 JWK thumbprint of an asymmetric key
+func (k *Key) Thumbprint() (string, error) {
+	jwk, ok := k.PublicJWK()
+	if !ok {
+		return "", fmt.Errorf("thumbprints are only defined for asymmetric keys")
+	}
+
+	// Members must be in lexicographic order with no whitespace
+	var canonical string
+	switch jwk.Kty {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, jwk.E, jwk.N)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, jwk.Crv, jwk.X)
+	}
+
+	sum := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// keySet holds the key used for signing and all keys accepted for verification
+type keySet struct {
+	active *Key
+	keys   map[string]*Key
+	// retiring counts retired keys that are still accepted for verification
+	retiring int
+}
+
+// newKeySet creates a key set signing with active and also accepting the given verify keys
+func newKeySet(active *Key, verify ...*Key) (*keySet, error) {
+	if !active.CanSign() {
+		return nil, fmt.Errorf("signing key %s has no private key", active.ID)
+	}
+
+	set := &keySet{
+		active: active,
+		keys:   map[string]*Key{active.ID: active},
+	}
+
+	for _, key := range verify {
+		if _, exists := set.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate key id: %s", key.ID)
+		}
+		set.keys[key.ID] = key
+	}
+
+	return set, nil
+}
+
+// sign signs the claims with the active key and stamps its kid header
+func (s *keySet) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.active.Method, claims)
+	token.Header["kid"] = s.active.ID
+	return token.SignedString(s.active.signKey)
+}
+
+// keyFunc selects the verification key by the token's kid header. Tokens
+// without a kid were issued before key IDs existed and are checked against
+// the active key.
+func (s *keySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	key := s.active
+	if kid, ok := token.Header["kid"].(string); ok {
+		if key, ok = s.keys[kid]; !ok {
+			return nil, fmt.Errorf("unknown key id: %s", kid)
+		}
+	}
+
+	// Reject algorithm substitution, e.g. HS256 signed with an RSA public key
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.verifyKey, nil
+}
+
+// jwks returns the public keys of all asymmetric keys in the set
+func (s *keySet) jwks() *JWKS {
+	set := &JWKS{Keys: []JWK{}}
+	for _, key := range s.keys {
+		if jwk, ok := key.PublicJWK(); ok {
+			set.Keys = append(set.Keys, *jwk)
+		}
+	}
+
+	// Keep the output stable for caching clients
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].Kid < set.Keys[j].Kid
+	})
+	return set
+}
+
+// parseVerifyKeyFiles loads a comma-separated list of key files, each optionally
+// prefixed with its key ID as kid=path
+func parseVerifyKeyFiles(list string) ([]*Key, error) {
+	var keys []*Key
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, path := "", entry
+		if i := strings.Index(entry, "="); i >= 0 {
+			id, path = entry[:i], entry[i+1:]
+		}
+
+		key, err := LoadKeyFile(id, path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
@@ -0,0 +1,111 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// Message represents an outgoing email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers outgoing email
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// NewMailer creates a mailer based on the configured driver
+func NewMailer(cfg *config.MailerConfig, log *logger.Logger) (Mailer, error) {
+	switch strings.ToLower(cfg.Driver) {
+	case "", "log":
+		return NewLogMailer(cfg.From, log), nil
+	case "file":
+		return NewFileMailer(cfg.From, cfg.OutputDir)
+	default:
+		return nil, fmt.Errorf("unsupported mailer driver: %s", cfg.Driver)
+	}
+}
+
+// LogMailer writes outgoing email to the application log. It is meant for
+// development: messages carry links with tokens, so their bodies are only
+// logged at debug level.
+type LogMailer struct {
+	from   string
+	logger *logger.Logger
+}
+
+// NewLogMailer creates a new log mailer
+func NewLogMailer(from string, log *logger.Logger) *LogMailer {
+	return &LogMailer{
+		from:   from,
+		logger: log,
+	}
+}
+
+// Send logs the message instead of delivering it
+func (m *LogMailer) Send(msg *Message) error {
+	m.logger.Info("Outgoing email",
+		zap.String("from", m.from),
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+	)
+	m.logger.Debug("Outgoing email body",
+		zap.String("to", msg.To),
+		zap.String("body", msg.Body),
+	)
+	return nil
+}
+
+// FileMailer writes each outgoing email to a file in a directory
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+// NewFileMailer creates a new file mailer
+func NewFileMailer(from, dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create mail output directory: %w", err)
+	}
+
+	return &FileMailer{
+		from: from,
+		dir:  dir,
+	}, nil
+}
+
+// Send writes the message to an .eml file in the output directory
+func (m *FileMailer) Send(msg *Message) error {
+	now := time.Now().UTC()
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405.000000000"), sanitizeFileName(msg.To))
+
+	content := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\n\r\n%s\r\n",
+		m.from, msg.To, msg.Subject, now.Format(time.RFC1123Z), msg.Body)
+
+	if err := os.WriteFile(filepath.Join(m.dir, name), []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	return nil
+}
+
+// sanitizeFileName replaces characters that are unsafe in file names
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_', r == '@':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
@@ -0,0 +1,95 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// tokenResponse is the token endpoint response (RFC 6749 section 5.1)
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchangeCode redeems an authorization code at a token endpoint
+func exchangeCode(ctx context.Context, client *http.Client, tokenURL, clientID, clientSecret string, req *AuthRequest, code string) (*tokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {req.RedirectURI},
+		"client_id":     {clientID},
+		"code_verifier": {req.CodeVerifier},
+	}
+	if clientSecret != "" {
+		form.Set("client_secret", clientSecret)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+
+	var token tokenResponse
+	status, err := doJSON(client, httpReq, &token)
+	if err != nil {
+		return nil, err
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("token exchange failed: %s %s", token.Error, token.ErrorDescription)
+	}
+	if status != http.StatusOK || token.AccessToken == "" {
+		return nil, fmt.Errorf("token exchange failed with status %d", status)
+	}
+
+	return &token, nil
+}
+
+// getJSON fetches a JSON document, optionally authorized with an access token
+func getJSON(ctx context.Context, client *http.Client, endpoint, accessToken string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	status, err := doJSON(client, req, v)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("GET %s failed with status %d", endpoint, status)
+	}
+	return nil
+}
+
+// doJSON sends a request and decodes a JSON response body of at most 1 MiB
+func doJSON(client *http.Client, req *http.Request, v interface{}) (int, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return resp.StatusCode, fmt.Errorf("invalid response from %s: %w", req.URL.Host, err)
+	}
+	return resp.StatusCode, nil
+}
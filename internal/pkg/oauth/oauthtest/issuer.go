@@ -0,0 +1,290 @@
+// Package oauthtest provides an in-process fake OpenID Connect issuer, so that
+// social login can be exercised without network access or real accounts.
+package oauthtest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/oauth"
+)
+
+// User is the account the fake issuer signs in
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Username      string
+}
+
+// grant is an issued, not yet redeemed authorization code
+type grant struct {
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	user          User
+	expiresAt     time.Time
+}
+
+// Issuer is a fake OpenID Provider supporting the authorization code flow with
+// PKCE. Its authorization endpoint signs in the configured user without asking.
+type Issuer struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	mu           sync.Mutex
+	user         User
+	codes        map[string]*grant
+	accessTokens map[string]User
+	tamper       func(claims jwt.MapClaims)
+}
+
+// NewIssuer starts a fake issuer on a local port
+func NewIssuer() (*Issuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := &Issuer{
+		ClientID:     "test-client",
+		ClientSecret: "test-secret",
+		key:          key,
+		kid:          rand.Text(),
+		user: User{
+			Subject:       "fake-user",
+			Email:         "fake.user@example.com",
+			EmailVerified: true,
+			Name:          "Fake User",
+			Username:      "fakeuser",
+		},
+		codes:        make(map[string]*grant),
+		accessTokens: make(map[string]User),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", issuer.handleDiscovery)
+	mux.HandleFunc("GET /authorize", issuer.handleAuthorize)
+	mux.HandleFunc("POST /token", issuer.handleToken)
+	mux.HandleFunc("GET /userinfo", issuer.handleUserinfo)
+	mux.HandleFunc("GET /jwks", issuer.handleJWKS)
+
+	issuer.server = httptest.NewServer(mux)
+	issuer.URL = issuer.server.URL
+	return issuer, nil
+}
+
+// Close shuts the issuer down
+func (i *Issuer) Close() {
+	i.server.Close()
+}
+
+// SetUser changes the account signed in by subsequent authorization requests
+func (i *Issuer) SetUser(user User) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.user = user
+}
+
+// TamperIDTokens passes the claims of ID tokens issued afterwards to tamper
+// before they are signed, so that clients can be tested against bad tokens
+func (i *Issuer) TamperIDTokens(tamper func(claims jwt.MapClaims)) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.tamper = tamper
+}
+
+// ProviderConfig returns the configuration of an OIDC provider using this issuer
+func (i *Issuer) ProviderConfig(name string) config.OAuthProviderConfig {
+	return config.OAuthProviderConfig{
+		Name:         name,
+		Type:         "oidc",
+		Issuer:       i.URL,
+		ClientID:     i.ClientID,
+		ClientSecret: i.ClientSecret,
+	}
+}
+
+func (i *Issuer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                i.URL,
+		"authorization_endpoint":                i.URL + "/authorize",
+		"token_endpoint":                        i.URL + "/token",
+		"userinfo_endpoint":                     i.URL + "/userinfo",
+		"jwks_uri":                              i.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (i *Issuer) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != i.ClientID || query.Get("response_type") != "code" {
+		http.Error(w, "invalid client or response type", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "PKCE with S256 is required", http.StatusBadRequest)
+		return
+	}
+
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || !redirectURI.IsAbs() {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := rand.Text()
+	i.mu.Lock()
+	i.codes[code] = &grant{
+		redirectURI:   query.Get("redirect_uri"),
+		codeChallenge: query.Get("code_challenge"),
+		nonce:         query.Get("nonce"),
+		user:          i.user,
+		expiresAt:     time.Now().Add(time.Minute),
+	}
+	i.mu.Unlock()
+
+	params := redirectURI.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirectURI.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (i *Issuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if clientID != i.ClientID || subtle.ConstantTimeCompare([]byte(clientSecret), []byte(i.ClientSecret)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	// Codes are single use
+	i.mu.Lock()
+	code := i.codes[r.PostFormValue("code")]
+	delete(i.codes, r.PostFormValue("code"))
+	i.mu.Unlock()
+
+	if code == nil || time.Now().After(code.expiresAt) ||
+		code.redirectURI != r.PostFormValue("redirect_uri") ||
+		oauth.CodeChallenge(r.PostFormValue("code_verifier")) != code.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                i.URL,
+		"aud":                i.ClientID,
+		"sub":                code.user.Subject,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"nonce":              code.nonce,
+		"email":              code.user.Email,
+		"email_verified":     code.user.EmailVerified,
+		"name":               code.user.Name,
+		"preferred_username": code.user.Username,
+	}
+	i.mu.Lock()
+	tamper := i.tamper
+	i.mu.Unlock()
+	if tamper != nil {
+		tamper(claims)
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = i.kid
+
+	signed, err := idToken.SignedString(i.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	accessToken := rand.Text()
+	i.mu.Lock()
+	i.accessTokens[accessToken] = code.user
+	i.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     signed,
+	})
+}
+
+func (i *Issuer) handleUserinfo(w http.ResponseWriter, r *http.Request) {
+	i.mu.Lock()
+	user, ok := i.accessTokens[bearerToken(r)]
+	i.mu.Unlock()
+
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"sub":                user.Subject,
+		"email":              user.Email,
+		"email_verified":     user.EmailVerified,
+		"name":               user.Name,
+		"preferred_username": user.Username,
+	})
+}
+
+func (i *Issuer) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": i.kid,
+			"n":   base64.RawURLEncoding.EncodeToString(i.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(i.key.E)).Bytes()),
+		}},
+	})
+}
+
+// bearerToken extracts the access token from the Authorization header
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) > len(prefix) && header[:len(prefix)] == prefix {
+		return header[len(prefix):]
+	}
+	return ""
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,291 @@
+package oauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+)
+
+// jwksRefreshInterval limits how often unknown key IDs trigger a JWKS download
+const jwksRefreshInterval = time.Minute
+
+// discoveryDocument holds the fields used from OpenID Provider Metadata
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// jsonWebKey is a public key published by an OpenID Provider
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// idTokenClaims holds the ID token and userinfo claims mapped to an Identity
+type idTokenClaims struct {
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	jwt.RegisteredClaims
+}
+
+// OIDCProvider signs users in with any OpenID Connect provider. Endpoints are
+// read from the issuer's discovery document and ID tokens are verified against
+// its published keys.
+type OIDCProvider struct {
+	cfg    config.OAuthProviderConfig
+	client *http.Client
+
+	mu            sync.Mutex
+	discovery     *discoveryDocument
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+// NewOIDCProvider creates an OpenID Connect provider. The discovery document is
+// fetched on first use, so an unreachable issuer does not prevent startup.
+func NewOIDCProvider(cfg config.OAuthProviderConfig, client *http.Client) (*OIDCProvider, error) {
+	if cfg.Issuer == "" {
+		return nil, fmt.Errorf("oauth provider %s: issuer is required", cfg.Name)
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	return &OIDCProvider{
+		cfg:    cfg,
+		client: client,
+	}, nil
+}
+
+// Name returns the provider name
+func (p *OIDCProvider) Name() string {
+	return p.cfg.Name
+}
+
+// DisplayName returns the provider name shown to users
+func (p *OIDCProvider) DisplayName() string {
+	return p.cfg.DisplayName
+}
+
+// AuthCodeURL returns the authorization endpoint URL for a login
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, req *AuthRequest) (string, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return buildAuthURL(discovery.AuthorizationEndpoint, url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {req.RedirectURI},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {req.State},
+		"nonce":                 {req.Nonce},
+		"code_challenge":        {CodeChallenge(req.CodeVerifier)},
+		"code_challenge_method": {"S256"},
+	}), nil
+}
+
+// Exchange redeems an authorization code and verifies the returned ID token
+func (p *OIDCProvider) Exchange(ctx context.Context, req *AuthRequest, code string) (*Identity, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := exchangeCode(ctx, p.client, discovery.TokenEndpoint, p.cfg.ClientID, p.cfg.ClientSecret, req, code)
+	if err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+
+	claims := &idTokenClaims{}
+	_, err = jwt.ParseWithClaims(token.IDToken, claims,
+		func(t *jwt.Token) (interface{}, error) {
+			kid, _ := t.Header["kid"].(string)
+			return p.key(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "EdDSA"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+	if claims.Nonce != req.Nonce {
+		return nil, fmt.Errorf("invalid id_token: nonce mismatch")
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("invalid id_token: missing subject")
+	}
+
+	// Some providers only return profile claims from the userinfo endpoint
+	if claims.Email == "" && discovery.UserinfoEndpoint != "" {
+		userinfo := &idTokenClaims{}
+		if err := getJSON(ctx, p.client, discovery.UserinfoEndpoint, token.AccessToken, userinfo); err != nil {
+			return nil, err
+		}
+		if userinfo.Subject != claims.Subject {
+			return nil, fmt.Errorf("userinfo subject does not match id_token")
+		}
+		claims.Email, claims.EmailVerified = userinfo.Email, userinfo.EmailVerified
+		if claims.Name == "" {
+			claims.Name = userinfo.Name
+		}
+		if claims.PreferredUsername == "" {
+			claims.PreferredUsername = userinfo.PreferredUsername
+		}
+	}
+
+	return &Identity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		Username:      claims.PreferredUsername,
+	}, nil
+}
+
+// discover fetches and caches the issuer's discovery document
+func (p *OIDCProvider) discover(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery discoveryDocument
+	endpoint := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, p.client, endpoint, "", &discovery); err != nil {
+		return nil, fmt.Errorf("oidc discovery for %s failed: %w", p.cfg.Name, err)
+	}
+	if discovery.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery for %s: issuer %q does not match %q", p.cfg.Name, discovery.Issuer, p.cfg.Issuer)
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// key returns the issuer's public key with the given ID, downloading the key
+// set again when the ID is unknown, e.g. after the issuer rotated its keys
+func (p *OIDCProvider) key(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, p.client, p.discovery.JWKSURI, "", &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		// Keys of unsupported types are skipped rather than failing the whole set
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// publicKey decodes an RSA, EC or Ed25519 public key
+func (k *jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// buildAuthURL appends query parameters to an authorization endpoint
+func buildAuthURL(endpoint string, params url.Values) string {
+	separator := "?"
+	if strings.Contains(endpoint, "?") {
+		separator = "&"
+	}
+	return endpoint + separator + params.Encode()
+}
@@ -0,0 +1,30 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// NewAuthRequest generates fresh state, nonce and PKCE verifier values
+func NewAuthRequest(redirectURI string) *AuthRequest {
+	return &AuthRequest{
+		RedirectURI:  redirectURI,
+		State:        randomString(),
+		Nonce:        randomString(),
+		CodeVerifier: randomString(),
+	}
+}
+
+// CodeChallenge returns the S256 PKCE challenge for a verifier (RFC 7636)
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// randomString returns 256 bits of randomness, URL-safe encoded
+func randomString() string {
+	bytes := make([]byte, 32)
+	rand.Read(bytes)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
@@ -0,0 +1,138 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+)
+
+// ErrProviderNotFound is returned for names that are not configured
+var ErrProviderNotFound = errors.New("oauth provider not found")
+
+// Identity is the account a user proved to own at a provider
+type Identity struct {
+	// Subject is the provider's stable identifier of the account
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Username      string
+}
+
+// AuthRequest carries the per-login values binding the authorization request
+// to its callback
+type AuthRequest struct {
+	RedirectURI string
+	State       string
+	// Nonce is bound into the ID token by OIDC providers
+	Nonce string
+	// CodeVerifier is the PKCE secret whose challenge is sent with the request
+	CodeVerifier string
+}
+
+// Provider is a social login provider using the authorization code flow
+type Provider interface {
+	Name() string
+	DisplayName() string
+	// AuthCodeURL returns the URL the user is sent to for signing in
+	AuthCodeURL(ctx context.Context, req *AuthRequest) (string, error)
+	// Exchange redeems an authorization code and returns the verified identity
+	Exchange(ctx context.Context, req *AuthRequest, code string) (*Identity, error)
+}
+
+// Registry holds the configured providers
+type Registry struct {
+	providers map[string]Provider
+	order     []string
+}
+
+// NewRegistry creates providers from configuration
+func NewRegistry(cfg *config.OAuthConfig) (*Registry, error) {
+	providerConfigs, err := cfg.ProviderConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	registry := &Registry{providers: make(map[string]Provider)}
+
+	for _, providerConfig := range providerConfigs {
+		provider, err := newProvider(providerConfig, client)
+		if err != nil {
+			return nil, err
+		}
+		if err := registry.Register(provider); err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
+
+// Register adds a provider to the registry
+func (r *Registry) Register(provider Provider) error {
+	if _, exists := r.providers[provider.Name()]; exists {
+		return fmt.Errorf("duplicate oauth provider: %s", provider.Name())
+	}
+	r.providers[provider.Name()] = provider
+	r.order = append(r.order, provider.Name())
+	return nil
+}
+
+// Get returns a provider by name
+func (r *Registry) Get(name string) (Provider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+	return provider, nil
+}
+
+// List returns the providers in configuration order
+func (r *Registry) List() []Provider {
+	providers := make([]Provider, len(r.order))
+	for i, name := range r.order {
+		providers[i] = r.providers[name]
+	}
+	return providers
+}
+
+// newProvider creates a provider of the configured type
+func newProvider(cfg config.OAuthProviderConfig, client *http.Client) (Provider, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("oauth provider name is required")
+	}
+	if cfg.ClientID == "" {
+		return nil, fmt.Errorf("oauth provider %s: client_id is required", cfg.Name)
+	}
+	if cfg.DisplayName == "" {
+		cfg.DisplayName = strings.ToUpper(cfg.Name[:1]) + cfg.Name[1:]
+	}
+
+	providerType := cfg.Type
+	if providerType == "" {
+		switch cfg.Name {
+		case "github":
+			providerType = "github"
+		case "google":
+			providerType = "oidc"
+		}
+	}
+	if cfg.Name == "google" && cfg.Issuer == "" {
+		cfg.Issuer = "https://accounts.google.com"
+	}
+
+	switch providerType {
+	case "oidc":
+		return NewOIDCProvider(cfg, client)
+	case "github":
+		return NewGitHubProvider(cfg, client), nil
+	default:
+		return nil, fmt.Errorf("oauth provider %s: unsupported type %q", cfg.Name, cfg.Type)
+	}
+}
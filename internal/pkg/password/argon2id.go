@@ -0,0 +1,155 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// argon2idPrefix starts every argon2id hash in PHC string format
+	argon2idPrefix = "$argon2id$"
+	// minArgon2idSaltLength is the shortest salt, in bytes, new hashes may use
+	minArgon2idSaltLength = 8
+	// minArgon2idKeyLength is the shortest key, in bytes, new hashes may use
+	minArgon2idKeyLength = 16
+)
+
+// ErrInvalidHash is returned for encoded hashes that cannot be parsed
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// Argon2idParams holds the argon2id cost parameters
+type Argon2idParams struct {
+	// Memory is the memory cost in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Validate reports parameters new hashes cannot be created with. argon2id
+// needs at least one iteration and lane and 8 KiB of memory per lane.
+func (p Argon2idParams) Validate() error {
+	if err := p.validateCost(); err != nil {
+		return err
+	}
+	if p.SaltLength < minArgon2idSaltLength {
+		return fmt.Errorf("argon2id salt length must be at least %d bytes", minArgon2idSaltLength)
+	}
+	if p.KeyLength < minArgon2idKeyLength {
+		return fmt.Errorf("argon2id key length must be at least %d bytes", minArgon2idKeyLength)
+	}
+	return nil
+}
+
+// validateCost reports cost parameters argon2id cannot hash with
+func (p Argon2idParams) validateCost() error {
+	switch {
+	case p.Iterations < 1:
+		return errors.New("argon2id iterations must be at least 1")
+	case p.Parallelism < 1:
+		return errors.New("argon2id parallelism must be at least 1")
+	case p.Memory < 8*uint32(p.Parallelism):
+		return fmt.Errorf("argon2id memory must be at least %d KiB for a parallelism of %d", 8*uint32(p.Parallelism), p.Parallelism)
+	}
+	return nil
+}
+
+// Argon2idHasher hashes passwords with argon2id. Hashes are encoded in PHC
+// string format, e.g. $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>.
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+// NewArgon2idHasher creates a new argon2id hasher
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	return &Argon2idHasher{params: params}
+}
+
+// Hash hashes a password with a random salt
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix, argon2.Version,
+		h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify reports whether a password matches an encoded hash, using the
+// parameters stored in the hash
+func (h *Argon2idHasher) Verify(encoded, password string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// Supports reports whether an encoded hash is an argon2id hash
+func (h *Argon2idHasher) Supports(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2idPrefix)
+}
+
+// NeedsRehash reports whether a hash was created with other parameters
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, _, _, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params != h.params
+}
+
+// decodeArgon2id parses an argon2id hash in PHC string format
+func decodeArgon2id(encoded string) (Argon2idParams, []byte, []byte, error) {
+	var params Argon2idParams
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("%w: unsupported argon2 version %d", ErrInvalidHash, version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	if err := params.validateCost(); err != nil {
+		return params, nil, nil, fmt.Errorf("%w: %s", ErrInvalidHash, err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
@@ -0,0 +1,97 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+)
+
+// Hasher hashes passwords into self-describing encoded hashes
+type Hasher interface {
+	// Hash returns the encoded hash of a password
+	Hash(password string) (string, error)
+	// Verify reports whether a password matches an encoded hash
+	Verify(encoded, password string) (bool, error)
+	// Supports reports whether an encoded hash was created by this algorithm
+	Supports(encoded string) bool
+	// NeedsRehash reports whether a supported hash was created with other parameters
+	NeedsRehash(encoded string) bool
+}
+
+// Manager hashes new passwords with the configured algorithm and verifies
+// hashes created by any supported algorithm, so stored hashes can be upgraded
+// as users sign in
+type Manager struct {
+	preferred Hasher
+	hashers   []Hasher
+}
+
+// NewManager creates a password manager based on the configured algorithm
+func NewManager(cfg *config.PasswordConfig) (*Manager, error) {
+	params := Argon2idParams{
+		Memory:      cfg.Argon2Memory,
+		Iterations:  cfg.Argon2Iterations,
+		Parallelism: cfg.Argon2Parallelism,
+		SaltLength:  cfg.Argon2SaltLength,
+		KeyLength:   cfg.Argon2KeyLength,
+	}
+	// Argon2 panics on invalid parameters, so refuse them at startup
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid password hashing configuration: %w", err)
+	}
+
+	argon2id := NewArgon2idHasher(params)
+	bcrypt := NewBcryptHasher(cfg.BcryptCost)
+
+	var preferred Hasher
+	switch strings.ToLower(cfg.Algorithm) {
+	case "", "argon2id":
+		preferred = argon2id
+	case "bcrypt":
+		preferred = bcrypt
+	default:
+		return nil, fmt.Errorf("unsupported password algorithm: %s", cfg.Algorithm)
+	}
+
+	return &Manager{
+		preferred: preferred,
+		hashers:   []Hasher{argon2id, bcrypt},
+	}, nil
+}
+
+// Hash hashes a password with the configured algorithm
+func (m *Manager) Hash(password string) (string, error) {
+	return m.preferred.Hash(password)
+}
+
+// Verify reports whether a password matches an encoded hash of any supported
+// algorithm. Hashes of unknown algorithms, such as the empty hash of users who
+// only sign in with a social login, never match.
+func (m *Manager) Verify(encoded, password string) (bool, error) {
+	for _, hasher := range m.hashers {
+		if hasher.Supports(encoded) {
+			return hasher.Verify(encoded, password)
+		}
+	}
+	return false, nil
+}
+
+// Supports reports whether an encoded hash was created by a supported algorithm
+func (m *Manager) Supports(encoded string) bool {
+	for _, hasher := range m.hashers {
+		if hasher.Supports(encoded) {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsRehash reports whether an encoded hash was created by another algorithm
+// or with other parameters than the configured ones
+func (m *Manager) NeedsRehash(encoded string) bool {
+	if !m.preferred.Supports(encoded) {
+		return true
+	}
+	return m.preferred.NeedsRehash(encoded)
+}
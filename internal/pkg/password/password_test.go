@@ -0,0 +1,82 @@
+package password_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/password"
+)
+
+// testConfig returns cheap but valid argon2id parameters
+func testConfig() config.PasswordConfig {
+	return config.PasswordConfig{
+		Algorithm:         "argon2id",
+		BcryptCost:        4,
+		Argon2Memory:      64,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 2,
+		Argon2SaltLength:  16,
+		Argon2KeyLength:   32,
+	}
+}
+
+func TestNewManagerRejectsInvalidArgon2Params(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *config.PasswordConfig)
+	}{
+		{"zero iterations", func(cfg *config.PasswordConfig) { cfg.Argon2Iterations = 0 }},
+		{"zero parallelism", func(cfg *config.PasswordConfig) { cfg.Argon2Parallelism = 0 }},
+		{"zero memory", func(cfg *config.PasswordConfig) { cfg.Argon2Memory = 0 }},
+		{"memory below 8 KiB per lane", func(cfg *config.PasswordConfig) { cfg.Argon2Memory = 15 }},
+		{"short salt", func(cfg *config.PasswordConfig) { cfg.Argon2SaltLength = 4 }},
+		{"short key", func(cfg *config.PasswordConfig) { cfg.Argon2KeyLength = 0 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			tt.modify(&cfg)
+			if _, err := password.NewManager(&cfg); err == nil {
+				t.Error("NewManager accepted invalid parameters")
+			}
+		})
+	}
+
+	// The parameters are needed to verify argon2id hashes, so they are
+	// checked even when new hashes use bcrypt
+	cfg := testConfig()
+	cfg.Algorithm = "bcrypt"
+	cfg.Argon2Iterations = 0
+	if _, err := password.NewManager(&cfg); err == nil {
+		t.Error("NewManager accepted invalid argon2id parameters with bcrypt")
+	}
+}
+
+func TestManagerRejectsHashWithInvalidArgon2Params(t *testing.T) {
+	cfg := testConfig()
+	manager, err := password.NewManager(&cfg)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	hash, err := manager.Hash("secret")
+	if err != nil {
+		t.Fatalf("Hash failed: %v", err)
+	}
+	if ok, err := manager.Verify(hash, "secret"); err != nil || !ok {
+		t.Fatalf("Verify = %v, %v; want true, nil", ok, err)
+	}
+
+	// Stored hashes with parameters argon2id cannot work with are refused
+	// rather than making it panic
+	for _, encoded := range []string{
+		"$argon2id$v=19$m=64,t=0,p=2$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U",
+		"$argon2id$v=19$m=64,t=1,p=0$c2FsdHNhbHRzYWx0c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U",
+	} {
+		if _, err := manager.Verify(encoded, "secret"); !errors.Is(err, password.ErrInvalidHash) {
+			t.Errorf("Verify(%s) returned %v, want %v", encoded, err, password.ErrInvalidHash)
+		}
+	}
+}
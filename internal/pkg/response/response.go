@@ -1,7 +1,10 @@
 package response
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
@@ -51,6 +54,20 @@ func Created(c echo.Context, data interface{}, message ...string) error {
 	return c.JSON(http.StatusCreated, resp)
 }
 
+// Accepted returns a response for a request that will be processed in the background
+func Accepted(c echo.Context, data interface{}, message ...string) error {
+	resp := Response{
+		Success: true,
+		Data:    data,
+	}
+
+	if len(message) > 0 {
+		resp.Message = message[0]
+	}
+
+	return c.JSON(http.StatusAccepted, resp)
+}
+
 // BadRequest returns a bad request error response
 func BadRequest(c echo.Context, message string, details ...interface{}) error {
 	resp := Response{
@@ -139,6 +156,84 @@ func Conflict(c echo.Context, message string, details ...interface{}) error {
 	return c.JSON(http.StatusConflict, resp)
 }
 
+// Gone returns a gone error response for a resource that is no longer available
+func Gone(c echo.Context, message ...string) error {
+	msg := "Resource no longer available"
+	if len(message) > 0 {
+		msg = message[0]
+	}
+
+	resp := Response{
+		Success: false,
+		Error: &ErrorInfo{
+			Code:    "GONE",
+			Message: msg,
+		},
+	}
+
+	return c.JSON(http.StatusGone, resp)
+}
+
+// RequestEntityTooLarge returns a payload too large error response
+func RequestEntityTooLarge(c echo.Context, message ...string) error {
+	msg := "Request entity too large"
+	if len(message) > 0 {
+		msg = message[0]
+	}
+
+	resp := Response{
+		Success: false,
+		Error: &ErrorInfo{
+			Code:    "REQUEST_ENTITY_TOO_LARGE",
+			Message: msg,
+		},
+	}
+
+	return c.JSON(http.StatusRequestEntityTooLarge, resp)
+}
+
+// UnsupportedMediaType returns an unsupported media type error response
+func UnsupportedMediaType(c echo.Context, message ...string) error {
+	msg := "Unsupported media type"
+	if len(message) > 0 {
+		msg = message[0]
+	}
+
+	resp := Response{
+		Success: false,
+		Error: &ErrorInfo{
+			Code:    "UNSUPPORTED_MEDIA_TYPE",
+			Message: msg,
+		},
+	}
+
+	return c.JSON(http.StatusUnsupportedMediaType, resp)
+}
+
+// TooManyRequests returns a too many requests error response. A positive
+// retryAfter is sent in the Retry-After header.
+func TooManyRequests(c echo.Context, retryAfter time.Duration, message ...string) error {
+	msg := "Too many requests"
+	if len(message) > 0 {
+		msg = message[0]
+	}
+
+	if retryAfter > 0 {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+
+	resp := Response{
+		Success: false,
+		Error: &ErrorInfo{
+			Code:    "TOO_MANY_REQUESTS",
+			Message: msg,
+		},
+	}
+
+	return c.JSON(http.StatusTooManyRequests, resp)
+}
+
 // InternalServerError returns an internal server error response
 func InternalServerError(c echo.Context, message ...string) error {
 	msg := "Internal server error"
@@ -155,4 +250,21 @@ func InternalServerError(c echo.Context, message ...string) error {
 	}
 
 	return c.JSON(http.StatusInternalServerError, resp)
-}
\ No newline at end of file
+}
+// BadGateway returns a bad gateway response for failures of upstream services
+func BadGateway(c echo.Context, message ...string) error {
+	msg := "Bad gateway"
+	if len(message) > 0 {
+		msg = message[0]
+	}
+
+	resp := Response{
+		Success: false,
+		Error: &ErrorInfo{
+			Code:    "BAD_GATEWAY",
+			Message: msg,
+		},
+	}
+
+	return c.JSON(http.StatusBadGateway, resp)
+}
@@ -0,0 +1,145 @@
+package storage_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/storage"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/storage/storagetest"
+)
+
+// newS3Storage creates an S3 storage using a bucket of the fake service
+func newS3Storage(t *testing.T, cfg config.StorageConfig) storage.Storage {
+	t.Helper()
+
+	store, err := storage.NewStorage(&cfg)
+	if err != nil {
+		t.Fatalf("NewStorage failed: %v", err)
+	}
+	return store
+}
+
+func TestS3Storage(t *testing.T) {
+	server := storagetest.NewS3Server()
+	defer server.Close()
+
+	store := newS3Storage(t, server.StorageConfig("files"))
+	ctx := context.Background()
+	key := "ab/abcdef.txt"
+	content := []byte("hello from the s3 storage")
+
+	if exists, err := store.Exists(ctx, key); err != nil || exists {
+		t.Fatalf("Exists before Put = %v, %v; want false, nil", exists, err)
+	}
+
+	if err := store.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	data, contentType, ok := server.Object("files", key)
+	if !ok || !bytes.Equal(data, content) || contentType != "text/plain" {
+		t.Fatalf("stored object = %q (%s), want %q (text/plain)", data, contentType, content)
+	}
+
+	if exists, err := store.Exists(ctx, key); err != nil || !exists {
+		t.Fatalf("Exists after Put = %v, %v; want true, nil", exists, err)
+	}
+
+	obj, err := store.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	got, err := io.ReadAll(obj.Body)
+	obj.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) || obj.Size != int64(len(content)) {
+		t.Errorf("Get = %q (%d bytes), want %q (%d bytes)", got, obj.Size, content, len(content))
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if server.Len() != 0 {
+		t.Errorf("%d objects left after Delete, want 0", server.Len())
+	}
+	if exists, err := store.Exists(ctx, key); err != nil || exists {
+		t.Errorf("Exists after Delete = %v, %v; want false, nil", exists, err)
+	}
+	if _, err := store.Get(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get after Delete returned %v, want %v", err, storage.ErrNotFound)
+	}
+
+	// Deleting a missing object is not an error
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing object failed: %v", err)
+	}
+}
+
+func TestS3StorageRejectsInvalidKeys(t *testing.T) {
+	server := storagetest.NewS3Server()
+	defer server.Close()
+
+	store := newS3Storage(t, server.StorageConfig("files"))
+	for _, key := range []string{"", "../secret", "a//b", "a/./b", "a b", "a?b"} {
+		if err := store.Put(context.Background(), key, bytes.NewReader(nil), 0, "text/plain"); err == nil {
+			t.Errorf("Put accepted invalid key %q", key)
+		}
+	}
+	if server.Len() != 0 {
+		t.Errorf("%d objects stored under invalid keys, want 0", server.Len())
+	}
+}
+
+func TestS3StorageSignatureRejected(t *testing.T) {
+	server := storagetest.NewS3Server()
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		modify func(cfg *config.StorageConfig)
+	}{
+		{"wrong secret key", func(cfg *config.StorageConfig) { cfg.S3SecretKey = "wrong-secret-key" }},
+		{"wrong access key", func(cfg *config.StorageConfig) { cfg.S3AccessKey = "wrong-access-key" }},
+		{"wrong region", func(cfg *config.StorageConfig) { cfg.S3Region = "eu-west-1" }},
+	}
+
+	ctx := context.Background()
+	key := "ab/abcdef.txt"
+	content := []byte("hello")
+
+	// An object stored with valid credentials, to read and delete with invalid ones
+	valid := newS3Storage(t, server.StorageConfig("files"))
+	if err := valid.Put(ctx, key, bytes.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := server.StorageConfig("files")
+			tt.modify(&cfg)
+			store := newS3Storage(t, cfg)
+
+			if err := store.Put(ctx, "ab/other.txt", bytes.NewReader(content), int64(len(content)), "text/plain"); err == nil {
+				t.Error("Put succeeded with an invalid signature")
+			}
+			if _, err := store.Get(ctx, key); err == nil || errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("Get returned %v, want a signature error", err)
+			}
+			if _, err := store.Exists(ctx, key); err == nil {
+				t.Error("Exists succeeded with an invalid signature")
+			}
+			if err := store.Delete(ctx, key); err == nil {
+				t.Error("Delete succeeded with an invalid signature")
+			}
+		})
+	}
+
+	if _, _, ok := server.Object("files", key); !ok || server.Len() != 1 {
+		t.Errorf("invalid signatures changed the bucket: %d objects", server.Len())
+	}
+}
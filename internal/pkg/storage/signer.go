@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidSignature is returned for signed URLs that were altered, not
+// issued by this service or expired
+var ErrInvalidSignature = errors.New("invalid or expired signature")
+
+// URLSigner issues URLs that grant access to a path until they expire, by
+// appending the expiry and an HMAC-SHA256 signature of the path and expiry
+type URLSigner struct {
+	baseURL  string
+	secret   []byte
+	duration time.Duration
+}
+
+// NewURLSigner creates a URL signer for paths below a base URL
+func NewURLSigner(baseURL, secret string, duration time.Duration) *URLSigner {
+	return &URLSigner{
+		baseURL:  baseURL,
+		secret:   []byte(secret),
+		duration: duration,
+	}
+}
+
+// Sign returns the signed URL of a path and when it expires
+func (s *URLSigner) Sign(path string) (string, time.Time) {
+	expiresAt := time.Now().Add(s.duration).Truncate(time.Second)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := url.Values{
+		"expires":   {expires},
+		"signature": {s.signature(path, expires)},
+	}
+	return s.baseURL + path + "?" + query.Encode(), expiresAt
+}
+
+// Verify checks the expiry and signature a signed URL of a path carries in its query
+func (s *URLSigner) Verify(path string, query url.Values) error {
+	expires := query.Get("expires")
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return ErrInvalidSignature
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(query.Get("signature"))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	expected, _ := base64.RawURLEncoding.DecodeString(s.signature(path, expires))
+	if !hmac.Equal(signature, expected) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// signature returns the encoded signature of a path and expiry
+func (s *URLSigner) signature(path, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(path + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
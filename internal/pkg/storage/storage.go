@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+)
+
+// ErrNotFound is returned for keys without a stored object
+var ErrNotFound = errors.New("object not found")
+
+// Object is a stored object being read
+type Object struct {
+	Body io.ReadCloser
+	Size int64
+}
+
+// Storage stores objects under slash separated keys
+type Storage interface {
+	// Put stores an object of the given size, replacing any object stored under the key
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get opens a stored object. The caller must close its body.
+	Get(ctx context.Context, key string) (*Object, error)
+	// Exists reports whether an object is stored under the key
+	Exists(ctx context.Context, key string) (bool, error)
+	// Delete removes an object. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// NewStorage creates a storage based on the configured driver
+func NewStorage(cfg *config.StorageConfig) (Storage, error) {
+	switch strings.ToLower(cfg.Driver) {
+	case "", "local":
+		return NewLocalStorage(cfg.LocalDir)
+	case "s3":
+		return NewS3Storage(&S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s", cfg.Driver)
+	}
+}
+
+// ContentKey returns the key of content with the given SHA-256 hash. Objects
+// are spread over directories by the first byte of their hash.
+func ContentKey(sum []byte) string {
+	h := hex.EncodeToString(sum)
+	return h[:2] + "/" + h
+}
+
+// validKey reports whether a key is a relative slash separated path of
+// letters, digits, dots, dashes and underscores, without empty or dot segments
+func validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+		for _, r := range segment {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
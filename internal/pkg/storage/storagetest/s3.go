@@ -0,0 +1,161 @@
+// Package storagetest provides an in-process fake S3 service, so that the S3
+// storage can be exercised without network access or real buckets.
+package storagetest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/storage"
+)
+
+// object is a stored object
+type object struct {
+	data        []byte
+	contentType string
+}
+
+// S3Server is a fake S3 service keeping objects in memory. It supports
+// putting, getting, checking and deleting objects with path-style addressing,
+// and rejects requests without a valid AWS Signature Version 4.
+type S3Server struct {
+	URL       string
+	Region    string
+	AccessKey string
+	SecretKey string
+
+	server *httptest.Server
+
+	mu      sync.Mutex
+	objects map[string]*object
+}
+
+// NewS3Server starts a fake S3 service on a local port
+func NewS3Server() *S3Server {
+	s := &S3Server{
+		Region:    "us-east-1",
+		AccessKey: "test-access-key",
+		SecretKey: "test-secret-key",
+		objects:   make(map[string]*object),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts the fake service down
+func (s *S3Server) Close() {
+	s.server.Close()
+}
+
+// StorageConfig returns the storage configuration using a bucket of the fake service
+func (s *S3Server) StorageConfig(bucket string) config.StorageConfig {
+	return config.StorageConfig{
+		Driver:      "s3",
+		S3Endpoint:  s.URL,
+		S3Region:    s.Region,
+		S3Bucket:    bucket,
+		S3AccessKey: s.AccessKey,
+		S3SecretKey: s.SecretKey,
+		S3PathStyle: true,
+	}
+}
+
+// Object returns the content and content type of a stored object
+func (s *S3Server) Object(bucket, key string) ([]byte, string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok := s.objects[bucket+"/"+key]
+	if !ok {
+		return nil, "", false
+	}
+	return obj.data, obj.contentType, true
+}
+
+// Len returns the number of stored objects
+func (s *S3Server) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.objects)
+}
+
+// handle serves an object request
+func (s *S3Server) handle(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		http.Error(w, "SignatureDoesNotMatch", http.StatusForbidden)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	if bucket, key, ok := strings.Cut(name, "/"); !ok || bucket == "" || key == "" {
+		http.Error(w, "InvalidRequest", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "IncompleteBody", http.StatusBadRequest)
+			return
+		}
+		s.objects[name] = &object{data: data, contentType: r.Header.Get("Content-Type")}
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		obj, ok := s.objects[name]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			io.Copy(w, bytes.NewReader(obj.data))
+		}
+	case http.MethodDelete:
+		delete(s.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// authorized verifies the AWS Signature Version 4 of a request
+func (s *S3Server) authorized(r *http.Request) bool {
+	fields := map[string]string{}
+	params, ok := strings.CutPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	if !ok {
+		return false
+	}
+	for _, field := range strings.Split(params, ",") {
+		if name, value, ok := strings.Cut(strings.TrimSpace(field), "="); ok {
+			fields[name] = value
+		}
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(amzDate) < 8 {
+		return false
+	}
+	scope := amzDate[:8] + "/" + s.Region + "/s3/aws4_request"
+	if fields["Credential"] != s.AccessKey+"/"+scope {
+		return false
+	}
+
+	signedHeaders, canonical := storage.CanonicalRequest(r, r.Header.Get("X-Amz-Content-Sha256"))
+	if fields["SignedHeaders"] != signedHeaders {
+		return false
+	}
+	return fields["Signature"] == storage.SignatureV4(s.SecretKey, amzDate, s.Region, "s3", canonical)
+}
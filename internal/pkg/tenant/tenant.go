@@ -0,0 +1,121 @@
+// Package tenant scopes database statements to an organization. Once an
+// organization is attached to the context of a statement, every statement on
+// a model with an org_id column is restricted to the rows of that
+// organization, and created rows are assigned to it.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Column is the column holding the organization of tenant-scoped rows. The
+// field mapped to it must be a uint.
+const Column = "org_id"
+
+// ErrCrossTenant is returned when creating a row for another organization
+// than the one the statement is scoped to
+var ErrCrossTenant = errors.New("row belongs to another organization")
+
+// contextKey is the context key of the organization ID
+type contextKey struct{}
+
+// WithOrgID returns a context scoping database statements to an organization
+func WithOrgID(ctx context.Context, orgID uint) context.Context {
+	return context.WithValue(ctx, contextKey{}, orgID)
+}
+
+// OrgID returns the organization a context is scoped to
+func OrgID(ctx context.Context) (uint, bool) {
+	orgID, ok := ctx.Value(contextKey{}).(uint)
+	return orgID, ok && orgID != 0
+}
+
+// Plugin registers the callbacks scoping statements to the organization of
+// their context. Raw SQL is never scoped.
+type Plugin struct{}
+
+// Name returns the name of the plugin
+func (Plugin) Name() string {
+	return "tenant"
+}
+
+// Initialize registers the callbacks of the plugin
+func (Plugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("tenant:assign", assign); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("tenant:query", scope); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("tenant:row", scope); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenant:update", scope); err != nil {
+		return err
+	}
+	return callbacks.Delete().Before("gorm:delete").Register("tenant:delete", scope)
+}
+
+// scope restricts a statement to the rows of the organization of its context
+func scope(db *gorm.DB) {
+	orgID, field := scoped(db)
+	if field == nil {
+		return
+	}
+
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: Column}, Value: orgID},
+	}})
+}
+
+// assign assigns created rows to the organization of the statement's context
+func assign(db *gorm.DB) {
+	orgID, field := scoped(db)
+	if field == nil {
+		return
+	}
+
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			assignRow(db, field, reflect.Indirect(rv.Index(i)), orgID)
+		}
+	case reflect.Struct:
+		assignRow(db, field, rv, orgID)
+	}
+}
+
+// assignRow sets the organization of a row, refusing rows of other organizations
+func assignRow(db *gorm.DB, field *schema.Field, row reflect.Value, orgID uint) {
+	ctx := db.Statement.Context
+	value, zero := field.ValueOf(ctx, row)
+	if zero {
+		db.AddError(field.Set(ctx, row, orgID))
+		return
+	}
+	if value != orgID {
+		db.AddError(ErrCrossTenant)
+	}
+}
+
+// scoped returns the organization a statement is scoped to and the field
+// holding the organization of its model, or a nil field when the statement is
+// not scoped
+func scoped(db *gorm.DB) (uint, *schema.Field) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return 0, nil
+	}
+	orgID, ok := OrgID(db.Statement.Context)
+	if !ok {
+		return 0, nil
+	}
+	return orgID, db.Statement.Schema.LookUpField(Column)
+}
@@ -0,0 +1,117 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Period is the time step in seconds (RFC 6238 default)
+	Period = 30
+	// Digits is the number of digits in a generated code
+	Digits = 6
+	// secretSize is the secret length in bytes (160 bits, as recommended by RFC 4226)
+	secretSize = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret generates a new random base32-encoded shared secret
+func GenerateSecret() (string, error) {
+	bytes := make([]byte, secretSize)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(bytes), nil
+}
+
+// KeyURI returns the otpauth:// URI used to provision authenticator apps
+func KeyURI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprintf("%d", Digits))
+	params.Set("period", fmt.Sprintf("%d", Period))
+
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// Code returns the code for the given secret at time t
+func Code(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return generate(key, timeStep(t)), nil
+}
+
+// Validate checks a code against the secret, accepting codes up to skew steps
+// before or after t. It returns the matching time step so callers can reject
+// replays of an already used code.
+func Validate(secret, code string, t time.Time, skew int) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+
+	current := timeStep(t)
+	for i := -skew; i <= skew; i++ {
+		step := current + int64(i)
+		if subtle.ConstantTimeCompare([]byte(generate(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// timeStep returns the RFC 6238 time step for t
+func timeStep(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// decodeSecret decodes a base32 secret, tolerating lowercase and padding
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.TrimRight(strings.TrimSpace(secret), "="))
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid totp secret: %w", err)
+	}
+	return key, nil
+}
+
+// generate computes the HOTP value (RFC 4226) for a counter
+func generate(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%mod)
+}
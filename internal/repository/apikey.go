@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+	"gorm.io/gorm"
+)
+
+// APIKeyRepository handles API key data operations
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// Create creates a new API key
+func (r *APIKeyRepository) Create(key *model.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+// GetByHash retrieves an API key by the hash of its secret
+func (r *APIKeyRepository) GetByHash(keyHash string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.Where("key_hash = ?", keyHash).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetByUserAndID retrieves an API key owned by a user
+func (r *APIKeyRepository) GetByUserAndID(userID, id uint) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.Where("user_id = ? AND id = ?", userID, id).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListByUser lists the API keys of a user, newest first
+func (r *APIKeyRepository) ListByUser(userID uint) ([]*model.APIKey, error) {
+	var keys []*model.APIKey
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// CountByUser returns the number of API keys of a user
+func (r *APIKeyRepository) CountByUser(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.APIKey{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// Update updates an API key
+func (r *APIKeyRepository) Update(key *model.APIKey) error {
+	return r.db.Save(key).Error
+}
+
+// Touch records the use of an API key
+func (r *APIKeyRepository) Touch(id uint, ipAddress string, usedAt time.Time) error {
+	return r.db.Model(&model.APIKey{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"last_used_at": usedAt,
+			"last_used_ip": ipAddress,
+		}).Error
+}
+
+// Delete permanently deletes an API key owned by a user
+func (r *APIKeyRepository) Delete(userID, id uint) error {
+	result := r.db.Unscoped().Where("user_id = ? AND id = ?", userID, id).Delete(&model.APIKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return types.ErrAPIKeyNotFound
+	}
+	return nil
+}
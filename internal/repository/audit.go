@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"gorm.io/gorm"
+)
+
+// AuditRepository handles audit event data operations
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Create stores an audit event
+func (r *AuditRepository) Create(event *model.AuditEvent) error {
+	return r.db.Create(event).Error
+}
+
+// ListByUser retrieves the most recent audit events for a user
+func (r *AuditRepository) ListByUser(userID uint, limit int) ([]*model.AuditEvent, error) {
+	var events []*model.AuditEvent
+	err := r.db.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
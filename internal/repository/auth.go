@@ -68,9 +68,86 @@ func (r *AuthRepository) RevokeAllUserRefreshTokens(userID uint) error {
 		Update("is_revoked", true).Error
 }
 
-// CleanupExpiredTokens removes expired refresh tokens
+// RevokeOtherUserRefreshTokens revokes all refresh tokens for a user except
+// those of the given family
+func (r *AuthRepository) RevokeOtherUserRefreshTokens(userID uint, keepFamilyID string) error {
+	return r.db.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND family_id <> ? AND is_revoked = ?", userID, keepFamilyID, false).
+		Update("is_revoked", true).Error
+}
+
+// GetActiveRefreshTokenByFamily retrieves the active refresh token of one of a
+// user's login sessions. It returns ErrSessionNotFound if there is none.
+func (r *AuthRepository) GetActiveRefreshTokenByFamily(userID uint, familyID string) (*model.RefreshToken, error) {
+	var refreshToken model.RefreshToken
+	err := r.db.Where("user_id = ? AND family_id = ? AND is_revoked = ? AND expires_at > ?",
+		userID, familyID, false, time.Now()).
+		Order("created_at DESC").
+		First(&refreshToken).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &refreshToken, nil
+}
+
+// RotateRefreshToken revokes a refresh token and stores its replacement. It fails with
+// ErrTokenRevoked if the old token was revoked concurrently.
+func (r *AuthRepository) RotateRefreshToken(oldToken string, newToken *model.RefreshToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.RefreshToken{}).
+			Where("token = ? AND is_revoked = ?", oldToken, false).
+			Update("is_revoked", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return types.ErrTokenRevoked
+		}
+
+		return tx.Create(newToken).Error
+	})
+}
+
+// RevokeRefreshTokenFamily revokes all refresh tokens in a family
+func (r *AuthRepository) RevokeRefreshTokenFamily(familyID string) error {
+	return r.db.Model(&model.RefreshToken{}).
+		Where("family_id = ? AND is_revoked = ?", familyID, false).
+		Update("is_revoked", true).Error
+}
+
+// RevokeUserRefreshTokenFamily revokes the refresh tokens of one of a user's
+// login sessions. It returns ErrSessionNotFound if the session has no active token.
+func (r *AuthRepository) RevokeUserRefreshTokenFamily(userID uint, familyID string) error {
+	result := r.db.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND family_id = ? AND is_revoked = ?", userID, familyID, false).
+		Update("is_revoked", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return types.ErrSessionNotFound
+	}
+	return nil
+}
+
+// CleanupExpiredTokens removes expired refresh and single-use tokens
 func (r *AuthRepository) CleanupExpiredTokens() error {
-	return r.db.Where("expires_at < ?", time.Now()).Delete(&model.RefreshToken{}).Error
+	now := time.Now()
+	for _, token := range []interface{}{
+		&model.RefreshToken{},
+		&model.PasswordResetToken{},
+		&model.MagicLinkToken{},
+		&model.EmailVerificationToken{},
+		&model.OAuthAuthorizationCode{},
+	} {
+		if err := r.db.Where("expires_at < ?", now).Delete(token).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // IsRefreshTokenValid checks if refresh token is valid (not revoked and not expired)
@@ -91,4 +168,136 @@ func (r *AuthRepository) IsRefreshTokenValid(token string) (bool, error) {
 // UpdateRefreshToken updates an existing refresh token
 func (r *AuthRepository) UpdateRefreshToken(token *model.RefreshToken) error {
 	return r.db.Save(token).Error
-}
\ No newline at end of file
+}
+
+// CreatePasswordResetToken creates a new password reset token
+func (r *AuthRepository) CreatePasswordResetToken(token *model.PasswordResetToken) error {
+	return r.db.Create(token).Error
+}
+
+// InvalidateUserPasswordResetTokens marks all unused password reset tokens for a user as used
+func (r *AuthRepository) InvalidateUserPasswordResetTokens(userID uint) error {
+	return r.db.Model(&model.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
+
+// ConsumePasswordResetToken atomically marks an unused, unexpired password reset token
+// as used and returns it
+func (r *AuthRepository) ConsumePasswordResetToken(tokenHash string) (*model.PasswordResetToken, error) {
+	var resetToken model.PasswordResetToken
+	if err := r.consumeToken(&resetToken, tokenHash); err != nil {
+		return nil, err
+	}
+	return &resetToken, nil
+}
+
+// CreateMagicLinkToken creates a new magic link token
+func (r *AuthRepository) CreateMagicLinkToken(token *model.MagicLinkToken) error {
+	return r.db.Create(token).Error
+}
+
+// InvalidateUserMagicLinkTokens marks all unused magic link tokens for a user as used
+func (r *AuthRepository) InvalidateUserMagicLinkTokens(userID uint) error {
+	return r.db.Model(&model.MagicLinkToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
+
+// ConsumeMagicLinkToken atomically marks an unused, unexpired magic link token
+// as used and returns it
+func (r *AuthRepository) ConsumeMagicLinkToken(tokenHash string) (*model.MagicLinkToken, error) {
+	var magicLinkToken model.MagicLinkToken
+	if err := r.consumeToken(&magicLinkToken, tokenHash); err != nil {
+		return nil, err
+	}
+	return &magicLinkToken, nil
+}
+
+// CreateEmailVerificationToken creates a new email verification token
+func (r *AuthRepository) CreateEmailVerificationToken(token *model.EmailVerificationToken) error {
+	return r.db.Create(token).Error
+}
+
+// InvalidateUserEmailVerificationTokens marks all unused email verification tokens for a user as used
+func (r *AuthRepository) InvalidateUserEmailVerificationTokens(userID uint) error {
+	return r.db.Model(&model.EmailVerificationToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
+
+// ConsumeEmailVerificationToken atomically marks an unused, unexpired email verification
+// token as used and returns it
+func (r *AuthRepository) ConsumeEmailVerificationToken(tokenHash string) (*model.EmailVerificationToken, error) {
+	var verificationToken model.EmailVerificationToken
+	if err := r.consumeToken(&verificationToken, tokenHash); err != nil {
+		return nil, err
+	}
+	return &verificationToken, nil
+}
+
+// CreateAuthorizationCode creates a new OAuth authorization code
+func (r *AuthRepository) CreateAuthorizationCode(code *model.OAuthAuthorizationCode) error {
+	return r.db.Create(code).Error
+}
+
+// GetAuthorizationCode retrieves an OAuth authorization code by its hash, whether or not it was used
+func (r *AuthRepository) GetAuthorizationCode(codeHash string) (*model.OAuthAuthorizationCode, error) {
+	var code model.OAuthAuthorizationCode
+	if err := r.db.Where("token_hash = ?", codeHash).First(&code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrInvalidToken
+		}
+		return nil, err
+	}
+	return &code, nil
+}
+
+// ConsumeAuthorizationCode atomically marks an unused, unexpired OAuth authorization
+// code as used and returns it
+func (r *AuthRepository) ConsumeAuthorizationCode(codeHash string) (*model.OAuthAuthorizationCode, error) {
+	var code model.OAuthAuthorizationCode
+	if err := r.consumeToken(&code, codeHash); err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// singleUseToken is implemented by models embedding model.SingleUseToken
+type singleUseToken interface {
+	SingleUse() *model.SingleUseToken
+}
+
+// consumeToken loads the single-use token with the given hash into dest and
+// atomically marks it as used
+func (r *AuthRepository) consumeToken(dest singleUseToken, tokenHash string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ?", tokenHash).First(dest).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return types.ErrInvalidToken
+			}
+			return err
+		}
+
+		token := dest.SingleUse()
+		if token.UsedAt != nil {
+			return types.ErrTokenRevoked
+		}
+		if token.ExpiresAt.Before(time.Now()) {
+			return types.ErrTokenExpired
+		}
+
+		// Guard against a concurrent consumer having used the token in the meantime
+		now := time.Now()
+		result := tx.Model(dest).Where("used_at IS NULL").Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return types.ErrTokenRevoked
+		}
+
+		token.UsedAt = &now
+		return nil
+	})
+}
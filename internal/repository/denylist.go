@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"gorm.io/gorm"
+)
+
+// denylistKeyPrefix namespaces denylist entries in the KV table
+const denylistKeyPrefix = "denylist:"
+
+// DenylistStore persists token denylist entries in the KV table, so that
+// revocations survive restarts and are shared between instances
+type DenylistStore struct {
+	kvRepo *KVRepository
+}
+
+// NewDenylistStore creates a new KV-backed denylist store
+func NewDenylistStore(kvRepo *KVRepository) *DenylistStore {
+	return &DenylistStore{kvRepo: kvRepo}
+}
+
+// Set stores a value that is kept until expiresAt
+func (s *DenylistStore) Set(key, value string, expiresAt time.Time) error {
+	return s.kvRepo.SetWithExpiry(denylistKeyPrefix+key, value, expiresAt)
+}
+
+// Get returns the value of an unexpired entry
+func (s *DenylistStore) Get(key string) (string, bool, error) {
+	var kv model.KV
+	if err := s.kvRepo.live().Where("key = ?", denylistKeyPrefix+key).First(&kv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return kv.Value, true, nil
+}
+
+// Purge removes expired entries
+func (s *DenylistStore) Purge() error {
+	return s.kvRepo.DeleteExpired()
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+	"gorm.io/gorm"
+)
+
+// FileRepository handles uploaded file data operations
+type FileRepository struct {
+	db *gorm.DB
+}
+
+// NewFileRepository creates a new file repository
+func NewFileRepository(db *gorm.DB) *FileRepository {
+	return &FileRepository{db: db}
+}
+
+// Create creates a file
+func (r *FileRepository) Create(file *model.File) error {
+	return r.db.Create(file).Error
+}
+
+// GetByID retrieves a file by ID
+func (r *FileRepository) GetByID(id uint) (*model.File, error) {
+	var file model.File
+	if err := r.db.First(&file, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrFileNotFound
+		}
+		return nil, err
+	}
+	return &file, nil
+}
+
+// GetByUser retrieves a file uploaded by a user
+func (r *FileRepository) GetByUser(userID, id uint) (*model.File, error) {
+	var file model.File
+	err := r.db.Where("user_id = ? AND purpose = ?", userID, model.FilePurposeUpload).First(&file, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrFileNotFound
+		}
+		return nil, err
+	}
+	return &file, nil
+}
+
+// ListByUser lists the files of a user, newest first
+func (r *FileRepository) ListByUser(userID uint) ([]*model.File, error) {
+	var files []*model.File
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Find(&files).Error
+	return files, err
+}
+
+// Delete permanently deletes a file
+func (r *FileRepository) Delete(id uint) error {
+	result := r.db.Unscoped().Delete(&model.File{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return types.ErrFileNotFound
+	}
+	return nil
+}
+
+// KeyInUse reports whether any file still refers to a stored object
+func (r *FileRepository) KeyInUse(key string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.File{}).Where("object_key = ? OR thumbnail_key = ?", key, key).Count(&count).Error
+	return count > 0, err
+}
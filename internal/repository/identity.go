@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+	"gorm.io/gorm"
+)
+
+// IdentityRepository handles linked social login identity data operations
+type IdentityRepository struct {
+	db *gorm.DB
+}
+
+// NewIdentityRepository creates a new identity repository
+func NewIdentityRepository(db *gorm.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// Create links a new identity
+func (r *IdentityRepository) Create(identity *model.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+// GetByProviderSubject retrieves the identity of a provider account
+func (r *IdentityRepository) GetByProviderSubject(provider, subject string) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrIdentityNotFound
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// ListByUser lists the identities linked to a user
+func (r *IdentityRepository) ListByUser(userID uint) ([]*model.UserIdentity, error) {
+	var identities []*model.UserIdentity
+	err := r.db.Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	return identities, err
+}
+
+// Touch records a login with an identity
+func (r *IdentityRepository) Touch(id uint, email string, at time.Time) error {
+	return r.db.Model(&model.UserIdentity{}).Where("id = ?", id).
+		UpdateColumns(map[string]interface{}{
+			"email":         email,
+			"last_login_at": at,
+		}).Error
+}
+
+// Delete permanently unlinks an identity of a user, so the provider account
+// can be linked again later
+func (r *IdentityRepository) Delete(userID, id uint) error {
+	result := r.db.Unscoped().Where("user_id = ? AND id = ?", userID, id).Delete(&model.UserIdentity{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return types.ErrIdentityNotFound
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+	"gorm.io/gorm"
+)
+
+// InvitationRepository handles registration invitation data operations
+type InvitationRepository struct {
+	db *gorm.DB
+}
+
+// NewInvitationRepository creates a new invitation repository
+func NewInvitationRepository(db *gorm.DB) *InvitationRepository {
+	return &InvitationRepository{db: db}
+}
+
+// Create creates a new invitation
+func (r *InvitationRepository) Create(invitation *model.Invitation) error {
+	return r.db.Create(invitation).Error
+}
+
+// GetByID retrieves an invitation by ID
+func (r *InvitationRepository) GetByID(id uint) (*model.Invitation, error) {
+	var invitation model.Invitation
+	if err := r.db.Preload("Role").First(&invitation, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// List lists all invitations, newest first
+func (r *InvitationRepository) List() ([]*model.Invitation, error) {
+	var invitations []*model.Invitation
+	err := r.db.Preload("Role").Order("created_at DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+// Delete revokes an invitation
+func (r *InvitationRepository) Delete(id uint) error {
+	result := r.db.Delete(&model.Invitation{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return types.ErrInvitationNotFound
+	}
+	return nil
+}
+
+// Consume atomically uses up one use of an unexpired invitation and returns it.
+// Invitations bound to an email address can only be used for that address.
+// It returns ErrInvalidInvitation if the code cannot be used.
+func (r *InvitationRepository) Consume(codeHash, email string) (*model.Invitation, error) {
+	var invitation model.Invitation
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Preload("Role").Where("code_hash = ?", codeHash).First(&invitation).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return types.ErrInvalidInvitation
+			}
+			return err
+		}
+
+		if invitation.Email != "" && !strings.EqualFold(invitation.Email, email) {
+			return types.ErrInvalidInvitation
+		}
+
+		// Guard against concurrent registrations using up the invitation in the meantime
+		result := tx.Model(&model.Invitation{}).
+			Where("id = ? AND use_count < max_uses AND (expires_at IS NULL OR expires_at > ?)", invitation.ID, time.Now()).
+			UpdateColumn("use_count", gorm.Expr("use_count + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return types.ErrInvalidInvitation
+		}
+
+		invitation.UseCount++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// Release gives back a use of an invitation whose registration failed
+func (r *InvitationRepository) Release(id uint) error {
+	return r.db.Model(&model.Invitation{}).
+		Where("id = ? AND use_count > 0", id).
+		UpdateColumn("use_count", gorm.Expr("use_count - 1")).Error
+}
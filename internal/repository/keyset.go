@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/pagination"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Keyset orders a listing by a column with the primary key breaking ties, so
+// that every row has a stable position a cursor can point at. Unlike offsets,
+// such positions neither slow down deep into a table nor shift when rows are
+// inserted or deleted in front of them.
+type Keyset[T any] struct {
+	// Sort is the name of the sort key carried by cursors
+	Sort   string
+	Column string
+	Desc   bool
+	// Value returns the Column value of a row
+	Value func(row *T) any
+	// ID returns the primary key of a row
+	ID func(row *T) uint
+}
+
+// KeysetPage is a page of a listing with the positions of the adjacent pages,
+// which are nil at either end of the listing
+type KeysetPage[T any] struct {
+	Items []*T
+	Next  *pagination.Cursor
+	Prev  *pagination.Cursor
+}
+
+// Paginate retrieves up to limit rows of a query following a cursor, or
+// preceding it for backward cursors, or from the start without a cursor. The
+// cursor must have been issued for the same keyset.
+func (k *Keyset[T]) Paginate(query *gorm.DB, cursor *pagination.Cursor, limit int) (*KeysetPage[T], error) {
+	desc := k.Desc
+	backward := false
+	if cursor != nil {
+		if cursor.Sort != k.Sort || cursor.Desc != k.Desc {
+			return nil, types.ErrInvalidCursor
+		}
+		backward = cursor.Backward
+		if backward {
+			desc = !desc
+		}
+
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		if k.Column == "id" {
+			query = query.Where("id "+op+" ?", cursor.ID)
+		} else {
+			value, err := k.decode(cursor.Value)
+			if err != nil {
+				return nil, err
+			}
+			query = query.Where(fmt.Sprintf("%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?)", k.Column, op), value, value, cursor.ID)
+		}
+	}
+
+	var rows []*T
+	if err := k.order(query, desc).Limit(limit + 1).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+	if backward {
+		slices.Reverse(rows)
+		return k.page(rows, more, true), nil
+	}
+	return k.page(rows, cursor != nil, more), nil
+}
+
+// PaginateOffset retrieves up to limit rows of a query starting at an offset.
+// The page carries cursors so that clients can continue with keyset pagination.
+func (k *Keyset[T]) PaginateOffset(query *gorm.DB, offset, limit int) (*KeysetPage[T], error) {
+	var rows []*T
+	if err := k.order(query, k.Desc).Offset(offset).Limit(limit + 1).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	more := len(rows) > limit
+	if more {
+		rows = rows[:limit]
+	}
+	return k.page(rows, offset > 0, more), nil
+}
+
+// order sorts a query by the keyset
+func (k *Keyset[T]) order(query *gorm.DB, desc bool) *gorm.DB {
+	query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: k.Column}, Desc: desc})
+	if k.Column != "id" {
+		query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: desc})
+	}
+	return query
+}
+
+// page builds a page of rows in listing order, with cursors towards the
+// previous and next rows if there are any
+func (k *Keyset[T]) page(rows []*T, hasPrev, hasNext bool) *KeysetPage[T] {
+	page := &KeysetPage[T]{Items: rows}
+	if len(rows) == 0 {
+		return page
+	}
+	if hasPrev {
+		page.Prev = k.cursor(rows[0], true)
+	}
+	if hasNext {
+		page.Next = k.cursor(rows[len(rows)-1], false)
+	}
+	return page
+}
+
+// cursor returns the cursor pointing after a row, or before it when backward
+func (k *Keyset[T]) cursor(row *T, backward bool) *pagination.Cursor {
+	cursor := &pagination.Cursor{
+		Sort:     k.Sort,
+		Desc:     k.Desc,
+		ID:       k.ID(row),
+		Backward: backward,
+	}
+	if k.Column != "id" {
+		// Sort key values are strings, numbers and times, which always encode
+		cursor.Value, _ = json.Marshal(k.Value(row))
+	}
+	return cursor
+}
+
+// decode parses the sort key value of a cursor into the type of the column
+func (k *Keyset[T]) decode(data json.RawMessage) (any, error) {
+	value := reflect.New(reflect.TypeOf(k.Value(new(T))))
+	if err := json.Unmarshal(data, value.Interface()); err != nil {
+		return nil, types.ErrInvalidCursor
+	}
+	return value.Elem().Interface(), nil
+}
@@ -2,9 +2,12 @@ package repository
 
 import (
 	"errors"
+	"strconv"
+	"time"
 
 	"github.com/ray-d-song/go-echo-monolithic/internal/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // KVRepository handles key-value data operations
@@ -19,19 +22,45 @@ func NewKVRepository(db *gorm.DB) *KVRepository {
 
 // Set stores a key-value pair
 func (r *KVRepository) Set(key, value string) error {
-	kv := &model.KV{
+	return r.upsert(&model.KV{
 		Key:   key,
 		Value: value,
-	}
-	
-	// Use GORM's Upsert functionality
-	return r.db.Save(kv).Error
+	})
+}
+
+// SetWithExpiry stores a key-value pair that expires at the given time
+func (r *KVRepository) SetWithExpiry(key, value string, expiresAt time.Time) error {
+	return r.upsert(&model.KV{
+		Key:       key,
+		Value:     value,
+		ExpiresAt: &expiresAt,
+	})
+}
+
+// SetForUser stores a key-value pair holding data of a user, which expires at
+// the given time
+func (r *KVRepository) SetForUser(userID uint, key, value string, expiresAt time.Time) error {
+	return r.upsert(&model.KV{
+		Key:       key,
+		Value:     value,
+		ExpiresAt: &expiresAt,
+		UserID:    &userID,
+	})
+}
+
+// upsert inserts a key-value pair or overwrites the existing one, reviving it if
+// it was deleted
+func (r *KVRepository) upsert(kv *model.KV) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "expires_at", "user_id", "updated_at", "deleted_at"}),
+	}).Create(kv).Error
 }
 
 // Get retrieves a value by key
 func (r *KVRepository) Get(key string) (string, error) {
 	var kv model.KV
-	if err := r.db.Where("key = ?", key).First(&kv).Error; err != nil {
+	if err := r.live().Where("key = ?", key).First(&kv).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return "", nil // Return empty string for non-existent keys
 		}
@@ -40,6 +69,48 @@ func (r *KVRepository) Get(key string) (string, error) {
 	return kv.Value, nil
 }
 
+// GetBool retrieves a boolean value by key. Non-existent keys read as false.
+func (r *KVRepository) GetBool(key string) (bool, error) {
+	value, err := r.Get(key)
+	if err != nil || value == "" {
+		return false, err
+	}
+	return strconv.ParseBool(value)
+}
+
+// Take retrieves a value and deletes it, so that it can be read only once.
+// It returns an empty string if the key does not exist or was taken concurrently.
+func (r *KVRepository) Take(key string) (string, error) {
+	var kv model.KV
+	if err := r.live().Where("key = ?", key).First(&kv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	result := r.db.Unscoped().Where("id = ?", kv.ID).Delete(&model.KV{})
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected == 0 {
+		return "", nil
+	}
+	return kv.Value, nil
+}
+
+// DeleteExpired permanently removes expired key-value pairs
+func (r *KVRepository) DeleteExpired() error {
+	return r.db.Unscoped().
+		Where("expires_at IS NOT NULL AND expires_at <= ?", time.Now()).
+		Delete(&model.KV{}).Error
+}
+
+// live scopes a query to entries that have not expired
+func (r *KVRepository) live() *gorm.DB {
+	return r.db.Where("expires_at IS NULL OR expires_at > ?", time.Now())
+}
+
 // Delete removes a key-value pair
 func (r *KVRepository) Delete(key string) error {
 	result := r.db.Where("key = ?", key).Delete(&model.KV{})
@@ -52,21 +123,21 @@ func (r *KVRepository) Delete(key string) error {
 // Exists checks if a key exists
 func (r *KVRepository) Exists(key string) (bool, error) {
 	var count int64
-	err := r.db.Model(&model.KV{}).Where("key = ?", key).Count(&count).Error
+	err := r.live().Model(&model.KV{}).Where("key = ?", key).Count(&count).Error
 	return count > 0, err
 }
 
 // GetAll retrieves all key-value pairs
 func (r *KVRepository) GetAll() ([]model.KV, error) {
 	var kvs []model.KV
-	err := r.db.Find(&kvs).Error
+	err := r.live().Find(&kvs).Error
 	return kvs, err
 }
 
 // GetKeys retrieves all keys
 func (r *KVRepository) GetKeys() ([]string, error) {
 	var keys []string
-	err := r.db.Model(&model.KV{}).Pluck("key", &keys).Error
+	err := r.live().Model(&model.KV{}).Pluck("key", &keys).Error
 	return keys, err
 }
 
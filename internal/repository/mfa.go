@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"gorm.io/gorm"
+)
+
+// MFARepository handles two-factor authentication data operations
+type MFARepository struct {
+	db *gorm.DB
+}
+
+// NewMFARepository creates a new MFA repository
+func NewMFARepository(db *gorm.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+// ReplaceRecoveryCodes replaces all recovery codes of a user with the given hashes
+func (r *MFARepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&model.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]*model.MFARecoveryCode, len(codeHashes))
+		for i, hash := range codeHashes {
+			codes[i] = &model.MFARecoveryCode{
+				UserID:   userID,
+				CodeHash: hash,
+			}
+		}
+
+		return tx.Create(&codes).Error
+	})
+}
+
+// ConsumeRecoveryCode atomically marks an unused recovery code as used.
+// It reports whether a matching unused code was found.
+func (r *MFARepository) ConsumeRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := r.db.Model(&model.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+// ConsumeTOTPStep atomically records the time step of a used TOTP code. It
+// reports whether the step is later than the last one used, so that each code
+// is accepted only once even by concurrent requests.
+func (r *MFARepository) ConsumeTOTPStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&model.User{}).
+		Where("id = ? AND mfa_last_used_step < ?", userID, step).
+		Update("mfa_last_used_step", step)
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// CountUnusedRecoveryCodes returns the number of recovery codes a user has left
+func (r *MFARepository) CountUnusedRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.MFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// DeleteRecoveryCodes removes all recovery codes of a user
+func (r *MFARepository) DeleteRecoveryCodes(userID uint) error {
+	return r.db.Unscoped().Where("user_id = ?", userID).Delete(&model.MFARecoveryCode{}).Error
+}
@@ -0,0 +1,70 @@
+package repository_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+)
+
+func TestMFAConsumeTOTPStep(t *testing.T) {
+	db := newTestDB(t, &model.User{})
+	repo := repository.NewMFARepository(db)
+
+	user := &model.User{Username: "alice", Email: "alice@example.com", PasswordHash: "unused"}
+	if err := repository.NewUserRepository(db).Create(user); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		step int64
+		want bool
+	}{
+		{100, true},
+		{100, false},
+		{99, false},
+		{101, true},
+	} {
+		consumed, err := repo.ConsumeTOTPStep(user.ID, tt.step)
+		if err != nil {
+			t.Fatalf("ConsumeTOTPStep(%d) failed: %v", tt.step, err)
+		}
+		if consumed != tt.want {
+			t.Errorf("ConsumeTOTPStep(%d) = %v, want %v", tt.step, consumed, tt.want)
+		}
+	}
+}
+
+func TestMFAConsumeTOTPStepConcurrently(t *testing.T) {
+	db := newTestDB(t, &model.User{})
+	repo := repository.NewMFARepository(db)
+
+	user := &model.User{Username: "alice", Email: "alice@example.com", PasswordHash: "unused"}
+	if err := repository.NewUserRepository(db).Create(user); err != nil {
+		t.Fatal(err)
+	}
+
+	const workers = 10
+	var wg sync.WaitGroup
+	var accepted atomic.Int32
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			consumed, err := repo.ConsumeTOTPStep(user.ID, 100)
+			if err != nil {
+				t.Errorf("ConsumeTOTPStep failed: %v", err)
+			}
+			if consumed {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if accepted.Load() != 1 {
+		t.Errorf("step accepted %d times, want once", accepted.Load())
+	}
+}
@@ -1,6 +1,12 @@
 package repository
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
 	"github.com/ray-d-song/go-echo-monolithic/internal/model"
 	"gorm.io/gorm"
 )
@@ -17,19 +23,240 @@ func NewMigrator(db *gorm.DB) *Migrator {
 
 // AutoMigrate runs automatic migrations for all models
 func (m *Migrator) AutoMigrate() error {
-	return m.db.AutoMigrate(
+	if err := m.normalizeUserIdentifiers(); err != nil {
+		return err
+	}
+
+	if err := m.db.AutoMigrate(
+		&model.Permission{},
+		&model.Role{},
 		&model.User{},
 		&model.RefreshToken{},
+		&model.PasswordResetToken{},
+		&model.MagicLinkToken{},
+		&model.EmailVerificationToken{},
+		&model.MFARecoveryCode{},
+		&model.AuditEvent{},
+		&model.LoginThrottle{},
+		&model.APIKey{},
+		&model.UserIdentity{},
+		&model.OAuthClient{},
+		&model.OAuthConsent{},
+		&model.OAuthAuthorizationCode{},
+		&model.Invitation{},
+		&model.DataExport{},
+		&model.File{},
+		&model.Organization{},
+		&model.Membership{},
+		&model.OrgInvitation{},
 		&model.KV{},
-	)
+	); err != nil {
+		return err
+	}
+
+	if err := m.backfillRefreshTokenFamilies(); err != nil {
+		return err
+	}
+
+	return m.seedRBAC()
+}
+
+// seedRBAC creates the built-in permissions and roles, and moves users from the
+// legacy role column to role assignments
+func (m *Migrator) seedRBAC() error {
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		permissions := make([]model.Permission, len(model.BuiltinPermissions))
+		for i, builtin := range model.BuiltinPermissions {
+			permission := model.Permission{Name: builtin.Name}
+			if err := tx.Where(model.Permission{Name: builtin.Name}).
+				Assign(model.Permission{Description: builtin.Description}).
+				FirstOrCreate(&permission).Error; err != nil {
+				return err
+			}
+			permissions[i] = permission
+		}
+
+		// The admin role always holds every permission
+		admin := model.Role{Name: model.RoleAdmin}
+		if err := tx.Where(model.Role{Name: model.RoleAdmin}).
+			Attrs(model.Role{Description: "Full access to the application"}).
+			Assign(model.Role{System: true}).
+			FirstOrCreate(&admin).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&admin).Association("Permissions").Replace(permissions); err != nil {
+			return err
+		}
+
+		user := model.Role{Name: model.RoleUser}
+		if err := tx.Where(model.Role{Name: model.RoleUser}).
+			Attrs(model.Role{Description: "Default role of registered users"}).
+			Assign(model.Role{System: true}).
+			FirstOrCreate(&user).Error; err != nil {
+			return err
+		}
+
+		return m.migrateLegacyRoles(tx)
+	})
+}
+
+// migrateLegacyRoles assigns roles to users based on the old users.role column.
+// The column is cleared afterwards so that later role changes are not undone.
+func (m *Migrator) migrateLegacyRoles(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&model.User{}, "role") {
+		return nil
+	}
+
+	var legacy []struct {
+		ID   uint
+		Role string
+	}
+	if err := tx.Table("users").Select("id, role").
+		Where("role IS NOT NULL AND role <> ''").
+		Scan(&legacy).Error; err != nil {
+		return err
+	}
+
+	for _, row := range legacy {
+		role := model.Role{Name: row.Role}
+		if err := tx.Where(model.Role{Name: row.Role}).FirstOrCreate(&role).Error; err != nil {
+			return err
+		}
+
+		user := model.User{BaseModel: model.BaseModel{ID: row.ID}}
+		if err := tx.Model(&user).Association("Roles").Append(&role); err != nil {
+			return err
+		}
+
+		if err := tx.Table("users").Where("id = ?", row.ID).Update("role", "").Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// backfillRefreshTokenFamilies gives active refresh tokens issued before token
+// families were introduced a family of their own, so they show up as sessions
+func (m *Migrator) backfillRefreshTokenFamilies() error {
+	var tokens []*model.RefreshToken
+	if err := m.db.Where("family_id = ? OR family_id IS NULL", "").
+		Where("is_revoked = ?", false).
+		Find(&tokens).Error; err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		bytes := make([]byte, 16)
+		if _, err := rand.Read(bytes); err != nil {
+			return err
+		}
+
+		if err := m.db.Model(token).Updates(map[string]interface{}{
+			"family_id":          hex.EncodeToString(bytes),
+			"session_started_at": token.CreatedAt,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// normalizeUserIdentifiers fills the canonical username and email columns of
+// existing users before their unique indexes are created. It fails, listing
+// the affected users, if usernames or emails only differ in case, since those
+// accounts must be renamed first.
+func (m *Migrator) normalizeUserIdentifiers() error {
+	migrator := m.db.Migrator()
+	if !migrator.HasTable(&model.User{}) {
+		return nil
+	}
+	if migrator.HasIndex(&model.User{}, "UsernameNormalized") && migrator.HasIndex(&model.User{}, "EmailNormalized") {
+		return nil
+	}
+
+	for _, field := range []string{"UsernameNormalized", "EmailNormalized"} {
+		if !migrator.HasColumn(&model.User{}, field) {
+			if err := migrator.AddColumn(&model.User{}, field); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Soft-deleted users are included as the unique indexes cover them too
+	var users []*model.User
+	if err := m.db.Unscoped().Select("id", "username", "email").Find(&users).Error; err != nil {
+		return err
+	}
+
+	usernames := make(map[string][]uint)
+	emails := make(map[string][]uint)
+	for _, user := range users {
+		user.Normalize()
+		usernames[user.UsernameNormalized] = append(usernames[user.UsernameNormalized], user.ID)
+		emails[user.EmailNormalized] = append(emails[user.EmailNormalized], user.ID)
+	}
+
+	collisions := append(identifierCollisions("username", usernames), identifierCollisions("email", emails)...)
+	if len(collisions) > 0 {
+		return fmt.Errorf("usernames and emails must be unique regardless of case, rename these users first: %s",
+			strings.Join(collisions, "; "))
+	}
+
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		for _, user := range users {
+			if err := tx.Unscoped().Model(&model.User{}).Where("id = ?", user.ID).UpdateColumns(map[string]interface{}{
+				"username_normalized": user.UsernameNormalized,
+				"email_normalized":    user.EmailNormalized,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// identifierCollisions describes the canonical identifiers shared by several users
+func identifierCollisions(kind string, ids map[string][]uint) []string {
+	var collisions []string
+	for identifier, userIDs := range ids {
+		if len(userIDs) > 1 {
+			collisions = append(collisions, fmt.Sprintf("%s %q is used by users %v", kind, identifier, userIDs))
+		}
+	}
+	sort.Strings(collisions)
+	return collisions
 }
 
 // DropTables drops all tables (use with caution)
 func (m *Migrator) DropTables() error {
 	return m.db.Migrator().DropTable(
 		&model.KV{},
+		&model.OrgInvitation{},
+		&model.Membership{},
+		&model.Organization{},
+		&model.File{},
+		&model.DataExport{},
+		&model.Invitation{},
+		&model.OAuthAuthorizationCode{},
+		&model.OAuthConsent{},
+		&model.OAuthClient{},
+		&model.UserIdentity{},
+		&model.APIKey{},
+		&model.LoginThrottle{},
+		&model.AuditEvent{},
+		&model.MFARecoveryCode{},
+		&model.EmailVerificationToken{},
+		&model.MagicLinkToken{},
+		&model.PasswordResetToken{},
 		&model.RefreshToken{},
+		"user_roles",
+		userSearchTable,
 		&model.User{},
+		"role_permissions",
+		&model.Role{},
+		&model.Permission{},
 	)
 }
 
@@ -44,5 +271,5 @@ func (m *Migrator) CreateIndexes() error {
 		return err
 	}
 
-	return nil
+	return m.createUserSearchIndex()
 }
\ No newline at end of file
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OAuthClientRepository handles OAuth client and consent data operations
+type OAuthClientRepository struct {
+	db *gorm.DB
+}
+
+// NewOAuthClientRepository creates a new OAuth client repository
+func NewOAuthClientRepository(db *gorm.DB) *OAuthClientRepository {
+	return &OAuthClientRepository{db: db}
+}
+
+// Create registers a new client
+func (r *OAuthClientRepository) Create(client *model.OAuthClient) error {
+	return r.db.Create(client).Error
+}
+
+// GetByID retrieves a client by its database ID
+func (r *OAuthClientRepository) GetByID(id uint) (*model.OAuthClient, error) {
+	var client model.OAuthClient
+	if err := r.db.First(&client, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrOAuthClientNotFound
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+// GetByClientID retrieves a client by its public client ID
+func (r *OAuthClientRepository) GetByClientID(clientID string) (*model.OAuthClient, error) {
+	var client model.OAuthClient
+	if err := r.db.Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrOAuthClientNotFound
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+// List lists all clients
+func (r *OAuthClientRepository) List() ([]*model.OAuthClient, error) {
+	var clients []*model.OAuthClient
+	err := r.db.Order("name ASC").Find(&clients).Error
+	return clients, err
+}
+
+// Update updates an existing client
+func (r *OAuthClientRepository) Update(client *model.OAuthClient) error {
+	return r.db.Save(client).Error
+}
+
+// Delete permanently removes a client along with the consents given to it
+func (r *OAuthClientRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var client model.OAuthClient
+		if err := tx.First(&client, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return types.ErrOAuthClientNotFound
+			}
+			return err
+		}
+
+		if err := tx.Unscoped().Where("client_id = ?", client.ClientID).Delete(&model.OAuthConsent{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&client).Error
+	})
+}
+
+// GetConsent retrieves the scopes a user has consented to for a client. It
+// returns an empty string if the user never authorized the client.
+func (r *OAuthClientRepository) GetConsent(userID uint, clientID string) (string, error) {
+	var consent model.OAuthConsent
+	if err := r.db.Where("user_id = ? AND client_id = ?", userID, clientID).First(&consent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return consent.Scope, nil
+}
+
+// SaveConsent stores the scopes a user has consented to for a client
+func (r *OAuthClientRepository) SaveConsent(userID uint, clientID, scope string) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"scope", "updated_at", "deleted_at"}),
+	}).Create(&model.OAuthConsent{
+		UserID:   userID,
+		ClientID: clientID,
+		Scope:    scope,
+	}).Error
+}
@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/tenant"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OrganizationRepository handles organization, membership and organization
+// invitation data operations. Methods taking a context work within the
+// organization the context is scoped to (see package tenant).
+type OrganizationRepository struct {
+	db *gorm.DB
+}
+
+// NewOrganizationRepository creates a new organization repository
+func NewOrganizationRepository(db *gorm.DB) *OrganizationRepository {
+	return &OrganizationRepository{db: db}
+}
+
+// Create creates an organization owned by a user
+func (r *OrganizationRepository) Create(org *model.Organization, ownerID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.Membership{
+			OrgID:  org.ID,
+			UserID: ownerID,
+			Role:   model.OrgRoleOwner,
+		}).Error
+	})
+}
+
+// GetByID retrieves an organization by ID
+func (r *OrganizationRepository) GetByID(id uint) (*model.Organization, error) {
+	var org model.Organization
+	if err := r.db.First(&org, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrOrgNotFound
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+// SlugTaken reports whether an organization other than exceptID uses a slug
+func (r *OrganizationRepository) SlugTaken(slug string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&model.Organization{}).
+		Where("slug = ? AND id <> ?", slug, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+// Update updates an organization
+func (r *OrganizationRepository) Update(org *model.Organization) error {
+	return r.db.Save(org).Error
+}
+
+// Delete permanently deletes an organization with its memberships and invitations
+func (r *OrganizationRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return deleteOrg(tx, id)
+	})
+}
+
+// ListMemberships lists the memberships of a user in every organization,
+// oldest first
+func (r *OrganizationRepository) ListMemberships(userID uint) ([]*model.Membership, error) {
+	var memberships []*model.Membership
+	err := r.db.Preload("Organization").Where("user_id = ?", userID).Order("id").Find(&memberships).Error
+	return memberships, err
+}
+
+// FindMembership retrieves the membership of a user in an organization
+func (r *OrganizationRepository) FindMembership(orgID, userID uint) (*model.Membership, error) {
+	var membership model.Membership
+	err := r.db.Where("org_id = ? AND user_id = ?", orgID, userID).First(&membership).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrMemberNotFound
+		}
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// FirstMembership retrieves the oldest membership of a user
+func (r *OrganizationRepository) FirstMembership(userID uint) (*model.Membership, error) {
+	var membership model.Membership
+	if err := r.db.Where("user_id = ?", userID).Order("id").First(&membership).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrMemberNotFound
+		}
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// ListMembers lists the members of the organization, oldest first
+func (r *OrganizationRepository) ListMembers(ctx context.Context) ([]*model.Membership, error) {
+	var members []*model.Membership
+	err := r.db.WithContext(ctx).Preload("User").Order("id").Find(&members).Error
+	return members, err
+}
+
+// GetMember retrieves the membership of a user in the organization
+func (r *OrganizationRepository) GetMember(ctx context.Context, userID uint) (*model.Membership, error) {
+	var member model.Membership
+	if err := r.db.WithContext(ctx).Preload("User").Where("user_id = ?", userID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrMemberNotFound
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// UpdateMemberRole changes the role of a member of the organization. It fails
+// with ErrLastOwner when the organization would be left without an owner.
+func (r *OrganizationRepository) UpdateMemberRole(ctx context.Context, userID uint, role string) error {
+	orgID, ok := tenant.OrgID(ctx)
+	if !ok {
+		return types.ErrNoActiveOrg
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Membership{}).Where("user_id = ?", userID).Update("role", role)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return types.ErrMemberNotFound
+		}
+
+		return keepOwner(tx, orgID)
+	})
+}
+
+// RemoveMember removes a user from the organization. Sessions working in the
+// organization are left without an active organization. It fails with
+// ErrLastOwner when the organization would be left without an owner.
+func (r *OrganizationRepository) RemoveMember(ctx context.Context, userID uint) error {
+	orgID, ok := tenant.OrgID(ctx)
+	if !ok {
+		return types.ErrNoActiveOrg
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("user_id = ?", userID).Delete(&model.Membership{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return types.ErrMemberNotFound
+		}
+		if err := keepOwner(tx, orgID); err != nil {
+			return err
+		}
+
+		return tx.Model(&model.RefreshToken{}).Where("user_id = ? AND active_org_id = ?", userID, orgID).
+			Update("active_org_id", nil).Error
+	})
+}
+
+// CreateInvitation creates an invitation to the organization, replacing
+// earlier invitations of the same address
+func (r *OrganizationRepository) CreateInvitation(ctx context.Context, invitation *model.OrgInvitation) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("email_normalized = ?", invitation.EmailNormalized).
+			Delete(&model.OrgInvitation{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(invitation).Error
+	})
+}
+
+// ListInvitations lists the unexpired invitations to the organization, newest first
+func (r *OrganizationRepository) ListInvitations(ctx context.Context) ([]*model.OrgInvitation, error) {
+	var invitations []*model.OrgInvitation
+	err := r.db.WithContext(ctx).Where("expires_at > ?", time.Now()).Order("id DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+// DeleteInvitation deletes an invitation to the organization
+func (r *OrganizationRepository) DeleteInvitation(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Unscoped().Delete(&model.OrgInvitation{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return types.ErrInvitationNotFound
+	}
+	return nil
+}
+
+// GetInvitationByTokenHash retrieves an invitation to any organization by the
+// hash of its token
+func (r *OrganizationRepository) GetInvitationByTokenHash(tokenHash string) (*model.OrgInvitation, error) {
+	var invitation model.OrgInvitation
+	if err := r.db.Preload("Organization").Where("token_hash = ?", tokenHash).First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// AcceptInvitation adds the invited user to the organization and consumes the invitation
+func (r *OrganizationRepository) AcceptInvitation(ctx context.Context, invitation *model.OrgInvitation, member *model.Membership) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Delete(&model.OrgInvitation{}, invitation.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Accepted concurrently
+			return types.ErrInvitationNotFound
+		}
+		return tx.Create(member).Error
+	})
+}
+
+// leaveOrgs removes a user from all their organizations. An organization
+// losing its last owner passes to its longest-standing member, and an
+// organization losing its last member is deleted.
+func leaveOrgs(tx *gorm.DB, userID uint) error {
+	var memberships []*model.Membership
+	if err := tx.Where("user_id = ?", userID).Find(&memberships).Error; err != nil {
+		return err
+	}
+
+	for _, membership := range memberships {
+		if err := tx.Unscoped().Delete(membership).Error; err != nil {
+			return err
+		}
+		if membership.Role != model.OrgRoleOwner {
+			continue
+		}
+
+		owners, err := countOwners(tx, membership.OrgID)
+		if err != nil {
+			return err
+		}
+		if owners > 0 {
+			continue
+		}
+
+		var successor model.Membership
+		err = tx.Where("org_id = ?", membership.OrgID).Order("id").First(&successor).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := deleteOrg(tx, membership.OrgID); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			if err := tx.Model(&successor).Update("role", model.OrgRoleOwner).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// keepOwner fails with ErrLastOwner when an organization has no owner left
+// after a change within the transaction
+func keepOwner(tx *gorm.DB, orgID uint) error {
+	owners, err := countOwners(tx, orgID)
+	if err != nil {
+		return err
+	}
+	if owners == 0 {
+		return types.ErrLastOwner
+	}
+	return nil
+}
+
+// countOwners counts the owners of an organization. The organization is locked
+// until the end of the transaction first, so that concurrent changes of its
+// owners are applied one after another and each sees the others' result.
+// Changes write before counting, which takes the write lock on databases
+// without row locks.
+func countOwners(tx *gorm.DB, orgID uint) (int64, error) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+		First(&model.Organization{}, orgID).Error; err != nil {
+		return 0, err
+	}
+
+	var owners int64
+	err := tx.Model(&model.Membership{}).
+		Where("org_id = ? AND role = ?", orgID, model.OrgRoleOwner).Count(&owners).Error
+	return owners, err
+}
+
+// deleteOrg permanently deletes an organization with everything belonging to it
+func deleteOrg(tx *gorm.DB, id uint) error {
+	for _, owned := range []interface{}{
+		&model.Membership{},
+		&model.OrgInvitation{},
+	} {
+		if err := tx.Unscoped().Where("org_id = ?", id).Delete(owned).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&model.RefreshToken{}).Where("active_org_id = ?", id).
+		Update("active_org_id", nil).Error; err != nil {
+		return err
+	}
+
+	result := tx.Unscoped().Delete(&model.Organization{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return types.ErrOrgNotFound
+	}
+	return nil
+}
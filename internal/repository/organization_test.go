@@ -0,0 +1,109 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/tenant"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// newTestOrg creates an organization owned by two users and returns the
+// repository, a context scoped to the organization and the owners' IDs
+func newTestOrg(t *testing.T) (*repository.OrganizationRepository, context.Context, []uint) {
+	t.Helper()
+
+	db := newTestDB(t, &model.User{}, &model.Organization{}, &model.Membership{}, &model.RefreshToken{})
+	users := repository.NewUserRepository(db)
+	orgs := repository.NewOrganizationRepository(db)
+
+	var owners []uint
+	for _, name := range []string{"alice", "bob"} {
+		user := &model.User{Username: name, Email: name + "@example.com", PasswordHash: "unused"}
+		if err := users.Create(user); err != nil {
+			t.Fatal(err)
+		}
+		owners = append(owners, user.ID)
+	}
+
+	org := &model.Organization{Name: "Acme", Slug: "acme", CreatedByID: owners[0]}
+	if err := orgs.Create(org, owners[0]); err != nil {
+		t.Fatal(err)
+	}
+	ctx := tenant.WithOrgID(context.Background(), org.ID)
+	if err := db.WithContext(ctx).Create(&model.Membership{UserID: owners[1], Role: model.OrgRoleOwner}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return orgs, ctx, owners
+}
+
+// runConcurrently runs fn once per owner at the same time and returns the errors
+func runConcurrently(owners []uint, fn func(userID uint) error) []error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(owners))
+	for i, userID := range owners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(userID)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// checkOneOwnerLeft checks that exactly one of the concurrent changes was
+// refused and that the organization kept an owner
+func checkOneOwnerLeft(t *testing.T, orgs *repository.OrganizationRepository, ctx context.Context, errs []error) {
+	t.Helper()
+
+	var refused int
+	for _, err := range errs {
+		switch {
+		case errors.Is(err, types.ErrLastOwner):
+			refused++
+		case err != nil:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if refused != 1 {
+		t.Errorf("%d changes refused, want 1", refused)
+	}
+
+	members, err := orgs.ListMembers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var owners int
+	for _, member := range members {
+		if member.Role == model.OrgRoleOwner {
+			owners++
+		}
+	}
+	if owners != 1 {
+		t.Errorf("%d owners left, want 1", owners)
+	}
+}
+
+func TestOrganizationDemoteOwnersConcurrently(t *testing.T) {
+	orgs, ctx, owners := newTestOrg(t)
+
+	errs := runConcurrently(owners, func(userID uint) error {
+		return orgs.UpdateMemberRole(ctx, userID, model.OrgRoleMember)
+	})
+	checkOneOwnerLeft(t, orgs, ctx, errs)
+}
+
+func TestOrganizationRemoveOwnersConcurrently(t *testing.T) {
+	orgs, ctx, owners := newTestOrg(t)
+
+	errs := runConcurrently(owners, func(userID uint) error {
+		return orgs.RemoveMember(ctx, userID)
+	})
+	checkOneOwnerLeft(t, orgs, ctx, errs)
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+	"gorm.io/gorm"
+)
+
+// UserData is everything stored about a user
+type UserData struct {
+	User        *model.User
+	Sessions    []*model.RefreshToken
+	APIKeys     []*model.APIKey
+	Identities  []*model.UserIdentity
+	Consents    []*model.OAuthConsent
+	KV          []*model.KV
+	Files       []*model.File
+	Memberships []*model.Membership
+	AuditEvents []*model.AuditEvent
+}
+
+// PrivacyRepository handles personal data export operations
+type PrivacyRepository struct {
+	db *gorm.DB
+}
+
+// NewPrivacyRepository creates a new privacy repository
+func NewPrivacyRepository(db *gorm.DB) *PrivacyRepository {
+	return &PrivacyRepository{db: db}
+}
+
+// CreateExport creates a data export
+func (r *PrivacyRepository) CreateExport(export *model.DataExport) error {
+	return r.db.Create(export).Error
+}
+
+// GetExport retrieves a data export of a user
+func (r *PrivacyRepository) GetExport(userID, id uint) (*model.DataExport, error) {
+	var export model.DataExport
+	if err := r.db.Where("user_id = ?", userID).First(&export, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrExportNotFound
+		}
+		return nil, err
+	}
+	return &export, nil
+}
+
+// HasActiveExport reports whether a user has an export that is being built or
+// waiting to be downloaded
+func (r *PrivacyRepository) HasActiveExport(userID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.DataExport{}).
+		Where("user_id = ? AND status IN ?", userID, []string{model.DataExportPending, model.DataExportRunning, model.DataExportReady}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ClaimPendingExport marks the oldest pending export as running and returns
+// it, or nil if there is none. Concurrent workers never claim the same export.
+func (r *PrivacyRepository) ClaimPendingExport() (*model.DataExport, error) {
+	for {
+		var export model.DataExport
+		if err := r.db.Where("status = ?", model.DataExportPending).Order("id").First(&export).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		result := r.db.Model(&model.DataExport{}).
+			Where("id = ? AND status = ?", export.ID, model.DataExportPending).
+			Update("status", model.DataExportRunning)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			export.Status = model.DataExportRunning
+			return &export, nil
+		}
+	}
+}
+
+// ClaimDownload marks a ready export of a user as downloaded and returns it,
+// so that it can be downloaded only once
+func (r *PrivacyRepository) ClaimDownload(userID, id uint) (*model.DataExport, error) {
+	export, err := r.GetExport(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if export.Status != model.DataExportReady || export.ExpiresAt == nil || !export.ExpiresAt.After(time.Now()) {
+		return nil, types.ErrExportNotReady
+	}
+
+	now := time.Now()
+	result := r.db.Model(&model.DataExport{}).
+		Where("id = ? AND status = ?", id, model.DataExportReady).
+		Updates(map[string]interface{}{"status": model.DataExportDownloaded, "downloaded_at": now})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, types.ErrExportNotReady
+	}
+
+	export.Status = model.DataExportDownloaded
+	export.DownloadedAt = &now
+	return export, nil
+}
+
+// UpdateExport updates a data export
+func (r *PrivacyRepository) UpdateExport(export *model.DataExport) error {
+	return r.db.Save(export).Error
+}
+
+// ListExpiredExports retrieves ready exports that were not downloaded in time
+func (r *PrivacyRepository) ListExpiredExports(now time.Time) ([]*model.DataExport, error) {
+	var exports []*model.DataExport
+	err := r.db.Where("status = ? AND expires_at <= ?", model.DataExportReady, now).Find(&exports).Error
+	return exports, err
+}
+
+// ListFilesByUser retrieves the archive paths of a user's exports that were
+// not removed yet
+func (r *PrivacyRepository) ListFilesByUser(userID uint) ([]string, error) {
+	var paths []string
+	err := r.db.Model(&model.DataExport{}).
+		Where("user_id = ? AND file_path <> ''", userID).
+		Pluck("file_path", &paths).Error
+	return paths, err
+}
+
+// UserData retrieves everything stored about a user
+func (r *PrivacyRepository) UserData(userID uint) (*UserData, error) {
+	data := &UserData{User: &model.User{}}
+	if err := r.db.Preload("Roles").First(data.User, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	for _, owned := range []interface{}{
+		&data.Sessions,
+		&data.APIKeys,
+		&data.Identities,
+		&data.Consents,
+		&data.Files,
+		&data.AuditEvents,
+	} {
+		if err := r.db.Where("user_id = ?", userID).Order("id").Find(owned).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	err := r.db.Where("user_id = ? AND (expires_at IS NULL OR expires_at > ?)", userID, time.Now()).
+		Order("key").Find(&data.KV).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Preload("Organization").Where("user_id = ?", userID).Order("id").Find(&data.Memberships).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+	"gorm.io/gorm"
+)
+
+// RBACRepository handles role and permission data operations
+type RBACRepository struct {
+	db *gorm.DB
+}
+
+// NewRBACRepository creates a new RBAC repository
+func NewRBACRepository(db *gorm.DB) *RBACRepository {
+	return &RBACRepository{db: db}
+}
+
+// ListRoles retrieves all roles with their permissions
+func (r *RBACRepository) ListRoles() ([]*model.Role, error) {
+	var roles []*model.Role
+	err := r.db.Preload("Permissions").Order("name").Find(&roles).Error
+	return roles, err
+}
+
+// GetRoleByID retrieves a role with its permissions by ID
+func (r *RBACRepository) GetRoleByID(id uint) (*model.Role, error) {
+	var role model.Role
+	if err := r.db.Preload("Permissions").First(&role, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// GetRolesByName retrieves the roles with the given names
+func (r *RBACRepository) GetRolesByName(names []string) ([]model.Role, error) {
+	var roles []model.Role
+	if len(names) == 0 {
+		return roles, nil
+	}
+	err := r.db.Where("name IN ?", names).Find(&roles).Error
+	return roles, err
+}
+
+// ExistsByName checks if a role with the given name exists
+func (r *RBACRepository) ExistsByName(name string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Role{}).Where("name = ?", name).Count(&count).Error
+	return count > 0, err
+}
+
+// CreateRole creates a role along with its permissions
+func (r *RBACRepository) CreateRole(role *model.Role) error {
+	return r.db.Create(role).Error
+}
+
+// UpdateRole updates a role and replaces its permissions
+func (r *RBACRepository) UpdateRole(role *model.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Permissions").Save(role).Error; err != nil {
+			return err
+		}
+		return tx.Model(role).Association("Permissions").Replace(role.Permissions)
+	})
+}
+
+// DeleteRole permanently deletes a role and removes it from all users
+func (r *RBACRepository) DeleteRole(role *model.Role) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM user_roles WHERE role_id = ?", role.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(role).Error
+	})
+}
+
+// ListPermissions retrieves all permissions
+func (r *RBACRepository) ListPermissions() ([]*model.Permission, error) {
+	var permissions []*model.Permission
+	err := r.db.Order("name").Find(&permissions).Error
+	return permissions, err
+}
+
+// GetPermissionsByName retrieves the permissions with the given names
+func (r *RBACRepository) GetPermissionsByName(names []string) ([]model.Permission, error) {
+	var permissions []model.Permission
+	if len(names) == 0 {
+		return permissions, nil
+	}
+	err := r.db.Where("name IN ?", names).Find(&permissions).Error
+	return permissions, err
+}
+
+// GetUserRoles retrieves the roles of a user with their permissions
+func (r *RBACRepository) GetUserRoles(userID uint) ([]model.Role, error) {
+	var roles []model.Role
+	err := r.db.Preload("Permissions").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.name").
+		Find(&roles).Error
+	return roles, err
+}
+
+// SetUserRoles replaces the roles of a user
+func (r *RBACRepository) SetUserRoles(user *model.User, roles []model.Role) error {
+	return r.db.Model(user).Association("Roles").Replace(roles)
+}
+
+// AddUserRole grants a role to a user
+func (r *RBACRepository) AddUserRole(user *model.User, role *model.Role) error {
+	return r.db.Model(user).Association("Roles").Append(role)
+}
+
+// RemoveUserRole revokes a role from a user
+func (r *RBACRepository) RemoveUserRole(user *model.User, role *model.Role) error {
+	return r.db.Model(user).Association("Roles").Delete(role)
+}
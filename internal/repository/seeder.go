@@ -1,23 +1,27 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/ray-d-song/go-echo-monolithic/internal/model"
 	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/logger"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/password"
 	"gorm.io/gorm"
 )
 
 // Seeder handles database seeding operations
 type Seeder struct {
-	db     *gorm.DB
-	logger *logger.Logger
+	db        *gorm.DB
+	logger    *logger.Logger
+	passwords *password.Manager
 }
 
 // NewSeeder creates a new seeder instance
-func NewSeeder(db *gorm.DB, logger *logger.Logger) *Seeder {
+func NewSeeder(db *gorm.DB, logger *logger.Logger, passwords *password.Manager) *Seeder {
 	return &Seeder{
-		db:     db,
-		logger: logger,
+		db:        db,
+		logger:    logger,
+		passwords: passwords,
 	}
 }
 
@@ -36,13 +40,22 @@ func (s *Seeder) SeedAll() error {
 
 // SeedUsers creates sample users
 func (s *Seeder) SeedUsers() error {
+	// Built-in roles are created by the migration
+	var adminRole, userRole model.Role
+	if err := s.db.Where("name = ?", model.RoleAdmin).First(&adminRole).Error; err != nil {
+		return err
+	}
+	if err := s.db.Where("name = ?", model.RoleUser).First(&userRole).Error; err != nil {
+		return err
+	}
+
 	users := []*model.User{
 		{
 			Username:  "admin",
 			Email:     "admin@example.com",
 			FirstName: "Admin",
 			LastName:  "User",
-			Role:      "admin",
+			Roles:     []model.Role{adminRole},
 			IsActive:  true,
 		},
 		{
@@ -50,7 +63,7 @@ func (s *Seeder) SeedUsers() error {
 			Email:     "john.doe@example.com",
 			FirstName: "John",
 			LastName:  "Doe",
-			Role:      "user",
+			Roles:     []model.Role{userRole},
 			IsActive:  true,
 		},
 		{
@@ -58,7 +71,7 @@ func (s *Seeder) SeedUsers() error {
 			Email:     "jane.smith@example.com",
 			FirstName: "Jane",
 			LastName:  "Smith",
-			Role:      "user",
+			Roles:     []model.Role{userRole},
 			IsActive:  true,
 		},
 		{
@@ -66,7 +79,7 @@ func (s *Seeder) SeedUsers() error {
 			Email:     "demo@example.com",
 			FirstName: "Demo",
 			LastName:  "User",
-			Role:      "user",
+			Roles:     []model.Role{userRole},
 			IsActive:  false,
 		},
 	}
@@ -74,17 +87,22 @@ func (s *Seeder) SeedUsers() error {
 	for _, user := range users {
 		// Check if user already exists
 		var existingUser model.User
-		if err := s.db.Where("username = ? OR email = ?", user.Username, user.Email).First(&existingUser).Error; err == nil {
+		user.Normalize()
+		if err := s.db.Where("username_normalized = ? OR email_normalized = ?", user.UsernameNormalized, user.EmailNormalized).First(&existingUser).Error; err == nil {
 			s.logger.Info("User already exists, skipping: " + user.Username)
 			continue
 		}
 
 		// Hash password (default password: "password123")
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+		hashedPassword, err := s.passwords.Hash("password123")
 		if err != nil {
 			return err
 		}
-		user.PasswordHash = string(hashedPassword)
+		user.PasswordHash = hashedPassword
+
+		// Seeded accounts are treated as already verified
+		verifiedAt := time.Now()
+		user.EmailVerifiedAt = &verifiedAt
 
 		if err := s.db.Create(user).Error; err != nil {
 			return err
@@ -118,6 +136,10 @@ func (s *Seeder) SeedKVData() error {
 			Key:   "default_user_role",
 			Value: "user",
 		},
+		{
+			Key:   model.SettingAllowRegister,
+			Value: "true",
+		},
 		{
 			Key:   "welcome_message",
 			Value: "Welcome to our application!",
@@ -144,10 +166,78 @@ func (s *Seeder) SeedKVData() error {
 // ClearData removes all seeded data (useful for testing)
 func (s *Seeder) ClearData() error {
 	// Delete in reverse order due to foreign key constraints
+	if err := s.db.Unscoped().Delete(&model.OrgInvitation{}, "1 = 1").Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(&model.Membership{}, "1 = 1").Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(&model.Organization{}, "1 = 1").Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(&model.File{}, "1 = 1").Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(&model.DataExport{}, "1 = 1").Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(&model.Invitation{}, "1 = 1").Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(&model.OAuthAuthorizationCode{}, "1 = 1").Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(&model.OAuthConsent{}, "1 = 1").Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(&model.UserIdentity{}, "1 = 1").Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(&model.APIKey{}, "1 = 1").Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(&model.LoginThrottle{}, "1 = 1").Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(&model.AuditEvent{}, "1 = 1").Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(&model.MFARecoveryCode{}, "1 = 1").Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(&model.EmailVerificationToken{}, "1 = 1").Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(&model.MagicLinkToken{}, "1 = 1").Error; err != nil {
+		return err
+	}
+
+	if err := s.db.Unscoped().Delete(&model.PasswordResetToken{}, "1 = 1").Error; err != nil {
+		return err
+	}
+
 	if err := s.db.Unscoped().Delete(&model.RefreshToken{}, "1 = 1").Error; err != nil {
 		return err
 	}
 
+	if err := s.db.Exec("DELETE FROM user_roles").Error; err != nil {
+		return err
+	}
+
 	if err := s.db.Unscoped().Delete(&model.User{}, "1 = 1").Error; err != nil {
 		return err
 	}
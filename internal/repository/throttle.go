@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginThrottleRepository handles failed login tracking data operations
+type LoginThrottleRepository struct {
+	db *gorm.DB
+}
+
+// NewLoginThrottleRepository creates a new login throttle repository
+func NewLoginThrottleRepository(db *gorm.DB) *LoginThrottleRepository {
+	return &LoginThrottleRepository{db: db}
+}
+
+// Get retrieves the throttle for a key, returning nil if there is none
+func (r *LoginThrottleRepository) Get(key string) (*model.LoginThrottle, error) {
+	var throttle model.LoginThrottle
+	if err := r.db.Where("key = ?", key).First(&throttle).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// Update loads the throttle for a key, creating it if needed, applies fn and
+// saves the result in a single transaction. The row is locked until the
+// transaction ends, so concurrent updates of a key apply one after another.
+func (r *LoginThrottleRepository) Update(key string, fn func(throttle *model.LoginThrottle)) (*model.LoginThrottle, error) {
+	var throttle model.LoginThrottle
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Inserting first also takes the write lock on databases without row locks
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoNothing: true,
+		}).Create(&model.LoginThrottle{Key: key}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("key = ?", key).
+			First(&throttle).Error; err != nil {
+			return err
+		}
+		fn(&throttle)
+		return tx.Save(&throttle).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// Delete permanently removes the throttle for a key
+func (r *LoginThrottleRepository) Delete(key string) error {
+	return r.db.Unscoped().Where("key = ?", key).Delete(&model.LoginThrottle{}).Error
+}
+
+// ListActive retrieves throttles that currently lock or delay login attempts
+func (r *LoginThrottleRepository) ListActive() ([]*model.LoginThrottle, error) {
+	var throttles []*model.LoginThrottle
+	now := time.Now()
+	err := r.db.Where("locked_until > ? OR next_attempt_at > ?", now, now).
+		Order("last_failure_at DESC").
+		Find(&throttles).Error
+	return throttles, err
+}
+
+// DeleteStale permanently removes throttles without failures since the given time
+// that no longer lock anything
+func (r *LoginThrottleRepository) DeleteStale(before time.Time) error {
+	return r.db.Unscoped().
+		Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", before, time.Now()).
+		Delete(&model.LoginThrottle{}).Error
+}
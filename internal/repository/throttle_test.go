@@ -0,0 +1,76 @@
+package repository_test
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/tenant"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB opens a fresh SQLite database with the given models migrated and
+// statements scoped to the organization of their context
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "app.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.Use(tenant.Plugin{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return db
+}
+
+func TestLoginThrottleUpdateConcurrentFirstFailures(t *testing.T) {
+	repo := repository.NewLoginThrottleRepository(newTestDB(t, &model.LoginThrottle{}))
+
+	const workers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.Update("user:alice", func(throttle *model.LoginThrottle) {
+				throttle.Failures++
+			})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+
+	throttle, err := repo.Get("user:alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if throttle.Failures != workers {
+		t.Errorf("Failures = %d, want %d", throttle.Failures, workers)
+	}
+}
@@ -2,10 +2,15 @@ package repository
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/pagination"
 	"github.com/ray-d-song/go-echo-monolithic/internal/types"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // UserRepository handles user data operations
@@ -20,6 +25,7 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 
 // Create creates a new user
 func (r *UserRepository) Create(user *model.User) error {
+	user.Normalize()
 	if err := r.db.Create(user).Error; err != nil {
 		if errors.Is(err, gorm.ErrDuplicatedKey) {
 			return types.ErrUserAlreadyExists
@@ -32,7 +38,7 @@ func (r *UserRepository) Create(user *model.User) error {
 // GetByID retrieves a user by ID
 func (r *UserRepository) GetByID(id uint) (*model.User, error) {
 	var user model.User
-	if err := r.db.First(&user, id).Error; err != nil {
+	if err := r.db.Preload("Roles").First(&user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, types.ErrUserNotFound
 		}
@@ -41,10 +47,10 @@ func (r *UserRepository) GetByID(id uint) (*model.User, error) {
 	return &user, nil
 }
 
-// GetByUsername retrieves a user by username
+// GetByUsername retrieves a user by username, ignoring case
 func (r *UserRepository) GetByUsername(username string) (*model.User, error) {
 	var user model.User
-	if err := r.db.Where("username = ?", username).First(&user).Error; err != nil {
+	if err := r.db.Preload("Roles").Where("username_normalized = ?", model.NormalizeIdentifier(username)).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, types.ErrUserNotFound
 		}
@@ -53,10 +59,10 @@ func (r *UserRepository) GetByUsername(username string) (*model.User, error) {
 	return &user, nil
 }
 
-// GetByEmail retrieves a user by email
+// GetByEmail retrieves a user by email, ignoring case
 func (r *UserRepository) GetByEmail(email string) (*model.User, error) {
 	var user model.User
-	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := r.db.Preload("Roles").Where("email_normalized = ?", model.NormalizeIdentifier(email)).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, types.ErrUserNotFound
 		}
@@ -65,9 +71,31 @@ func (r *UserRepository) GetByEmail(email string) (*model.User, error) {
 	return &user, nil
 }
 
-// Update updates a user
+// GetByLogin retrieves a user by the identifier entered at login, which is
+// an email address if it contains an @ and a username otherwise
+func (r *UserRepository) GetByLogin(identifier string) (*model.User, error) {
+	if strings.Contains(identifier, "@") {
+		return r.GetByEmail(identifier)
+	}
+	return r.GetByUsername(identifier)
+}
+
+// GetByPendingEmail retrieves a user by an email address awaiting confirmation
+func (r *UserRepository) GetByPendingEmail(email string) (*model.User, error) {
+	var user model.User
+	if err := r.db.Preload("Roles").Where("pending_email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Update updates a user. Role assignments are managed by the RBAC repository.
 func (r *UserRepository) Update(user *model.User) error {
-	return r.db.Save(user).Error
+	user.Normalize()
+	return r.db.Omit(clause.Associations).Save(user).Error
 }
 
 // Delete soft deletes a user
@@ -82,30 +110,254 @@ func (r *UserRepository) Delete(id uint) error {
 	return nil
 }
 
-// List retrieves users with pagination
-func (r *UserRepository) List(offset, limit int) ([]*model.User, error) {
-	var users []*model.User
-	err := r.db.Offset(offset).Limit(limit).Find(&users).Error
-	return users, err
+// userSortKeys are the keysets of the fields user listings can be sorted by.
+// Usernames and emails sort regardless of case.
+var userSortKeys = map[string]Keyset[model.User]{
+	"id":         {Column: "id", Value: func(u *model.User) any { return u.ID }},
+	"username":   {Column: "username_normalized", Value: func(u *model.User) any { return u.UsernameNormalized }},
+	"email":      {Column: "email_normalized", Value: func(u *model.User) any { return u.EmailNormalized }},
+	"first_name": {Column: "first_name", Value: func(u *model.User) any { return u.FirstName }},
+	"last_name":  {Column: "last_name", Value: func(u *model.User) any { return u.LastName }},
+	"created_at": {Column: "created_at", Value: func(u *model.User) any { return u.CreatedAt }},
+	"updated_at": {Column: "updated_at", Value: func(u *model.User) any { return u.UpdatedAt }},
+}
+
+// IsUserSortField reports whether user listings can be sorted by a field
+func IsUserSortField(field string) bool {
+	_, ok := userSortKeys[field]
+	return ok
+}
+
+// userKeyset returns the keyset ordering a user listing
+func userKeyset(filter *types.UserFilter) *Keyset[model.User] {
+	sort := filter.Sort
+	keyset, ok := userSortKeys[sort]
+	if !ok {
+		sort = "id"
+		keyset = userSortKeys[sort]
+	}
+
+	keyset.Sort = sort
+	keyset.Desc = strings.EqualFold(filter.Order, "desc")
+	keyset.ID = func(u *model.User) uint { return u.ID }
+	return &keyset
+}
+
+// List retrieves users matching a filter with offset pagination
+func (r *UserRepository) List(filter *types.UserFilter, offset, limit int) (*KeysetPage[model.User], error) {
+	return userKeyset(filter).PaginateOffset(r.filter(filter).Preload("Roles"), offset, limit)
 }
 
-// Count returns total number of users
-func (r *UserRepository) Count() (int64, error) {
+// ListAfter retrieves users matching a filter with keyset pagination, starting
+// at a cursor or at the beginning of the listing
+func (r *UserRepository) ListAfter(filter *types.UserFilter, cursor *pagination.Cursor, limit int) (*KeysetPage[model.User], error) {
+	return userKeyset(filter).Paginate(r.filter(filter).Preload("Roles"), cursor, limit)
+}
+
+// Count returns the number of users matching a filter
+func (r *UserRepository) Count(filter *types.UserFilter) (int64, error) {
 	var count int64
-	err := r.db.Model(&model.User{}).Count(&count).Error
+	err := r.filter(filter).Count(&count).Error
 	return count, err
 }
 
-// ExistsByUsername checks if user exists by username
+// filter builds a user query narrowed by a filter
+func (r *UserRepository) filter(filter *types.UserFilter) *gorm.DB {
+	query := r.db.Model(&model.User{})
+
+	if filter.Query != "" {
+		query = r.search(query, filter.Query)
+	}
+	if filter.Role != "" {
+		query = query.Where("id IN (?)", r.db.Model(&model.Role{}).
+			Select("user_roles.user_id").
+			Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+			Where("roles.name = ?", filter.Role))
+	}
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+
+	return query
+}
+
+// ExistsByUsername checks if user exists by username, ignoring case
 func (r *UserRepository) ExistsByUsername(username string) (bool, error) {
 	var count int64
-	err := r.db.Model(&model.User{}).Where("username = ?", username).Count(&count).Error
+	err := r.db.Model(&model.User{}).Where("username_normalized = ?", model.NormalizeIdentifier(username)).Count(&count).Error
 	return count > 0, err
 }
 
-// ExistsByEmail checks if user exists by email
+// ExistsByEmail checks if user exists by email, ignoring case
 func (r *UserRepository) ExistsByEmail(email string) (bool, error) {
 	var count int64
-	err := r.db.Model(&model.User{}).Where("email = ?", email).Count(&count).Error
+	err := r.db.Model(&model.User{}).Where("email_normalized = ?", model.NormalizeIdentifier(email)).Count(&count).Error
+	return count > 0, err
+}
+
+// GetByIDUnscoped retrieves a user by ID, including soft-deleted users
+func (r *UserRepository) GetByIDUnscoped(id uint) (*model.User, error) {
+	var user model.User
+	if err := r.db.Unscoped().Preload("Roles").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, types.ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UsernameTaken checks if a username is used by another user, ignoring case.
+// Soft-deleted users keep their username until they are purged.
+func (r *UserRepository) UsernameTaken(username string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&model.User{}).
+		Where("username_normalized = ? AND id <> ?", model.NormalizeIdentifier(username), exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// EmailTaken checks if an email is used by another user, ignoring case.
+// Soft-deleted users keep their email until they are purged.
+func (r *UserRepository) EmailTaken(email string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&model.User{}).
+		Where("email_normalized = ? AND id <> ?", model.NormalizeIdentifier(email), exceptID).
+		Count(&count).Error
 	return count > 0, err
-}
\ No newline at end of file
+}
+
+// Restore undoes the soft deletion of a user
+func (r *UserRepository) Restore(id uint) error {
+	result := r.db.Unscoped().Model(&model.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return types.ErrUserNotFound
+	}
+	return nil
+}
+
+// Purge permanently deletes a user together with their credentials, sessions,
+// linked accounts and role assignments. Audit events are kept.
+func (r *UserRepository) Purge(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteUserData(tx, id); err != nil {
+			return err
+		}
+		return deleteUser(tx, id)
+	})
+}
+
+// Erase removes the personal data of a user. The user is deleted, or with
+// keepAccount replaced by a disabled, soft-deleted account without personal
+// data. Audit events are kept without the client addresses and user agents.
+func (r *UserRepository) Erase(id uint, keepAccount bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteUserData(tx, id); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.AuditEvent{}).Where("user_id = ?", id).
+			Updates(map[string]interface{}{"ip_address": "", "user_agent": ""}).Error; err != nil {
+			return err
+		}
+
+		if !keepAccount {
+			return deleteUser(tx, id)
+		}
+
+		if err := tx.Exec("DELETE FROM user_roles WHERE user_id = ?", id).Error; err != nil {
+			return err
+		}
+
+		anonymous := fmt.Sprintf("erased-%d", id)
+		now := time.Now()
+		result := tx.Unscoped().Model(&model.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+			"username":             anonymous,
+			"username_normalized":  anonymous,
+			"email":                anonymous + "@erased.invalid",
+			"email_normalized":     anonymous + "@erased.invalid",
+			"email_verified_at":    nil,
+			"pending_email":        "",
+			"password_hash":        "",
+			"password_changed_at":  nil,
+			"first_name":           "",
+			"last_name":            "",
+			"is_active":            false,
+			"mfa_secret":           "",
+			"mfa_enabled_at":       nil,
+			"mfa_last_used_step":   0,
+			"erasure_scheduled_at": nil,
+			"avatar_id":            nil,
+			"deleted_at":           gorm.Expr("COALESCE(deleted_at, ?)", now),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return types.ErrUserNotFound
+		}
+		return nil
+	})
+}
+
+// ListDueErasures retrieves users, deleted or not, whose scheduled erasure is due
+func (r *UserRepository) ListDueErasures(now time.Time) ([]*model.User, error) {
+	var users []*model.User
+	err := r.db.Unscoped().Where("erasure_scheduled_at IS NOT NULL AND erasure_scheduled_at <= ?", now).Find(&users).Error
+	return users, err
+}
+
+// deleteUserData permanently deletes the credentials, sessions, linked
+// accounts and other data tied to a user
+func deleteUserData(tx *gorm.DB, id uint) error {
+	if err := leaveOrgs(tx, id); err != nil {
+		return err
+	}
+
+	for _, owned := range []interface{}{
+		&model.RefreshToken{},
+		&model.PasswordResetToken{},
+		&model.MagicLinkToken{},
+		&model.EmailVerificationToken{},
+		&model.MFARecoveryCode{},
+		&model.APIKey{},
+		&model.UserIdentity{},
+		&model.OAuthConsent{},
+		&model.OAuthAuthorizationCode{},
+		&model.DataExport{},
+		&model.File{},
+		&model.KV{},
+	} {
+		if err := tx.Unscoped().Where("user_id = ?", id).Delete(owned).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteUser permanently deletes a user and their role assignments
+func deleteUser(tx *gorm.DB, id uint) error {
+	if err := tx.Exec("DELETE FROM user_roles WHERE user_id = ?", id).Error; err != nil {
+		return err
+	}
+
+	result := tx.Unscoped().Delete(&model.User{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return types.ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// The full-text index over usernames, emails and names. Postgres indexes
+// userSearchDocument, MySQL a FULLTEXT index and SQLite an FTS5 table kept in
+// sync by triggers. SQLite builds without FTS5 (the sqlite_fts5 build tag)
+// and other databases fall back to LIKE.
+const (
+	userSearchIndex = "idx_users_search"
+	userSearchTable = "users_fts"
+
+	// userSearchDocument splits emails and usernames into words like the
+	// search terms are split
+	userSearchDocument = `to_tsvector('simple', translate(coalesce(username, '') || ' ' || coalesce(email, '') || ' ' || coalesce(first_name, '') || ' ' || coalesce(last_name, ''), '@._-', '    '))`
+)
+
+// createUserSearchIndex creates the full-text index of the database in use
+func (m *Migrator) createUserSearchIndex() error {
+	switch m.db.Dialector.Name() {
+	case "postgres":
+		return m.db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON users USING GIN (%s)", userSearchIndex, userSearchDocument)).Error
+	case "mysql":
+		if m.db.Migrator().HasIndex("users", userSearchIndex) {
+			return nil
+		}
+		return m.db.Exec(fmt.Sprintf("CREATE FULLTEXT INDEX %s ON users (username, email, first_name, last_name)", userSearchIndex)).Error
+	case "sqlite":
+		return m.createSQLiteUserSearchIndex()
+	default:
+		return nil
+	}
+}
+
+// createSQLiteUserSearchIndex creates the FTS5 table and the triggers that keep
+// it in sync with the users table
+func (m *Migrator) createSQLiteUserSearchIndex() error {
+	var fts5 bool
+	if err := m.db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+		return err
+	}
+	if !fts5 {
+		// Without FTS5 the triggers of an index created by another build
+		// would make every write to users fail
+		for _, trigger := range []string{"insert", "update", "delete"} {
+			if err := m.db.Exec(fmt.Sprintf("DROP TRIGGER IF EXISTS %s_%s", userSearchTable, trigger)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := m.db.Exec(fmt.Sprintf(
+		"CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(username, email, first_name, last_name, content='users', content_rowid='id')",
+		userSearchTable,
+	)).Error
+	if err != nil {
+		return err
+	}
+
+	if hasSQLiteUserSearchIndex(m.db) {
+		return nil
+	}
+
+	// The index may be stale when its triggers are missing, so it is rebuilt
+	columns := "username, email, first_name, last_name"
+	statements := []string{
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %[1]s_insert AFTER INSERT ON users BEGIN "+
+			"INSERT INTO %[1]s(rowid, %[2]s) VALUES (new.id, new.username, new.email, new.first_name, new.last_name); END",
+			userSearchTable, columns),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %[1]s_delete AFTER DELETE ON users BEGIN "+
+			"INSERT INTO %[1]s(%[1]s, rowid, %[2]s) VALUES ('delete', old.id, old.username, old.email, old.first_name, old.last_name); END",
+			userSearchTable, columns),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS %[1]s_update AFTER UPDATE ON users BEGIN "+
+			"INSERT INTO %[1]s(%[1]s, rowid, %[2]s) VALUES ('delete', old.id, old.username, old.email, old.first_name, old.last_name); "+
+			"INSERT INTO %[1]s(rowid, %[2]s) VALUES (new.id, new.username, new.email, new.first_name, new.last_name); END",
+			userSearchTable, columns),
+		fmt.Sprintf("INSERT INTO %[1]s(%[1]s) VALUES ('rebuild')", userSearchTable),
+	}
+
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// hasSQLiteUserSearchIndex reports whether the FTS5 table exists and is kept
+// in sync with the users table
+func hasSQLiteUserSearchIndex(db *gorm.DB) bool {
+	var count int64
+	err := db.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'trigger' AND name = ?", userSearchTable+"_insert").
+		Scan(&count).Error
+	return err == nil && count > 0
+}
+
+// search narrows a user query to users whose username, email or names contain
+// words starting with every word of the search query
+func (r *UserRepository) search(query *gorm.DB, q string) *gorm.DB {
+	terms := searchTerms(q)
+	if len(terms) == 0 {
+		return r.searchLike(query, q)
+	}
+
+	switch r.db.Dialector.Name() {
+	case "postgres":
+		for i, term := range terms {
+			terms[i] = term + ":*"
+		}
+		return query.Where(userSearchDocument+" @@ to_tsquery('simple', ?)", strings.Join(terms, " & "))
+	case "mysql":
+		for i, term := range terms {
+			terms[i] = "+" + term + "*"
+		}
+		return query.Where("MATCH(username, email, first_name, last_name) AGAINST (? IN BOOLEAN MODE)", strings.Join(terms, " "))
+	case "sqlite":
+		if !hasSQLiteUserSearchIndex(r.db) {
+			return r.searchLike(query, q)
+		}
+		for i, term := range terms {
+			terms[i] = `"` + term + `"*`
+		}
+		return query.Where(fmt.Sprintf("id IN (SELECT rowid FROM %[1]s WHERE %[1]s MATCH ?)", userSearchTable), strings.Join(terms, " "))
+	default:
+		return r.searchLike(query, q)
+	}
+}
+
+// searchLike narrows a user query to users whose username, email or names
+// contain every word of the search query, without an index
+func (r *UserRepository) searchLike(query *gorm.DB, q string) *gorm.DB {
+	escaper := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	for _, term := range strings.Fields(strings.ToLower(q)) {
+		pattern := "%" + escaper.Replace(term) + "%"
+		query = query.Where(
+			"username_normalized LIKE ? ESCAPE '!' OR email_normalized LIKE ? ESCAPE '!' OR LOWER(first_name) LIKE ? ESCAPE '!' OR LOWER(last_name) LIKE ? ESCAPE '!'",
+			pattern, pattern, pattern, pattern,
+		)
+	}
+	return query
+}
+
+// searchTerms splits a search query into lowercase words of letters and digits,
+// which need no escaping in any full-text query syntax
+func searchTerms(q string) []string {
+	return strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
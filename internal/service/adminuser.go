@@ -0,0 +1,373 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/validator"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// AdminUserService handles user management by administrators
+type AdminUserService struct {
+	userRepo        *repository.UserRepository
+	userService     *UserService
+	rbacService     *RBACService
+	passwordService *PasswordService
+	fileService     *FileService
+	auditService    *AuditService
+	validator       *validator.Validator
+}
+
+// NewAdminUserService creates a new admin user service
+func NewAdminUserService(
+	userRepo *repository.UserRepository,
+	userService *UserService,
+	rbacService *RBACService,
+	passwordService *PasswordService,
+	fileService *FileService,
+	auditService *AuditService,
+	validator *validator.Validator,
+) *AdminUserService {
+	return &AdminUserService{
+		userRepo:        userRepo,
+		userService:     userService,
+		rbacService:     rbacService,
+		passwordService: passwordService,
+		fileService:     fileService,
+		auditService:    auditService,
+		validator:       validator,
+	}
+}
+
+// Get retrieves a user, including soft-deleted users
+func (s *AdminUserService) Get(id uint) (*model.User, error) {
+	return s.userRepo.GetByIDUnscoped(id)
+}
+
+// Create creates a user on behalf of an administrator, who can only grant roles
+// whose permissions they hold. Without a password the user is mailed a link to
+// choose one.
+func (s *AdminUserService) Create(adminID uint, req *types.AdminCreateUserRequest, client *types.ClientInfo) (*model.User, error) {
+	if err := s.validateIdentity(0, req.Username, req.Email); err != nil {
+		return nil, err
+	}
+	if err := s.validateNames(&req.FirstName, &req.LastName); err != nil {
+		return nil, err
+	}
+	if req.Password != "" {
+		if err := s.validator.ValidatePassword(req.Password); err != nil {
+			return nil, fmt.Errorf("%w: %s", types.ErrValidationFailed, err)
+		}
+	}
+	if len(req.Roles) > 0 {
+		if err := s.rbacService.CheckGrantable(adminID, req.Roles); err != nil {
+			return nil, err
+		}
+	}
+
+	user := &model.User{
+		Username:  req.Username,
+		Email:     req.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		IsActive:  req.IsActive == nil || *req.IsActive,
+	}
+	if req.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if req.Password != "" {
+		hashedPassword, err := s.userService.hashPassword(req.Password)
+		if err != nil {
+			return nil, err
+		}
+		user.PasswordHash = hashedPassword
+	}
+
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if len(req.Roles) > 0 {
+		user, err = s.rbacService.SetUserRoles(user.ID, &types.SetUserRolesRequest{Roles: req.Roles})
+	} else {
+		err = s.rbacService.AssignDefaultRole(user)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.RecordBy(adminID, user.ID, model.AuditEventUserCreated, client, nil)
+
+	if req.Password == "" && user.IsActive {
+		if err := s.passwordService.sendResetLink(user); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.userRepo.GetByID(user.ID)
+}
+
+// Update applies an administrator's changes to a user. Disabling a user signs
+// them out everywhere; role changes apply from the user's next token refresh.
+func (s *AdminUserService) Update(adminID, id uint, req *types.AdminUpdateUserRequest, client *types.ClientInfo) (*model.User, error) {
+	if adminID == id && ((req.IsActive != nil && !*req.IsActive) || req.Roles != nil) {
+		return nil, types.ErrOwnAccount
+	}
+
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.rbacService.CheckOutranks(adminID, id); err != nil {
+		return nil, err
+	}
+
+	username, email := user.Username, user.Email
+	if req.Username != nil {
+		username = *req.Username
+	}
+	if req.Email != nil {
+		email = *req.Email
+	}
+	if err := s.validateIdentity(id, username, email); err != nil {
+		return nil, err
+	}
+	if err := s.validateNames(req.FirstName, req.LastName); err != nil {
+		return nil, err
+	}
+	if req.Roles != nil {
+		if err := s.rbacService.CheckGrantable(adminID, *req.Roles); err != nil {
+			return nil, err
+		}
+	}
+
+	var changed []string
+	if username != user.Username {
+		user.Username = username
+		changed = append(changed, "username")
+	}
+	if email != user.Email {
+		user.Email = email
+		user.EmailVerifiedAt = nil
+		user.PendingEmail = ""
+		changed = append(changed, "email")
+	}
+	if req.FirstName != nil && *req.FirstName != user.FirstName {
+		user.FirstName = *req.FirstName
+		changed = append(changed, "first_name")
+	}
+	if req.LastName != nil && *req.LastName != user.LastName {
+		user.LastName = *req.LastName
+		changed = append(changed, "last_name")
+	}
+	if req.EmailVerified != nil && *req.EmailVerified != (user.EmailVerifiedAt != nil) {
+		user.EmailVerifiedAt = nil
+		if *req.EmailVerified {
+			now := time.Now()
+			user.EmailVerifiedAt = &now
+		}
+		changed = append(changed, "email_verified")
+	}
+
+	deactivated := false
+	if req.IsActive != nil && *req.IsActive != user.IsActive {
+		user.IsActive = *req.IsActive
+		deactivated = !user.IsActive
+		changed = append(changed, "is_active")
+	}
+
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	if req.Roles != nil {
+		if _, err := s.rbacService.SetUserRoles(id, &types.SetUserRolesRequest{Roles: *req.Roles}); err != nil {
+			return nil, err
+		}
+		changed = append(changed, "roles")
+	}
+
+	if deactivated {
+		if err := s.userService.RevokeAllSessions(id); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(changed) > 0 {
+		s.auditService.RecordBy(adminID, id, model.AuditEventUserUpdated, client, map[string]interface{}{
+			"fields": changed,
+		})
+	}
+
+	return s.userRepo.GetByID(id)
+}
+
+// ForcePasswordReset invalidates a user's password, signs them out everywhere
+// and mails them a link to choose a new one
+func (s *AdminUserService) ForcePasswordReset(adminID, id uint, client *types.ClientInfo) error {
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.rbacService.CheckOutranks(adminID, id); err != nil {
+		return err
+	}
+
+	if err := s.passwordService.ForceReset(user); err != nil {
+		return err
+	}
+
+	s.auditService.RecordBy(adminID, id, model.AuditEventPasswordResetForced, client, nil)
+	return nil
+}
+
+// RevokeSessions signs a user out of all devices
+func (s *AdminUserService) RevokeSessions(adminID, id uint, client *types.ClientInfo) error {
+	if _, err := s.userRepo.GetByID(id); err != nil {
+		return err
+	}
+	if err := s.rbacService.CheckOutranks(adminID, id); err != nil {
+		return err
+	}
+
+	if err := s.userService.RevokeAllSessions(id); err != nil {
+		return err
+	}
+
+	s.auditService.RecordBy(adminID, id, model.AuditEventSessionsRevoked, client, nil)
+	return nil
+}
+
+// Delete soft deletes a user, who can be restored until purged
+func (s *AdminUserService) Delete(adminID, id uint, client *types.ClientInfo) error {
+	if adminID == id {
+		return types.ErrOwnAccount
+	}
+	if _, err := s.userRepo.GetByID(id); err != nil {
+		return err
+	}
+	if err := s.rbacService.CheckOutranks(adminID, id); err != nil {
+		return err
+	}
+
+	if err := s.userService.Delete(id); err != nil {
+		return err
+	}
+
+	s.auditService.RecordBy(adminID, id, model.AuditEventUserDeleted, client, nil)
+	return nil
+}
+
+// Restore restores a soft-deleted user. Their sessions stay revoked.
+func (s *AdminUserService) Restore(adminID, id uint, client *types.ClientInfo) (*model.User, error) {
+	user, err := s.userRepo.GetByIDUnscoped(id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.DeletedAt.Valid {
+		return nil, types.ErrUserNotDeleted
+	}
+	if err := s.rbacService.CheckOutranks(adminID, id); err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.Restore(id); err != nil {
+		return nil, err
+	}
+
+	s.auditService.RecordBy(adminID, id, model.AuditEventUserRestored, client, nil)
+	return s.userRepo.GetByID(id)
+}
+
+// Purge permanently deletes a user and everything tied to their account
+// except the audit trail
+func (s *AdminUserService) Purge(ctx context.Context, adminID, id uint, client *types.ClientInfo) error {
+	if adminID == id {
+		return types.ErrOwnAccount
+	}
+
+	user, err := s.userRepo.GetByIDUnscoped(id)
+	if err != nil {
+		return err
+	}
+	if err := s.rbacService.CheckOutranks(adminID, id); err != nil {
+		return err
+	}
+
+	// Deny access tokens that are still valid
+	if err := s.userService.RevokeAllSessions(id); err != nil {
+		return err
+	}
+
+	if err := s.fileService.DeleteByUser(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.Purge(id); err != nil {
+		return err
+	}
+
+	s.auditService.RecordBy(adminID, id, model.AuditEventUserPurged, client, map[string]interface{}{
+		"username": user.Username,
+	})
+	return nil
+}
+
+// ToResponse converts a User model to an AdminUserResponse
+func (s *AdminUserService) ToResponse(user *model.User) *types.AdminUserResponse {
+	response := &types.AdminUserResponse{
+		UserResponse:          *s.userService.ToResponse(user),
+		PasswordResetRequired: user.PasswordHash == "",
+		PasswordChangedAt:     user.PasswordChangedAt,
+	}
+	if user.DeletedAt.Valid {
+		response.DeletedAt = &user.DeletedAt.Time
+	}
+	return response
+}
+
+// validateIdentity validates a username and email and makes sure no other user
+// has them
+func (s *AdminUserService) validateIdentity(id uint, username, email string) error {
+	if err := s.validator.ValidateUsername(username); err != nil {
+		return fmt.Errorf("%w: %s", types.ErrValidationFailed, err)
+	}
+	if err := s.validator.ValidateEmail(email); err != nil {
+		return fmt.Errorf("%w: %s", types.ErrValidationFailed, err)
+	}
+
+	if taken, err := s.userRepo.UsernameTaken(username, id); err != nil {
+		return err
+	} else if taken {
+		return types.ErrUserAlreadyExists
+	}
+
+	if taken, err := s.userRepo.EmailTaken(email, id); err != nil {
+		return err
+	} else if taken {
+		return types.ErrUserAlreadyExists
+	}
+
+	return nil
+}
+
+// validateNames validates the names that are being set
+func (s *AdminUserService) validateNames(firstName, lastName *string) error {
+	if firstName != nil {
+		if err := s.validator.ValidateName(*firstName, "first name"); err != nil {
+			return fmt.Errorf("%w: %s", types.ErrValidationFailed, err)
+		}
+	}
+	if lastName != nil {
+		if err := s.validator.ValidateName(*lastName, "last name"); err != nil {
+			return fmt.Errorf("%w: %s", types.ErrValidationFailed, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// adminClient is the client administrators act from in tests
+var adminClient = &types.ClientInfo{IPAddress: "127.0.0.1", UserAgent: "admin-test"}
+
+func TestAdminUserDeleteRefusals(t *testing.T) {
+	var adminUsers *service.AdminUserService
+	env := newRBACTestEnv(t, &adminUsers)
+
+	tests := []struct {
+		name   string
+		userID uint
+		want   error
+	}{
+		{"own account", env.moderator.ID, types.ErrOwnAccount},
+		{"higher-ranked user", env.admin.ID, types.ErrOutranked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := adminUsers.Delete(env.moderator.ID, tt.userID, adminClient); !errors.Is(err, tt.want) {
+				t.Fatalf("Delete returned %v, want %v", err, tt.want)
+			}
+			if _, err := env.users.GetByID(tt.userID); err != nil {
+				t.Errorf("user was deleted: %v", err)
+			}
+		})
+	}
+}
+
+func TestAdminUserUpdateRefusals(t *testing.T) {
+	var adminUsers *service.AdminUserService
+	env := newRBACTestEnv(t, &adminUsers)
+
+	inactive := false
+	roles := []string{model.RoleUser}
+	name := "Renamed"
+
+	tests := []struct {
+		name   string
+		userID uint
+		req    *types.AdminUpdateUserRequest
+		want   error
+	}{
+		{"deactivate own account", env.moderator.ID, &types.AdminUpdateUserRequest{IsActive: &inactive}, types.ErrOwnAccount},
+		{"change own roles", env.moderator.ID, &types.AdminUpdateUserRequest{Roles: &roles}, types.ErrOwnAccount},
+		{"deactivate a higher-ranked user", env.admin.ID, &types.AdminUpdateUserRequest{IsActive: &inactive}, types.ErrOutranked},
+		{"rename a higher-ranked user", env.admin.ID, &types.AdminUpdateUserRequest{FirstName: &name}, types.ErrOutranked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := adminUsers.Update(env.moderator.ID, tt.userID, tt.req, adminClient); !errors.Is(err, tt.want) {
+				t.Fatalf("Update returned %v, want %v", err, tt.want)
+			}
+			user, err := env.users.GetByID(tt.userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !user.IsActive || user.FirstName == name {
+				t.Errorf("user was changed: active %v, first name %q", user.IsActive, user.FirstName)
+			}
+		})
+	}
+}
+
+func TestAdminUserActionsAreNotImpersonation(t *testing.T) {
+	var adminUsers *service.AdminUserService
+	var audits *repository.AuditRepository
+	env := newRBACTestEnv(t, &adminUsers, &audits)
+	user := newTestUser(t, env.users, "user")
+
+	if err := adminUsers.Delete(env.moderator.ID, user.ID, adminClient); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	events, err := audits.ListByUser(user.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Event != model.AuditEventUserDeleted {
+		t.Fatalf("recorded %d events, want one %s event", len(events), model.AuditEventUserDeleted)
+	}
+	if event := events[0]; event.ActorID == nil || *event.ActorID != env.moderator.ID || event.Impersonated {
+		t.Errorf("event actor %v, impersonated %v; want %d acting directly", event.ActorID, event.Impersonated, env.moderator.ID)
+	}
+}
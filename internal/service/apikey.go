@@ -0,0 +1,243 @@
+package service
+
+import (
+	"crypto/rand"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+const (
+	// apiKeyPrefix starts every API key so that it can be told apart from a JWT
+	apiKeyPrefix = "gem_"
+	// apiKeyDisplayLength is the number of leading characters kept to identify a key
+	apiKeyDisplayLength = len(apiKeyPrefix) + 8
+	// apiKeyTouchInterval limits how often last-used tracking writes to the database
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyService handles personal access token business logic
+type APIKeyService struct {
+	cfg         *config.AuthConfig
+	apiKeyRepo  *repository.APIKeyRepository
+	userRepo    *repository.UserRepository
+	rbacService *RBACService
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(cfg *config.AuthConfig, apiKeyRepo *repository.APIKeyRepository, userRepo *repository.UserRepository, rbacService *RBACService) *APIKeyService {
+	return &APIKeyService{
+		cfg:         cfg,
+		apiKeyRepo:  apiKeyRepo,
+		userRepo:    userRepo,
+		rbacService: rbacService,
+	}
+}
+
+// Create creates an API key for a user. The returned key is never retrievable again.
+func (s *APIKeyService) Create(userID uint, req *types.CreateAPIKeyRequest) (*types.APIKeyCreatedResponse, error) {
+	if err := s.validateName(req.Name); err != nil {
+		return nil, err
+	}
+
+	scopes, err := s.validateScopes(userID, req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expiry must be in the future", types.ErrValidationFailed)
+	}
+
+	count, err := s.apiKeyRepo.CountByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= int64(s.cfg.APIKeyMaxPerUser) {
+		return nil, fmt.Errorf("%w: at most %d API keys are allowed", types.ErrValidationFailed, s.cfg.APIKeyMaxPerUser)
+	}
+
+	secret := apiKeyPrefix + rand.Text() + rand.Text()
+	key := &model.APIKey{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    secret[:apiKeyDisplayLength],
+		KeyHash:   hashToken(secret),
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	if err := s.apiKeyRepo.Create(key); err != nil {
+		return nil, err
+	}
+
+	return &types.APIKeyCreatedResponse{
+		APIKeyResponse: *s.ToResponse(key),
+		Key:            secret,
+	}, nil
+}
+
+// List lists the API keys of a user
+func (s *APIKeyService) List(userID uint) ([]*types.APIKeyResponse, error) {
+	keys, err := s.apiKeyRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*types.APIKeyResponse, len(keys))
+	for i, key := range keys {
+		responses[i] = s.ToResponse(key)
+	}
+	return responses, nil
+}
+
+// Get retrieves an API key of a user
+func (s *APIKeyService) Get(userID, id uint) (*types.APIKeyResponse, error) {
+	key, err := s.apiKeyRepo.GetByUserAndID(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.ToResponse(key), nil
+}
+
+// Update renames an API key or changes its scopes
+func (s *APIKeyService) Update(userID, id uint, req *types.UpdateAPIKeyRequest) (*types.APIKeyResponse, error) {
+	key, err := s.apiKeyRepo.GetByUserAndID(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		if err := s.validateName(*req.Name); err != nil {
+			return nil, err
+		}
+		key.Name = *req.Name
+	}
+
+	if req.Scopes != nil {
+		scopes, err := s.validateScopes(userID, *req.Scopes)
+		if err != nil {
+			return nil, err
+		}
+		key.Scopes = strings.Join(scopes, " ")
+	}
+
+	if err := s.apiKeyRepo.Update(key); err != nil {
+		return nil, err
+	}
+	return s.ToResponse(key), nil
+}
+
+// Delete revokes an API key of a user
+func (s *APIKeyService) Delete(userID, id uint) error {
+	return s.apiKeyRepo.Delete(userID, id)
+}
+
+// IsAPIKey reports whether a bearer credential is an API key rather than a JWT
+func (s *APIKeyService) IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, apiKeyPrefix)
+}
+
+// AuthenticateAPIKey resolves an API key to the user it acts for. The key may
+// only use the permissions in its scopes that the user currently holds.
+func (s *APIKeyService) AuthenticateAPIKey(secret string, client *types.ClientInfo) (*types.APIKeyPrincipal, error) {
+	key, err := s.apiKeyRepo.GetByHash(hashToken(secret))
+	if err != nil {
+		if err == types.ErrAPIKeyNotFound {
+			return nil, types.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if key.ExpiresAt != nil && key.ExpiresAt.Before(now) {
+		return nil, types.ErrTokenExpired
+	}
+
+	user, err := s.userRepo.GetByID(key.UserID)
+	if err != nil {
+		if err == types.ErrUserNotFound {
+			return nil, types.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, types.ErrForbidden
+	}
+
+	roles, granted, err := s.rbacService.Grants(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var permissions []string
+	for _, scope := range strings.Fields(key.Scopes) {
+		if slices.Contains(granted, scope) {
+			permissions = append(permissions, scope)
+		}
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.apiKeyRepo.Touch(key.ID, client.IPAddress, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return &types.APIKeyPrincipal{
+		KeyID:       key.ID,
+		UserID:      user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		Roles:       roles,
+		Permissions: permissions,
+	}, nil
+}
+
+// ToResponse converts an APIKey model to an APIKeyResponse
+func (s *APIKeyService) ToResponse(key *model.APIKey) *types.APIKeyResponse {
+	return &types.APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     append([]string{}, strings.Fields(key.Scopes)...),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		LastUsedIP: key.LastUsedIP,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+// validateName checks the display name of an API key
+func (s *APIKeyService) validateName(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", types.ErrValidationFailed)
+	}
+	if len(name) > 100 {
+		return fmt.Errorf("%w: name must be at most 100 characters", types.ErrValidationFailed)
+	}
+	return nil
+}
+
+// validateScopes checks that a user holds every requested scope
+func (s *APIKeyService) validateScopes(userID uint, scopes []string) ([]string, error) {
+	_, granted, err := s.rbacService.Grants(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes = uniqueStrings(scopes)
+	for _, scope := range scopes {
+		if !slices.Contains(granted, scope) {
+			return nil, fmt.Errorf("%w: scope %q is not granted to you", types.ErrValidationFailed, scope)
+		}
+	}
+	return scopes, nil
+}
@@ -0,0 +1,72 @@
+package service_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/app"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+)
+
+// newTestApp builds the application on a fresh, migrated database and
+// populates targets, pointers to the services and repositories a test uses.
+// Configuration is read from the environment, so tests can set APP_ variables
+// before calling it.
+func newTestApp(t *testing.T, targets ...interface{}) {
+	t.Helper()
+
+	dir := t.TempDir()
+	t.Setenv("APP_DATABASE_TYPE", "sqlite")
+	t.Setenv("APP_DATABASE_DATABASE", filepath.Join(dir, "app.db"))
+	t.Setenv("APP_STORAGE_DRIVER", "local")
+	t.Setenv("APP_STORAGE_LOCAL_DIR", filepath.Join(dir, "storage"))
+	t.Setenv("APP_MAILER_DRIVER", "log")
+	t.Setenv("APP_LOGGER_LEVEL", "error")
+
+	var db *gorm.DB
+	var migrator *repository.Migrator
+	var kvRepo *repository.KVRepository
+	application := fx.New(
+		app.Container,
+		fx.NopLogger,
+		fx.Populate(append([]interface{}{&db, &migrator, &kvRepo}, targets...)...),
+	)
+	if err := application.Err(); err != nil {
+		t.Fatalf("failed to build application: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := migrator.AutoMigrate(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	if err := kvRepo.Set(model.SettingAllowRegister, "true"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestUser creates an active user with a verified email address
+func newTestUser(t *testing.T, users *repository.UserRepository, username string) *model.User {
+	t.Helper()
+
+	now := time.Now()
+	user := &model.User{
+		Username:        username,
+		Email:           username + "@example.com",
+		EmailVerifiedAt: &now,
+		PasswordHash:    "unused",
+		IsActive:        true,
+	}
+	if err := users.Create(user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
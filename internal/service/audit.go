@@ -0,0 +1,90 @@
+package service
+
+import (
+	"encoding/json"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/logger"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+	"go.uber.org/zap"
+)
+
+// AuditService records security-relevant events
+type AuditService struct {
+	auditRepo *repository.AuditRepository
+	logger    *logger.Logger
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(auditRepo *repository.AuditRepository, logger *logger.Logger) *AuditService {
+	return &AuditService{
+		auditRepo: auditRepo,
+		logger:    logger,
+	}
+}
+
+// Record stores an audit event and writes it to the log. Failures are logged
+// rather than returned so that auditing never breaks the audited operation.
+func (s *AuditService) Record(userID uint, event string, client *types.ClientInfo, details map[string]interface{}) {
+	s.record(nil, false, userID, event, client, details)
+}
+
+// RecordBy records an audit event for something another user, such as an
+// administrator, did to a user
+func (s *AuditService) RecordBy(actorID, userID uint, event string, client *types.ClientInfo, details map[string]interface{}) {
+	s.record(&actorID, false, userID, event, client, details)
+}
+
+// RecordAs records an audit event for something an administrator did while
+// impersonating a user
+func (s *AuditService) RecordAs(actorID, userID uint, event string, client *types.ClientInfo, details map[string]interface{}) {
+	s.record(&actorID, true, userID, event, client, details)
+}
+
+// RecordImpersonatedRequest records a request made with an impersonation token
+func (s *AuditService) RecordImpersonatedRequest(actorID, userID uint, method, path string, status int, client *types.ClientInfo) {
+	s.RecordAs(actorID, userID, model.AuditEventImpersonatedRequest, client, map[string]interface{}{
+		"method": method,
+		"path":   path,
+		"status": status,
+	})
+}
+
+// record stores and logs an audit event, optionally performed by an actor
+// directly or while impersonating the user
+func (s *AuditService) record(actorID *uint, impersonated bool, userID uint, event string, client *types.ClientInfo, details map[string]interface{}) {
+	auditEvent := &model.AuditEvent{
+		UserID:       userID,
+		Event:        event,
+		ActorID:      actorID,
+		Impersonated: impersonated,
+	}
+	if client != nil {
+		auditEvent.IPAddress = client.IPAddress
+		auditEvent.UserAgent = client.UserAgent
+	}
+
+	if len(details) > 0 {
+		data, err := json.Marshal(details)
+		if err != nil {
+			s.logger.Error("Failed to encode audit event details", zap.String("event", event), zap.Error(err))
+		}
+		auditEvent.Details = string(data)
+	}
+
+	fields := []zap.Field{
+		zap.String("event", event),
+		zap.Uint("user_id", userID),
+		zap.String("ip", auditEvent.IPAddress),
+		zap.String("details", auditEvent.Details),
+	}
+	if actorID != nil {
+		fields = append(fields, zap.Uint("actor_id", *actorID), zap.Bool("impersonated", impersonated))
+	}
+	s.logger.Info("Audit event", fields...)
+
+	if err := s.auditRepo.Create(auditEvent); err != nil {
+		s.logger.Error("Failed to record audit event", zap.String("event", event), zap.Error(err))
+	}
+}
@@ -3,44 +3,91 @@ package service
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
 	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/denylist"
 	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/jwt"
 	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/validator"
 	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
 	"github.com/ray-d-song/go-echo-monolithic/internal/types"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // AuthService handles authentication business logic
 type AuthService struct {
-	userRepo    *repository.UserRepository
-	authRepo    *repository.AuthRepository
-	jwtManager  *jwt.Manager
-	validator   *validator.Validator
-	userService *UserService
+	cfg                 *config.AuthConfig
+	userRepo            *repository.UserRepository
+	authRepo            *repository.AuthRepository
+	jwtManager          *jwt.Manager
+	validator           *validator.Validator
+	userService         *UserService
+	verificationService *VerificationService
+	mfaService          *MFAService
+	auditService        *AuditService
+	denylist            *denylist.Denylist
+	lockoutService      *LockoutService
+	rbacService         *RBACService
+	invitationService   *InvitationService
+	kvRepo              *repository.KVRepository
+	orgRepo             *repository.OrganizationRepository
 }
 
 // NewAuthService creates a new auth service
 func NewAuthService(
+	cfg *config.AuthConfig,
 	userRepo *repository.UserRepository,
 	authRepo *repository.AuthRepository,
 	jwtManager *jwt.Manager,
 	validator *validator.Validator,
 	userService *UserService,
+	verificationService *VerificationService,
+	mfaService *MFAService,
+	auditService *AuditService,
+	denylist *denylist.Denylist,
+	lockoutService *LockoutService,
+	rbacService *RBACService,
+	invitationService *InvitationService,
+	kvRepo *repository.KVRepository,
+	orgRepo *repository.OrganizationRepository,
 ) *AuthService {
 	return &AuthService{
-		userRepo:    userRepo,
-		authRepo:    authRepo,
-		jwtManager:  jwtManager,
-		validator:   validator,
-		userService: userService,
+		cfg:                 cfg,
+		userRepo:            userRepo,
+		authRepo:            authRepo,
+		jwtManager:          jwtManager,
+		validator:           validator,
+		userService:         userService,
+		verificationService: verificationService,
+		mfaService:          mfaService,
+		auditService:        auditService,
+		denylist:            denylist,
+		lockoutService:      lockoutService,
+		rbacService:         rbacService,
+		invitationService:   invitationService,
+		kvRepo:              kvRepo,
+		orgRepo:             orgRepo,
 	}
 }
 
-// Register registers a new user
-func (s *AuthService) Register(req *types.RegisterRequest) (*types.AuthResponse, error) {
+// Register registers a new user. While self-service registration is closed a
+// valid invitation code is required.
+func (s *AuthService) Register(req *types.RegisterRequest, client *types.ClientInfo) (*types.AuthResponse, error) {
+	if req.InviteCode == "" {
+		open, err := s.kvRepo.GetBool(model.SettingAllowRegister)
+		if err != nil {
+			return nil, err
+		}
+		if !open {
+			return nil, types.ErrRegistrationClosed
+		}
+	}
+
 	// Validate input
 	if err := s.validator.ValidateUsername(req.Username); err != nil {
 		return nil, err
@@ -58,25 +105,34 @@ func (s *AuthService) Register(req *types.RegisterRequest) (*types.AuthResponse,
 		return nil, err
 	}
 
-	// Check if user already exists
-	if exists, err := s.userRepo.ExistsByUsername(req.Username); err != nil {
+	// Check if user already exists. Soft-deleted users keep their username
+	// and email until they are purged.
+	if taken, err := s.userRepo.UsernameTaken(req.Username, 0); err != nil {
 		return nil, err
-	} else if exists {
+	} else if taken {
 		return nil, types.ErrUserAlreadyExists
 	}
 
-	if exists, err := s.userRepo.ExistsByEmail(req.Email); err != nil {
+	if taken, err := s.userRepo.EmailTaken(req.Email, 0); err != nil {
 		return nil, err
-	} else if exists {
+	} else if taken {
 		return nil, types.ErrUserAlreadyExists
 	}
 
 	// Hash password
-	hashedPassword, err := s.hashPassword(req.Password)
+	hashedPassword, err := s.userService.hashPassword(req.Password)
 	if err != nil {
 		return nil, err
 	}
 
+	var invitation *model.Invitation
+	if req.InviteCode != "" {
+		invitation, err = s.invitationService.Consume(req.InviteCode, req.Email)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Create user
 	user := &model.User{
 		Username:     req.Username,
@@ -88,36 +144,43 @@ func (s *AuthService) Register(req *types.RegisterRequest) (*types.AuthResponse,
 	}
 
 	if err := s.userRepo.Create(user); err != nil {
+		if invitation != nil {
+			_ = s.invitationService.Release(invitation)
+		}
 		return nil, err
 	}
 
-	// Generate tokens
-	tokenPair, err := s.jwtManager.GenerateTokenPair(user.ID, user.Username, user.Email)
+	if invitation != nil && invitation.Role != nil {
+		err = s.rbacService.AssignRole(user, invitation.Role.Name)
+	} else {
+		err = s.rbacService.AssignDefaultRole(user)
+	}
 	if err != nil {
+		// Undo the registration, so that the username, email and invitation
+		// can be used again
+		_ = s.userRepo.Purge(user.ID)
+		if invitation != nil {
+			_ = s.invitationService.Release(invitation)
+		}
 		return nil, err
 	}
 
-	// Store refresh token
-	refreshToken := &model.RefreshToken{
-		UserID:    user.ID,
-		Token:     tokenPair.RefreshToken,
-		ExpiresAt: time.Now().Add(s.jwtManager.GetRefreshTokenDuration()),
-		IsRevoked: false,
-	}
+	// A failed delivery is logged by the verification service and must not fail
+	// the registration; the user can request a new link
+	_ = s.verificationService.SendVerification(user, user.Email)
 
-	if err := s.authRepo.CreateRefreshToken(refreshToken); err != nil {
-		return nil, err
+	// Unverified users cannot sign in yet, so don't hand out tokens either
+	if s.cfg.RequireEmailVerification {
+		return &types.AuthResponse{
+			User: s.userService.ToResponse(user),
+		}, nil
 	}
 
-	return &types.AuthResponse{
-		User:         s.userService.ToResponse(user),
-		AccessToken:  tokenPair.AccessToken,
-		RefreshToken: tokenPair.RefreshToken,
-	}, nil
+	return s.issueAuthResponse(user, client)
 }
 
 // Login authenticates a user and returns tokens
-func (s *AuthService) Login(req *types.LoginRequest) (*types.AuthResponse, error) {
+func (s *AuthService) Login(req *types.LoginRequest, client *types.ClientInfo) (*types.AuthResponse, error) {
 	// Validate input
 	if req.Username == "" {
 		return nil, types.ErrValidationFailed
@@ -126,102 +189,255 @@ func (s *AuthService) Login(req *types.LoginRequest) (*types.AuthResponse, error
 		return nil, types.ErrValidationFailed
 	}
 
-	// Get user by username
-	user, err := s.userRepo.GetByUsername(req.Username)
-	if err != nil {
-		if err == types.ErrUserNotFound {
-			return nil, types.ErrInvalidCredentials
-		}
+	// Get user by username or email
+	user, err := s.userRepo.GetByLogin(req.Username)
+	if err != nil && err != types.ErrUserNotFound {
 		return nil, err
 	}
 
+	// Attempts are throttled per account, whichever identifier was entered
+	account := req.Username
+	if user != nil {
+		account = user.Username
+	}
+
+	// Refuse attempts while the account or client IP is locked out
+	if err := s.lockoutService.Check(account, client); err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		if err := s.lockoutService.RecordFailure(account, 0, client); err != nil {
+			return nil, err
+		}
+		return nil, types.ErrInvalidCredentials
+	}
+
 	// Check if user is active
 	if !user.IsActive {
 		return nil, types.ErrForbidden
 	}
 
 	// Verify password
-	if !s.verifyPassword(user.PasswordHash, req.Password) {
+	if !s.userService.verifyPassword(user.PasswordHash, req.Password) {
+		if err := s.lockoutService.RecordFailure(user.Username, user.ID, client); err != nil {
+			return nil, err
+		}
 		return nil, types.ErrInvalidCredentials
 	}
 
-	// Generate tokens
-	tokenPair, err := s.jwtManager.GenerateTokenPair(user.ID, user.Username, user.Email)
+	// Move hashes created with an older algorithm or parameters to the current ones
+	if err := s.userService.UpgradePasswordHash(user, req.Password); err != nil {
+		return nil, err
+	}
+
+	if s.cfg.RequireEmailVerification && user.EmailVerifiedAt == nil {
+		return nil, types.ErrEmailNotVerified
+	}
+
+	return s.completeLogin(user, client)
+}
+
+// completeLogin finishes the login of a user whose first factor was verified,
+// either by issuing tokens or by asking for the second factor
+func (s *AuthService) completeLogin(user *model.User, client *types.ClientInfo) (*types.AuthResponse, error) {
+	// Users with two-factor authentication enabled must complete a second step,
+	// which is throttled as well
+	if user.MFAEnabledAt != nil {
+		mfaToken, err := s.jwtManager.GenerateMFAPendingToken(user.ID, user.Username, user.Email)
+		if err != nil {
+			return nil, err
+		}
+
+		return &types.AuthResponse{
+			User:        s.userService.ToResponse(user),
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		}, nil
+	}
+
+	if err := s.lockoutService.RecordSuccess(user.Username); err != nil {
+		return nil, err
+	}
+
+	return s.issueAuthResponse(user, client)
+}
+
+// VerifyMFA completes a two-factor login by exchanging an mfa_pending token and
+// a valid code for a token pair
+func (s *AuthService) VerifyMFA(req *types.MFAVerifyRequest, client *types.ClientInfo) (*types.AuthResponse, error) {
+	claims, err := s.jwtManager.ValidateMFAPendingToken(req.MFAToken)
+	if err != nil {
+		return nil, types.ErrInvalidToken
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Store refresh token
-	refreshToken := &model.RefreshToken{
-		UserID:    user.ID,
-		Token:     tokenPair.RefreshToken,
-		ExpiresAt: time.Now().Add(s.jwtManager.GetRefreshTokenDuration()),
-		IsRevoked: false,
+	if !user.IsActive {
+		return nil, types.ErrForbidden
 	}
 
-	if err := s.authRepo.CreateRefreshToken(refreshToken); err != nil {
+	if err := s.lockoutService.Check(user.Username, client); err != nil {
 		return nil, err
 	}
 
-	return &types.AuthResponse{
-		User:         s.userService.ToResponse(user),
+	valid, err := s.mfaService.VerifyCode(user, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		if err := s.lockoutService.RecordFailure(user.Username, user.ID, client); err != nil {
+			return nil, err
+		}
+		return nil, types.ErrInvalidMFACode
+	}
+
+	if err := s.lockoutService.RecordSuccess(user.Username); err != nil {
+		return nil, err
+	}
+
+	return s.issueAuthResponse(user, client)
+}
+
+// RefreshToken generates new tokens using refresh token. The presented token is
+// rotated: it is revoked and replaced by a new token in the same family.
+func (s *AuthService) RefreshToken(req *types.RefreshTokenRequest, client *types.ClientInfo) (*types.TokenResponse, error) {
+	tokenPair, _, err := s.rotateRefreshToken(req.RefreshToken, "", client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.TokenResponse{
 		AccessToken:  tokenPair.AccessToken,
 		RefreshToken: tokenPair.RefreshToken,
 	}, nil
 }
 
-// RefreshToken generates new tokens using refresh token
-func (s *AuthService) RefreshToken(req *types.RefreshTokenRequest) (*types.TokenResponse, error) {
+// RefreshClientToken rotates a refresh token issued to an OAuth client and
+// returns the new token pair along with the scopes granted to the client
+func (s *AuthService) RefreshClientToken(refreshToken, clientID string, client *types.ClientInfo) (*jwt.TokenPair, string, error) {
+	tokenPair, storedToken, err := s.rotateRefreshToken(refreshToken, clientID, client)
+	if err != nil {
+		return nil, "", err
+	}
+	return tokenPair, storedToken.Scope, nil
+}
+
+// rotateRefreshToken revokes a refresh token and issues a new token pair in the
+// same family. Tokens can only be refreshed by the OAuth client they were issued
+// to; clientID is empty for the application's own logins.
+func (s *AuthService) rotateRefreshToken(refreshToken, clientID string, client *types.ClientInfo) (*jwt.TokenPair, *model.RefreshToken, error) {
 	// Validate refresh token
-	claims, err := s.jwtManager.ValidateRefreshToken(req.RefreshToken)
+	claims, err := s.jwtManager.ValidateRefreshToken(refreshToken)
 	if err != nil {
-		return nil, types.ErrInvalidToken
+		return nil, nil, types.ErrInvalidToken
 	}
 
 	// Check if token exists and is not revoked
-	storedToken, err := s.authRepo.GetRefreshToken(req.RefreshToken)
+	storedToken, err := s.authRepo.GetRefreshToken(refreshToken)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if storedToken.ClientID != clientID {
+		return nil, nil, types.ErrInvalidToken
 	}
 
 	if storedToken.IsRevoked {
-		return nil, types.ErrTokenRevoked
+		return nil, nil, s.handleRefreshTokenReuse(storedToken, client)
 	}
 
 	if storedToken.ExpiresAt.Before(time.Now()) {
-		return nil, types.ErrTokenExpired
+		return nil, nil, types.ErrTokenExpired
 	}
 
 	// Get user
 	user, err := s.userRepo.GetByID(claims.UserID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if !user.IsActive {
-		return nil, types.ErrForbidden
+		return nil, nil, types.ErrForbidden
+	}
+
+	// Roles are resolved again on every refresh, so grant changes reach the
+	// access token within one access token lifetime
+	opts, err := s.tokenOptions(user.ID, storedToken.FamilyID, storedToken.ClientID, storedToken.Scope, storedToken.ActiveOrgID)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Generate new tokens
-	tokenPair, err := s.jwtManager.GenerateTokenPair(user.ID, user.Username, user.Email)
+	tokenPair, err := s.jwtManager.GenerateTokenPair(user.ID, user.Username, user.Email, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Replace the old refresh token with a new member of the same family
+	newRefreshToken := s.successorRefreshToken(storedToken, tokenPair.RefreshToken, client)
+	if err := s.authRepo.RotateRefreshToken(refreshToken, newRefreshToken); err != nil {
+		if err == types.ErrTokenRevoked {
+			// Another request rotated the token first
+			return nil, nil, s.handleRefreshTokenReuse(storedToken, client)
+		}
+		return nil, nil, err
+	}
+
+	return tokenPair, storedToken, nil
+}
+
+// RenewSession issues a new token pair for one of the user's login sessions,
+// replacing its refresh token. It keeps the session signed in after every
+// token of the user issued so far was revoked.
+func (s *AuthService) RenewSession(user *model.User, sessionID string, client *types.ClientInfo) (*types.TokenResponse, error) {
+	storedToken, err := s.authRepo.GetActiveRefreshTokenByFamily(user.ID, sessionID)
 	if err != nil {
 		return nil, err
 	}
+	return s.renewSession(user, storedToken, client)
+}
+
+// SwitchOrganization makes an organization the user is a member of the active
+// organization of one of their login sessions, and issues the session a new
+// token pair carrying it. Zero leaves the session without an active organization.
+func (s *AuthService) SwitchOrganization(user *model.User, sessionID string, orgID uint, client *types.ClientInfo) (*types.TokenResponse, error) {
+	storedToken, err := s.authRepo.GetActiveRefreshTokenByFamily(user.ID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	storedToken.ActiveOrgID = nil
+	if orgID != 0 {
+		if _, err := s.orgRepo.FindMembership(orgID, user.ID); err != nil {
+			if errors.Is(err, types.ErrMemberNotFound) {
+				return nil, types.ErrOrgNotFound
+			}
+			return nil, err
+		}
+		storedToken.ActiveOrgID = &orgID
+	}
+
+	return s.renewSession(user, storedToken, client)
+}
 
-	// Revoke old refresh token
-	if err := s.authRepo.RevokeRefreshToken(req.RefreshToken); err != nil {
+// renewSession replaces the refresh token of a session with a new token pair
+func (s *AuthService) renewSession(user *model.User, storedToken *model.RefreshToken, client *types.ClientInfo) (*types.TokenResponse, error) {
+	opts, err := s.tokenOptions(user.ID, storedToken.FamilyID, storedToken.ClientID, storedToken.Scope, storedToken.ActiveOrgID)
+	if err != nil {
 		return nil, err
 	}
 
-	// Store new refresh token
-	newRefreshToken := &model.RefreshToken{
-		UserID:    user.ID,
-		Token:     tokenPair.RefreshToken,
-		ExpiresAt: time.Now().Add(s.jwtManager.GetRefreshTokenDuration()),
-		IsRevoked: false,
+	tokenPair, err := s.jwtManager.GenerateTokenPair(user.ID, user.Username, user.Email, opts...)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.authRepo.CreateRefreshToken(newRefreshToken); err != nil {
+	newRefreshToken := s.successorRefreshToken(storedToken, tokenPair.RefreshToken, client)
+	if err := s.authRepo.RotateRefreshToken(storedToken.Token, newRefreshToken); err != nil {
 		return nil, err
 	}
 
@@ -231,14 +447,318 @@ func (s *AuthService) RefreshToken(req *types.RefreshTokenRequest) (*types.Token
 	}, nil
 }
 
-// Logout revokes refresh token
+// successorRefreshToken builds the refresh token replacing storedToken in its
+// family, carrying over the session details
+func (s *AuthService) successorRefreshToken(storedToken *model.RefreshToken, token string, client *types.ClientInfo) *model.RefreshToken {
+	now := time.Now()
+	return &model.RefreshToken{
+		UserID:           storedToken.UserID,
+		Token:            token,
+		ExpiresAt:        now.Add(s.jwtManager.GetRefreshTokenDuration()),
+		IsRevoked:        false,
+		FamilyID:         storedToken.FamilyID,
+		UserAgent:        client.UserAgent,
+		IPAddress:        client.IPAddress,
+		DeviceName:       storedToken.DeviceName,
+		SessionStartedAt: storedToken.SessionStartedAt,
+		LastUsedAt:       &now,
+		ClientID:         storedToken.ClientID,
+		Scope:            storedToken.Scope,
+		ActiveOrgID:      storedToken.ActiveOrgID,
+	}
+}
+
+// handleRefreshTokenReuse revokes the whole family of a refresh token that was
+// presented after being revoked. Only one party can hold the latest token of a
+// family, so reuse means the token was leaked; following OAuth 2.1 guidance,
+// the legitimate client has to log in again as well.
+func (s *AuthService) handleRefreshTokenReuse(token *model.RefreshToken, client *types.ClientInfo) error {
+	// Tokens issued before families were introduced have no family to revoke
+	if token.FamilyID == "" {
+		if err := s.LogoutAllDevices(token.UserID); err != nil {
+			return err
+		}
+	} else if err := s.RevokeTokenFamily(token.FamilyID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(token.UserID, model.AuditEventRefreshTokenReuse, client, map[string]interface{}{
+		"refresh_token_id": token.ID,
+		"family_id":        token.FamilyID,
+	})
+
+	return types.ErrTokenRevoked
+}
+
+// RevokeTokenFamily revokes all refresh tokens of a family along with the
+// access tokens of its session
+func (s *AuthService) RevokeTokenFamily(familyID string) error {
+	if err := s.authRepo.RevokeRefreshTokenFamily(familyID); err != nil {
+		return err
+	}
+	return s.denylist.RevokeSession(familyID)
+}
+
+// Logout revokes refresh token and the access tokens of its session
 func (s *AuthService) Logout(refreshToken string) error {
-	return s.authRepo.RevokeRefreshToken(refreshToken)
+	storedToken, err := s.authRepo.GetRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+
+	if err := s.authRepo.RevokeRefreshToken(refreshToken); err != nil {
+		return err
+	}
+
+	if storedToken.FamilyID == "" {
+		return nil
+	}
+	return s.denylist.RevokeSession(storedToken.FamilyID)
 }
 
-// LogoutAllDevices revokes all refresh tokens for a user
+// LogoutAllDevices revokes all refresh tokens for a user, along with every
+// access token issued so far
 func (s *AuthService) LogoutAllDevices(userID uint) error {
-	return s.authRepo.RevokeAllUserRefreshTokens(userID)
+	return s.userService.RevokeAllSessions(userID)
+}
+
+// ListSessions lists the active login sessions of a user, flagging the one
+// identified by currentSessionID
+func (s *AuthService) ListSessions(userID uint, currentSessionID string) ([]*types.SessionResponse, error) {
+	tokens, err := s.authRepo.GetRefreshTokenByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each session has exactly one active refresh token
+	sessions := make([]*types.SessionResponse, len(tokens))
+	for i, token := range tokens {
+		startedAt := token.SessionStartedAt
+		if startedAt.IsZero() {
+			startedAt = token.CreatedAt
+		}
+
+		sessions[i] = &types.SessionResponse{
+			ID:         token.FamilyID,
+			DeviceName: token.DeviceName,
+			UserAgent:  token.UserAgent,
+			IPAddress:  token.IPAddress,
+			CreatedAt:  startedAt,
+			LastUsedAt: token.LastUsedAt,
+			ExpiresAt:  token.ExpiresAt,
+			Current:    token.FamilyID != "" && token.FamilyID == currentSessionID,
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+
+	return sessions, nil
+}
+
+// RevokeSession logs out one of the user's sessions
+func (s *AuthService) RevokeSession(userID uint, sessionID string) error {
+	if sessionID == "" {
+		return types.ErrSessionNotFound
+	}
+
+	if err := s.authRepo.RevokeUserRefreshTokenFamily(userID, sessionID); err != nil {
+		return err
+	}
+	return s.denylist.RevokeSession(sessionID)
+}
+
+// Impersonate issues an administrator a short-lived access token for acting as
+// another user. The token carries the user's grants and names the administrator
+// as its actor; administrators can only impersonate active users whose
+// permissions they hold themselves.
+func (s *AuthService) Impersonate(adminID, userID uint, client *types.ClientInfo) (*types.ImpersonationResponse, error) {
+	if adminID == userID {
+		return nil, fmt.Errorf("%w: you cannot impersonate yourself", types.ErrCannotImpersonate)
+	}
+
+	admin, err := s.userRepo.GetByID(adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, fmt.Errorf("%w: account is disabled", types.ErrCannotImpersonate)
+	}
+
+	if err := s.rbacService.CheckOutranks(adminID, userID); err != nil {
+		if errors.Is(err, types.ErrOutranked) {
+			return nil, fmt.Errorf("%w: %w", types.ErrCannotImpersonate, err)
+		}
+		return nil, err
+	}
+	roles, permissions, err := s.rbacService.Grants(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.jwtManager.GetImpersonationTokenDuration())
+	accessToken, err := s.jwtManager.GenerateImpersonationToken(user.ID, user.Username, user.Email,
+		jwt.Actor{UserID: admin.ID, Username: admin.Username},
+		jwt.WithRoles(roles, permissions))
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.RecordAs(admin.ID, user.ID, model.AuditEventImpersonationStarted, client, map[string]interface{}{
+		"impersonator": admin.Username,
+		"expires_at":   expiresAt,
+	})
+
+	return &types.ImpersonationResponse{
+		User:           s.userService.ToResponse(user),
+		AccessToken:    accessToken,
+		ExpiresAt:      expiresAt,
+		ImpersonatorID: admin.ID,
+	}, nil
+}
+
+// issueAuthResponse starts a new login session for the user and returns its token pair
+func (s *AuthService) issueAuthResponse(user *model.User, client *types.ClientInfo) (*types.AuthResponse, error) {
+	// Each login starts a new refresh token family, which identifies the session
+	familyID, err := newFamilyID()
+	if err != nil {
+		return nil, err
+	}
+
+	// New sessions start in the user's oldest organization
+	var activeOrgID *uint
+	membership, err := s.orgRepo.FirstMembership(user.ID)
+	switch {
+	case err == nil:
+		activeOrgID = &membership.OrgID
+	case !errors.Is(err, types.ErrMemberNotFound):
+		return nil, err
+	}
+
+	opts, err := s.tokenOptions(user.ID, familyID, "", "", activeOrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate tokens
+	tokenPair, err := s.jwtManager.GenerateTokenPair(user.ID, user.Username, user.Email, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store refresh token
+	now := time.Now()
+	refreshToken := &model.RefreshToken{
+		UserID:           user.ID,
+		Token:            tokenPair.RefreshToken,
+		ExpiresAt:        now.Add(s.jwtManager.GetRefreshTokenDuration()),
+		IsRevoked:        false,
+		FamilyID:         familyID,
+		UserAgent:        client.UserAgent,
+		IPAddress:        client.IPAddress,
+		DeviceName:       deviceName(client.UserAgent),
+		SessionStartedAt: now,
+		LastUsedAt:       &now,
+		ActiveOrgID:      activeOrgID,
+	}
+
+	if err := s.authRepo.CreateRefreshToken(refreshToken); err != nil {
+		return nil, err
+	}
+
+	return &types.AuthResponse{
+		User:         s.userService.ToResponse(user),
+		AccessToken:  tokenPair.AccessToken,
+		RefreshToken: tokenPair.RefreshToken,
+	}, nil
+}
+
+// IssueClientTokens issues tokens to an OAuth client acting for a user in the
+// session identified by familyID. A refresh token is only issued and stored
+// when withRefreshToken is set.
+func (s *AuthService) IssueClientTokens(user *model.User, familyID, clientID, clientName, scope string, withRefreshToken bool, client *types.ClientInfo) (*jwt.TokenPair, error) {
+	opts, err := s.tokenOptions(user.ID, familyID, clientID, scope, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if !withRefreshToken {
+		accessToken, err := s.jwtManager.GenerateAccessToken(user.ID, user.Username, user.Email, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &jwt.TokenPair{AccessToken: accessToken}, nil
+	}
+
+	tokenPair, err := s.jwtManager.GenerateTokenPair(user.ID, user.Username, user.Email, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The session shows up in the user's session list under the client's name
+	now := time.Now()
+	refreshToken := &model.RefreshToken{
+		UserID:           user.ID,
+		Token:            tokenPair.RefreshToken,
+		ExpiresAt:        now.Add(s.jwtManager.GetRefreshTokenDuration()),
+		IsRevoked:        false,
+		FamilyID:         familyID,
+		UserAgent:        client.UserAgent,
+		IPAddress:        client.IPAddress,
+		DeviceName:       clientName,
+		SessionStartedAt: now,
+		LastUsedAt:       &now,
+		ClientID:         clientID,
+		Scope:            scope,
+	}
+
+	if err := s.authRepo.CreateRefreshToken(refreshToken); err != nil {
+		return nil, err
+	}
+
+	return tokenPair, nil
+}
+
+// tokenOptions builds the claims of a user's tokens in a session. Tokens issued
+// to an OAuth client only carry the permissions among the granted scopes that
+// the user currently holds, and no organization.
+func (s *AuthService) tokenOptions(userID uint, familyID, clientID, scope string, activeOrgID *uint) ([]jwt.TokenOption, error) {
+	roles, permissions, err := s.rbacService.Grants(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []jwt.TokenOption{jwt.WithSessionID(familyID)}
+	if clientID == "" {
+		opts = append(opts, jwt.WithRoles(roles, permissions))
+
+		// Like roles, the membership is resolved again on every refresh, so
+		// members who left the organization lose it
+		if activeOrgID != nil {
+			membership, err := s.orgRepo.FindMembership(*activeOrgID, userID)
+			switch {
+			case err == nil:
+				opts = append(opts, jwt.WithOrganization(membership.OrgID, membership.Role))
+			case !errors.Is(err, types.ErrMemberNotFound):
+				return nil, err
+			}
+		}
+		return opts, nil
+	}
+
+	var granted []string
+	for _, name := range strings.Fields(scope) {
+		if slices.Contains(permissions, name) {
+			granted = append(granted, name)
+		}
+	}
+	return append(opts, jwt.WithRoles(nil, granted), jwt.WithClient(clientID, scope)), nil
 }
 
 // generateRandomToken generates a random token string
@@ -249,15 +769,3 @@ func (s *AuthService) generateRandomToken() (string, error) {
 	}
 	return hex.EncodeToString(bytes), nil
 }
-
-// hashPassword hashes a password using bcrypt
-func (s *AuthService) hashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
-}
-
-// verifyPassword verifies a password against its hash
-func (s *AuthService) verifyPassword(hashedPassword, password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-	return err == nil
-}
\ No newline at end of file
@@ -0,0 +1,122 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/denylist"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/jwt"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+func TestRegisterUndoneWhenRoleAssignmentFails(t *testing.T) {
+	var auth *service.AuthService
+	var invitations *service.InvitationService
+	var users *repository.UserRepository
+	var kv *repository.KVRepository
+	var rbac *service.RBACService
+	newTestApp(t, &auth, &invitations, &users, &kv, &rbac)
+
+	admin := newTestUser(t, users, "admin")
+	if err := rbac.AssignRole(admin, model.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	invitation, err := invitations.Create(admin.ID, &types.CreateInvitationRequest{MaxUses: 1})
+	if err != nil {
+		t.Fatalf("Create invitation failed: %v", err)
+	}
+
+	// A default role that no longer exists makes the role assignment fail
+	if err := kv.Set("default_user_role", "missing"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &types.RegisterRequest{
+		Username:   "invited",
+		Email:      "invited@example.com",
+		Password:   testPassword,
+		InviteCode: invitation.Code,
+	}
+	if _, err := auth.Register(req, userClient); err == nil {
+		t.Fatal("Register succeeded without a role")
+	}
+
+	// Neither the username and email nor the invitation are used up
+	if taken, err := users.UsernameTaken(req.Username, 0); err != nil || taken {
+		t.Errorf("UsernameTaken = %v, %v; want false, nil", taken, err)
+	}
+	if taken, err := users.EmailTaken(req.Email, 0); err != nil || taken {
+		t.Errorf("EmailTaken = %v, %v; want false, nil", taken, err)
+	}
+
+	if err := kv.Set("default_user_role", model.RoleUser); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.Register(req, userClient); err != nil {
+		t.Errorf("Register with the released invitation failed: %v", err)
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	var auth *service.AuthService
+	var users *repository.UserRepository
+	var userService *service.UserService
+	var tokens *jwt.Manager
+	var list *denylist.Denylist
+	newTestApp(t, &auth, &users, &userService, &tokens, &list)
+	newPasswordUser(t, users, userService, "user")
+
+	login := func() *types.AuthResponse {
+		t.Helper()
+		result, err := auth.Login(&types.LoginRequest{Username: "user", Password: testPassword}, userClient)
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		return result
+	}
+	refresh := func(token string) (*types.TokenResponse, error) {
+		return auth.RefreshToken(&types.RefreshTokenRequest{RefreshToken: token}, userClient)
+	}
+	revoked := func(accessToken string) bool {
+		t.Helper()
+		claims, err := tokens.ValidateAccessToken(accessToken)
+		if err != nil {
+			t.Fatal(err)
+		}
+		revoked, err := list.IsRevoked(claims)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return revoked
+	}
+
+	stolen := login()
+	other := login()
+
+	rotated, err := refresh(stolen.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshToken failed: %v", err)
+	}
+
+	// Replaying the rotated token ends the whole session
+	if _, err := refresh(stolen.RefreshToken); !errors.Is(err, types.ErrTokenRevoked) {
+		t.Fatalf("replayed RefreshToken returned %v, want %v", err, types.ErrTokenRevoked)
+	}
+	if _, err := refresh(rotated.RefreshToken); !errors.Is(err, types.ErrTokenRevoked) {
+		t.Errorf("RefreshToken with the latest token of the family returned %v, want %v", err, types.ErrTokenRevoked)
+	}
+	if !revoked(rotated.AccessToken) {
+		t.Error("access token of the family is still accepted")
+	}
+
+	// Other sessions of the user are unaffected
+	if revoked(other.AccessToken) {
+		t.Error("access token of another session was revoked")
+	}
+	if _, err := refresh(other.RefreshToken); err != nil {
+		t.Errorf("RefreshToken of another session failed: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import "strings"
+
+// deviceName derives a friendly device name such as "Firefox on Linux" from a
+// User-Agent header
+func deviceName(userAgent string) string {
+	userAgent = strings.TrimSpace(userAgent)
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := ""
+	for _, candidate := range []struct{ token, name string }{
+		// Order matters: Edge and Opera also claim to be Chrome, and Chrome claims to be Safari
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			browser = candidate.name
+			break
+		}
+	}
+
+	platform := ""
+	for _, candidate := range []struct{ token, name string }{
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, candidate.token) {
+			platform = candidate.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	}
+
+	// Non-browser clients such as curl/8.0 or okhttp/4.9: use the product name
+	if product := strings.SplitN(strings.Fields(userAgent)[0], "/", 2)[0]; product != "" {
+		return product
+	}
+	return "Unknown device"
+}
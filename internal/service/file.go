@@ -0,0 +1,440 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/imaging"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/storage"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// File variants that can be downloaded
+const (
+	FileVariantContent   = "content"
+	FileVariantThumbnail = "thumbnail"
+)
+
+const (
+	// thumbnailContentType is the type of every thumbnail
+	thumbnailContentType = "image/jpeg"
+	// maxFileNameLength is the longest kept file name in bytes
+	maxFileNameLength = 255
+)
+
+// imageTypes are the image types that can be decoded into thumbnails
+var imageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// FileService handles file upload and avatar business logic
+type FileService struct {
+	fileRepo      *repository.FileRepository
+	userRepo      *repository.UserRepository
+	storage       storage.Storage
+	urls          *storage.URLSigner
+	maxUploadSize int64
+	allowedTypes  map[string]bool
+	thumbnailSize int
+}
+
+// NewFileService creates a new file service
+func NewFileService(
+	cfg *config.StorageConfig,
+	fileRepo *repository.FileRepository,
+	userRepo *repository.UserRepository,
+	store storage.Storage,
+	urls *storage.URLSigner,
+) (*FileService, error) {
+	if cfg.MaxUploadSize <= 0 {
+		return nil, fmt.Errorf("invalid max upload size: %d", cfg.MaxUploadSize)
+	}
+	if cfg.ThumbnailSize <= 0 {
+		return nil, fmt.Errorf("invalid thumbnail size: %d", cfg.ThumbnailSize)
+	}
+
+	allowedTypes := make(map[string]bool)
+	for _, contentType := range strings.Split(cfg.AllowedTypes, ",") {
+		if contentType = strings.ToLower(strings.TrimSpace(contentType)); contentType != "" {
+			allowedTypes[contentType] = true
+		}
+	}
+
+	return &FileService{
+		fileRepo:      fileRepo,
+		userRepo:      userRepo,
+		storage:       store,
+		urls:          urls,
+		maxUploadSize: cfg.MaxUploadSize,
+		allowedTypes:  allowedTypes,
+		thumbnailSize: cfg.ThumbnailSize,
+	}, nil
+}
+
+// MaxUploadSize returns the largest accepted upload in bytes
+func (s *FileService) MaxUploadSize() int64 {
+	return s.maxUploadSize
+}
+
+// Upload stores a file for a user. Its type is detected from its content and
+// must be allowed; images also get a thumbnail.
+func (s *FileService) Upload(ctx context.Context, userID uint, name string, content io.ReadSeeker, size int64) (*model.File, error) {
+	file, err := s.store(ctx, content, size, func(contentType string) error {
+		if !s.allowedTypes[contentType] {
+			return types.ErrFileTypeNotAllowed
+		}
+		return nil
+	}, imaging.Fit)
+	if err != nil {
+		return nil, err
+	}
+
+	file.UserID = userID
+	file.Purpose = model.FilePurposeUpload
+	file.Name = cleanFileName(name)
+	if err := s.fileRepo.Create(file); err != nil {
+		s.removeUnused(ctx, file)
+		return nil, err
+	}
+	return file, nil
+}
+
+// Get retrieves a file uploaded by a user
+func (s *FileService) Get(userID, id uint) (*model.File, error) {
+	return s.fileRepo.GetByUser(userID, id)
+}
+
+// List lists the files uploaded by a user, newest first
+func (s *FileService) List(userID uint) ([]*model.File, error) {
+	files, err := s.fileRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	uploads := make([]*model.File, 0, len(files))
+	for _, file := range files {
+		if file.Purpose == model.FilePurposeUpload {
+			uploads = append(uploads, file)
+		}
+	}
+	return uploads, nil
+}
+
+// Delete deletes a file uploaded by a user
+func (s *FileService) Delete(ctx context.Context, userID, id uint) error {
+	file, err := s.fileRepo.GetByUser(userID, id)
+	if err != nil {
+		return err
+	}
+	return s.delete(ctx, file)
+}
+
+// SetAvatar replaces the avatar of a user with a JPEG, PNG or GIF image. The
+// thumbnail is the centered square of the image.
+func (s *FileService) SetAvatar(ctx context.Context, userID uint, name string, content io.ReadSeeker, size int64) (*model.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := s.store(ctx, content, size, func(contentType string) error {
+		if !imageTypes[contentType] {
+			return types.ErrFileTypeNotAllowed
+		}
+		return nil
+	}, imaging.Fill)
+	if err != nil {
+		return nil, err
+	}
+
+	file.UserID = userID
+	file.Purpose = model.FilePurposeAvatar
+	file.Name = cleanFileName(name)
+	if err := s.fileRepo.Create(file); err != nil {
+		s.removeUnused(ctx, file)
+		return nil, err
+	}
+
+	previous := user.AvatarID
+	user.AvatarID = &file.ID
+	if err := s.userRepo.Update(user); err != nil {
+		s.delete(ctx, file)
+		return nil, err
+	}
+
+	if previous != nil {
+		s.deleteByID(ctx, *previous)
+	}
+	return user, nil
+}
+
+// RemoveAvatar removes the avatar of a user
+func (s *FileService) RemoveAvatar(ctx context.Context, userID uint) (*model.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.AvatarID == nil {
+		return user, nil
+	}
+
+	previous := *user.AvatarID
+	user.AvatarID = nil
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	s.deleteByID(ctx, previous)
+	return user, nil
+}
+
+// DeleteByUser deletes every file of a user, including their avatar, when
+// their account is removed
+func (s *FileService) DeleteByUser(ctx context.Context, userID uint) error {
+	files, err := s.fileRepo.ListByUser(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := s.delete(ctx, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FileContent is the content of a file being downloaded
+type FileContent struct {
+	*storage.Object
+	Name        string
+	ContentType string
+}
+
+// OpenSigned opens a variant of a file for a download through a signed URL.
+// It returns ErrForbidden for altered or expired URLs.
+func (s *FileService) OpenSigned(ctx context.Context, id uint, variant string, query url.Values) (*FileContent, error) {
+	if err := s.urls.Verify(fileURLPath(id, variant), query); err != nil {
+		return nil, types.ErrForbidden
+	}
+
+	file, err := s.fileRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.Open(ctx, file, variant)
+}
+
+// Open opens a variant of a file
+func (s *FileService) Open(ctx context.Context, file *model.File, variant string) (*FileContent, error) {
+	key, contentType := file.ObjectKey, file.ContentType
+	if variant == FileVariantThumbnail {
+		key, contentType = file.ThumbnailKey, thumbnailContentType
+	}
+	if key == "" {
+		return nil, types.ErrFileNotFound
+	}
+
+	obj, err := s.storage.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, types.ErrFileNotFound
+		}
+		return nil, err
+	}
+
+	return &FileContent{
+		Object:      obj,
+		Name:        file.Name,
+		ContentType: contentType,
+	}, nil
+}
+
+// ToResponse converts a file to its response with freshly signed URLs
+func (s *FileService) ToResponse(file *model.File) *types.FileResponse {
+	contentURL, expiresAt := s.urls.Sign(fileURLPath(file.ID, FileVariantContent))
+
+	resp := &types.FileResponse{
+		ID:           file.ID,
+		Name:         file.Name,
+		ContentType:  file.ContentType,
+		Size:         file.Size,
+		URL:          contentURL,
+		URLExpiresAt: expiresAt,
+		CreatedAt:    file.CreatedAt,
+	}
+	if file.ThumbnailKey != "" {
+		resp.ThumbnailURL, _ = s.urls.Sign(fileURLPath(file.ID, FileVariantThumbnail))
+	}
+	return resp
+}
+
+// store checks and stores uploaded content and its thumbnail, if it is an
+// image, under content-addressed keys. Content that is already stored is not
+// stored again.
+func (s *FileService) store(
+	ctx context.Context,
+	content io.ReadSeeker,
+	size int64,
+	checkType func(contentType string) error,
+	thumbnail func(img image.Image, size int) image.Image,
+) (*model.File, error) {
+	if size > s.maxUploadSize {
+		return nil, types.ErrFileTooLarge
+	}
+
+	contentType, err := detectContentType(content)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkType(contentType); err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if written, err := io.Copy(hash, content); err != nil {
+		return nil, err
+	} else if written != size {
+		return nil, fmt.Errorf("%w: upload size mismatch", types.ErrValidationFailed)
+	}
+
+	file := &model.File{
+		ContentType: contentType,
+		Size:        size,
+		ObjectKey:   storage.ContentKey(hash.Sum(nil)),
+	}
+
+	if imageTypes[contentType] {
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		img, err := imaging.Decode(content)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid image: %s", types.ErrValidationFailed, err)
+		}
+
+		thumb, err := imaging.EncodeJPEG(thumbnail(img, s.thumbnailSize))
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(thumb)
+		file.ThumbnailKey = storage.ContentKey(sum[:])
+
+		if err := s.put(ctx, file.ThumbnailKey, bytes.NewReader(thumb), int64(len(thumb)), thumbnailContentType); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := s.put(ctx, file.ObjectKey, content, size, contentType); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// put stores an object unless it is already stored
+func (s *FileService) put(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	exists, err := s.storage.Exists(ctx, key)
+	if err != nil || exists {
+		return err
+	}
+	return s.storage.Put(ctx, key, content, size, contentType)
+}
+
+// deleteByID deletes a file, ignoring failures since the file is no longer referenced
+func (s *FileService) deleteByID(ctx context.Context, id uint) {
+	if file, err := s.fileRepo.GetByID(id); err == nil {
+		s.delete(ctx, file)
+	}
+}
+
+// delete deletes a file and the stored objects no other file refers to
+func (s *FileService) delete(ctx context.Context, file *model.File) error {
+	if err := s.fileRepo.Delete(file.ID); err != nil {
+		return err
+	}
+	return s.removeUnused(ctx, file)
+}
+
+// removeUnused removes the stored objects of a file that no file refers to
+func (s *FileService) removeUnused(ctx context.Context, file *model.File) error {
+	for _, key := range []string{file.ObjectKey, file.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+
+		inUse, err := s.fileRepo.KeyInUse(key)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			continue
+		}
+		if err := s.storage.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileURLPath returns the path a variant of a file is downloaded from
+func fileURLPath(id uint, variant string) string {
+	return fmt.Sprintf("/api/files/%d/%s", id, variant)
+}
+
+// detectContentType detects the MIME type of content from its first bytes,
+// without parameters such as the charset
+func detectContentType(content io.ReadSeeker) (string, error) {
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if err != nil {
+		return "", types.ErrFileTypeNotAllowed
+	}
+	return contentType, nil
+}
+
+// cleanFileName returns the base name of an uploaded file name, shortened to
+// at most maxFileNameLength bytes
+func cleanFileName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "." || name == "/" || name == "" {
+		return "file"
+	}
+
+	for len(name) > maxFileNameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/validator"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+const (
+	// invitationCodePrefix starts every invitation code
+	invitationCodePrefix = "inv_"
+	// invitationDisplayLength is the number of leading characters kept to identify a code
+	invitationDisplayLength = len(invitationCodePrefix) + 8
+)
+
+// InvitationService handles registration invitation business logic
+type InvitationService struct {
+	invitationRepo *repository.InvitationRepository
+	rbacService    *RBACService
+	validator      *validator.Validator
+}
+
+// NewInvitationService creates a new invitation service
+func NewInvitationService(invitationRepo *repository.InvitationRepository, rbacService *RBACService, validator *validator.Validator) *InvitationService {
+	return &InvitationService{
+		invitationRepo: invitationRepo,
+		rbacService:    rbacService,
+		validator:      validator,
+	}
+}
+
+// Create creates an invitation on behalf of an administrator, who must hold
+// every permission of the role it grants. The returned code is never
+// retrievable again.
+func (s *InvitationService) Create(adminID uint, req *types.CreateInvitationRequest) (*types.InvitationCreatedResponse, error) {
+	if req.Email != "" {
+		if err := s.validator.ValidateEmail(req.Email); err != nil {
+			return nil, fmt.Errorf("%w: %s", types.ErrValidationFailed, err)
+		}
+	}
+
+	maxUses := req.MaxUses
+	if maxUses == 0 {
+		maxUses = 1
+	}
+	if maxUses < 0 {
+		return nil, fmt.Errorf("%w: max uses must be positive", types.ErrValidationFailed)
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expiry must be in the future", types.ErrValidationFailed)
+	}
+
+	var role *model.Role
+	if req.Role != "" {
+		var err error
+		role, err = s.grantableRole(adminID, req.Role)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	code := invitationCodePrefix + rand.Text()
+	invitation := &model.Invitation{
+		Prefix:      code[:invitationDisplayLength],
+		CodeHash:    hashToken(code),
+		Email:       req.Email,
+		MaxUses:     maxUses,
+		ExpiresAt:   req.ExpiresAt,
+		CreatedByID: adminID,
+		Role:        role,
+	}
+	if role != nil {
+		invitation.RoleID = &role.ID
+	}
+
+	if err := s.invitationRepo.Create(invitation); err != nil {
+		return nil, err
+	}
+
+	return &types.InvitationCreatedResponse{
+		InvitationResponse: *s.ToResponse(invitation),
+		Code:               code,
+	}, nil
+}
+
+// List lists all invitations
+func (s *InvitationService) List() ([]*types.InvitationResponse, error) {
+	invitations, err := s.invitationRepo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*types.InvitationResponse, len(invitations))
+	for i, invitation := range invitations {
+		responses[i] = s.ToResponse(invitation)
+	}
+	return responses, nil
+}
+
+// Get retrieves an invitation
+func (s *InvitationService) Get(id uint) (*types.InvitationResponse, error) {
+	invitation, err := s.invitationRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.ToResponse(invitation), nil
+}
+
+// Revoke revokes an invitation. Users who already registered with it are not affected.
+func (s *InvitationService) Revoke(id uint) error {
+	return s.invitationRepo.Delete(id)
+}
+
+// Consume uses up one use of an invitation code for a registration with the
+// given email address
+func (s *InvitationService) Consume(code, email string) (*model.Invitation, error) {
+	if !strings.HasPrefix(code, invitationCodePrefix) {
+		return nil, types.ErrInvalidInvitation
+	}
+	return s.invitationRepo.Consume(hashToken(code), email)
+}
+
+// Release gives back the use of an invitation whose registration failed
+func (s *InvitationService) Release(invitation *model.Invitation) error {
+	return s.invitationRepo.Release(invitation.ID)
+}
+
+// ToResponse converts an Invitation model to an InvitationResponse
+func (s *InvitationService) ToResponse(invitation *model.Invitation) *types.InvitationResponse {
+	response := &types.InvitationResponse{
+		ID:          invitation.ID,
+		Prefix:      invitation.Prefix,
+		Email:       invitation.Email,
+		MaxUses:     invitation.MaxUses,
+		UseCount:    invitation.UseCount,
+		ExpiresAt:   invitation.ExpiresAt,
+		CreatedByID: invitation.CreatedByID,
+		CreatedAt:   invitation.CreatedAt,
+	}
+	if invitation.Role != nil {
+		response.Role = invitation.Role.Name
+	}
+	return response
+}
+
+// grantableRole looks up a role an administrator may hand out, which requires
+// holding each of its permissions
+func (s *InvitationService) grantableRole(adminID uint, name string) (*model.Role, error) {
+	if err := s.rbacService.CheckGrantable(adminID, []string{name}); err != nil {
+		return nil, err
+	}
+	return s.rbacService.roleByName(name)
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// LockoutService protects logins against password guessing. Failed attempts are
+// counted per account and per client IP: after a few failures further attempts
+// are delayed progressively, and after too many the account or IP is locked.
+type LockoutService struct {
+	throttleRepo    *repository.LoginThrottleRepository
+	auditService    *AuditService
+	maxFailures     int
+	ipMaxFailures   int
+	failureWindow   time.Duration
+	lockoutDuration time.Duration
+	delayAfter      int
+	baseDelay       time.Duration
+	maxDelay        time.Duration
+}
+
+// NewLockoutService creates a new lockout service
+func NewLockoutService(
+	cfg *config.LockoutConfig,
+	throttleRepo *repository.LoginThrottleRepository,
+	auditService *AuditService,
+) (*LockoutService, error) {
+	failureWindow, err := time.ParseDuration(cfg.FailureWindow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse lockout failure window: %w", err)
+	}
+
+	lockoutDuration, err := time.ParseDuration(cfg.LockoutDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse lockout duration: %w", err)
+	}
+
+	baseDelay, err := time.ParseDuration(cfg.BaseDelay)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse lockout base delay: %w", err)
+	}
+
+	maxDelay, err := time.ParseDuration(cfg.MaxDelay)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse lockout max delay: %w", err)
+	}
+
+	return &LockoutService{
+		throttleRepo:    throttleRepo,
+		auditService:    auditService,
+		maxFailures:     cfg.MaxFailures,
+		ipMaxFailures:   cfg.IPMaxFailures,
+		failureWindow:   failureWindow,
+		lockoutDuration: lockoutDuration,
+		delayAfter:      cfg.DelayAfter,
+		baseDelay:       baseDelay,
+		maxDelay:        maxDelay,
+	}, nil
+}
+
+// Check returns a RetryAfterError if login attempts for the account or from
+// the client IP are currently locked or delayed. Accounts are identified by
+// username, so unknown usernames are throttled just like existing ones.
+func (s *LockoutService) Check(username string, client *types.ClientInfo) error {
+	for _, key := range s.keys(username, client) {
+		throttle, err := s.throttleRepo.Get(key)
+		if err != nil {
+			return err
+		}
+		if err := s.blocked(throttle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt. userID is 0 for unknown usernames.
+func (s *LockoutService) RecordFailure(username string, userID uint, client *types.ClientInfo) error {
+	throttle, err := s.recordFailure(accountKey(username), s.maxFailures)
+	if err != nil {
+		return err
+	}
+
+	// The failure counter restarts when a lock is applied
+	if throttle.Failures == 0 && userID != 0 {
+		s.auditService.Record(userID, model.AuditEventAccountLocked, client, map[string]interface{}{
+			"locked_until": throttle.LockedUntil,
+		})
+	}
+
+	if client != nil && client.IPAddress != "" {
+		if _, err := s.recordFailure(ipKey(client.IPAddress), s.ipMaxFailures); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RecordSuccess clears the failed attempts of an account after a successful login.
+// Failures from the client IP keep counting until they fall out of the window.
+func (s *LockoutService) RecordSuccess(username string) error {
+	return s.throttleRepo.Delete(accountKey(username))
+}
+
+// Status returns the lockout state of an account
+func (s *LockoutService) Status(username string) (*types.LockoutStatusResponse, error) {
+	throttle, err := s.throttleRepo.Get(accountKey(username))
+	if err != nil {
+		return nil, err
+	}
+	if throttle == nil {
+		throttle = &model.LoginThrottle{Key: accountKey(username)}
+	}
+	return s.toResponse(throttle), nil
+}
+
+// ListActive lists all accounts and IPs that are currently locked or delayed
+func (s *LockoutService) ListActive() ([]*types.LockoutStatusResponse, error) {
+	throttles, err := s.throttleRepo.ListActive()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*types.LockoutStatusResponse, len(throttles))
+	for i, throttle := range throttles {
+		statuses[i] = s.toResponse(throttle)
+	}
+	return statuses, nil
+}
+
+// Unlock clears the failed attempts and lock of an account
+func (s *LockoutService) Unlock(user *model.User, client *types.ClientInfo) error {
+	if err := s.throttleRepo.Delete(accountKey(user.Username)); err != nil {
+		return err
+	}
+
+	s.auditService.Record(user.ID, model.AuditEventAccountUnlocked, client, nil)
+	return nil
+}
+
+// Cleanup removes throttles without recent failures
+func (s *LockoutService) Cleanup() error {
+	return s.throttleRepo.DeleteStale(time.Now().Add(-s.failureWindow))
+}
+
+// recordFailure increments the failure count of a key and applies delays and locks
+func (s *LockoutService) recordFailure(key string, maxFailures int) (*model.LoginThrottle, error) {
+	return s.throttleRepo.Update(key, func(throttle *model.LoginThrottle) {
+		now := time.Now()
+
+		// Failures outside the window are forgotten
+		if now.Sub(throttle.LastFailureAt) > s.failureWindow {
+			throttle.Failures = 0
+		}
+
+		throttle.Failures++
+		throttle.LastFailureAt = now
+		throttle.NextAttemptAt = nil
+
+		if throttle.Failures >= maxFailures {
+			// The counter restarts once the lock expires
+			lockedUntil := now.Add(s.lockoutDuration)
+			throttle.LockedUntil = &lockedUntil
+			throttle.Failures = 0
+			return
+		}
+
+		if throttle.Failures > s.delayAfter {
+			delay := s.baseDelay << (throttle.Failures - s.delayAfter - 1)
+			if delay > s.maxDelay || delay <= 0 {
+				delay = s.maxDelay
+			}
+			nextAttemptAt := now.Add(delay)
+			throttle.NextAttemptAt = &nextAttemptAt
+		}
+	})
+}
+
+// blocked returns a RetryAfterError if the throttle currently refuses attempts
+func (s *LockoutService) blocked(throttle *model.LoginThrottle) error {
+	if throttle == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if throttle.LockedUntil != nil && throttle.LockedUntil.After(now) {
+		return &types.RetryAfterError{Err: types.ErrAccountLocked, RetryAfter: throttle.LockedUntil.Sub(now)}
+	}
+	if throttle.NextAttemptAt != nil && throttle.NextAttemptAt.After(now) {
+		return &types.RetryAfterError{Err: types.ErrTooManyAttempts, RetryAfter: throttle.NextAttemptAt.Sub(now)}
+	}
+	return nil
+}
+
+// keys returns the throttle keys that apply to a login attempt
+func (s *LockoutService) keys(username string, client *types.ClientInfo) []string {
+	keys := []string{accountKey(username)}
+	if client != nil && client.IPAddress != "" {
+		keys = append(keys, ipKey(client.IPAddress))
+	}
+	return keys
+}
+
+// toResponse converts a throttle to its API representation
+func (s *LockoutService) toResponse(throttle *model.LoginThrottle) *types.LockoutStatusResponse {
+	status := &types.LockoutStatusResponse{
+		Key:           throttle.Key,
+		Failures:      throttle.Failures,
+		NextAttemptAt: throttle.NextAttemptAt,
+		LockedUntil:   throttle.LockedUntil,
+	}
+	if !throttle.LastFailureAt.IsZero() {
+		status.LastFailureAt = &throttle.LastFailureAt
+	}
+	status.Locked = throttle.LockedUntil != nil && throttle.LockedUntil.After(time.Now())
+	return status
+}
+
+// accountKey returns the throttle key of an account
+func accountKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+// ipKey returns the throttle key of a client IP address
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/mailer"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// MagicLinkService handles passwordless login with links sent by email
+type MagicLinkService struct {
+	userRepo      *repository.UserRepository
+	authRepo      *repository.AuthRepository
+	kvRepo        *repository.KVRepository
+	authService   *AuthService
+	mailer        mailer.Mailer
+	publicURL     string
+	tokenDuration time.Duration
+}
+
+// NewMagicLinkService creates a new magic link service
+func NewMagicLinkService(
+	cfg *config.Config,
+	userRepo *repository.UserRepository,
+	authRepo *repository.AuthRepository,
+	kvRepo *repository.KVRepository,
+	authService *AuthService,
+	mailer mailer.Mailer,
+) (*MagicLinkService, error) {
+	tokenDuration, err := time.ParseDuration(cfg.Auth.MagicLinkTokenDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse magic link token duration: %w", err)
+	}
+
+	return &MagicLinkService{
+		userRepo:      userRepo,
+		authRepo:      authRepo,
+		kvRepo:        kvRepo,
+		authService:   authService,
+		mailer:        mailer,
+		publicURL:     cfg.Server.PublicURL,
+		tokenDuration: tokenDuration,
+	}, nil
+}
+
+// SendLink issues a magic link token and mails it to the user. Unknown or
+// disabled accounts are ignored so the endpoint cannot be used to discover
+// registered email addresses.
+func (s *MagicLinkService) SendLink(req *types.MagicLinkRequest) error {
+	if err := s.checkEnabled(); err != nil {
+		return err
+	}
+	if req.Email == "" {
+		return types.ErrValidationFailed
+	}
+
+	user, err := s.userRepo.GetByEmail(req.Email)
+	if err != nil {
+		if err == types.ErrUserNotFound {
+			return nil
+		}
+		return err
+	}
+
+	if !user.IsActive {
+		return nil
+	}
+
+	// Only the most recently issued link should work
+	if err := s.authRepo.InvalidateUserMagicLinkTokens(user.ID); err != nil {
+		return err
+	}
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	magicLinkToken := &model.MagicLinkToken{
+		UserID: user.ID,
+		Email:  user.Email,
+		SingleUseToken: model.SingleUseToken{
+			TokenHash: tokenHash,
+			ExpiresAt: time.Now().Add(s.tokenDuration),
+		},
+	}
+
+	if err := s.authRepo.CreateMagicLinkToken(magicLinkToken); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Your sign-in link",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to sign in. The link expires in %s and can only be used once.\n\n%s/magic-link?token=%s\n\nIf you did not request this you can ignore this email.",
+			user.Username, s.tokenDuration, s.publicURL, token,
+		),
+	})
+}
+
+// Login exchanges a magic link token for a token pair. Users with two-factor
+// authentication enabled still have to complete the second step.
+func (s *MagicLinkService) Login(req *types.MagicLinkVerifyRequest, client *types.ClientInfo) (*types.AuthResponse, error) {
+	if err := s.checkEnabled(); err != nil {
+		return nil, err
+	}
+	if req.Token == "" {
+		return nil, types.ErrInvalidToken
+	}
+
+	magicLinkToken, err := s.authRepo.ConsumeMagicLinkToken(hashToken(req.Token))
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(magicLinkToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, types.ErrForbidden
+	}
+
+	// The user changed their email since the link was sent
+	if magicLinkToken.Email != user.Email {
+		return nil, types.ErrInvalidToken
+	}
+
+	// Opening the link proves ownership of the address
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if err := s.userRepo.Update(user); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.authService.completeLogin(user, client)
+}
+
+// checkEnabled refuses magic link logins unless an administrator turned them on
+func (s *MagicLinkService) checkEnabled() error {
+	enabled, err := s.kvRepo.GetBool(model.SettingAllowMagicLink)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return types.ErrMagicLinkDisabled
+	}
+	return nil
+}
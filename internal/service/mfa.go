@@ -0,0 +1,253 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/totp"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+const (
+	// recoveryCodeCount is the number of recovery codes issued at a time
+	recoveryCodeCount = 10
+	// totpSkew is the number of time steps accepted before and after the current one
+	totpSkew = 1
+)
+
+// MFAService handles two-factor authentication business logic
+type MFAService struct {
+	userRepo    *repository.UserRepository
+	mfaRepo     *repository.MFARepository
+	userService    *UserService
+	lockoutService *LockoutService
+	issuer         string
+}
+
+// NewMFAService creates a new MFA service
+func NewMFAService(
+	userRepo *repository.UserRepository,
+	mfaRepo *repository.MFARepository,
+	userService *UserService,
+	lockoutService *LockoutService,
+	issuer string,
+) *MFAService {
+	return &MFAService{
+		userRepo:       userRepo,
+		mfaRepo:        mfaRepo,
+		userService:    userService,
+		lockoutService: lockoutService,
+		issuer:         issuer,
+	}
+}
+
+// Enroll starts two-factor enrollment by generating a new TOTP secret.
+// The secret only becomes effective after Confirm.
+func (s *MFAService) Enroll(userID uint) (*types.MFAEnrollResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.MFAEnabledAt != nil {
+		return nil, types.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	user.MFASecret = secret
+	user.MFALastUsedStep = 0
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return &types.MFAEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.KeyURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm completes enrollment with a code from the authenticator app and
+// returns the initial set of recovery codes
+func (s *MFAService) Confirm(userID uint, req *types.MFACodeRequest) (*types.MFARecoveryCodesResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.MFAEnabledAt != nil {
+		return nil, types.ErrMFAAlreadyEnabled
+	}
+	if user.MFASecret == "" {
+		return nil, types.ErrMFANotEnrolled
+	}
+
+	valid, err := s.verifyTOTP(user, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, types.ErrInvalidMFACode
+	}
+
+	now := time.Now()
+	user.MFAEnabledAt = &now
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return s.issueRecoveryCodes(user.ID)
+}
+
+// Disable turns off two-factor authentication. Both the password and a
+// current code are required.
+func (s *MFAService) Disable(userID uint, req *types.DisableMFARequest, client *types.ClientInfo) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.MFAEnabledAt == nil {
+		return types.ErrMFANotEnabled
+	}
+
+	if err := s.confirm(user, &req.Password, req.Code, client); err != nil {
+		return err
+	}
+
+	user.MFASecret = ""
+	user.MFAEnabledAt = nil
+	user.MFALastUsedStep = 0
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	return s.mfaRepo.DeleteRecoveryCodes(user.ID)
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after verifying a current code
+func (s *MFAService) RegenerateRecoveryCodes(userID uint, req *types.MFACodeRequest, client *types.ClientInfo) (*types.MFARecoveryCodesResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.MFAEnabledAt == nil {
+		return nil, types.ErrMFANotEnabled
+	}
+
+	if err := s.confirm(user, nil, req.Code, client); err != nil {
+		return nil, err
+	}
+
+	return s.issueRecoveryCodes(user.ID)
+}
+
+// confirm checks the code, and the password unless nil, of a signed-in user
+// confirming a change to their two-factor authentication. Wrong passwords and
+// codes are throttled like failed logins, and failures are only cleared once
+// both are right, so that knowing the password does not allow guessing codes.
+func (s *MFAService) confirm(user *model.User, password *string, code string, client *types.ClientInfo) error {
+	if err := s.lockoutService.Check(user.Username, client); err != nil {
+		return err
+	}
+
+	if password != nil && !s.userService.verifyPassword(user.PasswordHash, *password) {
+		if err := s.lockoutService.RecordFailure(user.Username, user.ID, client); err != nil {
+			return err
+		}
+		return types.ErrInvalidCredentials
+	}
+
+	valid, err := s.VerifyCode(user, code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		if err := s.lockoutService.RecordFailure(user.Username, user.ID, client); err != nil {
+			return err
+		}
+		return types.ErrInvalidMFACode
+	}
+
+	return s.lockoutService.RecordSuccess(user.Username)
+}
+
+// VerifyCode checks a TOTP code or consumes a recovery code for a user with
+// two-factor authentication enabled
+func (s *MFAService) VerifyCode(user *model.User, code string) (bool, error) {
+	if user.MFAEnabledAt == nil {
+		return false, types.ErrMFANotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return false, nil
+	}
+
+	if len(code) == totp.Digits {
+		return s.verifyTOTP(user, code)
+	}
+
+	return s.mfaRepo.ConsumeRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(code)))
+}
+
+// verifyTOTP validates a TOTP code and records its time step so that the
+// same code cannot be replayed
+func (s *MFAService) verifyTOTP(user *model.User, code string) (bool, error) {
+	step, ok := totp.Validate(user.MFASecret, code, time.Now(), totpSkew)
+	if !ok || step <= user.MFALastUsedStep {
+		return false, nil
+	}
+
+	consumed, err := s.mfaRepo.ConsumeTOTPStep(user.ID, step)
+	if err != nil || !consumed {
+		return false, err
+	}
+
+	user.MFALastUsedStep = step
+	return true, nil
+}
+
+// issueRecoveryCodes generates and stores a fresh set of recovery codes
+func (s *MFAService) issueRecoveryCodes(userID uint) (*types.MFARecoveryCodesResponse, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = hashToken(normalizeRecoveryCode(code))
+	}
+
+	if err := s.mfaRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &types.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// generateRecoveryCode generates a random code formatted as xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	bytes := make([]byte, 7)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(bytes))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeRecoveryCode strips formatting so codes can be typed loosely
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
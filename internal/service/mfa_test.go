@@ -0,0 +1,84 @@
+package service_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/totp"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// newMFAUser creates a user with a password and two-factor authentication
+// enabled, returning a recovery code
+func newMFAUser(t *testing.T, users *repository.UserRepository, userService *service.UserService, mfa *service.MFAService) (*model.User, string) {
+	t.Helper()
+
+	user := newPasswordUser(t, users, userService, "user")
+	enrollment, err := mfa.Enroll(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := totp.Code(enrollment.Secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovery, err := mfa.Confirm(user.ID, &types.MFACodeRequest{Code: code})
+	if err != nil {
+		t.Fatalf("Confirm failed: %v", err)
+	}
+	return user, recovery.RecoveryCodes[0]
+}
+
+func TestMFAChangesLockOutGuessing(t *testing.T) {
+	t.Setenv("APP_LOCKOUT_MAX_FAILURES", "5")
+	t.Setenv("APP_LOCKOUT_DELAY_AFTER", "10")
+
+	tests := []struct {
+		name     string
+		password bool
+		wrong    string
+		wrongErr error
+		attempt  func(mfa *service.MFAService, userID uint, code, value string) error
+	}{
+		{"disable with a wrong password", true, "wrong-password", types.ErrInvalidCredentials, func(mfa *service.MFAService, userID uint, code, password string) error {
+			return mfa.Disable(userID, &types.DisableMFARequest{Password: password, Code: code}, userClient)
+		}},
+		{"disable with a wrong code", false, "wrong-code", types.ErrInvalidMFACode, func(mfa *service.MFAService, userID uint, _, code string) error {
+			return mfa.Disable(userID, &types.DisableMFARequest{Password: testPassword, Code: code}, userClient)
+		}},
+		{"regenerate recovery codes with a wrong code", false, "wrong-code", types.ErrInvalidMFACode, func(mfa *service.MFAService, userID uint, _, code string) error {
+			_, err := mfa.RegenerateRecoveryCodes(userID, &types.MFACodeRequest{Code: code}, userClient)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var users *repository.UserRepository
+			var userService *service.UserService
+			var mfa *service.MFAService
+			newTestApp(t, &users, &userService, &mfa)
+			user, code := newMFAUser(t, users, userService, mfa)
+
+			right := code
+			if tt.password {
+				right = testPassword
+			}
+			lockUser(t, tt.wrong, right, tt.wrongErr, func(value string) error {
+				return tt.attempt(mfa, user.ID, code, value)
+			})
+
+			// The right attempt was refused, so two-factor authentication stays on
+			user, err := users.GetByID(user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.MFAEnabledAt == nil {
+				t.Error("two-factor authentication was disabled")
+			}
+		})
+	}
+}
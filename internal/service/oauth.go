@@ -0,0 +1,463 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/logger"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/oauth"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+	"go.uber.org/zap"
+)
+
+// oauthStateKeyPrefix prefixes the KV keys of pending social logins
+const oauthStateKeyPrefix = "oauth_state:"
+
+// usernameUnsafeChars matches characters not allowed in usernames
+var usernameUnsafeChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// oauthState is stored between the authorization request and the callback
+type oauthState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	ReturnTo     string `json:"return_to,omitempty"`
+	// LinkUserID is set when a signed-in user links a provider account
+	LinkUserID uint `json:"link_user_id,omitempty"`
+}
+
+// OAuthService handles social login business logic
+type OAuthService struct {
+	cfg           *config.OAuthConfig
+	publicURL     string
+	stateDuration time.Duration
+	registry      *oauth.Registry
+	identityRepo  *repository.IdentityRepository
+	userRepo      *repository.UserRepository
+	kvRepo        *repository.KVRepository
+	authService   *AuthService
+	rbacService   *RBACService
+	auditService  *AuditService
+	logger        *logger.Logger
+}
+
+// NewOAuthService creates a new OAuth service
+func NewOAuthService(
+	cfg *config.Config,
+	registry *oauth.Registry,
+	identityRepo *repository.IdentityRepository,
+	userRepo *repository.UserRepository,
+	kvRepo *repository.KVRepository,
+	authService *AuthService,
+	rbacService *RBACService,
+	auditService *AuditService,
+	logger *logger.Logger,
+) (*OAuthService, error) {
+	stateDuration, err := time.ParseDuration(cfg.OAuth.StateDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse oauth state duration: %w", err)
+	}
+
+	return &OAuthService{
+		cfg:           &cfg.OAuth,
+		publicURL:     strings.TrimSuffix(cfg.Server.PublicURL, "/"),
+		stateDuration: stateDuration,
+		registry:      registry,
+		identityRepo:  identityRepo,
+		userRepo:      userRepo,
+		kvRepo:        kvRepo,
+		authService:   authService,
+		rbacService:   rbacService,
+		auditService:  auditService,
+		logger:        logger,
+	}, nil
+}
+
+// Providers lists the configured providers
+func (s *OAuthService) Providers() []*types.OAuthProviderResponse {
+	providers := s.registry.List()
+	responses := make([]*types.OAuthProviderResponse, len(providers))
+	for i, provider := range providers {
+		responses[i] = &types.OAuthProviderResponse{
+			Name:        provider.Name(),
+			DisplayName: provider.DisplayName(),
+		}
+	}
+	return responses
+}
+
+// StartLogin begins the authorization code flow and returns the provider URL
+// to send the browser to, along with the state the browser must remember for
+// the callback. linkUserID is non-zero when linking to a signed-in user.
+func (s *OAuthService) StartLogin(ctx context.Context, providerName, returnTo string, linkUserID uint) (authURL, stateParam string, err error) {
+	provider, err := s.registry.Get(providerName)
+	if err != nil {
+		return "", "", err
+	}
+
+	if returnTo != "" && !isLocalPath(returnTo) {
+		return "", "", fmt.Errorf("%w: return_to must be a path of this application", types.ErrValidationFailed)
+	}
+
+	req := oauth.NewAuthRequest(s.callbackURL(providerName))
+	authURL, err = provider.AuthCodeURL(ctx, req)
+	if err != nil {
+		s.logger.Error("Failed to build provider authorization URL", zap.String("provider", providerName), zap.Error(err))
+		return "", "", types.ErrOAuthFailed
+	}
+
+	state, err := json.Marshal(&oauthState{
+		Provider:     providerName,
+		Nonce:        req.Nonce,
+		CodeVerifier: req.CodeVerifier,
+		ReturnTo:     returnTo,
+		LinkUserID:   linkUserID,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	expiresAt := time.Now().Add(s.stateDuration)
+	if linkUserID != 0 {
+		err = s.kvRepo.SetForUser(linkUserID, oauthStateKeyPrefix+req.State, string(state), expiresAt)
+	} else {
+		err = s.kvRepo.SetWithExpiry(oauthStateKeyPrefix+req.State, string(state), expiresAt)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	return authURL, req.State, nil
+}
+
+// StateDuration returns how long a started authorization flow can be completed
+func (s *OAuthService) StateDuration() time.Duration {
+	return s.stateDuration
+}
+
+// Callback completes the authorization code flow. browserState is the state
+// remembered by the browser completing the flow, which must be the browser
+// that started it, so that nobody can hand their own flow to someone else.
+// The state is single use, so a replayed callback is rejected.
+func (s *OAuthService) Callback(ctx context.Context, providerName, code, stateParam, browserState string, client *types.ClientInfo) (*types.OAuthCallbackResult, error) {
+	provider, err := s.registry.Get(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	if stateParam == "" || code == "" {
+		return nil, types.ErrInvalidToken
+	}
+	if subtle.ConstantTimeCompare([]byte(stateParam), []byte(browserState)) != 1 {
+		return nil, types.ErrInvalidToken
+	}
+
+	value, err := s.kvRepo.Take(oauthStateKeyPrefix + stateParam)
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, types.ErrInvalidToken
+	}
+
+	var state oauthState
+	if err := json.Unmarshal([]byte(value), &state); err != nil || state.Provider != providerName {
+		return nil, types.ErrInvalidToken
+	}
+
+	identity, err := provider.Exchange(ctx, &oauth.AuthRequest{
+		RedirectURI:  s.callbackURL(providerName),
+		State:        stateParam,
+		Nonce:        state.Nonce,
+		CodeVerifier: state.CodeVerifier,
+	}, code)
+	if err != nil {
+		s.logger.Warn("Sign in with provider failed", zap.String("provider", providerName), zap.Error(err))
+		return nil, types.ErrOAuthFailed
+	}
+
+	result := &types.OAuthCallbackResult{ReturnTo: state.ReturnTo}
+
+	if state.LinkUserID != 0 {
+		linked, err := s.link(providerName, identity, state.LinkUserID, client)
+		if err != nil {
+			return nil, err
+		}
+		result.Identity = s.ToIdentityResponse(linked)
+		return result, nil
+	}
+
+	result.Auth, err = s.login(providerName, identity, client)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ListIdentities lists the provider accounts linked to a user
+func (s *OAuthService) ListIdentities(userID uint) ([]*types.IdentityResponse, error) {
+	identities, err := s.identityRepo.ListByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*types.IdentityResponse, len(identities))
+	for i, identity := range identities {
+		responses[i] = s.ToIdentityResponse(identity)
+	}
+	return responses, nil
+}
+
+// Unlink removes a linked provider account. Users without a password must keep
+// at least one way to sign in.
+func (s *OAuthService) Unlink(userID, identityID uint, client *types.ClientInfo) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	identities, err := s.identityRepo.ListByUser(userID)
+	if err != nil {
+		return err
+	}
+
+	var target *model.UserIdentity
+	for _, identity := range identities {
+		if identity.ID == identityID {
+			target = identity
+		}
+	}
+	if target == nil {
+		return types.ErrIdentityNotFound
+	}
+
+	if user.PasswordHash == "" && len(identities) == 1 {
+		return fmt.Errorf("%w: set a password before unlinking your last sign-in method", types.ErrValidationFailed)
+	}
+
+	if err := s.identityRepo.Delete(userID, identityID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(userID, model.AuditEventIdentityUnlinked, client, map[string]interface{}{
+		"provider": target.Provider,
+	})
+	return nil
+}
+
+// ToIdentityResponse converts a UserIdentity model to an IdentityResponse
+func (s *OAuthService) ToIdentityResponse(identity *model.UserIdentity) *types.IdentityResponse {
+	return &types.IdentityResponse{
+		ID:          identity.ID,
+		Provider:    identity.Provider,
+		Email:       identity.Email,
+		LastLoginAt: identity.LastLoginAt,
+		CreatedAt:   identity.CreatedAt,
+	}
+}
+
+// login signs in the user linked to a provider account. Unknown accounts are
+// linked to the user with the same verified email, or get a new user.
+func (s *OAuthService) login(providerName string, identity *oauth.Identity, client *types.ClientInfo) (*types.AuthResponse, error) {
+	var user *model.User
+
+	linked, err := s.identityRepo.GetByProviderSubject(providerName, identity.Subject)
+	switch err {
+	case nil:
+		user, err = s.userRepo.GetByID(linked.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.identityRepo.Touch(linked.ID, identity.Email, time.Now()); err != nil {
+			return nil, err
+		}
+	case types.ErrIdentityNotFound:
+		user, err = s.userForNewIdentity(providerName, identity, client)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if !user.IsActive {
+		return nil, types.ErrForbidden
+	}
+
+	return s.authService.completeLogin(user, client)
+}
+
+// userForNewIdentity finds or creates the user a provider account signing in
+// for the first time belongs to
+func (s *OAuthService) userForNewIdentity(providerName string, identity *oauth.Identity, client *types.ClientInfo) (*model.User, error) {
+	if identity.Email == "" {
+		return nil, fmt.Errorf("%w: the provider did not share an email address", types.ErrValidationFailed)
+	}
+
+	user, err := s.userRepo.GetByEmail(identity.Email)
+	switch err {
+	case nil:
+		// Only link automatically when both sides proved ownership of the
+		// address, otherwise the provider account could take over the user
+		if !identity.EmailVerified || user.EmailVerifiedAt == nil {
+			return nil, types.ErrUserAlreadyExists
+		}
+	case types.ErrUserNotFound:
+		user, err = s.createUser(identity)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if _, err := s.linkIdentity(providerName, identity, user, client); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// createUser registers a new user for a provider account
+func (s *OAuthService) createUser(identity *oauth.Identity) (*model.User, error) {
+	if !s.cfg.AllowSignup {
+		return nil, types.ErrRegistrationClosed
+	}
+	if open, err := s.kvRepo.GetBool(model.SettingAllowRegister); err != nil {
+		return nil, err
+	} else if !open {
+		return nil, types.ErrRegistrationClosed
+	}
+
+	// Soft-deleted users keep their email until they are purged
+	if taken, err := s.userRepo.EmailTaken(identity.Email, 0); err != nil {
+		return nil, err
+	} else if taken {
+		return nil, types.ErrUserAlreadyExists
+	}
+
+	username, err := s.availableUsername(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	firstName, lastName, _ := strings.Cut(identity.Name, " ")
+	user := &model.User{
+		Username:  username,
+		Email:     identity.Email,
+		FirstName: firstName,
+		LastName:  lastName,
+		IsActive:  true,
+	}
+	if identity.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.rbacService.AssignDefaultRole(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// link links a provider account to a signed-in user
+func (s *OAuthService) link(providerName string, identity *oauth.Identity, userID uint, client *types.ClientInfo) (*model.UserIdentity, error) {
+	existing, err := s.identityRepo.GetByProviderSubject(providerName, identity.Subject)
+	switch err {
+	case nil:
+		if existing.UserID != userID {
+			return nil, types.ErrIdentityLinked
+		}
+		return existing, nil
+	case types.ErrIdentityNotFound:
+	default:
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.linkIdentity(providerName, identity, user, client)
+}
+
+// linkIdentity stores the link between a provider account and a user
+func (s *OAuthService) linkIdentity(providerName string, identity *oauth.Identity, user *model.User, client *types.ClientInfo) (*model.UserIdentity, error) {
+	now := time.Now()
+	linked := &model.UserIdentity{
+		UserID:      user.ID,
+		Provider:    providerName,
+		Subject:     identity.Subject,
+		Email:       identity.Email,
+		LastLoginAt: &now,
+	}
+
+	if err := s.identityRepo.Create(linked); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(user.ID, model.AuditEventIdentityLinked, client, map[string]interface{}{
+		"provider": providerName,
+		"email":    identity.Email,
+	})
+	return linked, nil
+}
+
+// availableUsername derives an unused username from a provider account
+func (s *OAuthService) availableUsername(identity *oauth.Identity) (string, error) {
+	base := identity.Username
+	if base == "" {
+		base, _, _ = strings.Cut(identity.Email, "@")
+	}
+	base = usernameUnsafeChars.ReplaceAllString(base, "")
+	if len(base) > 24 {
+		base = base[:24]
+	}
+	if len(base) < 3 {
+		base = "user" + base
+	}
+
+	candidate := base
+	for range 10 {
+		taken, err := s.userRepo.UsernameTaken(candidate, 0)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+
+		suffix, err := rand.Int(rand.Reader, big.NewInt(100000))
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s-%05d", base, suffix)
+	}
+
+	return "", types.ErrUserAlreadyExists
+}
+
+// callbackURL returns the redirect URI registered with a provider
+func (s *OAuthService) callbackURL(providerName string) string {
+	return s.publicURL + "/api/auth/oauth/" + url.PathEscape(providerName) + "/callback"
+}
+
+// isLocalPath reports whether a return URL stays on this application
+func isLocalPath(path string) bool {
+	return strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "//") && !strings.HasPrefix(path, "/\\")
+}
@@ -0,0 +1,306 @@
+package service_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/oauth"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/oauth/oauthtest"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// testProvider is the name the fake issuer is configured under
+const testProvider = "fake"
+
+// oauthTestEnv is an application wired to a fake OpenID Connect issuer
+type oauthTestEnv struct {
+	issuer *oauthtest.Issuer
+	oauth  *service.OAuthService
+	users  *repository.UserRepository
+	client *types.ClientInfo
+}
+
+// newOAuthTestEnv builds the application with the fake issuer as its only
+// social login provider
+func newOAuthTestEnv(t *testing.T) *oauthTestEnv {
+	t.Helper()
+
+	issuer, err := oauthtest.NewIssuer()
+	if err != nil {
+		t.Fatalf("failed to start issuer: %v", err)
+	}
+	t.Cleanup(issuer.Close)
+
+	providers, err := json.Marshal([]config.OAuthProviderConfig{issuer.ProviderConfig(testProvider)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("APP_OAUTH_PROVIDERS", string(providers))
+	t.Setenv("APP_OAUTH_ALLOW_SIGNUP", "true")
+
+	env := &oauthTestEnv{
+		issuer: issuer,
+		client: &types.ClientInfo{IPAddress: "127.0.0.1", UserAgent: "oauth-test"},
+	}
+	newTestApp(t, &env.oauth, &env.users)
+
+	return env
+}
+
+// authorize starts a login and lets the issuer sign in, returning the code and
+// state it sends back to the callback. tamper may change the authorization
+// request on its way to the issuer.
+func (e *oauthTestEnv) authorize(t *testing.T, linkUserID uint, tamper func(query url.Values)) (code, state string) {
+	t.Helper()
+
+	authURL, state, err := e.oauth.StartLogin(context.Background(), testProvider, "", linkUserID)
+	if err != nil {
+		t.Fatalf("StartLogin failed: %v", err)
+	}
+
+	if tamper != nil {
+		parsed, err := url.Parse(authURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		query := parsed.Query()
+		tamper(query)
+		parsed.RawQuery = query.Encode()
+		authURL = parsed.String()
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorization request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorization request returned %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Query().Get("state") != state {
+		t.Fatalf("issuer returned state %q, want %q", location.Query().Get("state"), state)
+	}
+	return location.Query().Get("code"), state
+}
+
+// callback completes a flow in the browser that started it
+func (e *oauthTestEnv) callback(code, state string) (*types.OAuthCallbackResult, error) {
+	return e.oauth.Callback(context.Background(), testProvider, code, state, state, e.client)
+}
+
+// createUser creates a local user with the given email address
+func (e *oauthTestEnv) createUser(t *testing.T, username, email string, verified bool) *model.User {
+	t.Helper()
+
+	user := &model.User{
+		Username:     username,
+		Email:        email,
+		PasswordHash: "unused",
+		IsActive:     true,
+	}
+	if verified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	if err := e.users.Create(user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+func TestOAuthCallbackSignsInNewUser(t *testing.T) {
+	env := newOAuthTestEnv(t)
+
+	result, err := env.callback(env.authorize(t, 0, nil))
+	if err != nil {
+		t.Fatalf("Callback failed: %v", err)
+	}
+	if result.Auth == nil || result.Auth.AccessToken == "" {
+		t.Fatal("Callback did not sign the user in")
+	}
+	if result.Auth.User.Email != "fake.user@example.com" {
+		t.Errorf("signed in as %q, want the issuer's user", result.Auth.User.Email)
+	}
+
+	// The provider account is linked, so the next login is the same user
+	again, err := env.callback(env.authorize(t, 0, nil))
+	if err != nil {
+		t.Fatalf("second Callback failed: %v", err)
+	}
+	if again.Auth.User.ID != result.Auth.User.ID {
+		t.Errorf("second login signed in user %d, want %d", again.Auth.User.ID, result.Auth.User.ID)
+	}
+}
+
+func TestOAuthCallbackRejectsStateMismatch(t *testing.T) {
+	env := newOAuthTestEnv(t)
+	code, state := env.authorize(t, 0, nil)
+
+	for _, browserState := range []string{"", "another-browser-state"} {
+		_, err := env.oauth.Callback(context.Background(), testProvider, code, state, browserState, env.client)
+		if !errors.Is(err, types.ErrInvalidToken) {
+			t.Errorf("Callback with browser state %q returned %v, want %v", browserState, err, types.ErrInvalidToken)
+		}
+	}
+
+	// A rejected attempt does not consume the flow of the browser that started it
+	if _, err := env.callback(code, state); err != nil {
+		t.Fatalf("Callback from the starting browser failed: %v", err)
+	}
+}
+
+func TestOAuthCallbackRejectsReplayedState(t *testing.T) {
+	env := newOAuthTestEnv(t)
+	code, state := env.authorize(t, 0, nil)
+
+	if _, err := env.callback(code, state); err != nil {
+		t.Fatalf("Callback failed: %v", err)
+	}
+	if _, err := env.callback(code, state); !errors.Is(err, types.ErrInvalidToken) {
+		t.Errorf("replayed Callback returned %v, want %v", err, types.ErrInvalidToken)
+	}
+}
+
+func TestOAuthCallbackRejectsUnknownState(t *testing.T) {
+	env := newOAuthTestEnv(t)
+	code, _ := env.authorize(t, 0, nil)
+
+	if _, err := env.callback(code, "forged-state"); !errors.Is(err, types.ErrInvalidToken) {
+		t.Errorf("Callback with an unknown state returned %v, want %v", err, types.ErrInvalidToken)
+	}
+}
+
+func TestOAuthCallbackRequiresCodeVerifier(t *testing.T) {
+	env := newOAuthTestEnv(t)
+
+	// The code is issued for another challenge, as when an attacker injects a
+	// code obtained in their own flow
+	code, state := env.authorize(t, 0, func(query url.Values) {
+		query.Set("code_challenge", oauth.CodeChallenge("attacker-verifier"))
+	})
+
+	if _, err := env.callback(code, state); !errors.Is(err, types.ErrOAuthFailed) {
+		t.Errorf("Callback returned %v, want %v", err, types.ErrOAuthFailed)
+	}
+}
+
+func TestOAuthCallbackRejectsInvalidIDToken(t *testing.T) {
+	env := newOAuthTestEnv(t)
+
+	tests := []struct {
+		name   string
+		tamper func(claims jwt.MapClaims)
+	}{
+		{"nonce mismatch", func(claims jwt.MapClaims) { claims["nonce"] = "another-nonce" }},
+		{"missing nonce", func(claims jwt.MapClaims) { delete(claims, "nonce") }},
+		{"wrong issuer", func(claims jwt.MapClaims) { claims["iss"] = "https://issuer.example.com" }},
+		{"wrong audience", func(claims jwt.MapClaims) { claims["aud"] = "another-client" }},
+		{"expired", func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }},
+		{"missing expiry", func(claims jwt.MapClaims) { delete(claims, "exp") }},
+		{"missing subject", func(claims jwt.MapClaims) { delete(claims, "sub") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env.issuer.TamperIDTokens(tt.tamper)
+			defer env.issuer.TamperIDTokens(nil)
+
+			if _, err := env.callback(env.authorize(t, 0, nil)); !errors.Is(err, types.ErrOAuthFailed) {
+				t.Errorf("Callback returned %v, want %v", err, types.ErrOAuthFailed)
+			}
+		})
+	}
+}
+
+func TestOAuthCallbackLinksVerifiedEmail(t *testing.T) {
+	env := newOAuthTestEnv(t)
+
+	tests := []struct {
+		name             string
+		providerVerified bool
+		localVerified    bool
+		wantErr          error
+	}{
+		{"both verified", true, true, nil},
+		{"provider email unverified", false, true, types.ErrUserAlreadyExists},
+		{"local email unverified", true, false, types.ErrUserAlreadyExists},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			username := "local" + string(rune('a'+i))
+			email := username + "@example.com"
+			user := env.createUser(t, username, email, tt.localVerified)
+			env.issuer.SetUser(oauthtest.User{
+				Subject:       "subject-" + username,
+				Email:         email,
+				EmailVerified: tt.providerVerified,
+				Name:          "Provider User",
+				Username:      "provider-" + username,
+			})
+
+			result, err := env.callback(env.authorize(t, 0, nil))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Callback returned %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && result.Auth.User.ID != user.ID {
+				t.Errorf("signed in user %d, want the existing user %d", result.Auth.User.ID, user.ID)
+			}
+		})
+	}
+}
+
+func TestOAuthCallbackKeepsIdentifiersOfDeletedUsers(t *testing.T) {
+	env := newOAuthTestEnv(t)
+
+	// A deleted user holding the provider account's username is skipped
+	deleted := env.createUser(t, "fakeuser", "deleted.user@example.com", true)
+	if err := env.users.Delete(deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := env.callback(env.authorize(t, 0, nil))
+	if err != nil {
+		t.Fatalf("Callback failed: %v", err)
+	}
+	if result.Auth.User.Username == "fakeuser" {
+		t.Error("new user got the username of a deleted user")
+	}
+
+	// A deleted user holding the provider account's email blocks the signup
+	deleted = env.createUser(t, "deleted", "other.user@example.com", true)
+	if err := env.users.Delete(deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+	env.issuer.SetUser(oauthtest.User{
+		Subject:       "other-user",
+		Email:         "other.user@example.com",
+		EmailVerified: true,
+		Username:      "otheruser",
+	})
+
+	if _, err := env.callback(env.authorize(t, 0, nil)); !errors.Is(err, types.ErrUserAlreadyExists) {
+		t.Errorf("Callback returned %v, want %v", err, types.ErrUserAlreadyExists)
+	}
+}
@@ -0,0 +1,885 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/jwt"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/oauth"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+const (
+	// oauthAuthorizeKeyPrefix prefixes the KV keys of authorization requests
+	// waiting for the user's consent
+	oauthAuthorizeKeyPrefix = "oauth_authorize:"
+	// clientSecretPrefix starts every client secret so that leaked secrets are easy to spot
+	clientSecretPrefix = "gcs_"
+	// consentPath is the page of the embedded SPA asking the user for consent
+	consentPath = "/consent"
+)
+
+// OAuth 2.0 error codes (RFC 6749)
+const (
+	oauthErrInvalidRequest          = "invalid_request"
+	oauthErrInvalidClient           = "invalid_client"
+	oauthErrInvalidGrant            = "invalid_grant"
+	oauthErrUnauthorizedClient      = "unauthorized_client"
+	oauthErrUnsupportedGrantType    = "unsupported_grant_type"
+	oauthErrUnsupportedResponseType = "unsupported_response_type"
+	oauthErrInvalidScope            = "invalid_scope"
+	oauthErrAccessDenied            = "access_denied"
+)
+
+// oidcScopes describes the OpenID Connect scopes clients can ask for
+var oidcScopes = map[string]string{
+	model.ScopeOpenID:  "Sign you in with your account",
+	model.ScopeProfile: "View your username and name",
+	model.ScopeEmail:   "View your email address",
+}
+
+// OAuthServerService implements the OAuth 2.0 authorization server and
+// OpenID Connect provider that internal applications sign users in with
+type OAuthServerService struct {
+	issuer          string
+	requestDuration time.Duration
+	codeDuration    time.Duration
+	clientRepo      *repository.OAuthClientRepository
+	authRepo        *repository.AuthRepository
+	userRepo        *repository.UserRepository
+	kvRepo          *repository.KVRepository
+	jwtManager      *jwt.Manager
+	authService     *AuthService
+	rbacService     *RBACService
+	auditService    *AuditService
+}
+
+// NewOAuthServerService creates a new OAuth server service
+func NewOAuthServerService(
+	cfg *config.Config,
+	clientRepo *repository.OAuthClientRepository,
+	authRepo *repository.AuthRepository,
+	userRepo *repository.UserRepository,
+	kvRepo *repository.KVRepository,
+	jwtManager *jwt.Manager,
+	authService *AuthService,
+	rbacService *RBACService,
+	auditService *AuditService,
+) (*OAuthServerService, error) {
+	requestDuration, err := time.ParseDuration(cfg.OAuthServer.AuthorizeRequestDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse authorize request duration: %w", err)
+	}
+
+	codeDuration, err := time.ParseDuration(cfg.OAuthServer.AuthorizationCodeDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse authorization code duration: %w", err)
+	}
+
+	return &OAuthServerService{
+		issuer:          strings.TrimSuffix(cfg.Server.PublicURL, "/"),
+		requestDuration: requestDuration,
+		codeDuration:    codeDuration,
+		clientRepo:      clientRepo,
+		authRepo:        authRepo,
+		userRepo:        userRepo,
+		kvRepo:          kvRepo,
+		jwtManager:      jwtManager,
+		authService:     authService,
+		rbacService:     rbacService,
+		auditService:    auditService,
+	}, nil
+}
+
+// ListClients lists the registered clients
+func (s *OAuthServerService) ListClients() ([]*types.OAuthClientResponse, error) {
+	clients, err := s.clientRepo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*types.OAuthClientResponse, len(clients))
+	for i, client := range clients {
+		responses[i] = s.ToClientResponse(client)
+	}
+	return responses, nil
+}
+
+// GetClient retrieves a registered client
+func (s *OAuthServerService) GetClient(id uint) (*model.OAuthClient, error) {
+	return s.clientRepo.GetByID(id)
+}
+
+// CreateClient registers a client on behalf of an administrator, who must hold
+// every permission the client may ask for. The returned secret of a
+// confidential client is never retrievable again.
+func (s *OAuthServerService) CreateClient(adminID uint, req *types.CreateOAuthClientRequest) (*types.OAuthClientCreatedResponse, error) {
+	if err := s.validateClientName(req.Name); err != nil {
+		return nil, err
+	}
+
+	grantTypes, err := s.validateGrantTypes(req.GrantTypes, req.Confidential)
+	if err != nil {
+		return nil, err
+	}
+
+	redirectURIs, err := s.validateRedirectURIs(req.RedirectURIs, grantTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, err := s.validateClientScopes(adminID, req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &model.OAuthClient{
+		ClientID:     strings.ToLower(rand.Text()),
+		Name:         strings.TrimSpace(req.Name),
+		RedirectURIs: strings.Join(redirectURIs, " "),
+		Scopes:       strings.Join(scopes, " "),
+		GrantTypes:   strings.Join(grantTypes, " "),
+		Trusted:      req.Trusted,
+	}
+
+	var secret string
+	if req.Confidential {
+		secret = clientSecretPrefix + rand.Text() + rand.Text()
+		client.SecretHash = hashToken(secret)
+	}
+
+	if err := s.clientRepo.Create(client); err != nil {
+		return nil, err
+	}
+
+	return &types.OAuthClientCreatedResponse{
+		OAuthClientResponse: *s.ToClientResponse(client),
+		ClientSecret:        secret,
+	}, nil
+}
+
+// UpdateClient changes the settings of a client on behalf of an administrator
+func (s *OAuthServerService) UpdateClient(adminID, id uint, req *types.UpdateOAuthClientRequest) (*model.OAuthClient, error) {
+	client, err := s.clientRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		if err := s.validateClientName(*req.Name); err != nil {
+			return nil, err
+		}
+		client.Name = strings.TrimSpace(*req.Name)
+	}
+
+	grantTypes := strings.Fields(client.GrantTypes)
+	if req.GrantTypes != nil {
+		grantTypes, err = s.validateGrantTypes(*req.GrantTypes, client.Confidential())
+		if err != nil {
+			return nil, err
+		}
+		client.GrantTypes = strings.Join(grantTypes, " ")
+	}
+
+	redirectURIs := strings.Fields(client.RedirectURIs)
+	if req.RedirectURIs != nil {
+		redirectURIs = *req.RedirectURIs
+	}
+	if req.RedirectURIs != nil || req.GrantTypes != nil {
+		redirectURIs, err = s.validateRedirectURIs(redirectURIs, grantTypes)
+		if err != nil {
+			return nil, err
+		}
+		client.RedirectURIs = strings.Join(redirectURIs, " ")
+	}
+
+	if req.Scopes != nil {
+		scopes, err := s.validateClientScopes(adminID, *req.Scopes)
+		if err != nil {
+			return nil, err
+		}
+		client.Scopes = strings.Join(scopes, " ")
+	}
+
+	if req.Trusted != nil {
+		client.Trusted = *req.Trusted
+	}
+
+	if err := s.clientRepo.Update(client); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// RotateClientSecret replaces the secret of a confidential client. The old
+// secret stops working immediately.
+func (s *OAuthServerService) RotateClientSecret(id uint) (*types.OAuthClientCreatedResponse, error) {
+	client, err := s.clientRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.Confidential() {
+		return nil, fmt.Errorf("%w: public clients have no secret", types.ErrValidationFailed)
+	}
+
+	secret := clientSecretPrefix + rand.Text() + rand.Text()
+	client.SecretHash = hashToken(secret)
+	if err := s.clientRepo.Update(client); err != nil {
+		return nil, err
+	}
+
+	return &types.OAuthClientCreatedResponse{
+		OAuthClientResponse: *s.ToClientResponse(client),
+		ClientSecret:        secret,
+	}, nil
+}
+
+// DeleteClient removes a client. Tokens already issued to it stay valid until
+// they expire, but can no longer be refreshed.
+func (s *OAuthServerService) DeleteClient(id uint) error {
+	return s.clientRepo.Delete(id)
+}
+
+// ToClientResponse converts an OAuthClient model to an OAuthClientResponse
+func (s *OAuthServerService) ToClientResponse(client *model.OAuthClient) *types.OAuthClientResponse {
+	return &types.OAuthClientResponse{
+		ID:           client.ID,
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		RedirectURIs: append([]string{}, strings.Fields(client.RedirectURIs)...),
+		Scopes:       append([]string{}, strings.Fields(client.Scopes)...),
+		GrantTypes:   append([]string{}, strings.Fields(client.GrantTypes)...),
+		Confidential: client.Confidential(),
+		Trusted:      client.Trusted,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+// Authorize validates an authorization request and returns the URL to send the
+// browser to: the consent screen, or the client's redirect URI with an error.
+// Requests that cannot be redirected back to the client safely fail with an error.
+func (s *OAuthServerService) Authorize(req *types.OAuthAuthorizeRequest) (string, error) {
+	client, err := s.clientRepo.GetByClientID(req.ClientID)
+	if err != nil {
+		return "", err
+	}
+
+	redirectURIs := strings.Fields(client.RedirectURIs)
+	if req.RedirectURI == "" && len(redirectURIs) == 1 {
+		req.RedirectURI = redirectURIs[0]
+	}
+	if !slices.Contains(redirectURIs, req.RedirectURI) {
+		return "", fmt.Errorf("%w: redirect_uri is not registered for the client", types.ErrValidationFailed)
+	}
+
+	if req.ResponseType != "code" {
+		return s.errorRedirect(req, oauthErrUnsupportedResponseType, "only the code response type is supported"), nil
+	}
+	if !slices.Contains(strings.Fields(client.GrantTypes), model.GrantTypeAuthorizationCode) {
+		return s.errorRedirect(req, oauthErrUnauthorizedClient, "the client may not use the authorization code grant"), nil
+	}
+
+	// PKCE is required for every client, as recommended by OAuth 2.1
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return s.errorRedirect(req, oauthErrInvalidRequest, "a code_challenge using the S256 method is required"), nil
+	}
+
+	scopes := uniqueStrings(strings.Fields(req.Scope))
+	if len(scopes) == 0 {
+		return s.errorRedirect(req, oauthErrInvalidScope, "scope is required"), nil
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(strings.Fields(client.Scopes), scope) {
+			return s.errorRedirect(req, oauthErrInvalidScope, fmt.Sprintf("scope %q is not allowed for the client", scope)), nil
+		}
+	}
+	if slices.Contains(scopes, model.ScopeOpenID) && !s.jwtManager.SupportsIDTokens() {
+		return s.errorRedirect(req, oauthErrInvalidScope, "openid is not supported without an asymmetric signing key"), nil
+	}
+	req.Scope = strings.Join(scopes, " ")
+
+	pending, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+
+	requestID := rand.Text()
+	if err := s.kvRepo.SetWithExpiry(oauthAuthorizeKeyPrefix+requestID, string(pending), time.Now().Add(s.requestDuration)); err != nil {
+		return "", err
+	}
+
+	return s.issuer + consentPath + "?request=" + url.QueryEscape(requestID), nil
+}
+
+// GetConsent describes a pending authorization request to the signed-in user
+func (s *OAuthServerService) GetConsent(userID uint, requestID string) (*types.OAuthConsentResponse, error) {
+	value, err := s.kvRepo.Get(oauthAuthorizeKeyPrefix + requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	req, client, err := s.pendingRequest(value)
+	if err != nil {
+		return nil, err
+	}
+
+	consentRequired, err := s.consentRequired(userID, client, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, err := s.describeScopes(strings.Fields(req.Scope))
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.OAuthConsentResponse{
+		ClientID:        client.ClientID,
+		ClientName:      client.Name,
+		Scopes:          scopes,
+		ConsentRequired: consentRequired,
+	}, nil
+}
+
+// Consent answers a pending authorization request for the signed-in user and
+// returns the client URL to send the browser back to, carrying either an
+// authorization code or the access_denied error. A request can only be answered once.
+func (s *OAuthServerService) Consent(userID uint, requestID string, approve bool, info *types.ClientInfo) (string, error) {
+	value, err := s.kvRepo.Take(oauthAuthorizeKeyPrefix + requestID)
+	if err != nil {
+		return "", err
+	}
+
+	req, client, err := s.pendingRequest(value)
+	if err != nil {
+		return "", err
+	}
+
+	if !approve {
+		return s.errorRedirect(req, oauthErrAccessDenied, "the user denied the request"), nil
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return "", err
+	}
+	if !user.IsActive {
+		return "", types.ErrForbidden
+	}
+
+	consentRequired, err := s.consentRequired(userID, client, req.Scope)
+	if err != nil {
+		return "", err
+	}
+	if consentRequired {
+		if err := s.saveConsent(userID, client.ClientID, req.Scope); err != nil {
+			return "", err
+		}
+		s.auditService.Record(userID, model.AuditEventOAuthConsentGranted, info, map[string]interface{}{
+			"client_id": client.ClientID,
+			"scope":     req.Scope,
+		})
+	}
+
+	code, codeHash, err := newOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	// The family of the tokens the code is exchanged into is fixed now, so that
+	// a replayed code can revoke them
+	familyID, err := newFamilyID()
+	if err != nil {
+		return "", err
+	}
+
+	authorizationCode := &model.OAuthAuthorizationCode{
+		SingleUseToken: model.SingleUseToken{
+			TokenHash: codeHash,
+			ExpiresAt: time.Now().Add(s.codeDuration),
+		},
+		ClientID:      client.ClientID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         req.Scope,
+		CodeChallenge: req.CodeChallenge,
+		Nonce:         req.Nonce,
+		FamilyID:      familyID,
+	}
+	if err := s.authRepo.CreateAuthorizationCode(authorizationCode); err != nil {
+		return "", err
+	}
+
+	return s.redirect(req, url.Values{"code": {code}}), nil
+}
+
+// Token handles a token request of a client. Protocol errors are returned as
+// *types.OAuthError.
+func (s *OAuthServerService) Token(req *types.OAuthTokenRequest, info *types.ClientInfo) (*types.OAuthTokenResponse, error) {
+	client, err := s.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case model.GrantTypeAuthorizationCode, model.GrantTypeRefreshToken, model.GrantTypeClientCredentials:
+	default:
+		return nil, types.NewOAuthError(oauthErrUnsupportedGrantType, "")
+	}
+
+	if !slices.Contains(strings.Fields(client.GrantTypes), req.GrantType) {
+		return nil, types.NewOAuthError(oauthErrUnauthorizedClient, "the client may not use this grant type")
+	}
+
+	switch req.GrantType {
+	case model.GrantTypeAuthorizationCode:
+		return s.exchangeCode(client, req, info)
+	case model.GrantTypeRefreshToken:
+		return s.refresh(client, req, info)
+	default:
+		return s.clientCredentials(client, req)
+	}
+}
+
+// UserInfo returns the claims about a user that the granted scopes allow a client to see
+func (s *OAuthServerService) UserInfo(userID uint, scope string) (*types.UserInfoResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := s.userClaims(user, scope)
+	return &types.UserInfoResponse{
+		Subject:           claims.Subject,
+		PreferredUsername: claims.PreferredUsername,
+		Name:              claims.Name,
+		GivenName:         claims.GivenName,
+		FamilyName:        claims.FamilyName,
+		Email:             claims.Email,
+		EmailVerified:     claims.EmailVerified,
+	}, nil
+}
+
+// Discovery returns the OpenID Connect discovery document
+func (s *OAuthServerService) Discovery() (*types.OpenIDConfiguration, error) {
+	permissions, err := s.rbacService.ListPermissions()
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := []string{model.ScopeProfile, model.ScopeEmail}
+	if s.jwtManager.SupportsIDTokens() {
+		scopes = append([]string{model.ScopeOpenID}, scopes...)
+	}
+	for _, permission := range permissions {
+		scopes = append(scopes, permission.Name)
+	}
+
+	return &types.OpenIDConfiguration{
+		Issuer:                 s.issuer,
+		AuthorizationEndpoint:  s.issuer + "/oauth/authorize",
+		TokenEndpoint:          s.issuer + "/oauth/token",
+		UserinfoEndpoint:       s.issuer + "/oauth/userinfo",
+		JWKSURI:                s.issuer + "/.well-known/jwks.json",
+		ScopesSupported:        scopes,
+		ResponseTypesSupported: []string{"code"},
+		GrantTypesSupported: []string{
+			model.GrantTypeAuthorizationCode,
+			model.GrantTypeRefreshToken,
+			model.GrantTypeClientCredentials,
+		},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{s.jwtManager.SigningAlgorithm()},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "nonce",
+			"preferred_username", "name", "given_name", "family_name", "email", "email_verified",
+		},
+	}, nil
+}
+
+// exchangeCode redeems an authorization code. The code is only spent once the
+// client proved it started the flow; a code presented again after that revokes
+// the tokens issued for it, as it must have been intercepted.
+func (s *OAuthServerService) exchangeCode(client *model.OAuthClient, req *types.OAuthTokenRequest, info *types.ClientInfo) (*types.OAuthTokenResponse, error) {
+	codeHash := hashToken(req.Code)
+	code, err := s.authRepo.GetAuthorizationCode(codeHash)
+	if err != nil {
+		if err == types.ErrInvalidToken {
+			return nil, types.NewOAuthError(oauthErrInvalidGrant, "the authorization code is invalid or expired")
+		}
+		return nil, err
+	}
+
+	if code.ClientID != client.ClientID || code.RedirectURI != req.RedirectURI {
+		return nil, types.NewOAuthError(oauthErrInvalidGrant, "the authorization code was issued to another client or redirect_uri")
+	}
+
+	if req.CodeVerifier == "" || subtle.ConstantTimeCompare([]byte(oauth.CodeChallenge(req.CodeVerifier)), []byte(code.CodeChallenge)) != 1 {
+		return nil, types.NewOAuthError(oauthErrInvalidGrant, "the code_verifier does not match the code_challenge")
+	}
+
+	if _, err := s.authRepo.ConsumeAuthorizationCode(codeHash); err != nil {
+		switch err {
+		case types.ErrTokenRevoked:
+			if err := s.handleCodeReuse(code, info); err != nil {
+				return nil, err
+			}
+			return nil, types.NewOAuthError(oauthErrInvalidGrant, "the authorization code was already used")
+		case types.ErrInvalidToken, types.ErrTokenExpired:
+			return nil, types.NewOAuthError(oauthErrInvalidGrant, "the authorization code is invalid or expired")
+		default:
+			return nil, err
+		}
+	}
+
+	user, err := s.userRepo.GetByID(code.UserID)
+	if err != nil {
+		if err == types.ErrUserNotFound {
+			return nil, types.NewOAuthError(oauthErrInvalidGrant, "the user no longer exists")
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, types.NewOAuthError(oauthErrInvalidGrant, "the account is disabled")
+	}
+
+	withRefreshToken := slices.Contains(strings.Fields(client.GrantTypes), model.GrantTypeRefreshToken)
+	tokenPair, err := s.authService.IssueClientTokens(user, code.FamilyID, client.ClientID, client.Name, code.Scope, withRefreshToken, info)
+	if err != nil {
+		return nil, err
+	}
+
+	response := s.tokenResponse(tokenPair, code.Scope)
+	if slices.Contains(strings.Fields(code.Scope), model.ScopeOpenID) {
+		claims := s.userClaims(user, code.Scope)
+		claims.Issuer = s.issuer
+		claims.Audience = []string{client.ClientID}
+		claims.Nonce = code.Nonce
+
+		response.IDToken, err = s.jwtManager.GenerateIDToken(claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+// handleCodeReuse revokes the session started with an authorization code that
+// was presented again
+func (s *OAuthServerService) handleCodeReuse(code *model.OAuthAuthorizationCode, info *types.ClientInfo) error {
+	if err := s.authService.RevokeTokenFamily(code.FamilyID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(code.UserID, model.AuditEventAuthorizationCodeReuse, info, map[string]interface{}{
+		"client_id": code.ClientID,
+		"family_id": code.FamilyID,
+	})
+	return nil
+}
+
+// refresh rotates a refresh token issued to the client
+func (s *OAuthServerService) refresh(client *model.OAuthClient, req *types.OAuthTokenRequest, info *types.ClientInfo) (*types.OAuthTokenResponse, error) {
+	tokenPair, scope, err := s.authService.RefreshClientToken(req.RefreshToken, client.ClientID, info)
+	if err != nil {
+		switch err {
+		case types.ErrInvalidToken, types.ErrTokenExpired, types.ErrTokenRevoked,
+			types.ErrUserNotFound, types.ErrForbidden:
+			return nil, types.NewOAuthError(oauthErrInvalidGrant, "the refresh token is invalid, expired or revoked")
+		default:
+			return nil, err
+		}
+	}
+
+	return s.tokenResponse(tokenPair, scope), nil
+}
+
+// clientCredentials issues an access token to a confidential client acting on
+// its own behalf. Without a scope parameter, every permission scope of the
+// client is granted.
+func (s *OAuthServerService) clientCredentials(client *model.OAuthClient, req *types.OAuthTokenRequest) (*types.OAuthTokenResponse, error) {
+	if !client.Confidential() {
+		return nil, types.NewOAuthError(oauthErrUnauthorizedClient, "public clients cannot use the client credentials grant")
+	}
+
+	var scopes []string
+	for _, scope := range strings.Fields(client.Scopes) {
+		if _, ok := oidcScopes[scope]; !ok {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if requested := uniqueStrings(strings.Fields(req.Scope)); len(requested) > 0 {
+		for _, scope := range requested {
+			if !slices.Contains(scopes, scope) {
+				return nil, types.NewOAuthError(oauthErrInvalidScope, fmt.Sprintf("scope %q is not allowed for the client", scope))
+			}
+		}
+		scopes = requested
+	}
+
+	scope := strings.Join(scopes, " ")
+	accessToken, err := s.jwtManager.GenerateAccessToken(0, "", "",
+		jwt.WithRoles(nil, scopes), jwt.WithClient(client.ClientID, scope))
+	if err != nil {
+		return nil, err
+	}
+
+	return s.tokenResponse(&jwt.TokenPair{AccessToken: accessToken}, scope), nil
+}
+
+// authenticateClient identifies the client of a token request. Confidential
+// clients must present their secret; public clients have none.
+func (s *OAuthServerService) authenticateClient(clientID, secret string) (*model.OAuthClient, error) {
+	if clientID == "" {
+		return nil, types.NewOAuthError(oauthErrInvalidClient, "client authentication failed")
+	}
+
+	client, err := s.clientRepo.GetByClientID(clientID)
+	if err != nil {
+		if err == types.ErrOAuthClientNotFound {
+			return nil, types.NewOAuthError(oauthErrInvalidClient, "client authentication failed")
+		}
+		return nil, err
+	}
+
+	if client.Confidential() {
+		if secret == "" || subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(client.SecretHash)) != 1 {
+			return nil, types.NewOAuthError(oauthErrInvalidClient, "client authentication failed")
+		}
+	} else if secret != "" {
+		return nil, types.NewOAuthError(oauthErrInvalidClient, "public clients have no secret")
+	}
+
+	return client, nil
+}
+
+// tokenResponse builds the token response for a token pair
+func (s *OAuthServerService) tokenResponse(tokenPair *jwt.TokenPair, scope string) *types.OAuthTokenResponse {
+	return &types.OAuthTokenResponse{
+		AccessToken:  tokenPair.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.jwtManager.GetAccessTokenDuration().Seconds()),
+		RefreshToken: tokenPair.RefreshToken,
+		Scope:        scope,
+	}
+}
+
+// userClaims returns the OpenID Connect claims about a user allowed by the granted scopes
+func (s *OAuthServerService) userClaims(user *model.User, scope string) *jwt.IDTokenClaims {
+	claims := &jwt.IDTokenClaims{}
+	claims.Subject = strconv.FormatUint(uint64(user.ID), 10)
+
+	scopes := strings.Fields(scope)
+	if slices.Contains(scopes, model.ScopeProfile) {
+		claims.PreferredUsername = user.Username
+		claims.Name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		claims.GivenName = user.FirstName
+		claims.FamilyName = user.LastName
+	}
+	if slices.Contains(scopes, model.ScopeEmail) {
+		verified := user.EmailVerifiedAt != nil
+		claims.Email = user.Email
+		claims.EmailVerified = &verified
+	}
+	return claims
+}
+
+// pendingRequest decodes a stored authorization request and loads its client
+func (s *OAuthServerService) pendingRequest(value string) (*types.OAuthAuthorizeRequest, *model.OAuthClient, error) {
+	if value == "" {
+		return nil, nil, types.ErrInvalidToken
+	}
+
+	var req types.OAuthAuthorizeRequest
+	if err := json.Unmarshal([]byte(value), &req); err != nil {
+		return nil, nil, types.ErrInvalidToken
+	}
+
+	// The client may have been deleted while the user was signing in
+	client, err := s.clientRepo.GetByClientID(req.ClientID)
+	if err != nil {
+		if err == types.ErrOAuthClientNotFound {
+			return nil, nil, types.ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+
+	return &req, client, nil
+}
+
+// consentRequired reports whether the user has to approve the scopes, which is
+// the case unless the client is trusted or the user already approved them
+func (s *OAuthServerService) consentRequired(userID uint, client *model.OAuthClient, scope string) (bool, error) {
+	if client.Trusted {
+		return false, nil
+	}
+
+	consented, err := s.clientRepo.GetConsent(userID, client.ClientID)
+	if err != nil {
+		return false, err
+	}
+
+	granted := strings.Fields(consented)
+	for _, name := range strings.Fields(scope) {
+		if !slices.Contains(granted, name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// saveConsent adds scopes to those a user already approved for a client
+func (s *OAuthServerService) saveConsent(userID uint, clientID, scope string) error {
+	consented, err := s.clientRepo.GetConsent(userID, clientID)
+	if err != nil {
+		return err
+	}
+
+	scopes := uniqueStrings(append(strings.Fields(consented), strings.Fields(scope)...))
+	return s.clientRepo.SaveConsent(userID, clientID, strings.Join(scopes, " "))
+}
+
+// describeScopes returns the consent screen descriptions of scopes
+func (s *OAuthServerService) describeScopes(scopes []string) ([]*types.OAuthScopeResponse, error) {
+	permissions, err := s.rbacService.ListPermissions()
+	if err != nil {
+		return nil, err
+	}
+
+	descriptions := make(map[string]string, len(oidcScopes)+len(permissions))
+	for name, description := range oidcScopes {
+		descriptions[name] = description
+	}
+	for _, permission := range permissions {
+		descriptions[permission.Name] = permission.Description
+	}
+
+	responses := make([]*types.OAuthScopeResponse, len(scopes))
+	for i, scope := range scopes {
+		responses[i] = &types.OAuthScopeResponse{
+			Name:        scope,
+			Description: descriptions[scope],
+		}
+	}
+	return responses, nil
+}
+
+// errorRedirect returns the client URL reporting an authorization error
+func (s *OAuthServerService) errorRedirect(req *types.OAuthAuthorizeRequest, code, description string) string {
+	return s.redirect(req, url.Values{
+		"error":             {code},
+		"error_description": {description},
+	})
+}
+
+// redirect returns the client's redirect URI with the given parameters, the
+// state and the issuer (RFC 9207) added to its query
+func (s *OAuthServerService) redirect(req *types.OAuthAuthorizeRequest, params url.Values) string {
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	params.Set("iss", s.issuer)
+
+	// Redirect URIs are validated on registration
+	target, _ := url.Parse(req.RedirectURI)
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	target.RawQuery = query.Encode()
+	return target.String()
+}
+
+// validateClientName checks the display name of a client
+func (s *OAuthServerService) validateClientName(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", types.ErrValidationFailed)
+	}
+	if len(name) > 100 {
+		return fmt.Errorf("%w: name must be at most 100 characters", types.ErrValidationFailed)
+	}
+	return nil
+}
+
+// validateGrantTypes checks the grant types of a client, defaulting to the
+// authorization code flow with refresh tokens
+func (s *OAuthServerService) validateGrantTypes(grantTypes []string, confidential bool) ([]string, error) {
+	grantTypes = uniqueStrings(grantTypes)
+	if len(grantTypes) == 0 {
+		return []string{model.GrantTypeAuthorizationCode, model.GrantTypeRefreshToken}, nil
+	}
+
+	for _, grantType := range grantTypes {
+		switch grantType {
+		case model.GrantTypeAuthorizationCode, model.GrantTypeRefreshToken:
+		case model.GrantTypeClientCredentials:
+			if !confidential {
+				return nil, fmt.Errorf("%w: public clients cannot use the client_credentials grant", types.ErrValidationFailed)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unsupported grant type %q", types.ErrValidationFailed, grantType)
+		}
+	}
+	return grantTypes, nil
+}
+
+// validateRedirectURIs checks the redirect URIs of a client. Clients using the
+// authorization code grant need at least one.
+func (s *OAuthServerService) validateRedirectURIs(redirectURIs []string, grantTypes []string) ([]string, error) {
+	redirectURIs = uniqueStrings(redirectURIs)
+	if len(redirectURIs) == 0 && slices.Contains(grantTypes, model.GrantTypeAuthorizationCode) {
+		return nil, fmt.Errorf("%w: at least one redirect URI is required", types.ErrValidationFailed)
+	}
+
+	for _, redirectURI := range redirectURIs {
+		target, err := url.Parse(redirectURI)
+		if err != nil || target.Scheme == "" || target.Fragment != "" || strings.ContainsAny(redirectURI, " #") {
+			return nil, fmt.Errorf("%w: redirect URI %q must be an absolute URL without fragment", types.ErrValidationFailed, redirectURI)
+		}
+
+		// Plain HTTP is only acceptable for apps listening on the loopback interface;
+		// native apps may also use a private-use scheme
+		if target.Scheme == "http" {
+			if host := target.Hostname(); host != "localhost" && host != "127.0.0.1" && host != "::1" {
+				return nil, fmt.Errorf("%w: redirect URI %q must use https", types.ErrValidationFailed, redirectURI)
+			}
+		}
+	}
+	return redirectURIs, nil
+}
+
+// validateClientScopes checks the scopes a client may ask for. Permission
+// scopes must be held by the administrator registering the client.
+func (s *OAuthServerService) validateClientScopes(adminID uint, scopes []string) ([]string, error) {
+	_, granted, err := s.rbacService.Grants(adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes = uniqueStrings(scopes)
+	for _, scope := range scopes {
+		if _, ok := oidcScopes[scope]; ok {
+			continue
+		}
+		if !slices.Contains(granted, scope) {
+			return nil, fmt.Errorf("%w: scope %q is not granted to you", types.ErrValidationFailed, scope)
+		}
+	}
+	return scopes, nil
+}
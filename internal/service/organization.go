@@ -0,0 +1,523 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/mailer"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/tenant"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/validator"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+const (
+	// maxOrgNameLength is the longest accepted organization name
+	maxOrgNameLength = 100
+	// maxOrgSlugLength is the longest accepted organization slug
+	maxOrgSlugLength = 50
+)
+
+var (
+	// orgSlugPattern matches lowercase words joined by single hyphens
+	orgSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	// orgSlugSeparators matches the runs of characters replaced by a hyphen
+	// when deriving a slug from a name
+	orgSlugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// OrganizationService handles organization, membership and organization
+// invitation business logic. Methods taking a context work within the
+// organization the context is scoped to, and check the role of the acting
+// user in it again rather than trusting the role in their token.
+type OrganizationService struct {
+	orgRepo            *repository.OrganizationRepository
+	userRepo           *repository.UserRepository
+	authService        *AuthService
+	auditService       *AuditService
+	validator          *validator.Validator
+	mailer             mailer.Mailer
+	publicURL          string
+	invitationDuration time.Duration
+}
+
+// NewOrganizationService creates a new organization service
+func NewOrganizationService(
+	cfg *config.Config,
+	orgRepo *repository.OrganizationRepository,
+	userRepo *repository.UserRepository,
+	authService *AuthService,
+	auditService *AuditService,
+	validator *validator.Validator,
+	mailer mailer.Mailer,
+) (*OrganizationService, error) {
+	invitationDuration, err := time.ParseDuration(cfg.Org.InvitationDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse organization invitation duration: %w", err)
+	}
+
+	return &OrganizationService{
+		orgRepo:            orgRepo,
+		userRepo:           userRepo,
+		authService:        authService,
+		auditService:       auditService,
+		validator:          validator,
+		mailer:             mailer,
+		publicURL:          cfg.Server.PublicURL,
+		invitationDuration: invitationDuration,
+	}, nil
+}
+
+// Create creates an organization owned by the user. It becomes the active
+// organization of the user's sessions once they switch to it.
+func (s *OrganizationService) Create(userID uint, req *types.CreateOrgRequest, client *types.ClientInfo) (*model.Membership, error) {
+	name := strings.TrimSpace(req.Name)
+	if err := validateOrgName(name); err != nil {
+		return nil, err
+	}
+
+	slug := req.Slug
+	if slug == "" {
+		slug = deriveOrgSlug(name)
+	}
+	if err := s.checkSlug(slug, 0); err != nil {
+		return nil, err
+	}
+
+	org := &model.Organization{
+		Name:        name,
+		Slug:        slug,
+		CreatedByID: userID,
+	}
+	if err := s.orgRepo.Create(org, userID); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(userID, model.AuditEventOrgCreated, client, map[string]interface{}{
+		"org_id": org.ID,
+		"slug":   org.Slug,
+	})
+
+	return &model.Membership{
+		OrgID:        org.ID,
+		UserID:       userID,
+		Role:         model.OrgRoleOwner,
+		Organization: *org,
+	}, nil
+}
+
+// List lists the organizations the user is a member of
+func (s *OrganizationService) List(userID uint) ([]*model.Membership, error) {
+	return s.orgRepo.ListMemberships(userID)
+}
+
+// Switch makes an organization the active organization of one of the user's
+// sessions and returns the session's new tokens. Zero leaves the session
+// without an active organization.
+func (s *OrganizationService) Switch(userID uint, sessionID string, req *types.SwitchOrgRequest, client *types.ClientInfo) (*types.TokenResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.authService.SwitchOrganization(user, sessionID, req.OrgID, client)
+}
+
+// Current retrieves the organization with the user's membership in it
+func (s *OrganizationService) Current(ctx context.Context, userID uint) (*model.Membership, error) {
+	membership, err := s.requireRole(ctx, userID, model.OrgRoleMember)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := s.orgRepo.GetByID(membership.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	membership.Organization = *org
+	return membership, nil
+}
+
+// Update renames the organization or changes its slug. Only admins and owners
+// can update it.
+func (s *OrganizationService) Update(ctx context.Context, userID uint, req *types.UpdateOrgRequest) (*model.Membership, error) {
+	membership, err := s.requireRole(ctx, userID, model.OrgRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := s.orgRepo.GetByID(membership.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if err := validateOrgName(name); err != nil {
+			return nil, err
+		}
+		org.Name = name
+	}
+	if req.Slug != nil && *req.Slug != org.Slug {
+		if err := s.checkSlug(*req.Slug, org.ID); err != nil {
+			return nil, err
+		}
+		org.Slug = *req.Slug
+	}
+
+	if err := s.orgRepo.Update(org); err != nil {
+		return nil, err
+	}
+
+	membership.Organization = *org
+	return membership, nil
+}
+
+// Delete permanently deletes the organization with its memberships and
+// invitations. Only owners can delete it.
+func (s *OrganizationService) Delete(ctx context.Context, userID uint, client *types.ClientInfo) error {
+	membership, err := s.requireRole(ctx, userID, model.OrgRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	if err := s.orgRepo.Delete(membership.OrgID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(userID, model.AuditEventOrgDeleted, client, map[string]interface{}{
+		"org_id": membership.OrgID,
+	})
+	return nil
+}
+
+// ListMembers lists the members of the organization
+func (s *OrganizationService) ListMembers(ctx context.Context, userID uint) ([]*model.Membership, error) {
+	if _, err := s.requireRole(ctx, userID, model.OrgRoleMember); err != nil {
+		return nil, err
+	}
+	return s.orgRepo.ListMembers(ctx)
+}
+
+// UpdateMember changes the role of a member. Admins manage admins and
+// members; only owners can make or unmake owners, and the last owner cannot
+// be demoted.
+func (s *OrganizationService) UpdateMember(ctx context.Context, actorID, userID uint, req *types.UpdateMemberRequest, client *types.ClientInfo) (*model.Membership, error) {
+	if model.OrgRoleRank(req.Role) == 0 {
+		return nil, fmt.Errorf("%w: role must be one of owner, admin or member", types.ErrValidationFailed)
+	}
+
+	actor, err := s.requireRole(ctx, actorID, model.OrgRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := s.orgRepo.GetMember(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role == req.Role {
+		return member, nil
+	}
+
+	if (member.Role == model.OrgRoleOwner || req.Role == model.OrgRoleOwner) && actor.Role != model.OrgRoleOwner {
+		return nil, types.ErrForbidden
+	}
+	if err := s.orgRepo.UpdateMemberRole(ctx, userID, req.Role); err != nil {
+		return nil, err
+	}
+
+	s.auditService.RecordBy(actorID, userID, model.AuditEventOrgMemberRoleChanged, client, map[string]interface{}{
+		"org_id":   member.OrgID,
+		"old_role": member.Role,
+		"new_role": req.Role,
+	})
+
+	member.Role = req.Role
+	return member, nil
+}
+
+// RemoveMember removes a member from the organization. Every member can
+// leave; removing others takes an admin, and removing an owner an owner. The
+// last owner cannot leave.
+func (s *OrganizationService) RemoveMember(ctx context.Context, actorID, userID uint, client *types.ClientInfo) error {
+	actor, err := s.requireRole(ctx, actorID, model.OrgRoleMember)
+	if err != nil {
+		return err
+	}
+
+	member := actor
+	if userID != actorID {
+		if model.OrgRoleRank(actor.Role) < model.OrgRoleRank(model.OrgRoleAdmin) {
+			return types.ErrForbidden
+		}
+
+		member, err = s.orgRepo.GetMember(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if member.Role == model.OrgRoleOwner && actor.Role != model.OrgRoleOwner {
+			return types.ErrForbidden
+		}
+	}
+
+	if err := s.orgRepo.RemoveMember(ctx, userID); err != nil {
+		return err
+	}
+
+	s.auditService.RecordBy(actorID, userID, model.AuditEventOrgMemberRemoved, client, map[string]interface{}{
+		"org_id": member.OrgID,
+		"role":   member.Role,
+	})
+	return nil
+}
+
+// Invite mails an invitation to join the organization to an email address,
+// replacing earlier invitations of the address. Only admins and owners can
+// invite, and only owners can invite owners.
+func (s *OrganizationService) Invite(ctx context.Context, actorID uint, req *types.CreateOrgInvitationRequest, client *types.ClientInfo) (*model.OrgInvitation, error) {
+	if err := s.validator.ValidateEmail(req.Email); err != nil {
+		return nil, fmt.Errorf("%w: %s", types.ErrValidationFailed, err)
+	}
+	role := req.Role
+	if role == "" {
+		role = model.OrgRoleMember
+	}
+	if model.OrgRoleRank(role) == 0 {
+		return nil, fmt.Errorf("%w: role must be one of owner, admin or member", types.ErrValidationFailed)
+	}
+
+	actor, err := s.requireRole(ctx, actorID, model.OrgRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if role == model.OrgRoleOwner && actor.Role != model.OrgRoleOwner {
+		return nil, types.ErrForbidden
+	}
+
+	org, err := s.orgRepo.GetByID(actor.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Registered users are matched by their verified address when they accept,
+	// so an address already in use by a member cannot join twice
+	if user, err := s.userRepo.GetByEmail(req.Email); err == nil {
+		if _, err := s.orgRepo.GetMember(ctx, user.ID); err == nil {
+			return nil, types.ErrAlreadyMember
+		} else if !errors.Is(err, types.ErrMemberNotFound) {
+			return nil, err
+		}
+	} else if !errors.Is(err, types.ErrUserNotFound) {
+		return nil, err
+	}
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := &model.OrgInvitation{
+		Email:           req.Email,
+		EmailNormalized: model.NormalizeIdentifier(req.Email),
+		Role:            role,
+		TokenHash:       tokenHash,
+		InvitedByID:     actorID,
+		ExpiresAt:       time.Now().Add(s.invitationDuration),
+	}
+	if err := s.orgRepo.CreateInvitation(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	inviter, err := s.userRepo.GetByID(actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mailer.Send(&mailer.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("Join %s", org.Name),
+		Body: fmt.Sprintf(
+			"Hi,\n\n%s invited you to join %s as %s. Sign in or register with this email address, verify it, and use the link below to accept. The link expires in %s.\n\n%s/orgs/invitations/accept?token=%s\n\nIf you were not expecting this invitation you can ignore this email.",
+			inviter.Username, org.Name, role, s.invitationDuration, s.publicURL, token,
+		),
+	}); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actorID, model.AuditEventOrgMemberInvited, client, map[string]interface{}{
+		"org_id": invitation.OrgID,
+		"email":  invitation.Email,
+		"role":   role,
+	})
+
+	return invitation, nil
+}
+
+// ListInvitations lists the pending invitations to the organization. Only
+// admins and owners can list them.
+func (s *OrganizationService) ListInvitations(ctx context.Context, actorID uint) ([]*model.OrgInvitation, error) {
+	if _, err := s.requireRole(ctx, actorID, model.OrgRoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.orgRepo.ListInvitations(ctx)
+}
+
+// RevokeInvitation deletes a pending invitation to the organization. Only
+// admins and owners can revoke invitations.
+func (s *OrganizationService) RevokeInvitation(ctx context.Context, actorID, id uint) error {
+	if _, err := s.requireRole(ctx, actorID, model.OrgRoleAdmin); err != nil {
+		return err
+	}
+	return s.orgRepo.DeleteInvitation(ctx, id)
+}
+
+// AcceptInvitation adds the user to the organization they were invited to.
+// The invitation must be addressed to the user's verified email address.
+func (s *OrganizationService) AcceptInvitation(userID uint, req *types.AcceptOrgInvitationRequest, client *types.ClientInfo) (*model.Membership, error) {
+	if req.Token == "" {
+		return nil, types.ErrInvitationNotFound
+	}
+
+	invitation, err := s.orgRepo.GetInvitationByTokenHash(hashToken(req.Token))
+	if err != nil {
+		return nil, err
+	}
+	if !invitation.ExpiresAt.After(time.Now()) {
+		return nil, types.ErrInvitationNotFound
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if model.NormalizeIdentifier(user.Email) != invitation.EmailNormalized {
+		return nil, types.ErrForbidden
+	}
+	if user.EmailVerifiedAt == nil {
+		return nil, types.ErrEmailNotVerified
+	}
+
+	ctx := tenant.WithOrgID(context.Background(), invitation.OrgID)
+	if _, err := s.orgRepo.GetMember(ctx, userID); err == nil {
+		return nil, types.ErrAlreadyMember
+	} else if !errors.Is(err, types.ErrMemberNotFound) {
+		return nil, err
+	}
+
+	member := &model.Membership{
+		UserID: userID,
+		Role:   invitation.Role,
+	}
+	if err := s.orgRepo.AcceptInvitation(ctx, invitation, member); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(userID, model.AuditEventOrgMemberJoined, client, map[string]interface{}{
+		"org_id": invitation.OrgID,
+		"role":   invitation.Role,
+	})
+
+	member.Organization = invitation.Organization
+	return member, nil
+}
+
+// ToResponse converts a membership with its organization to an organization
+// response
+func (s *OrganizationService) ToResponse(membership *model.Membership, activeOrgID uint) *types.OrgResponse {
+	return &types.OrgResponse{
+		ID:        membership.Organization.ID,
+		Name:      membership.Organization.Name,
+		Slug:      membership.Organization.Slug,
+		Role:      membership.Role,
+		Active:    membership.OrgID == activeOrgID,
+		CreatedAt: membership.Organization.CreatedAt,
+	}
+}
+
+// ToMemberResponse converts a membership with its user to a member response
+func (s *OrganizationService) ToMemberResponse(membership *model.Membership) *types.MemberResponse {
+	return &types.MemberResponse{
+		UserID:   membership.UserID,
+		Username: membership.User.Username,
+		Email:    membership.User.Email,
+		Role:     membership.Role,
+		JoinedAt: membership.CreatedAt,
+	}
+}
+
+// ToInvitationResponse converts an organization invitation to a response
+func (s *OrganizationService) ToInvitationResponse(invitation *model.OrgInvitation) *types.OrgInvitationResponse {
+	return &types.OrgInvitationResponse{
+		ID:          invitation.ID,
+		Email:       invitation.Email,
+		Role:        invitation.Role,
+		InvitedByID: invitation.InvitedByID,
+		ExpiresAt:   invitation.ExpiresAt,
+		CreatedAt:   invitation.CreatedAt,
+	}
+}
+
+// requireRole retrieves the membership of the acting user in the organization
+// and checks that their role is at least minRole
+func (s *OrganizationService) requireRole(ctx context.Context, userID uint, minRole string) (*model.Membership, error) {
+	if _, ok := tenant.OrgID(ctx); !ok {
+		return nil, types.ErrNoActiveOrg
+	}
+
+	membership, err := s.orgRepo.GetMember(ctx, userID)
+	if err != nil {
+		if errors.Is(err, types.ErrMemberNotFound) {
+			// Left or was removed since the token was issued
+			return nil, types.ErrForbidden
+		}
+		return nil, err
+	}
+	if model.OrgRoleRank(membership.Role) < model.OrgRoleRank(minRole) {
+		return nil, types.ErrForbidden
+	}
+	return membership, nil
+}
+
+// checkSlug validates a slug and checks that no other organization uses it
+func (s *OrganizationService) checkSlug(slug string, orgID uint) error {
+	if len(slug) < 2 || len(slug) > maxOrgSlugLength || !orgSlugPattern.MatchString(slug) {
+		return fmt.Errorf("%w: slug must be 2 to %d lowercase letters, digits and single hyphens", types.ErrValidationFailed, maxOrgSlugLength)
+	}
+
+	taken, err := s.orgRepo.SlugTaken(slug, orgID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return types.ErrOrgSlugTaken
+	}
+	return nil
+}
+
+// validateOrgName checks the length of a trimmed organization name
+func validateOrgName(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: name is required", types.ErrValidationFailed)
+	}
+	if len(name) > maxOrgNameLength {
+		return fmt.Errorf("%w: name must be at most %d characters", types.ErrValidationFailed, maxOrgNameLength)
+	}
+	return nil
+}
+
+// deriveOrgSlug derives a slug from an organization name
+func deriveOrgSlug(name string) string {
+	slug := orgSlugSeparators.ReplaceAllString(strings.ToLower(name), "-")
+	slug = strings.Trim(slug, "-")
+	if len(slug) > maxOrgSlugLength {
+		slug = strings.TrimRight(slug[:maxOrgSlugLength], "-")
+	}
+	return slug
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/mailer"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/validator"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// PasswordService handles password change and recovery business logic
+type PasswordService struct {
+	userRepo           *repository.UserRepository
+	authRepo           *repository.AuthRepository
+	userService        *UserService
+	authService        *AuthService
+	validator          *validator.Validator
+	mailer             mailer.Mailer
+	publicURL          string
+	resetTokenDuration time.Duration
+}
+
+// NewPasswordService creates a new password service
+func NewPasswordService(
+	cfg *config.Config,
+	userRepo *repository.UserRepository,
+	authRepo *repository.AuthRepository,
+	userService *UserService,
+	authService *AuthService,
+	validator *validator.Validator,
+	mailer mailer.Mailer,
+) (*PasswordService, error) {
+	resetDuration, err := time.ParseDuration(cfg.Auth.PasswordResetTokenDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse password reset token duration: %w", err)
+	}
+
+	return &PasswordService{
+		userRepo:           userRepo,
+		authRepo:           authRepo,
+		userService:        userService,
+		authService:        authService,
+		validator:          validator,
+		mailer:             mailer,
+		publicURL:          cfg.Server.PublicURL,
+		resetTokenDuration: resetDuration,
+	}, nil
+}
+
+// ForgotPassword issues a password reset token and mails it to the user.
+// Unknown or disabled accounts are ignored so the endpoint cannot be used
+// to discover registered email addresses.
+func (s *PasswordService) ForgotPassword(req *types.ForgotPasswordRequest) error {
+	if req.Email == "" {
+		return types.ErrValidationFailed
+	}
+
+	user, err := s.userRepo.GetByEmail(req.Email)
+	if err != nil {
+		if err == types.ErrUserNotFound {
+			return nil
+		}
+		return err
+	}
+
+	if !user.IsActive {
+		return nil
+	}
+
+	return s.sendResetLink(user)
+}
+
+// ForceReset makes a user choose a new password: the current one stops
+// working, all sessions are signed out and a reset link is mailed to the user
+func (s *PasswordService) ForceReset(user *model.User) error {
+	// An empty hash matches no password
+	user.PasswordHash = ""
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	if err := s.userService.RevokeAllSessions(user.ID); err != nil {
+		return err
+	}
+
+	return s.sendResetLink(user)
+}
+
+// sendResetLink issues a password reset token and mails it to the user
+func (s *PasswordService) sendResetLink(user *model.User) error {
+	// Only the most recently issued link should work
+	if err := s.authRepo.InvalidateUserPasswordResetTokens(user.ID); err != nil {
+		return err
+	}
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	resetToken := &model.PasswordResetToken{
+		UserID: user.ID,
+		SingleUseToken: model.SingleUseToken{
+			TokenHash: tokenHash,
+			ExpiresAt: time.Now().Add(s.resetTokenDuration),
+		},
+	}
+
+	if err := s.authRepo.CreatePasswordResetToken(resetToken); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to choose a new password. The link expires in %s.\n\n%s/reset-password?token=%s\n\nIf you did not request a password reset you can ignore this email.",
+			user.Username, s.resetTokenDuration, s.publicURL, token,
+		),
+	})
+}
+
+// ResetPassword sets a new password using a reset token and signs the user
+// out of all devices
+func (s *PasswordService) ResetPassword(req *types.ResetPasswordRequest) error {
+	if req.Token == "" {
+		return types.ErrInvalidToken
+	}
+	if err := s.validator.ValidatePassword(req.NewPassword); err != nil {
+		return fmt.Errorf("%w: %s", types.ErrValidationFailed, err)
+	}
+
+	resetToken, err := s.authRepo.ConsumePasswordResetToken(hashToken(req.Token))
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(resetToken.UserID)
+	if err != nil {
+		return err
+	}
+
+	if !user.IsActive {
+		return types.ErrForbidden
+	}
+
+	if err := s.userService.UpdatePassword(user, req.NewPassword); err != nil {
+		return err
+	}
+
+	// Whoever knew the old password must not stay signed in
+	return s.userService.RevokeAllSessions(user.ID)
+}
+
+// ChangePassword sets a new password for a signed-in user who knows the current
+// one. Unless asked to keep them, the user's other sessions are signed out and
+// the current session receives a new token pair, since every access token issued
+// before the change is revoked.
+func (s *PasswordService) ChangePassword(userID uint, sessionID string, req *types.ChangePasswordRequest, client *types.ClientInfo) (*types.TokenResponse, error) {
+	if req.CurrentPassword == "" {
+		return nil, types.ErrValidationFailed
+	}
+	if err := s.validator.ValidatePassword(req.NewPassword); err != nil {
+		return nil, fmt.Errorf("%w: %s", types.ErrValidationFailed, err)
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userService.ConfirmPassword(user, req.CurrentPassword, client); err != nil {
+		return nil, err
+	}
+
+	if err := s.userService.UpdatePassword(user, req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	if req.KeepOtherSessions {
+		return nil, nil
+	}
+
+	// Tokens without a session cannot be told apart from the others
+	if sessionID == "" {
+		return nil, s.userService.RevokeAllSessions(user.ID)
+	}
+
+	if err := s.userService.RevokeOtherSessions(user.ID, sessionID, *user.PasswordChangedAt); err != nil {
+		return nil, err
+	}
+
+	return s.authService.RenewSession(user, sessionID, client)
+}
@@ -0,0 +1,82 @@
+package service_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// testPassword is the password of users created by newPasswordUser
+const testPassword = "Correct-Horse-Battery-9"
+
+// userClient is the client users act from in tests
+var userClient = &types.ClientInfo{IPAddress: "127.0.0.1", UserAgent: "user-test"}
+
+// newPasswordUser creates a user with testPassword as their password
+func newPasswordUser(t *testing.T, users *repository.UserRepository, userService *service.UserService, username string) *model.User {
+	t.Helper()
+
+	user := newTestUser(t, users, username)
+	if err := userService.UpdatePassword(user, testPassword); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// lockUser makes wrong attempts until the account is locked, then checks that
+// even the right password or code is refused. Tests disable delays so that
+// only the lock applies.
+func lockUser(t *testing.T, wrong, right string, wrongErr error, attempt func(value string) error) {
+	t.Helper()
+
+	for i := 0; i < 5; i++ {
+		if err := attempt(wrong); !errors.Is(err, wrongErr) {
+			t.Fatalf("attempt %d returned %v, want %v", i+1, err, wrongErr)
+		}
+	}
+
+	var retryErr *types.RetryAfterError
+	if err := attempt(right); !errors.As(err, &retryErr) || !errors.Is(err, types.ErrAccountLocked) {
+		t.Fatalf("right attempt returned %v, want %v", err, types.ErrAccountLocked)
+	}
+}
+
+func TestChangePasswordLocksOutGuessing(t *testing.T) {
+	t.Setenv("APP_LOCKOUT_MAX_FAILURES", "5")
+	t.Setenv("APP_LOCKOUT_DELAY_AFTER", "10")
+
+	var users *repository.UserRepository
+	var userService *service.UserService
+	var passwords *service.PasswordService
+	newTestApp(t, &users, &userService, &passwords)
+	user := newPasswordUser(t, users, userService, "user")
+
+	lockUser(t, "wrong-password", testPassword, types.ErrInvalidCredentials, func(password string) error {
+		_, err := passwords.ChangePassword(user.ID, "", &types.ChangePasswordRequest{
+			CurrentPassword:   password,
+			NewPassword:       "Another-Horse-Battery-9",
+			KeepOtherSessions: true,
+		}, userClient)
+		return err
+	})
+}
+
+func TestRequestErasureLocksOutGuessing(t *testing.T) {
+	t.Setenv("APP_LOCKOUT_MAX_FAILURES", "5")
+	t.Setenv("APP_LOCKOUT_DELAY_AFTER", "10")
+
+	var users *repository.UserRepository
+	var userService *service.UserService
+	var privacy *service.PrivacyService
+	newTestApp(t, &users, &userService, &privacy)
+	user := newPasswordUser(t, users, userService, "user")
+
+	lockUser(t, "wrong-password", testPassword, types.ErrInvalidCredentials, func(password string) error {
+		_, err := privacy.RequestErasure(user.ID, &types.ErasureRequest{Password: password}, userClient)
+		return err
+	})
+}
@@ -0,0 +1,686 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/logger"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/mailer"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+	"go.uber.org/zap"
+)
+
+// Erasure modes
+const (
+	// ErasureModeAnonymize keeps a disabled account without personal data
+	ErasureModeAnonymize = "anonymize"
+	// ErasureModeDelete deletes the account for good
+	ErasureModeDelete = "delete"
+)
+
+// PrivacyService handles personal data export and account erasure business logic
+type PrivacyService struct {
+	privacyRepo        *repository.PrivacyRepository
+	userRepo           *repository.UserRepository
+	userService        *UserService
+	apiKeyService      *APIKeyService
+	fileService        *FileService
+	auditService       *AuditService
+	mailer             mailer.Mailer
+	logger             *logger.Logger
+	publicURL          string
+	exportDir          string
+	exportDuration     time.Duration
+	erasureGracePeriod time.Duration
+	erasureMode        string
+	// wake starts the worker right away when an export is requested
+	wake chan struct{}
+}
+
+// NewPrivacyService creates a new privacy service
+func NewPrivacyService(
+	cfg *config.Config,
+	privacyRepo *repository.PrivacyRepository,
+	userRepo *repository.UserRepository,
+	userService *UserService,
+	apiKeyService *APIKeyService,
+	fileService *FileService,
+	auditService *AuditService,
+	mailer mailer.Mailer,
+	logger *logger.Logger,
+) (*PrivacyService, error) {
+	exportDuration, err := time.ParseDuration(cfg.Privacy.ExportDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse data export duration: %w", err)
+	}
+
+	gracePeriod, err := time.ParseDuration(cfg.Privacy.ErasureGracePeriod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse erasure grace period: %w", err)
+	}
+
+	switch cfg.Privacy.ErasureMode {
+	case ErasureModeAnonymize, ErasureModeDelete:
+	default:
+		return nil, fmt.Errorf("unsupported erasure mode: %s", cfg.Privacy.ErasureMode)
+	}
+
+	return &PrivacyService{
+		privacyRepo:        privacyRepo,
+		userRepo:           userRepo,
+		userService:        userService,
+		apiKeyService:      apiKeyService,
+		fileService:        fileService,
+		auditService:       auditService,
+		mailer:             mailer,
+		logger:             logger,
+		publicURL:          cfg.Server.PublicURL,
+		exportDir:          cfg.Privacy.ExportDir,
+		exportDuration:     exportDuration,
+		erasureGracePeriod: gracePeriod,
+		erasureMode:        cfg.Privacy.ErasureMode,
+		wake:               make(chan struct{}, 1),
+	}, nil
+}
+
+// RequestExport queues an export of everything stored about a user. The
+// archive is built in the background and the user is mailed once it is ready.
+// Only one export can be in progress or waiting for download at a time.
+func (s *PrivacyService) RequestExport(userID uint, client *types.ClientInfo) (*types.DataExportResponse, error) {
+	active, err := s.privacyRepo.HasActiveExport(userID)
+	if err != nil {
+		return nil, err
+	}
+	if active {
+		return nil, types.ErrExportInProgress
+	}
+
+	export := &model.DataExport{
+		UserID: userID,
+		Status: model.DataExportPending,
+	}
+	if err := s.privacyRepo.CreateExport(export); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(userID, model.AuditEventDataExportRequested, client, map[string]interface{}{
+		"export_id": export.ID,
+	})
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return s.ToExportResponse(export), nil
+}
+
+// GetExport retrieves one of a user's data exports
+func (s *PrivacyService) GetExport(userID, id uint) (*types.DataExportResponse, error) {
+	export, err := s.privacyRepo.GetExport(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.ToExportResponse(export), nil
+}
+
+// OpenExport opens a ready data export for download. The export cannot be
+// downloaded again, and its archive is removed when the returned file is closed.
+func (s *PrivacyService) OpenExport(userID, id uint, client *types.ClientInfo) (*ExportFile, error) {
+	export, err := s.privacyRepo.ClaimDownload(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(export.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(userID, model.AuditEventDataExportDownloaded, client, map[string]interface{}{
+		"export_id": export.ID,
+	})
+
+	return &ExportFile{
+		File: file,
+		Name: fmt.Sprintf("data-export-%d.zip", export.ID),
+		onClose: func() {
+			s.removeExportFile(export)
+		},
+	}, nil
+}
+
+// ExportFile is the archive of a data export being downloaded
+type ExportFile struct {
+	*os.File
+	// Name is the file name offered to the user
+	Name    string
+	onClose func()
+}
+
+// Close closes the archive and removes it
+func (f *ExportFile) Close() error {
+	err := f.File.Close()
+	f.onClose()
+	return err
+}
+
+// RequestErasure schedules the erasure of a user's account at the end of the
+// grace period. Accounts with a password must confirm it.
+func (s *PrivacyService) RequestErasure(userID uint, req *types.ErasureRequest, client *types.ClientInfo) (*types.ErasureResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.ErasureScheduledAt != nil {
+		return nil, types.ErrErasureScheduled
+	}
+
+	if user.PasswordHash != "" {
+		if err := s.userService.ConfirmPassword(user, req.Password, client); err != nil {
+			return nil, err
+		}
+	}
+
+	scheduledAt := time.Now().Add(s.erasureGracePeriod)
+	user.ErasureScheduledAt = &scheduledAt
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(userID, model.AuditEventErasureRequested, client, map[string]interface{}{
+		"scheduled_at": scheduledAt,
+		"mode":         s.erasureMode,
+	})
+
+	if err := s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Your account will be erased",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nYour account and personal data will be erased on %s.\n\nIf you change your mind, sign in at %s and cancel the erasure before then.",
+			user.Username, scheduledAt.UTC().Format(time.RFC1123), s.publicURL,
+		),
+	}); err != nil {
+		s.logger.Error("Failed to send erasure notice", zap.Uint("user_id", userID), zap.Error(err))
+	}
+
+	return &types.ErasureResponse{
+		ScheduledAt: scheduledAt,
+		Mode:        s.erasureMode,
+	}, nil
+}
+
+// CancelErasure cancels the scheduled erasure of a user's account
+func (s *PrivacyService) CancelErasure(userID uint, client *types.ClientInfo) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.ErasureScheduledAt == nil {
+		return types.ErrErasureNotScheduled
+	}
+
+	user.ErasureScheduledAt = nil
+	if err := s.userRepo.Update(user); err != nil {
+		return err
+	}
+
+	s.auditService.Record(userID, model.AuditEventErasureCancelled, client, nil)
+	return nil
+}
+
+// StartWorker builds requested exports, removes expired ones and erases
+// accounts whose grace period is over, every interval and whenever an export
+// is requested, until the returned stop function is called
+func (s *PrivacyService) StartWorker(interval time.Duration, onError func(error)) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+			case <-s.wake:
+			case <-done:
+				ticker.Stop()
+				return
+			}
+			if err := s.process(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+// process runs one round of background work
+func (s *PrivacyService) process() error {
+	return errors.Join(s.buildExports(), s.expireExports(), s.eraseDueAccounts())
+}
+
+// buildExports builds every pending export
+func (s *PrivacyService) buildExports() error {
+	for {
+		export, err := s.privacyRepo.ClaimPendingExport()
+		if err != nil || export == nil {
+			return err
+		}
+
+		if err := s.buildExport(export); err != nil {
+			s.logger.Error("Failed to build data export", zap.Uint("export_id", export.ID), zap.Error(err))
+			export.Status = model.DataExportFailed
+			export.Error = err.Error()
+			if export.FilePath != "" {
+				os.Remove(export.FilePath)
+				export.FilePath = ""
+			}
+			if err := s.privacyRepo.UpdateExport(export); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// buildExport writes the archive of an export and notifies the user
+func (s *PrivacyService) buildExport(export *model.DataExport) error {
+	data, err := s.privacyRepo.UserData(export.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(s.exportDir, 0o700); err != nil {
+		return err
+	}
+
+	export.FilePath = filepath.Join(s.exportDir, fmt.Sprintf("export-%d-%d.zip", export.UserID, export.ID))
+	size, err := s.writeArchive(export.FilePath, data)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.exportDuration)
+	export.Status = model.DataExportReady
+	export.Size = size
+	export.CompletedAt = &now
+	export.ExpiresAt = &expiresAt
+	if err := s.privacyRepo.UpdateExport(export); err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(&mailer.Message{
+		To:      data.User.Email,
+		Subject: "Your data export is ready",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nThe export of your data is ready. Sign in at %s to download it before %s. It can be downloaded once.",
+			data.User.Username, s.publicURL, expiresAt.UTC().Format(time.RFC1123),
+		),
+	}); err != nil {
+		s.logger.Error("Failed to send data export notice", zap.Uint("export_id", export.ID), zap.Error(err))
+	}
+
+	return nil
+}
+
+// writeArchive writes a ZIP archive with one JSON document per kind of data
+// and the uploaded files, and returns its size
+func (s *PrivacyService) writeArchive(path string, data *repository.UserData) (int64, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	documents := []struct {
+		name    string
+		content interface{}
+	}{
+		{"profile.json", s.userService.ToResponse(data.User)},
+		{"sessions.json", exportSessions(data.Sessions)},
+		{"api_keys.json", s.exportAPIKeys(data.APIKeys)},
+		{"identities.json", exportIdentities(data.Identities)},
+		{"oauth_consents.json", exportConsents(data.Consents)},
+		{"kv.json", exportKV(data.KV)},
+		{"files.json", exportFiles(data.Files)},
+		{"organizations.json", exportMemberships(data.Memberships)},
+		{"audit_events.json", exportAuditEvents(data.AuditEvents)},
+	}
+
+	now := time.Now()
+	archive := zip.NewWriter(file)
+	for _, document := range documents {
+		w, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     document.name,
+			Method:   zip.Deflate,
+			Modified: now,
+		})
+		if err != nil {
+			return 0, err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(document.content); err != nil {
+			return 0, err
+		}
+	}
+	for _, f := range data.Files {
+		if err := s.writeArchiveFile(archive, f, now); err != nil {
+			return 0, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// writeArchiveFile adds the content of an uploaded file to an archive.
+// Files whose content is missing from the storage are left out.
+func (s *PrivacyService) writeArchiveFile(archive *zip.Writer, file *model.File, modified time.Time) error {
+	content, err := s.fileService.Open(context.Background(), file, FileVariantContent)
+	if err != nil {
+		if errors.Is(err, types.ErrFileNotFound) {
+			return nil
+		}
+		return err
+	}
+	defer content.Body.Close()
+
+	w, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     exportFilePath(file),
+		Method:   zip.Deflate,
+		Modified: modified,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, content.Body)
+	return err
+}
+
+// expireExports removes the archives of exports that were not downloaded in time
+func (s *PrivacyService) expireExports() error {
+	exports, err := s.privacyRepo.ListExpiredExports(time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		export.Status = model.DataExportExpired
+		s.removeExportFile(export)
+	}
+	return nil
+}
+
+// removeExportFile removes the archive of an export and forgets its path
+func (s *PrivacyService) removeExportFile(export *model.DataExport) {
+	if err := os.Remove(export.FilePath); err != nil && !os.IsNotExist(err) {
+		s.logger.Error("Failed to remove data export", zap.Uint("export_id", export.ID), zap.Error(err))
+		return
+	}
+
+	export.FilePath = ""
+	if err := s.privacyRepo.UpdateExport(export); err != nil {
+		s.logger.Error("Failed to update data export", zap.Uint("export_id", export.ID), zap.Error(err))
+	}
+}
+
+// eraseDueAccounts erases the accounts whose grace period is over
+func (s *PrivacyService) eraseDueAccounts() error {
+	users, err := s.userRepo.ListDueErasures(time.Now())
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, user := range users {
+		if err := s.erase(user); err != nil {
+			errs = append(errs, fmt.Errorf("failed to erase user %d: %w", user.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// erase signs a user out everywhere and erases their account and data
+func (s *PrivacyService) erase(user *model.User) error {
+	if err := s.userService.RevokeAllSessions(user.ID); err != nil {
+		return err
+	}
+
+	paths, err := s.privacyRepo.ListFilesByUser(user.ID)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := s.fileService.DeleteByUser(context.Background(), user.ID); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.Erase(user.ID, s.erasureMode == ErasureModeAnonymize); err != nil {
+		return err
+	}
+
+	s.auditService.Record(user.ID, model.AuditEventUserErased, nil, map[string]interface{}{
+		"mode": s.erasureMode,
+	})
+	return nil
+}
+
+// ToExportResponse converts a data export to its response
+func (s *PrivacyService) ToExportResponse(export *model.DataExport) *types.DataExportResponse {
+	return &types.DataExportResponse{
+		ID:           export.ID,
+		Status:       export.Status,
+		Size:         export.Size,
+		CreatedAt:    export.CreatedAt,
+		CompletedAt:  export.CompletedAt,
+		ExpiresAt:    export.ExpiresAt,
+		DownloadedAt: export.DownloadedAt,
+	}
+}
+
+// exportSession is a refresh token in a data export, without the token itself
+type exportSession struct {
+	ID         uint       `json:"id"`
+	SessionID  string     `json:"session_id"`
+	DeviceName string     `json:"device_name"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	ClientID   string     `json:"client_id,omitempty"`
+	Scope      string     `json:"scope,omitempty"`
+	StartedAt  time.Time  `json:"session_started_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Revoked    bool       `json:"revoked"`
+}
+
+// exportConsent is an OAuth client authorization in a data export
+type exportConsent struct {
+	ClientID  string    `json:"client_id"`
+	Scope     string    `json:"scope"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// exportKVEntry is a stored value in a data export
+type exportKVEntry struct {
+	Key       string     `json:"key"`
+	Value     string     `json:"value"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// exportFile is an uploaded file in a data export
+type exportFile struct {
+	ID          uint      `json:"id"`
+	Purpose     string    `json:"purpose"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Path        string    `json:"path"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// exportMembership is a membership in an organization in a data export
+type exportMembership struct {
+	OrgID    uint      `json:"org_id"`
+	Name     string    `json:"name"`
+	Slug     string    `json:"slug"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// exportAuditEvent is an audit event in a data export
+type exportAuditEvent struct {
+	Event        string          `json:"event"`
+	IPAddress    string          `json:"ip_address"`
+	UserAgent    string          `json:"user_agent"`
+	ActorID      *uint           `json:"actor_id,omitempty"`
+	Impersonated bool            `json:"impersonated,omitempty"`
+	Details      json.RawMessage `json:"details,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+func exportSessions(tokens []*model.RefreshToken) []*exportSession {
+	sessions := make([]*exportSession, len(tokens))
+	for i, token := range tokens {
+		sessions[i] = &exportSession{
+			ID:         token.ID,
+			SessionID:  token.FamilyID,
+			DeviceName: token.DeviceName,
+			UserAgent:  token.UserAgent,
+			IPAddress:  token.IPAddress,
+			ClientID:   token.ClientID,
+			Scope:      token.Scope,
+			StartedAt:  token.SessionStartedAt,
+			CreatedAt:  token.CreatedAt,
+			LastUsedAt: token.LastUsedAt,
+			ExpiresAt:  token.ExpiresAt,
+			Revoked:    token.IsRevoked,
+		}
+	}
+	return sessions
+}
+
+func (s *PrivacyService) exportAPIKeys(keys []*model.APIKey) []*types.APIKeyResponse {
+	responses := make([]*types.APIKeyResponse, len(keys))
+	for i, key := range keys {
+		responses[i] = s.apiKeyService.ToResponse(key)
+	}
+	return responses
+}
+
+func exportIdentities(identities []*model.UserIdentity) []*types.IdentityResponse {
+	responses := make([]*types.IdentityResponse, len(identities))
+	for i, identity := range identities {
+		responses[i] = &types.IdentityResponse{
+			ID:          identity.ID,
+			Provider:    identity.Provider,
+			Email:       identity.Email,
+			LastLoginAt: identity.LastLoginAt,
+			CreatedAt:   identity.CreatedAt,
+		}
+	}
+	return responses
+}
+
+func exportConsents(consents []*model.OAuthConsent) []*exportConsent {
+	entries := make([]*exportConsent, len(consents))
+	for i, consent := range consents {
+		entries[i] = &exportConsent{
+			ClientID:  consent.ClientID,
+			Scope:     consent.Scope,
+			CreatedAt: consent.CreatedAt,
+			UpdatedAt: consent.UpdatedAt,
+		}
+	}
+	return entries
+}
+
+func exportKV(kvs []*model.KV) []*exportKVEntry {
+	entries := make([]*exportKVEntry, len(kvs))
+	for i, kv := range kvs {
+		entries[i] = &exportKVEntry{
+			Key:       kv.Key,
+			Value:     kv.Value,
+			ExpiresAt: kv.ExpiresAt,
+		}
+	}
+	return entries
+}
+
+func exportFiles(files []*model.File) []*exportFile {
+	entries := make([]*exportFile, len(files))
+	for i, file := range files {
+		entries[i] = &exportFile{
+			ID:          file.ID,
+			Purpose:     file.Purpose,
+			Name:        file.Name,
+			ContentType: file.ContentType,
+			Size:        file.Size,
+			Path:        exportFilePath(file),
+			CreatedAt:   file.CreatedAt,
+		}
+	}
+	return entries
+}
+
+// exportFilePath returns the path of the content of a file in a data export
+func exportFilePath(file *model.File) string {
+	return fmt.Sprintf("files/%d-%s", file.ID, file.Name)
+}
+
+func exportMemberships(memberships []*model.Membership) []*exportMembership {
+	entries := make([]*exportMembership, len(memberships))
+	for i, membership := range memberships {
+		entries[i] = &exportMembership{
+			OrgID:    membership.OrgID,
+			Name:     membership.Organization.Name,
+			Slug:     membership.Organization.Slug,
+			Role:     membership.Role,
+			JoinedAt: membership.CreatedAt,
+		}
+	}
+	return entries
+}
+
+func exportAuditEvents(events []*model.AuditEvent) []*exportAuditEvent {
+	entries := make([]*exportAuditEvent, len(events))
+	for i, event := range events {
+		entries[i] = &exportAuditEvent{
+			Event:        event.Event,
+			IPAddress:    event.IPAddress,
+			UserAgent:    event.UserAgent,
+			ActorID:      event.ActorID,
+			Impersonated: event.Impersonated,
+			CreatedAt:    event.CreatedAt,
+		}
+		if json.Valid([]byte(event.Details)) {
+			entries[i].Details = json.RawMessage(event.Details)
+		}
+	}
+	return entries
+}
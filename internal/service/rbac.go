@@ -0,0 +1,421 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// defaultRoleKey is the KV key naming the role given to newly registered users
+const defaultRoleKey = "default_user_role"
+
+// roleNamePattern restricts role names to lowercase identifiers
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+
+// RBACService handles role-based access control business logic
+type RBACService struct {
+	rbacRepo *repository.RBACRepository
+	userRepo *repository.UserRepository
+	kvRepo   *repository.KVRepository
+}
+
+// NewRBACService creates a new RBAC service
+func NewRBACService(rbacRepo *repository.RBACRepository, userRepo *repository.UserRepository, kvRepo *repository.KVRepository) *RBACService {
+	return &RBACService{
+		rbacRepo: rbacRepo,
+		userRepo: userRepo,
+		kvRepo:   kvRepo,
+	}
+}
+
+// Grants returns the role names and the union of the permissions of a user
+func (s *RBACService) Grants(userID uint) (roles []string, permissions []string, err error) {
+	userRoles, err := s.rbacRepo.GetUserRoles(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, role := range userRoles {
+		roles = append(roles, role.Name)
+		for _, permission := range role.Permissions {
+			if !seen[permission.Name] {
+				seen[permission.Name] = true
+				permissions = append(permissions, permission.Name)
+			}
+		}
+	}
+	sort.Strings(permissions)
+
+	return roles, permissions, nil
+}
+
+// ListRoles lists all roles
+func (s *RBACService) ListRoles() ([]*types.RoleResponse, error) {
+	roles, err := s.rbacRepo.ListRoles()
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*types.RoleResponse, len(roles))
+	for i, role := range roles {
+		responses[i] = s.ToRoleResponse(role)
+	}
+	return responses, nil
+}
+
+// GetRole retrieves a role by ID
+func (s *RBACService) GetRole(id uint) (*model.Role, error) {
+	return s.rbacRepo.GetRoleByID(id)
+}
+
+// CreateRole creates a new role with permissions the administrator holds
+func (s *RBACService) CreateRole(adminID uint, req *types.CreateRoleRequest) (*model.Role, error) {
+	if err := s.validateRoleName(req.Name); err != nil {
+		return nil, err
+	}
+
+	if exists, err := s.rbacRepo.ExistsByName(req.Name); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, types.ErrRoleAlreadyExists
+	}
+
+	permissions, err := s.resolvePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	_, granted, err := s.Grants(adminID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPermissionsGranted(granted, permissions); err != nil {
+		return nil, err
+	}
+
+	role := &model.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: permissions,
+	}
+
+	if err := s.rbacRepo.CreateRole(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// UpdateRole updates a role. System roles keep their name, and the admin role
+// keeps all permissions. Administrators can only change roles they do not
+// hold, that grant nothing they lack, and only to permissions they hold.
+func (s *RBACService) UpdateRole(adminID, id uint, req *types.UpdateRoleRequest) (*model.Role, error) {
+	role, err := s.rbacRepo.GetRoleByID(id)
+	if err != nil {
+		return nil, err
+	}
+	granted, err := s.checkRoleEditable(adminID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil && *req.Name != role.Name {
+		if role.System {
+			return nil, types.ErrSystemRole
+		}
+		if err := s.validateRoleName(*req.Name); err != nil {
+			return nil, err
+		}
+		if exists, err := s.rbacRepo.ExistsByName(*req.Name); err != nil {
+			return nil, err
+		} else if exists {
+			return nil, types.ErrRoleAlreadyExists
+		}
+		role.Name = *req.Name
+	}
+
+	if req.Description != nil {
+		role.Description = *req.Description
+	}
+
+	if req.Permissions != nil {
+		if role.Name == model.RoleAdmin {
+			return nil, types.ErrSystemRole
+		}
+		permissions, err := s.resolvePermissions(*req.Permissions)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkPermissionsGranted(granted, permissions); err != nil {
+			return nil, err
+		}
+		role.Permissions = permissions
+	}
+
+	if err := s.rbacRepo.UpdateRole(role); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// DeleteRole deletes a role that is not a system role. Like UpdateRole, it
+// refuses roles the administrator holds or that grant something they lack.
+func (s *RBACService) DeleteRole(adminID, id uint) error {
+	role, err := s.rbacRepo.GetRoleByID(id)
+	if err != nil {
+		return err
+	}
+
+	if role.System {
+		return types.ErrSystemRole
+	}
+	if _, err := s.checkRoleEditable(adminID, role); err != nil {
+		return err
+	}
+
+	return s.rbacRepo.DeleteRole(role)
+}
+
+// ListPermissions lists all permissions
+func (s *RBACService) ListPermissions() ([]*types.PermissionResponse, error) {
+	permissions, err := s.rbacRepo.ListPermissions()
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*types.PermissionResponse, len(permissions))
+	for i, permission := range permissions {
+		responses[i] = &types.PermissionResponse{
+			Name:        permission.Name,
+			Description: permission.Description,
+		}
+	}
+	return responses, nil
+}
+
+// SetUserRoles replaces the roles of a user. Changes apply to access tokens
+// issued from then on, i.e. after the user's next token refresh.
+func (s *RBACService) SetUserRoles(userID uint, req *types.SetUserRolesRequest) (*model.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := s.rbacRepo.GetRolesByName(req.Roles)
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) != len(uniqueStrings(req.Roles)) {
+		return nil, types.ErrRoleNotFound
+	}
+
+	if err := s.rbacRepo.SetUserRoles(user, roles); err != nil {
+		return nil, err
+	}
+
+	return s.userRepo.GetByID(userID)
+}
+
+// CheckGrantable makes sure an administrator holds every permission of the
+// named roles, so that nobody can hand out more access than they have
+func (s *RBACService) CheckGrantable(adminID uint, roleNames []string) error {
+	_, granted, err := s.Grants(adminID)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range uniqueStrings(roleNames) {
+		role, err := s.roleByName(name)
+		if err != nil {
+			return err
+		}
+
+		// Load the role's permissions
+		role, err = s.GetRole(role.ID)
+		if err != nil {
+			return err
+		}
+
+		for _, permission := range role.Permissions {
+			if !slices.Contains(granted, permission.Name) {
+				return fmt.Errorf("%w: permission %q of role %q is not granted to you", types.ErrValidationFailed, permission.Name, role.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckOutranks makes sure an administrator holds every permission of a
+// user, so that nobody can take over or lock out a more privileged account
+func (s *RBACService) CheckOutranks(adminID, userID uint) error {
+	_, granted, err := s.Grants(adminID)
+	if err != nil {
+		return err
+	}
+	_, permissions, err := s.Grants(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, permission := range permissions {
+		if !slices.Contains(granted, permission) {
+			return fmt.Errorf("%w: %s", types.ErrOutranked, permission)
+		}
+	}
+	return nil
+}
+
+// CheckRoleChange makes sure an administrator may replace the roles of a user:
+// not their own, only of users they outrank, and only with grantable roles
+func (s *RBACService) CheckRoleChange(adminID, userID uint, roleNames []string) error {
+	if adminID == userID {
+		return types.ErrOwnAccount
+	}
+	if err := s.CheckOutranks(adminID, userID); err != nil {
+		return err
+	}
+	return s.CheckGrantable(adminID, roleNames)
+}
+
+// checkRoleEditable makes sure an administrator may change a role: not one
+// they hold, which would change their own access, and not one granting a
+// permission they lack. It returns the administrator's permissions.
+func (s *RBACService) checkRoleEditable(adminID uint, role *model.Role) ([]string, error) {
+	roles, granted, err := s.Grants(adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(roles, role.Name) {
+		return nil, types.ErrOwnRole
+	}
+	for _, permission := range role.Permissions {
+		if !slices.Contains(granted, permission.Name) {
+			return nil, fmt.Errorf("%w: %s", types.ErrRoleOutranks, permission.Name)
+		}
+	}
+	return granted, nil
+}
+
+// checkPermissionsGranted makes sure permissions put into a role are among the
+// granted permissions of the administrator
+func checkPermissionsGranted(granted []string, permissions []model.Permission) error {
+	for _, permission := range permissions {
+		if !slices.Contains(granted, permission.Name) {
+			return fmt.Errorf("%w: permission %q is not granted to you", types.ErrValidationFailed, permission.Name)
+		}
+	}
+	return nil
+}
+
+// AssignRole grants a role to a user by name
+func (s *RBACService) AssignRole(user *model.User, roleName string) error {
+	role, err := s.roleByName(roleName)
+	if err != nil {
+		return err
+	}
+	return s.rbacRepo.AddUserRole(user, role)
+}
+
+// RevokeRole removes a role from a user by name
+func (s *RBACService) RevokeRole(user *model.User, roleName string) error {
+	role, err := s.roleByName(roleName)
+	if err != nil {
+		return err
+	}
+	return s.rbacRepo.RemoveUserRole(user, role)
+}
+
+// AssignDefaultRole grants a new user the role named by the default_user_role
+// setting, falling back to the built-in user role
+func (s *RBACService) AssignDefaultRole(user *model.User) error {
+	roleName, err := s.kvRepo.Get(defaultRoleKey)
+	if err != nil {
+		return err
+	}
+	if roleName == "" {
+		roleName = model.RoleUser
+	}
+
+	return s.AssignRole(user, roleName)
+}
+
+// ToRoleResponse converts a Role model to a RoleResponse
+func (s *RBACService) ToRoleResponse(role *model.Role) *types.RoleResponse {
+	permissions := make([]string, len(role.Permissions))
+	for i, permission := range role.Permissions {
+		permissions[i] = permission.Name
+	}
+	sort.Strings(permissions)
+
+	return &types.RoleResponse{
+		ID:          role.ID,
+		Name:        role.Name,
+		Description: role.Description,
+		System:      role.System,
+		Permissions: permissions,
+		CreatedAt:   role.CreatedAt,
+		UpdatedAt:   role.UpdatedAt,
+	}
+}
+
+// roleByName retrieves a single role by name
+func (s *RBACService) roleByName(name string) (*model.Role, error) {
+	roles, err := s.rbacRepo.GetRolesByName([]string{name})
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) == 0 {
+		return nil, types.ErrRoleNotFound
+	}
+	return &roles[0], nil
+}
+
+// resolvePermissions looks up permissions by name, rejecting unknown names
+func (s *RBACService) resolvePermissions(names []string) ([]model.Permission, error) {
+	names = uniqueStrings(names)
+
+	permissions, err := s.rbacRepo.GetPermissionsByName(names)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(permissions) != len(names) {
+		known := make(map[string]bool, len(permissions))
+		for _, permission := range permissions {
+			known[permission.Name] = true
+		}
+		for _, name := range names {
+			if !known[name] {
+				return nil, fmt.Errorf("%w: unknown permission %q", types.ErrValidationFailed, name)
+			}
+		}
+	}
+
+	return permissions, nil
+}
+
+// validateRoleName checks that a role name is a lowercase identifier
+func (s *RBACService) validateRoleName(name string) error {
+	if !roleNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: role name must be 2-50 lowercase letters, digits, '-' or '_', starting with a letter", types.ErrValidationFailed)
+	}
+	return nil
+}
+
+// uniqueStrings returns the distinct values of a slice, preserving order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,176 @@
+package service_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// rbacTestEnv holds a super administrator with the admin role and a
+// moderator whose support role can manage users and roles, but not delete users
+type rbacTestEnv struct {
+	rbac      *service.RBACService
+	users     *repository.UserRepository
+	admin     *model.User
+	moderator *model.User
+	roles     map[string]uint
+}
+
+// newRBACTestEnv builds the application, populating targets like newTestApp
+func newRBACTestEnv(t *testing.T, targets ...interface{}) *rbacTestEnv {
+	t.Helper()
+
+	env := &rbacTestEnv{}
+	newTestApp(t, append([]interface{}{&env.rbac, &env.users}, targets...)...)
+
+	env.admin = newTestUser(t, env.users, "admin")
+	if err := env.rbac.AssignRole(env.admin, model.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+
+	env.createRole(t, "support", model.PermissionUsersRead, model.PermissionUsersWrite, model.PermissionRolesRead, model.PermissionRolesWrite)
+	env.moderator = newTestUser(t, env.users, "moderator")
+	if err := env.rbac.AssignRole(env.moderator, "support"); err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+// createRole creates a role as the super administrator
+func (e *rbacTestEnv) createRole(t *testing.T, name string, permissions ...string) {
+	t.Helper()
+
+	if _, err := e.rbac.CreateRole(e.admin.ID, &types.CreateRoleRequest{Name: name, Permissions: permissions}); err != nil {
+		t.Fatalf("CreateRole(%s) failed: %v", name, err)
+	}
+	e.loadRoles(t)
+}
+
+// loadRoles maps role names to IDs
+func (e *rbacTestEnv) loadRoles(t *testing.T) {
+	t.Helper()
+
+	roles, err := e.rbac.ListRoles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.roles = make(map[string]uint, len(roles))
+	for _, role := range roles {
+		e.roles[role.Name] = role.ID
+	}
+}
+
+// permissions returns the permissions a user currently holds
+func (e *rbacTestEnv) permissions(t *testing.T, user *model.User) []string {
+	t.Helper()
+
+	_, permissions, err := e.rbac.Grants(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return permissions
+}
+
+func TestRBACRoleEditsCannotEscalate(t *testing.T) {
+	env := newRBACTestEnv(t)
+	env.createRole(t, "auditor", model.PermissionConfigWrite)
+	escalated := []string{model.PermissionUsersRead, model.PermissionUsersDelete}
+
+	tests := []struct {
+		name    string
+		edit    func() error
+		wantErr error
+	}{
+		{"create role with a permission not held", func() error {
+			_, err := env.rbac.CreateRole(env.moderator.ID, &types.CreateRoleRequest{Name: "deleter", Permissions: escalated})
+			return err
+		}, types.ErrValidationFailed},
+		{"add a permission not held to the default role", func() error {
+			_, err := env.rbac.UpdateRole(env.moderator.ID, env.roles[model.RoleUser], &types.UpdateRoleRequest{Permissions: &escalated})
+			return err
+		}, types.ErrValidationFailed},
+		{"change a role held", func() error {
+			permissions := []string{model.PermissionUsersRead}
+			_, err := env.rbac.UpdateRole(env.moderator.ID, env.roles["support"], &types.UpdateRoleRequest{Permissions: &permissions})
+			return err
+		}, types.ErrOwnRole},
+		{"change a role granting a permission not held", func() error {
+			description := "changed"
+			_, err := env.rbac.UpdateRole(env.moderator.ID, env.roles["auditor"], &types.UpdateRoleRequest{Description: &description})
+			return err
+		}, types.ErrRoleOutranks},
+		{"delete a role held", func() error {
+			return env.rbac.DeleteRole(env.moderator.ID, env.roles["support"])
+		}, types.ErrOwnRole},
+		{"delete a role granting a permission not held", func() error {
+			return env.rbac.DeleteRole(env.moderator.ID, env.roles["auditor"])
+		}, types.ErrRoleOutranks},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.edit(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// The default role is what the moderator would pick up at the next refresh
+	if err := env.rbac.AssignRole(env.moderator, model.RoleUser); err != nil {
+		t.Fatal(err)
+	}
+	if permissions := env.permissions(t, env.moderator); slices.Contains(permissions, model.PermissionUsersDelete) {
+		t.Errorf("moderator gained %s: %v", model.PermissionUsersDelete, permissions)
+	}
+}
+
+func TestRBACRoleEditsWithinOwnPermissions(t *testing.T) {
+	env := newRBACTestEnv(t)
+
+	permissions := []string{model.PermissionUsersRead}
+	if _, err := env.rbac.CreateRole(env.moderator.ID, &types.CreateRoleRequest{Name: "viewer", Permissions: permissions}); err != nil {
+		t.Fatalf("CreateRole failed: %v", err)
+	}
+	env.loadRoles(t)
+
+	permissions = append(permissions, model.PermissionRolesRead)
+	if _, err := env.rbac.UpdateRole(env.moderator.ID, env.roles["viewer"], &types.UpdateRoleRequest{Permissions: &permissions}); err != nil {
+		t.Fatalf("UpdateRole failed: %v", err)
+	}
+	if err := env.rbac.DeleteRole(env.moderator.ID, env.roles["viewer"]); err != nil {
+		t.Fatalf("DeleteRole failed: %v", err)
+	}
+}
+
+func TestRBACCheckRoleChange(t *testing.T) {
+	env := newRBACTestEnv(t)
+	env.createRole(t, "viewer", model.PermissionUsersRead)
+	user := newTestUser(t, env.users, "user")
+
+	tests := []struct {
+		name    string
+		adminID uint
+		userID  uint
+		roles   []string
+		wantErr error
+	}{
+		{"grant a role within own permissions", env.moderator.ID, user.ID, []string{"viewer"}, nil},
+		{"grant a role with a permission not held", env.moderator.ID, user.ID, []string{model.RoleAdmin}, types.ErrValidationFailed},
+		{"change own roles", env.moderator.ID, env.moderator.ID, []string{"viewer"}, types.ErrOwnAccount},
+		{"change roles of a higher-ranked user", env.moderator.ID, env.admin.ID, []string{"viewer"}, types.ErrOutranked},
+		{"change roles of a lower-ranked user", env.admin.ID, env.moderator.ID, []string{model.RoleAdmin}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := env.rbac.CheckRoleChange(tt.adminID, tt.userID, tt.roles); !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
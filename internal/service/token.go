@@ -0,0 +1,32 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// newOpaqueToken generates a random token and the hash that should be persisted for it
+func newOpaqueToken() (token string, hash string, err error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(bytes)
+	return token, hashToken(token), nil
+}
+
+// newFamilyID generates an identifier for a new refresh token family
+func newFamilyID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// hashToken returns the SHA-256 hex digest of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
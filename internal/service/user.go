@@ -1,21 +1,55 @@
 package service
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/denylist"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/pagination"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/password"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/storage"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/validator"
 	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
 	"github.com/ray-d-song/go-echo-monolithic/internal/types"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // UserService handles user business logic
 type UserService struct {
-	userRepo *repository.UserRepository
+	userRepo            *repository.UserRepository
+	authRepo            *repository.AuthRepository
+	verificationService *VerificationService
+	lockoutService      *LockoutService
+	denylist            *denylist.Denylist
+	passwords           *password.Manager
+	cursors             *pagination.Codec
+	fileURLs            *storage.URLSigner
+	validator           *validator.Validator
 }
 
 // NewUserService creates a new user service
-func NewUserService(userRepo *repository.UserRepository) *UserService {
+func NewUserService(
+	userRepo *repository.UserRepository,
+	authRepo *repository.AuthRepository,
+	verificationService *VerificationService,
+	lockoutService *LockoutService,
+	denylist *denylist.Denylist,
+	passwords *password.Manager,
+	cursors *pagination.Codec,
+	fileURLs *storage.URLSigner,
+	validator *validator.Validator,
+) *UserService {
 	return &UserService{
-		userRepo: userRepo,
+		userRepo:            userRepo,
+		authRepo:            authRepo,
+		verificationService: verificationService,
+		lockoutService:      lockoutService,
+		denylist:            denylist,
+		passwords:           passwords,
+		cursors:             cursors,
+		fileURLs:            fileURLs,
+		validator:           validator,
 	}
 }
 
@@ -48,59 +82,249 @@ func (s *UserService) Update(userID uint, req *types.UpdateUserRequest) (*model.
 	if req.LastName != nil {
 		user.LastName = *req.LastName
 	}
+
+	// A new email only takes effect once the user confirms it
+	emailChanged := false
 	if req.Email != nil {
-		// Check if email is already taken by another user
-		existingUser, err := s.userRepo.GetByEmail(*req.Email)
-		if err == nil && existingUser.ID != userID {
-			return nil, types.ErrUserAlreadyExists
+		email := strings.TrimSpace(*req.Email)
+		if err := s.validator.ValidateEmail(email); err != nil {
+			return nil, fmt.Errorf("%w: %s", types.ErrValidationFailed, err)
+		}
+
+		if model.NormalizeIdentifier(email) == user.EmailNormalized {
+			// Asking for the current address cancels a pending change
+			user.PendingEmail = ""
+		} else if email != user.PendingEmail {
+			// Check if email is already taken by another user
+			if taken, err := s.userRepo.EmailTaken(email, userID); err != nil {
+				return nil, err
+			} else if taken {
+				return nil, types.ErrUserAlreadyExists
+			}
+			user.PendingEmail = email
+			emailChanged = true
 		}
-		user.Email = *req.Email
 	}
 
 	if err := s.userRepo.Update(user); err != nil {
 		return nil, err
 	}
 
+	if emailChanged {
+		if err := s.verificationService.SendVerification(user, user.PendingEmail); err != nil {
+			return nil, err
+		}
+	}
+
 	return user, nil
 }
 
-// Delete soft deletes a user
+// UpdatePassword hashes and stores a new password for the user
+func (s *UserService) UpdatePassword(user *model.User, password string) error {
+	hashedPassword, err := s.hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.PasswordHash = hashedPassword
+	user.PasswordChangedAt = &now
+	return s.userRepo.Update(user)
+}
+
+// Delete soft deletes a user and ends all of their sessions
 func (s *UserService) Delete(id uint) error {
-	return s.userRepo.Delete(id)
+	if err := s.userRepo.Delete(id); err != nil {
+		return err
+	}
+	return s.RevokeAllSessions(id)
+}
+
+// Deactivate disables a user account and ends all of their sessions
+func (s *UserService) Deactivate(id uint) (*model.User, error) {
+	user, err := s.userRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.IsActive = false
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	if err := s.RevokeAllSessions(id); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// RevokeAllSessions revokes the user's refresh tokens and denies every access
+// token issued so far
+func (s *UserService) RevokeAllSessions(id uint) error {
+	if err := s.authRepo.RevokeAllUserRefreshTokens(id); err != nil {
+		return err
+	}
+	return s.denylist.RevokeUser(id, time.Now())
+}
+
+// RevokeOtherSessions revokes the user's refresh tokens except those of the
+// given session and denies every access token issued before the given time,
+// including the session's own
+func (s *UserService) RevokeOtherSessions(id uint, keepSessionID string, before time.Time) error {
+	if err := s.authRepo.RevokeOtherUserRefreshTokens(id, keepSessionID); err != nil {
+		return err
+	}
+	return s.denylist.RevokeUserBefore(id, before)
+}
+
+// UserPage is a page of a user listing with the cursors of the adjacent pages,
+// which are empty at either end of the listing
+type UserPage struct {
+	Users      []*model.User
+	NextCursor string
+	PrevCursor string
+}
+
+// List retrieves users matching a filter with offset pagination
+func (s *UserService) List(filter *types.UserFilter, offset, limit int) (*UserPage, error) {
+	if err := validateUserFilter(filter); err != nil {
+		return nil, err
+	}
+
+	page, err := s.userRepo.List(filter, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.userPage(page)
+}
+
+// ListAfter retrieves users matching a filter with keyset pagination, starting
+// at a cursor issued for a previous page of the same listing
+func (s *UserService) ListAfter(filter *types.UserFilter, cursor string, limit int) (*UserPage, error) {
+	if err := validateUserFilter(filter); err != nil {
+		return nil, err
+	}
+
+	position, err := s.cursors.Decode(cursor)
+	if err != nil {
+		return nil, types.ErrInvalidCursor
+	}
+
+	page, err := s.userRepo.ListAfter(filter, position, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.userPage(page)
+}
+
+// userPage encodes the cursors of a page of users
+func (s *UserService) userPage(page *repository.KeysetPage[model.User]) (*UserPage, error) {
+	result := &UserPage{Users: page.Items}
+
+	var err error
+	if page.Next != nil {
+		if result.NextCursor, err = s.cursors.Encode(page.Next); err != nil {
+			return nil, err
+		}
+	}
+	if page.Prev != nil {
+		if result.PrevCursor, err = s.cursors.Encode(page.Prev); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
 }
 
-// List retrieves users with pagination
-func (s *UserService) List(offset, limit int) ([]*model.User, error) {
-	return s.userRepo.List(offset, limit)
+// validateUserFilter validates the sort order and date range of a user listing
+func validateUserFilter(filter *types.UserFilter) error {
+	if filter.Sort != "" && !repository.IsUserSortField(filter.Sort) {
+		return fmt.Errorf("%w: cannot sort by %q", types.ErrValidationFailed, filter.Sort)
+	}
+	if filter.Order != "" && filter.Order != "asc" && filter.Order != "desc" {
+		return fmt.Errorf("%w: order must be asc or desc", types.ErrValidationFailed)
+	}
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && !filter.CreatedAfter.Before(*filter.CreatedBefore) {
+		return fmt.Errorf("%w: created_after must be before created_before", types.ErrValidationFailed)
+	}
+	return nil
 }
 
-// Count returns total number of users
-func (s *UserService) Count() (int64, error) {
-	return s.userRepo.Count()
+// Count returns the number of users matching a filter
+func (s *UserService) Count(filter *types.UserFilter) (int64, error) {
+	return s.userRepo.Count(filter)
 }
 
 // ToResponse converts User model to UserResponse
 func (s *UserService) ToResponse(user *model.User) *types.UserResponse {
-	return &types.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		IsActive:  user.IsActive,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+	roles := make([]string, len(user.Roles))
+	for i, role := range user.Roles {
+		roles[i] = role.Name
+	}
+
+	resp := &types.UserResponse{
+		ID:                 user.ID,
+		Username:           user.Username,
+		Email:              user.Email,
+		EmailVerified:      user.EmailVerifiedAt != nil,
+		PendingEmail:       user.PendingEmail,
+		FirstName:          user.FirstName,
+		LastName:           user.LastName,
+		IsActive:           user.IsActive,
+		MFAEnabled:         user.MFAEnabledAt != nil,
+		Roles:              roles,
+		ErasureScheduledAt: user.ErasureScheduledAt,
+		CreatedAt:          user.CreatedAt,
+		UpdatedAt:          user.UpdatedAt,
+	}
+	if user.AvatarID != nil {
+		resp.AvatarURL, _ = s.fileURLs.Sign(fileURLPath(*user.AvatarID, FileVariantContent))
+		resp.AvatarThumbnailURL, _ = s.fileURLs.Sign(fileURLPath(*user.AvatarID, FileVariantThumbnail))
+	}
+	return resp
+}
+
+// UpgradePasswordHash rehashes a password that was just verified if its stored
+// hash was created with another algorithm or other parameters than configured.
+// The password itself does not change.
+func (s *UserService) UpgradePasswordHash(user *model.User, password string) error {
+	if !s.passwords.NeedsRehash(user.PasswordHash) {
+		return nil
+	}
+
+	hashedPassword, err := s.hashPassword(password)
+	if err != nil {
+		return err
 	}
+
+	user.PasswordHash = hashedPassword
+	return s.userRepo.Update(user)
 }
 
-// hashPassword hashes a password using bcrypt
+// hashPassword hashes a password with the configured algorithm
 func (s *UserService) hashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
+	return s.passwords.Hash(password)
+}
+
+// ConfirmPassword checks the password of a signed-in user confirming a
+// sensitive change. Wrong passwords are throttled like failed logins, so a
+// stolen session cannot be used to guess the password.
+func (s *UserService) ConfirmPassword(user *model.User, password string, client *types.ClientInfo) error {
+	if err := s.lockoutService.Check(user.Username, client); err != nil {
+		return err
+	}
+
+	if !s.verifyPassword(user.PasswordHash, password) {
+		if err := s.lockoutService.RecordFailure(user.Username, user.ID, client); err != nil {
+			return err
+		}
+		return types.ErrInvalidCredentials
+	}
+
+	return s.lockoutService.RecordSuccess(user.Username)
 }
 
 // verifyPassword verifies a password against its hash
 func (s *UserService) verifyPassword(hashedPassword, password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-	return err == nil
+	valid, err := s.passwords.Verify(hashedPassword, password)
+	return err == nil && valid
 }
\ No newline at end of file
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/config"
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/logger"
+	"github.com/ray-d-song/go-echo-monolithic/internal/pkg/mailer"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+	"go.uber.org/zap"
+)
+
+// VerificationService handles email ownership verification
+type VerificationService struct {
+	userRepo      *repository.UserRepository
+	authRepo      *repository.AuthRepository
+	mailer        mailer.Mailer
+	logger        *logger.Logger
+	publicURL     string
+	tokenDuration time.Duration
+}
+
+// NewVerificationService creates a new verification service
+func NewVerificationService(
+	cfg *config.Config,
+	userRepo *repository.UserRepository,
+	authRepo *repository.AuthRepository,
+	mailer mailer.Mailer,
+	logger *logger.Logger,
+) (*VerificationService, error) {
+	tokenDuration, err := time.ParseDuration(cfg.Auth.EmailVerificationTokenDuration)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse email verification token duration: %w", err)
+	}
+
+	return &VerificationService{
+		userRepo:      userRepo,
+		authRepo:      authRepo,
+		mailer:        mailer,
+		logger:        logger,
+		publicURL:     cfg.Server.PublicURL,
+		tokenDuration: tokenDuration,
+	}, nil
+}
+
+// SendVerification issues a verification token for the given address and mails
+// it there. Previously issued links for the user stop working.
+func (s *VerificationService) SendVerification(user *model.User, email string) error {
+	if err := s.sendVerification(user, email); err != nil {
+		s.logger.Error("Failed to send verification email",
+			zap.Uint("user_id", user.ID),
+			zap.Error(err),
+		)
+		return err
+	}
+	return nil
+}
+
+// sendVerification issues and mails a verification token
+func (s *VerificationService) sendVerification(user *model.User, email string) error {
+	if err := s.authRepo.InvalidateUserEmailVerificationTokens(user.ID); err != nil {
+		return err
+	}
+
+	token, tokenHash, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	verificationToken := &model.EmailVerificationToken{
+		UserID: user.ID,
+		Email:  email,
+		SingleUseToken: model.SingleUseToken{
+			TokenHash: tokenHash,
+			ExpiresAt: time.Now().Add(s.tokenDuration),
+		},
+	}
+
+	if err := s.authRepo.CreateEmailVerificationToken(verificationToken); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(&mailer.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease confirm this email address by opening the link below. The link expires in %s.\n\n%s/verify-email?token=%s\n\nIf you did not request this you can ignore this email.",
+			user.Username, s.tokenDuration, s.publicURL, token,
+		),
+	})
+}
+
+// VerifyEmail consumes a verification token. A token for the current address marks
+// it as verified; a token for the pending address makes it the user's email.
+func (s *VerificationService) VerifyEmail(req *types.VerifyEmailRequest) (*model.User, error) {
+	if req.Token == "" {
+		return nil, types.ErrInvalidToken
+	}
+
+	verificationToken, err := s.authRepo.ConsumeEmailVerificationToken(hashToken(req.Token))
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(verificationToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	email := model.NormalizeIdentifier(verificationToken.Email)
+	switch {
+	case email == model.NormalizeIdentifier(user.Email):
+		user.EmailVerifiedAt = &now
+	case user.PendingEmail != "" && email == model.NormalizeIdentifier(user.PendingEmail):
+		// The address may have been claimed since the change was requested,
+		// also by a user who was deleted since
+		taken, err := s.userRepo.EmailTaken(user.PendingEmail, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, types.ErrUserAlreadyExists
+		}
+		user.Email = user.PendingEmail
+		user.PendingEmail = ""
+		user.EmailVerifiedAt = &now
+	default:
+		// The pending change was cancelled or replaced after the link was sent
+		return nil, types.ErrInvalidToken
+	}
+
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ResendVerification sends a new verification link to an unverified or pending
+// address. Unknown addresses are ignored so the endpoint cannot be used to
+// discover registered email addresses.
+func (s *VerificationService) ResendVerification(req *types.ResendVerificationRequest) error {
+	if req.Email == "" {
+		return types.ErrValidationFailed
+	}
+
+	user, err := s.userRepo.GetByEmail(req.Email)
+	if err == nil {
+		if user.EmailVerifiedAt != nil {
+			return nil
+		}
+		return s.SendVerification(user, user.Email)
+	}
+	if err != types.ErrUserNotFound {
+		return err
+	}
+
+	user, err = s.userRepo.GetByPendingEmail(req.Email)
+	if err != nil {
+		if err == types.ErrUserNotFound {
+			return nil
+		}
+		return err
+	}
+
+	return s.SendVerification(user, user.PendingEmail)
+}
@@ -0,0 +1,87 @@
+package service_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ray-d-song/go-echo-monolithic/internal/model"
+	"github.com/ray-d-song/go-echo-monolithic/internal/repository"
+	"github.com/ray-d-song/go-echo-monolithic/internal/service"
+	"github.com/ray-d-song/go-echo-monolithic/internal/types"
+)
+
+// verificationTestEnv holds a user who asked to change their email address
+type verificationTestEnv struct {
+	verification *service.VerificationService
+	users        *repository.UserRepository
+	auth         *repository.AuthRepository
+	user         *model.User
+}
+
+func newVerificationTestEnv(t *testing.T, pendingEmail string) *verificationTestEnv {
+	t.Helper()
+
+	env := &verificationTestEnv{}
+	newTestApp(t, &env.verification, &env.users, &env.auth)
+
+	env.user = newTestUser(t, env.users, "user")
+	env.user.PendingEmail = pendingEmail
+	if err := env.users.Update(env.user); err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+// issueToken stores a verification token for an address as the mailed link would carry
+func (e *verificationTestEnv) issueToken(t *testing.T, email string) string {
+	t.Helper()
+
+	token := "token-for-" + email
+	sum := sha256.Sum256([]byte(token))
+	if err := e.auth.CreateEmailVerificationToken(&model.EmailVerificationToken{
+		SingleUseToken: model.SingleUseToken{
+			TokenHash: hex.EncodeToString(sum[:]),
+			ExpiresAt: time.Now().Add(time.Hour),
+		},
+		UserID: e.user.ID,
+		Email:  email,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerifyEmailMatchesPendingEmailCaseInsensitively(t *testing.T) {
+	env := newVerificationTestEnv(t, "New.Address@example.com")
+	token := env.issueToken(t, "new.address@EXAMPLE.com")
+
+	user, err := env.verification.VerifyEmail(&types.VerifyEmailRequest{Token: token})
+	if err != nil {
+		t.Fatalf("VerifyEmail failed: %v", err)
+	}
+	if user.Email != "New.Address@example.com" || user.PendingEmail != "" {
+		t.Errorf("email %q, pending %q; want the pending address to replace the email", user.Email, user.PendingEmail)
+	}
+}
+
+func TestVerifyEmailRefusesAddressOfDeletedUser(t *testing.T) {
+	env := newVerificationTestEnv(t, "taken@example.com")
+	token := env.issueToken(t, "taken@example.com")
+
+	// The address was claimed by a user who was deleted since
+	other := newTestUser(t, env.users, "other")
+	other.Email = "Taken@example.com"
+	if err := env.users.Update(other); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.users.Delete(other.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := env.verification.VerifyEmail(&types.VerifyEmailRequest{Token: token}); !errors.Is(err, types.ErrUserAlreadyExists) {
+		t.Errorf("VerifyEmail returned %v, want %v", err, types.ErrUserAlreadyExists)
+	}
+}
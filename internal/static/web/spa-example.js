@@ -4,7 +4,8 @@ class SimpleRouter {
     this.routes = {
       '/': 'home',
       '/home': 'home',
-      '/about': 'about'
+      '/about': 'about',
+      '/consent': 'consent'
     };
     this.currentRoute = '/';
     this.init();
@@ -82,6 +83,10 @@ class SimpleRouter {
     if (tabName === 'about' && (!aboutContainer.hasChildNodes() || aboutContainer.innerHTML.includes('Loading'))) {
       this.renderAboutPage(aboutContainer);
     }
+
+    if (tabName === 'consent') {
+      this.renderConsentPage(document.getElementById('consent'));
+    }
   }
 
   renderHomePage(container) {
@@ -140,6 +145,162 @@ class SimpleRouter {
       </div>
     `;
   }
+
+  // Consent screen of the OAuth authorization server. /oauth/authorize sends
+  // the browser here with the ID of the pending authorization request.
+  async renderConsentPage(container) {
+    const requestId = new URLSearchParams(window.location.search).get('request');
+    if (!requestId) {
+      this.renderConsentError(container, 'Missing authorization request.');
+      return;
+    }
+
+    const token = localStorage.getItem('access_token');
+    if (!token) {
+      this.renderSignIn(container, () => this.renderConsentPage(container));
+      return;
+    }
+
+    const res = await fetch(`/api/oauth/consent/${encodeURIComponent(requestId)}`, {
+      headers: { Authorization: `Bearer ${token}` }
+    });
+    if (res.status === 401) {
+      localStorage.removeItem('access_token');
+      this.renderSignIn(container, () => this.renderConsentPage(container));
+      return;
+    }
+
+    const body = await res.json();
+    if (!res.ok) {
+      this.renderConsentError(container, this.errorMessage(body));
+      return;
+    }
+
+    const consent = body.data;
+    if (!consent.consent_required) {
+      await this.answerConsent(container, requestId, true);
+      return;
+    }
+
+    const scopes = consent.scopes
+      .map(scope => `<li>${this.escapeHTML(scope.description || scope.name)}</li>`)
+      .join('');
+
+    container.innerHTML = `
+      <h2>Authorize ${this.escapeHTML(consent.client_name)}</h2>
+
+      <div class="content-section">
+        <p>${this.escapeHTML(consent.client_name)} would like to:</p>
+        <ul>${scopes}</ul>
+      </div>
+
+      <div class="button-row">
+        <button class="button" data-answer="deny">Deny</button>
+        <button class="button primary" data-answer="approve">Allow</button>
+      </div>
+    `;
+
+    container.querySelectorAll('[data-answer]').forEach(button => {
+      button.addEventListener('click', () => {
+        this.answerConsent(container, requestId, button.getAttribute('data-answer') === 'approve');
+      });
+    });
+  }
+
+  async answerConsent(container, requestId, approve) {
+    const res = await fetch(`/api/oauth/consent/${encodeURIComponent(requestId)}`, {
+      method: 'POST',
+      headers: {
+        Authorization: `Bearer ${localStorage.getItem('access_token')}`,
+        'Content-Type': 'application/json'
+      },
+      body: JSON.stringify({ approve })
+    });
+
+    const body = await res.json();
+    if (!res.ok) {
+      this.renderConsentError(container, this.errorMessage(body));
+      return;
+    }
+
+    // Hand the authorization code or the error back to the client
+    window.location.assign(body.data.redirect_to);
+  }
+
+  // Minimal sign-in form, including the two-factor step, for users reaching
+  // the consent screen without a session
+  renderSignIn(container, onSignedIn, mfaToken = null, error = '') {
+    const fields = mfaToken
+      ? `
+        <div class="form-field">
+          <label for="signin-code">Authentication code</label>
+          <input id="signin-code" name="code" autocomplete="one-time-code" required>
+        </div>`
+      : `
+        <div class="form-field">
+          <label for="signin-username">Username</label>
+          <input id="signin-username" name="username" autocomplete="username" required>
+        </div>
+        <div class="form-field">
+          <label for="signin-password">Password</label>
+          <input id="signin-password" name="password" type="password" autocomplete="current-password" required>
+        </div>`;
+
+    container.innerHTML = `
+      <h2>Sign in</h2>
+      ${error ? `<p class="error-message">${this.escapeHTML(error)}</p>` : ''}
+      <form>
+        ${fields}
+        <div class="button-row">
+          <button class="button primary" type="submit">Continue</button>
+        </div>
+      </form>
+    `;
+
+    container.querySelector('form').addEventListener('submit', async (e) => {
+      e.preventDefault();
+      const form = new FormData(e.target);
+      const [url, payload] = mfaToken
+        ? ['/api/auth/mfa/verify', { mfa_token: mfaToken, code: form.get('code') }]
+        : ['/api/auth/login', { username: form.get('username'), password: form.get('password') }];
+
+      const res = await fetch(url, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(payload)
+      });
+      const body = await res.json();
+
+      if (!res.ok) {
+        this.renderSignIn(container, onSignedIn, mfaToken, this.errorMessage(body));
+        return;
+      }
+      if (body.data.mfa_required) {
+        this.renderSignIn(container, onSignedIn, body.data.mfa_token);
+        return;
+      }
+
+      localStorage.setItem('access_token', body.data.access_token);
+      onSignedIn();
+    });
+  }
+
+  renderConsentError(container, message) {
+    container.innerHTML = `
+      <h2>Authorization failed</h2>
+      <p class="error-message">${this.escapeHTML(message)}</p>
+    `;
+  }
+
+  errorMessage(body) {
+    return (body.error && body.error.message) || body.message || 'Something went wrong.';
+  }
+
+  escapeHTML(value) {
+    const element = document.createElement('div');
+    element.textContent = value;
+    return element.innerHTML;
+  }
 }
 
 // Initialize the router when DOM is ready
@@ -1,8 +1,12 @@
 package types
 
+// PageRequestBox is the page requested from a listing: a page number and size
+// for offset pagination, or a cursor from a previous page and a size for
+// keyset pagination
 type PageRequestBox struct {
-	Page int `json:"page"`
-	Size int `json:"size"`
+	Page   int    `json:"page"`
+	Size   int    `json:"size"`
+	Cursor string `json:"cursor,omitempty"`
 }
 
 type PageResponseBox[T any] struct {
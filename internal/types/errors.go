@@ -1,17 +1,98 @@
 package types
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 // Common error types
 var (
-	ErrUserNotFound       = errors.New("user not found")
-	ErrUserAlreadyExists  = errors.New("user already exists")
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrInvalidToken       = errors.New("invalid token")
-	ErrTokenExpired       = errors.New("token expired")
-	ErrTokenRevoked       = errors.New("token revoked")
-	ErrUnauthorized       = errors.New("unauthorized")
-	ErrForbidden          = errors.New("forbidden")
-	ErrValidationFailed   = errors.New("validation failed")
-	ErrInternalServer     = errors.New("internal server error")
-)
\ No newline at end of file
+	ErrUserNotFound        = errors.New("user not found")
+	ErrUserAlreadyExists   = errors.New("user already exists")
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrTokenExpired        = errors.New("token expired")
+	ErrTokenRevoked        = errors.New("token revoked")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrForbidden           = errors.New("forbidden")
+	ErrEmailNotVerified    = errors.New("email not verified")
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication already enabled")
+	ErrMFANotEnabled       = errors.New("two-factor authentication not enabled")
+	ErrMFANotEnrolled      = errors.New("two-factor authentication enrollment not started")
+	ErrInvalidMFACode      = errors.New("invalid two-factor authentication code")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrAccountLocked       = errors.New("account temporarily locked")
+	ErrTooManyAttempts     = errors.New("too many attempts")
+	ErrRoleNotFound        = errors.New("role not found")
+	ErrRoleAlreadyExists   = errors.New("role already exists")
+	ErrSystemRole          = errors.New("system roles cannot be renamed or deleted")
+	ErrAPIKeyNotFound      = errors.New("api key not found")
+	ErrIdentityNotFound    = errors.New("linked identity not found")
+	ErrIdentityLinked      = errors.New("identity already linked to another user")
+	ErrOAuthFailed         = errors.New("sign in with provider failed")
+	ErrRegistrationClosed  = errors.New("registration is closed")
+	ErrInvitationNotFound  = errors.New("invitation not found")
+	ErrInvalidInvitation   = errors.New("invalid or expired invitation code")
+	ErrMagicLinkDisabled   = errors.New("magic link login is disabled")
+	ErrCannotImpersonate   = errors.New("user cannot be impersonated")
+	ErrOutranked           = errors.New("user holds a permission you lack")
+	ErrRoleOutranks        = errors.New("role grants a permission you lack")
+	ErrOwnRole             = errors.New("administrators cannot change or delete a role they hold")
+	ErrOwnAccount          = errors.New("administrators cannot disable, delete or change the roles of their own account")
+	ErrUserNotDeleted      = errors.New("user is not deleted")
+	ErrInvalidCursor       = errors.New("invalid cursor")
+	ErrExportNotFound      = errors.New("data export not found")
+	ErrExportInProgress    = errors.New("a data export is already in progress")
+	ErrExportNotReady      = errors.New("data export is not ready, expired or already downloaded")
+	ErrErasureScheduled    = errors.New("account erasure already scheduled")
+	ErrErasureNotScheduled = errors.New("account erasure not scheduled")
+	ErrFileNotFound        = errors.New("file not found")
+	ErrFileTooLarge        = errors.New("file too large")
+	ErrFileTypeNotAllowed  = errors.New("file type not allowed")
+	ErrOAuthClientNotFound = errors.New("oauth client not found")
+	ErrOrgNotFound         = errors.New("organization not found")
+	ErrOrgSlugTaken        = errors.New("organization slug already taken")
+	ErrMemberNotFound      = errors.New("organization member not found")
+	ErrAlreadyMember       = errors.New("already a member of the organization")
+	ErrLastOwner           = errors.New("organizations must keep at least one owner")
+	ErrNoActiveOrg         = errors.New("no active organization")
+	ErrValidationFailed    = errors.New("validation failed")
+	ErrInternalServer      = errors.New("internal server error")
+)
+
+// RetryAfterError is returned when an operation is refused for a limited time
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", e.Err, e.RetryAfter)
+}
+
+// Unwrap returns the underlying error
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// OAuthError is an OAuth 2.0 protocol error, reported to clients with one of
+// the error codes of RFC 6749
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+// NewOAuthError creates an OAuth 2.0 protocol error
+func NewOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// Error implements the error interface
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
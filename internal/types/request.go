@@ -1,15 +1,20 @@
 package types
 
-// RegisterRequest represents user registration request
+import "time"
+
+// RegisterRequest represents user registration request. InviteCode is required
+// while self-service registration is closed.
 type RegisterRequest struct {
-	Username  string `json:"username"`
-	Email     string `json:"email"`
-	Password  string `json:"password"`
-	FirstName string `json:"first_name,omitempty"`
-	LastName  string `json:"last_name,omitempty"`
+	Username   string `json:"username"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	FirstName  string `json:"first_name,omitempty"`
+	LastName   string `json:"last_name,omitempty"`
+	InviteCode string `json:"invite_code,omitempty"`
 }
 
-// LoginRequest represents user login request
+// LoginRequest represents user login request. Username may also be the
+// user's email address; both are matched regardless of case.
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
@@ -27,7 +32,253 @@ type UpdateUserRequest struct {
 	Email     *string `json:"email,omitempty"`
 }
 
+// UserFilter narrows and orders a user listing. Query matches words of
+// usernames, emails and names by prefix; CreatedAfter is inclusive and
+// CreatedBefore exclusive. Order is asc or desc.
+type UserFilter struct {
+	Query         string
+	Role          string
+	IsActive      *bool
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Sort          string
+	Order         string
+}
+
 // LogoutRequest represents logout request
 type LogoutRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
+
+// CreateRoleRequest represents a role creation request
+type CreateRoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRoleRequest represents a role update request. Permissions, when given,
+// replace the current permissions of the role.
+type UpdateRoleRequest struct {
+	Name        *string   `json:"name,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Permissions *[]string `json:"permissions,omitempty"`
+}
+
+// AdminCreateUserRequest represents a user created by an administrator. When
+// Password is omitted the user is mailed a link to choose one; Roles default
+// to the default role and IsActive to true.
+type AdminCreateUserRequest struct {
+	Username      string   `json:"username"`
+	Email         string   `json:"email"`
+	Password      string   `json:"password,omitempty"`
+	FirstName     string   `json:"first_name,omitempty"`
+	LastName      string   `json:"last_name,omitempty"`
+	Roles         []string `json:"roles,omitempty"`
+	IsActive      *bool    `json:"is_active,omitempty"`
+	EmailVerified bool     `json:"email_verified,omitempty"`
+}
+
+// AdminUpdateUserRequest represents an administrator's changes to a user. A
+// new email takes effect immediately; it counts as verified only if
+// EmailVerified is set. Roles, when given, replace the user's roles.
+type AdminUpdateUserRequest struct {
+	Username      *string   `json:"username,omitempty"`
+	Email         *string   `json:"email,omitempty"`
+	FirstName     *string   `json:"first_name,omitempty"`
+	LastName      *string   `json:"last_name,omitempty"`
+	IsActive      *bool     `json:"is_active,omitempty"`
+	EmailVerified *bool     `json:"email_verified,omitempty"`
+	Roles         *[]string `json:"roles,omitempty"`
+}
+
+// SetUserRolesRequest represents a request replacing the roles of a user
+type SetUserRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+// CreateAPIKeyRequest represents an API key creation request. Scopes must be
+// permissions the user holds; the key never expires when ExpiresAt is omitted.
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreateInvitationRequest represents a registration invitation creation request.
+// The invitation can be used by anyone unless Email is set, grants the default
+// role unless Role is set, can be used once unless MaxUses is set and never
+// expires when ExpiresAt is omitted.
+type CreateInvitationRequest struct {
+	Email     string     `json:"email,omitempty"`
+	Role      string     `json:"role,omitempty"`
+	MaxUses   int        `json:"max_uses,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// UpdateAPIKeyRequest represents an API key update request
+type UpdateAPIKeyRequest struct {
+	Name   *string   `json:"name,omitempty"`
+	Scopes *[]string `json:"scopes,omitempty"`
+}
+
+// OAuthLinkRequest represents a request to link a social login account.
+// ReturnTo is a path of this application the browser is sent to afterwards.
+type OAuthLinkRequest struct {
+	ReturnTo string `json:"return_to,omitempty"`
+}
+
+// CreateOAuthClientRequest represents an OAuth client registration request.
+// Confidential clients are issued a secret; public clients rely on PKCE alone.
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	GrantTypes   []string `json:"grant_types"`
+	Confidential bool     `json:"confidential"`
+	Trusted      bool     `json:"trusted"`
+}
+
+// UpdateOAuthClientRequest represents an OAuth client update request
+type UpdateOAuthClientRequest struct {
+	Name         *string   `json:"name,omitempty"`
+	RedirectURIs *[]string `json:"redirect_uris,omitempty"`
+	Scopes       *[]string `json:"scopes,omitempty"`
+	GrantTypes   *[]string `json:"grant_types,omitempty"`
+	Trusted      *bool     `json:"trusted,omitempty"`
+}
+
+// OAuthAuthorizeRequest represents an OAuth 2.0 authorization request
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `query:"response_type" json:"response_type"`
+	ClientID            string `query:"client_id" json:"client_id"`
+	RedirectURI         string `query:"redirect_uri" json:"redirect_uri"`
+	Scope               string `query:"scope" json:"scope"`
+	State               string `query:"state" json:"state"`
+	Nonce               string `query:"nonce" json:"nonce"`
+	CodeChallenge       string `query:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `query:"code_challenge_method" json:"code_challenge_method"`
+}
+
+// OAuthConsentRequest represents a user's answer on the consent screen
+type OAuthConsentRequest struct {
+	Approve bool `json:"approve"`
+}
+
+// OAuthTokenRequest represents an OAuth 2.0 token request. Client credentials
+// may also be sent with HTTP Basic authentication.
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// ClientInfo describes the client making a request
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+// ForgotPasswordRequest represents a password reset link request
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest represents a password reset using a reset token
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// MagicLinkRequest represents a magic link login request
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+// MagicLinkVerifyRequest represents the exchange of a magic link for tokens
+type MagicLinkVerifyRequest struct {
+	Token string `json:"token"`
+}
+
+// ChangePasswordRequest represents a password change by a signed-in user. Other
+// sessions are signed out unless KeepOtherSessions is set.
+type ChangePasswordRequest struct {
+	CurrentPassword   string `json:"current_password"`
+	NewPassword       string `json:"new_password"`
+	KeepOtherSessions bool   `json:"keep_other_sessions"`
+}
+
+// VerifyEmailRequest represents an email verification request
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// ResendVerificationRequest represents a request to resend the email verification link
+type ResendVerificationRequest struct {
+	Email string `json:"email"`
+}
+
+// MFACodeRequest represents a request carrying a two-factor authentication code
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+// DisableMFARequest represents a request to turn off two-factor authentication
+type DisableMFARequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// ErasureRequest represents a request to erase the signed-in user's account.
+// Password is required for accounts that have one.
+type ErasureRequest struct {
+	Password string `json:"password"`
+}
+
+// MFAVerifyRequest represents the second step of a two-factor login.
+// Code may be a TOTP code or a recovery code.
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+// CreateOrgRequest represents an organization creation request. The slug is
+// derived from the name unless set.
+type CreateOrgRequest struct {
+	Name string `json:"name"`
+	Slug string `json:"slug,omitempty"`
+}
+
+// UpdateOrgRequest represents an organization update request
+type UpdateOrgRequest struct {
+	Name *string `json:"name,omitempty"`
+	Slug *string `json:"slug,omitempty"`
+}
+
+// SwitchOrgRequest represents a request to change the active organization of
+// the session. Zero leaves every organization.
+type SwitchOrgRequest struct {
+	OrgID uint `json:"org_id"`
+}
+
+// CreateOrgInvitationRequest represents an invitation to join the active
+// organization. Role defaults to member.
+type CreateOrgInvitationRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role,omitempty"`
+}
+
+// AcceptOrgInvitationRequest represents the acceptance of an invitation to an organization
+type AcceptOrgInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+// UpdateMemberRequest represents a change of the role of an organization member
+type UpdateMemberRequest struct {
+	Role string `json:"role"`
+}
@@ -5,20 +5,322 @@ import "time"
 // AuthResponse represents authentication response
 type AuthResponse struct {
 	User         *UserResponse `json:"user"`
-	AccessToken  string        `json:"access_token"`
-	RefreshToken string        `json:"refresh_token"`
+	AccessToken  string        `json:"access_token,omitempty"`
+	RefreshToken string        `json:"refresh_token,omitempty"`
+	MFARequired  bool          `json:"mfa_required,omitempty"`
+	MFAToken     string        `json:"mfa_token,omitempty"`
+}
+
+// ImpersonationResponse represents an access token for acting as another user.
+// It cannot be refreshed and expires at ExpiresAt.
+type ImpersonationResponse struct {
+	User           *UserResponse `json:"user"`
+	AccessToken    string        `json:"access_token"`
+	ExpiresAt      time.Time     `json:"expires_at"`
+	ImpersonatorID uint          `json:"impersonator_id"`
 }
 
 // UserResponse represents user response
 type UserResponse struct {
+	ID                 uint       `json:"id"`
+	Username           string     `json:"username"`
+	Email              string     `json:"email"`
+	EmailVerified      bool       `json:"email_verified"`
+	PendingEmail       string     `json:"pending_email,omitempty"`
+	FirstName          string     `json:"first_name"`
+	LastName           string     `json:"last_name"`
+	IsActive           bool       `json:"is_active"`
+	MFAEnabled         bool       `json:"mfa_enabled"`
+	Roles              []string   `json:"roles"`
+	ErasureScheduledAt *time.Time `json:"erasure_scheduled_at,omitempty"`
+	AvatarURL          string     `json:"avatar_url,omitempty"`
+	AvatarThumbnailURL string     `json:"avatar_thumbnail_url,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+// AdminUserResponse represents a user as seen by administrators, including
+// soft-deleted users
+type AdminUserResponse struct {
+	UserResponse
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	PasswordChangedAt     *time.Time `json:"password_changed_at,omitempty"`
+	DeletedAt             *time.Time `json:"deleted_at,omitempty"`
+}
+
+// RoleResponse represents a role with its permissions
+type RoleResponse struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	System      bool      `json:"system"`
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PermissionResponse represents a permission
+type PermissionResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// APIKeyResponse represents an API key without its secret
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeyCreatedResponse represents a new API key. The key is only ever shown once.
+type APIKeyCreatedResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// InvitationResponse represents a registration invitation
+type InvitationResponse struct {
+	ID          uint       `json:"id"`
+	Prefix      string     `json:"prefix"`
+	Email       string     `json:"email,omitempty"`
+	Role        string     `json:"role,omitempty"`
+	MaxUses     int        `json:"max_uses"`
+	UseCount    int        `json:"use_count"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	CreatedByID uint       `json:"created_by_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// InvitationCreatedResponse represents a new invitation. The code is only ever shown once.
+type InvitationCreatedResponse struct {
+	InvitationResponse
+	Code string `json:"code"`
+}
+
+// APIKeyPrincipal is the identity a request authenticated with an API key acts as
+type APIKeyPrincipal struct {
+	KeyID    uint
+	UserID   uint
+	Username string
+	Email    string
+	Roles    []string
+	// Permissions are the key's scopes that the user still holds
+	Permissions []string
+}
+
+// OAuthProviderResponse represents a configured social login provider
+type OAuthProviderResponse struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// AuthorizationURLResponse represents the provider URL to send the browser to
+type AuthorizationURLResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// IdentityResponse represents a social login account linked to a user
+type IdentityResponse struct {
+	ID          uint       `json:"id"`
+	Provider    string     `json:"provider"`
+	Email       string     `json:"email"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// OAuthCallbackResult is the outcome of a provider callback: a login, or a
+// newly linked identity
+type OAuthCallbackResult struct {
+	Auth     *AuthResponse
+	Identity *IdentityResponse
+	// ReturnTo is the application path the browser should be sent back to, if any
+	ReturnTo string
+}
+
+// OAuthClientResponse represents a registered OAuth client
+type OAuthClientResponse struct {
+	ID           uint      `json:"id"`
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	GrantTypes   []string  `json:"grant_types"`
+	Confidential bool      `json:"confidential"`
+	Trusted      bool      `json:"trusted"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// OAuthClientCreatedResponse represents a registered client along with its
+// secret, which is never retrievable again
+type OAuthClientCreatedResponse struct {
+	OAuthClientResponse
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// OAuthScopeResponse represents a scope a client asks the user for
+type OAuthScopeResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// OAuthConsentResponse represents a pending authorization request shown on the consent screen
+type OAuthConsentResponse struct {
+	ClientID   string                `json:"client_id"`
+	ClientName string                `json:"client_name"`
+	Scopes     []*OAuthScopeResponse `json:"scopes"`
+	// ConsentRequired is false when the user already authorized these scopes
+	// or the client is trusted, so the request can be approved right away
+	ConsentRequired bool `json:"consent_required"`
+}
+
+// OAuthRedirectResponse represents the client URL to send the browser back to
+type OAuthRedirectResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// OAuthTokenResponse represents an OAuth 2.0 token response
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// OAuthErrorResponse represents an OAuth 2.0 error response
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// UserInfoResponse represents the OpenID Connect claims about a user, limited
+// to the scopes granted to the client
+type UserInfoResponse struct {
+	Subject           string `json:"sub"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Name              string `json:"name,omitempty"`
+	GivenName         string `json:"given_name,omitempty"`
+	FamilyName        string `json:"family_name,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
+}
+
+// OpenIDConfiguration represents the OpenID Connect discovery document
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// MFAEnrollResponse represents a started two-factor enrollment
+type MFAEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// MFARecoveryCodesResponse represents freshly generated recovery codes.
+// The codes are only ever shown once.
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// FileResponse represents an uploaded file. The URLs are signed and stop
+// working at URLExpiresAt.
+type FileResponse struct {
+	ID           uint      `json:"id"`
+	Name         string    `json:"name"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	URLExpiresAt time.Time `json:"url_expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// OrgResponse represents an organization and the current user's role in it
+type OrgResponse struct {
 	ID        uint      `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	IsActive  bool      `json:"is_active"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	Role      string    `json:"role"`
+	Active    bool      `json:"active"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MemberResponse represents a member of an organization
+type MemberResponse struct {
+	UserID   uint      `json:"user_id"`
+	Username string    `json:"username"`
+	Email    string    `json:"email"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// OrgInvitationResponse represents a pending invitation to an organization
+type OrgInvitationResponse struct {
+	ID          uint      `json:"id"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	InvitedByID uint      `json:"invited_by_id"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// DataExportResponse represents an export of a user's data
+type DataExportResponse struct {
+	ID          uint       `json:"id"`
+	Status      string     `json:"status"`
+	Size        int64      `json:"size,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// ExpiresAt is when a ready export can no longer be downloaded
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	DownloadedAt *time.Time `json:"downloaded_at,omitempty"`
+}
+
+// ErasureResponse represents the scheduled erasure of an account
+type ErasureResponse struct {
+	ScheduledAt time.Time `json:"scheduled_at"`
+	// Mode is anonymize when an anonymous account is kept, delete otherwise
+	Mode string `json:"mode"`
+}
+
+// SessionResponse represents a login session on a device
+type SessionResponse struct {
+	ID         string     `json:"id"`
+	DeviceName string     `json:"device_name"`
+	UserAgent  string     `json:"user_agent"`
+	IPAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Current    bool       `json:"current"`
+}
+
+// LockoutStatusResponse represents the failed login state of an account or client IP
+type LockoutStatusResponse struct {
+	Key           string     `json:"key"`
+	Failures      int        `json:"failures"`
+	LastFailureAt *time.Time `json:"last_failure_at"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+	Locked        bool       `json:"locked"`
 }
 
 // TokenResponse represents token refresh response
@@ -27,11 +329,14 @@ type TokenResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
-// PaginatedResponse represents paginated response
+// PaginatedResponse represents paginated response. The cursors let clients
+// continue from this page with keyset pagination, whose pages have no number.
 type PaginatedResponse struct {
-	Data  interface{} `json:"data"`
-	Total int64       `json:"total"`
-	Page  int         `json:"page"`
-	Limit int         `json:"limit"`
-	Pages int64       `json:"pages"`
+	Data       interface{} `json:"data"`
+	Total      int64       `json:"total"`
+	Page       int         `json:"page,omitempty"`
+	Limit      int         `json:"limit"`
+	Pages      int64       `json:"pages"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	PrevCursor string      `json:"prev_cursor,omitempty"`
 }
\ No newline at end of file